@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"learn/internal/config"
+	"learn/internal/gateway"
+	"learn/internal/pkg/cron"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/queue"
+	"learn/internal/pkg/scheduler"
+	"learn/internal/repository"
+	"learn/internal/router"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// background is the processing that runs outside of HTTP requests: event bus consumers, the
+// outbox relay, job workers and schedulers. serve runs it next to the API unless
+// --background=false, worker runs only this.
+type background struct {
+	eventBus         events.Bus
+	outboxRelay      *events.OutboxRelay
+	jobQueue         *queue.JobQueue
+	cronScheduler    *cron.Scheduler
+	webhookScheduler *scheduler.WebhookDispatchScheduler
+	logger           *slog.Logger
+}
+
+// startBackground starts every background component. Event handlers must already be subscribed
+// to eventBus, a consumer without handlers acknowledges the events it reads.
+func startBackground(log *slog.Logger, db *gorm.DB, eventBus events.Bus, jobQueue *queue.JobQueue, cronScheduler *cron.Scheduler, gateways *gateway.Registry) *background {
+	b := &background{eventBus: eventBus, jobQueue: jobQueue, cronScheduler: cronScheduler, logger: log}
+
+	eventBus.Start()
+
+	// Domain events committed to the outbox table are relayed to the stream
+	b.outboxRelay = events.NewOutboxRelay(repository.NewOutboxRepository(db), eventBus, log)
+	go b.outboxRelay.Start()
+
+	router.RegisterJobHandlers(jobQueue, db, log, eventBus, gateways)
+	jobQueue.Start()
+
+	// Cancellation runs interrupted by a restart continue from their saved cursor
+	router.NewEventCancellationService(db, log, eventBus, jobQueue, gateways).ResumeUnfinished()
+
+	// Order expiration, payment reconciliation and the other scheduled jobs, each run on one node
+	cronScheduler.Start()
+
+	// Send organizer webhooks queued by the event handlers, retrying failed deliveries
+	b.webhookScheduler = scheduler.NewWebhookDispatchScheduler(
+		router.NewWebhookService(db, log),
+		config.AppConfig.WebhookDispatchInterval,
+		log,
+	)
+	go b.webhookScheduler.Start()
+
+	log.Info("Background processing started")
+	return b
+}
+
+// Stop stops the schedulers, then drains the job workers and the event bus consumers
+func (b *background) Stop() {
+	b.cronScheduler.Stop()
+	b.webhookScheduler.Stop()
+	b.jobQueue.Stop()
+	b.outboxRelay.Stop()
+	b.eventBus.Stop()
+
+	b.logger.Info("Background processing stopped")
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"learn/internal/config"
+	"learn/internal/database"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/logger"
+	"learn/internal/repository"
+	"learn/internal/router"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const eventsReplayBatchSize = 100
+
+var (
+	eventsTypes      []string
+	eventsTailFrom   string
+	eventsListSince  string
+	eventsListLimit  int64
+	eventsReplayFrom string
+	eventsReplayTo   string
+	eventsReplayDry  bool
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Inspect and replay the event stream",
+	Long: `This command reads events_stream directly, without the consumer group, to inspect what was
+published and to re-dispatch events to the registered event handlers.`,
+}
+
+var eventsTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Follow new events on the stream",
+	// Errors are reported once by Execute, without the usage, and exit with status 1
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logger.NewLogger()
+		config.InitConfig(log)
+		config.ConnectRedis(log)
+		defer config.Rdb.Close()
+
+		eventBus := events.NewEventBus(config.Rdb, log)
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		err := eventBus.TailStream(ctx, eventsTailFrom, func(message events.StreamMessage) {
+			if matchesEventTypes(message.EventType) {
+				printStreamMessage(cmd.OutOrStdout(), message)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to tail event stream: %w", err)
+		}
+		return nil
+	},
+}
+
+var eventsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List events on the stream, oldest first",
+	// Errors are reported once by Execute, without the usage, and exit with status 1
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logger.NewLogger()
+		config.InitConfig(log)
+		config.ConnectRedis(log)
+		defer config.Rdb.Close()
+
+		from := "-"
+		if eventsListSince != "" {
+			since, err := parseSince(eventsListSince)
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			from = events.StreamIDAt(since)
+		}
+
+		eventBus := events.NewEventBus(config.Rdb, log)
+		listed := int64(0)
+		err := scanStream(eventBus, from, "+", func(message events.StreamMessage) (bool, error) {
+			if !matchesEventTypes(message.EventType) {
+				return true, nil
+			}
+			printStreamMessage(cmd.OutOrStdout(), message)
+			listed++
+			return listed < eventsListLimit, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read event stream: %w", err)
+		}
+		return nil
+	},
+}
+
+var eventsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a single event with its payload and delivery state",
+	Args:  cobra.ExactArgs(1),
+	// Errors are reported once by Execute, without the usage, and exit with status 1
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logger.NewLogger()
+		config.InitConfig(log)
+		config.ConnectRedis(log)
+		defer config.Rdb.Close()
+
+		eventBus := events.NewEventBus(config.Rdb, log)
+		message, err := eventBus.GetStreamMessage(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get event %s: %w", args[0], err)
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "ID:           %s\n", message.ID)
+		fmt.Fprintf(out, "Type:         %s\n", message.EventType)
+		fmt.Fprintf(out, "Published at: %s\n", message.PublishedAt.Format(time.RFC3339Nano))
+		if envelope := message.Envelope; envelope != nil {
+			fmt.Fprintf(out, "Version:      %d\n", envelope.Version)
+			fmt.Fprintf(out, "Event ID:     %s\n", envelope.EventID)
+			fmt.Fprintf(out, "Correlation:  %s\n", envelope.CorrelationID)
+			if !envelope.OccurredAt.IsZero() {
+				fmt.Fprintf(out, "Occurred at:  %s\n", envelope.OccurredAt.Format(time.RFC3339Nano))
+			}
+		}
+
+		pending, err := eventBus.GetPendingInfo(message.ID)
+		switch {
+		case err != nil:
+			fmt.Fprintf(out, "Delivery:     unknown (%s)\n", err.Error())
+		case pending == nil:
+			fmt.Fprintln(out, "Delivery:     acknowledged or not delivered yet")
+		default:
+			fmt.Fprintf(out, "Delivery:     pending on %s for %s, %d failed attempt(s)\n", pending.Consumer, pending.Idle.Round(time.Second), pending.Attempts)
+		}
+
+		var payload bytes.Buffer
+		if err := json.Indent(&payload, []byte(message.Payload), "", "  "); err != nil {
+			payload.Reset()
+			payload.WriteString(message.Payload)
+		}
+		fmt.Fprintf(out, "Payload:\n%s\n", payload.String())
+		return nil
+	},
+}
+
+var eventsReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Re-dispatch events of a stream range to the registered event handlers",
+	Long: `This command runs the registered event handlers again for every event between --from and --to
+(both inclusive). Replayed events bypass the consumer group: nothing is acknowledged, retried or
+dead-lettered, and the first handler error stops the replay and makes the command exit with status 1.
+Use --dry-run to only list what would run.`,
+	// A failed replay is reported once by Execute, without the usage
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logger.NewLogger()
+		config.InitConfig(log)
+		db := database.InitDatabase(log)
+		config.ConnectRedis(log)
+		defer config.Rdb.Close()
+
+		eventBus := events.NewEventBus(config.Rdb, log)
+		router.RegisterEventHandlersWithRepos(eventBus,
+			repository.NewOrderRepository(db),
+			repository.NewPaymentRepository(db),
+			repository.NewTicketRepository(db),
+			repository.NewEventRepository(db),
+			repository.NewWebhookRepository(db),
+			log)
+
+		out := cmd.OutOrStdout()
+		replayed, skipped := 0, 0
+		err := scanStream(eventBus, eventsReplayFrom, eventsReplayTo, func(message events.StreamMessage) (bool, error) {
+			if !matchesEventTypes(message.EventType) {
+				return true, nil
+			}
+
+			handlers, err := eventBus.Redeliver(message, eventsReplayDry)
+			if errors.Is(err, events.ErrNoHandlers) {
+				fmt.Fprintf(out, "%s %s skipped: no handlers\n", message.ID, message.EventType)
+				skipped++
+				return true, nil
+			}
+			if err != nil {
+				return false, fmt.Errorf("replaying %s: %w", message.ID, err)
+			}
+
+			verb := "replayed to"
+			if eventsReplayDry {
+				verb = "would replay to"
+			}
+			fmt.Fprintf(out, "%s %s %s %d handler(s)\n", message.ID, message.EventType, verb, handlers)
+			replayed++
+			return true, nil
+		})
+
+		log.Info("Event replay finished",
+			slog.Bool("dry_run", eventsReplayDry),
+			slog.Int("replayed", replayed),
+			slog.Int("skipped", skipped))
+
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("event replay stopped: %w", err)
+		}
+		return nil
+	},
+}
+
+// scanStream walks the stream range in batches and calls visit for every message until it
+// returns false or an error
+func scanStream(eventBus *events.EventBus, from string, to string, visit func(events.StreamMessage) (bool, error)) error {
+	start := from
+	for {
+		messages, err := eventBus.ReadStream(start, to, eventsReplayBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			more, err := visit(message)
+			if err != nil || !more {
+				return err
+			}
+		}
+
+		if len(messages) < eventsReplayBatchSize {
+			return nil
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+func matchesEventTypes(eventType string) bool {
+	if len(eventsTypes) == 0 {
+		return true
+	}
+	for _, t := range eventsTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSince accepts a duration relative to now ("2h") or an RFC3339 timestamp
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a duration like 2h or an RFC3339 timestamp, got %q", value)
+	}
+	return t, nil
+}
+
+func printStreamMessage(out io.Writer, message events.StreamMessage) {
+	fmt.Fprintf(out, "%s  %s  %-24s %s\n", message.ID, message.PublishedAt.Format(time.RFC3339), message.EventType, message.Payload)
+}
+
+func init() {
+	eventsCmd.PersistentFlags().StringSliceVar(&eventsTypes, "type", nil, "only include these event types (repeatable or comma separated)")
+
+	eventsTailCmd.Flags().StringVar(&eventsTailFrom, "from", "$", "stream ID to follow from, $ only shows new events")
+
+	eventsListCmd.Flags().StringVar(&eventsListSince, "since", "", "only list events published since a duration ago (2h) or an RFC3339 timestamp")
+	eventsListCmd.Flags().Int64Var(&eventsListLimit, "limit", 100, "maximum number of events to list")
+
+	eventsReplayCmd.Flags().StringVar(&eventsReplayFrom, "from", "", "first stream ID to replay (inclusive)")
+	eventsReplayCmd.Flags().StringVar(&eventsReplayTo, "to", "+", "last stream ID to replay (inclusive), + for the end of the stream")
+	eventsReplayCmd.Flags().BoolVar(&eventsReplayDry, "dry-run", false, "only decode the events and report the handlers they would be dispatched to")
+	eventsReplayCmd.MarkFlagRequired("from")
+
+	eventsCmd.AddCommand(eventsTailCmd, eventsListCmd, eventsShowCmd, eventsReplayCmd)
+	rootCmd.AddCommand(eventsCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"learn/internal/config"
+	"learn/internal/database"
+	"learn/internal/gateway/providers"
+	"learn/internal/pkg/logger"
+	"learn/internal/router"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var reconcileOlderThan time.Duration
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Reconcile pending payments with the payment gateway",
+	Long: `This command checks payments that are still PENDING against the status API of their
+payment gateway, applies transitions whose webhook was lost and flags mismatches for admin review.
+A failed run makes the command exit with status 1.`,
+	// A failed run is reported once by Execute, without the usage
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := logger.NewLogger()
+
+		config.InitConfig(log)
+		db := database.InitDatabase(log)
+		config.ConnectRedis(log)
+		defer func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+			if config.Rdb != nil {
+				config.Rdb.Close()
+			}
+		}()
+
+		gateways := providers.NewRegistry(log)
+
+		olderThan := config.AppConfig.PaymentReconcileAfter
+		if cmd.Flags().Changed("older-than") {
+			olderThan = reconcileOlderThan
+		}
+
+		summary, err := router.NewPaymentReconciliationService(db, log, gateways).Reconcile(olderThan)
+		if err != nil {
+			return fmt.Errorf("payment reconciliation failed: %w", err)
+		}
+
+		log.Info("Payment reconciliation completed",
+			slog.Int("checked", summary.Checked),
+			slog.Int("applied", summary.Applied),
+			slog.Int("unchanged", summary.Unchanged),
+			slog.Int("flagged", summary.Flagged),
+			slog.Int("failed", summary.Failed),
+			slog.String("duration", summary.Duration))
+		return nil
+	},
+}
+
+func init() {
+	reconcileCmd.Flags().DurationVar(&reconcileOlderThan, "older-than", 15*time.Minute, "only check payments pending for longer than this (defaults to PAYMENT_RECONCILE_AFTER)")
+	rootCmd.AddCommand(reconcileCmd)
+}
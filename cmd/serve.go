@@ -5,6 +5,7 @@ import (
 	"errors"
 	"learn/internal/config"
 	"learn/internal/database"
+	"learn/internal/gateway/providers"
 	"learn/internal/model"
 	"learn/internal/pkg/events"
 	"learn/internal/pkg/logger"
@@ -22,10 +23,13 @@ import (
 	"gorm.io/gorm"
 )
 
+var serveBackground bool
+
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Starts the API server",
-	Long:  `This command starts the HTTP API server for the application.`,
+	Long: `This command starts the HTTP API server for the application. Event consumers, job workers and
+schedulers run in the same process unless --background=false, in which case the worker command runs them.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// 1. Initialize Logger
 		log := logger.NewLogger()
@@ -35,13 +39,11 @@ var serveCmd = &cobra.Command{
 		db := database.InitDatabase(log)
 		config.ConnectRedis(log)
 
-		// 3. Initialize event bus with Redis for Streams
-		eventBus := events.NewEventBus(config.Rdb, log)
-		eventBus.Start()
+		// 3. Initialize event bus, Redis Streams unless EVENT_BUS_DRIVER=memory
+		eventBus := events.NewBus(config.Rdb, log)
 
-		// 4. Initialize and start job queue
-		jobQueue := queue.NewJobQueue(5, log)
-		jobQueue.Start()
+		// 4. Initialize the Redis job queue, its workers run with the background processing
+		jobQueue := queue.NewJobQueue(config.Rdb, log)
 
 		// Environment-based migration
 		env := config.AppConfig.AppEnv
@@ -52,7 +54,9 @@ var serveCmd = &cobra.Command{
 			// Auto migrate only in development/testing
 			db.AutoMigrate(&model.User{}, &model.Venue{}, &model.Guest{}, &model.Event{},
 				&model.EventPrice{}, &model.EventGuest{}, &model.Order{}, &model.Ticket{},
-				&model.Payment{}, &model.OrderLineItem{})
+				&model.Payment{}, &model.OrderLineItem{}, &model.TicketScan{}, &model.TicketTransfer{},
+				&model.Refund{}, &model.RefundTicket{}, &model.EventCancellation{}, &model.PaymentReconciliationIssue{}, &model.OutboxEvent{},
+				&model.WebhookEndpoint{}, &model.WebhookDelivery{}, &model.SchedulerRun{}, &model.WaitlistEntry{})
 
 			log.Info("Auto-migration completed for development environment")
 		} else {
@@ -64,10 +68,36 @@ var serveCmd = &cobra.Command{
 			seed.SeedUsers(db, log)
 		}
 
-		// 5. Setup Router with dependencies
-		r := router.SetupRouter(log, db, eventBus)
+		// 5. Payment providers, resolved by payment method when charging and by name afterwards
+		gateways := providers.NewRegistry(log)
+
+		// 6. Scheduled jobs, they run with the background processing and admins can trigger them from the API
+		cronScheduler, err := router.NewCronScheduler(db, log, gateways)
+		if err != nil {
+			log.Error("failed to register scheduler jobs", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		// 7. Live attendance dashboards read every ticket scan themselves, with or without the background processing
+		attendanceHub := events.NewAttendanceHub(log)
+		attendanceHub.Start(eventBus)
+
+		// 8. Setup Router with dependencies, this also subscribes the event handlers
+		r := router.SetupRouter(log, db, eventBus, jobQueue, cronScheduler, gateways, attendanceHub)
+
+		// 9. Event consumers, job workers and schedulers, unless they run in a separate worker
+		var bg *background
+		if serveBackground {
+			bg = startBackground(log, db, eventBus, jobQueue, cronScheduler, gateways)
+		} else if config.AppConfig.EventBusDriver == "memory" {
+			// In-memory events never leave this process, so they are still handled here
+			log.Warn("EVENT_BUS_DRIVER=memory: event handlers keep running in serve with --background=false")
+			eventBus.Start()
+		} else {
+			log.Info("Background processing disabled, run the worker command to process events, jobs and schedules")
+		}
 
-		// 6. Run Server with graceful shutdown
+		// 10. Run Server with graceful shutdown
 		srv := &http.Server{
 			Addr:    ":8080",
 			Handler: r,
@@ -95,8 +125,12 @@ var serveCmd = &cobra.Command{
 			log.Info("Server shutdown completed")
 		}
 
-		jobQueue.Stop()
-		eventBus.Stop()
+		attendanceHub.Stop()
+		if bg != nil {
+			bg.Stop()
+		} else {
+			eventBus.Stop()
+		}
 
 		if sqlDB, err := db.DB(); err == nil {
 			if err := sqlDB.Close(); err != nil {
@@ -131,5 +165,6 @@ func createEnums(db *gorm.DB) {
 }
 
 func init() {
+	serveCmd.Flags().BoolVar(&serveBackground, "background", true, "Run event consumers, job workers and schedulers in this process")
 	rootCmd.AddCommand(serveCmd)
 }
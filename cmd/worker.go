@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"learn/internal/config"
+	"learn/internal/database"
+	"learn/internal/gateway/providers"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/logger"
+	"learn/internal/pkg/queue"
+	"learn/internal/pkg/response"
+	"learn/internal/repository"
+	"learn/internal/router"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+const workerHealthTimeout = 2 * time.Second
+
+var workerHealthAddr string
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Runs event consumers, job workers and schedulers without the HTTP API",
+	Long: `This command runs the background processing of the application on its own: event bus
+consumers, the outbox relay, job workers and schedulers. Run serve with --background=false next to it
+to scale the API and the workers independently. GET /health on --health-addr reports whether the
+database and Redis are reachable.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log := logger.NewLogger()
+
+		config.InitConfig(log)
+		db := database.InitDatabase(log)
+		config.ConnectRedis(log)
+
+		if config.AppConfig.EventBusDriver == "memory" {
+			log.Warn("EVENT_BUS_DRIVER=memory: this worker only receives events published by itself, events of the API are handled by serve")
+		}
+
+		eventBus := events.NewBus(config.Rdb, log)
+		router.RegisterEventHandlersWithRepos(eventBus,
+			repository.NewOrderRepository(db),
+			repository.NewPaymentRepository(db),
+			repository.NewTicketRepository(db),
+			repository.NewEventRepository(db),
+			repository.NewWebhookRepository(db),
+			log)
+
+		gateways := providers.NewRegistry(log)
+		jobQueue := queue.NewJobQueue(config.Rdb, log)
+		cronScheduler, err := router.NewCronScheduler(db, log, gateways)
+		if err != nil {
+			log.Error("failed to register scheduler jobs", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		bg := startBackground(log, db, eventBus, jobQueue, cronScheduler, gateways)
+
+		var stopping atomic.Bool
+		srv := &http.Server{
+			Addr:    workerHealthAddr,
+			Handler: workerHealthHandler(db, &stopping),
+		}
+
+		go func() {
+			log.Info("Starting worker health endpoint", slog.String("addr", workerHealthAddr))
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to run worker health endpoint", slog.String("error", err.Error()))
+			}
+		}()
+
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(quit)
+		<-quit
+
+		// Report unhealthy while draining, the health endpoint stays up until the workers stopped
+		log.Info("Shutting down worker")
+		stopping.Store(true)
+		bg.Stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Error("worker health endpoint forced to shutdown", slog.String("error", err.Error()))
+		}
+
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				log.Error("failed to close database connection", slog.String("error", err.Error()))
+			}
+		}
+		if config.Rdb != nil {
+			if err := config.Rdb.Close(); err != nil {
+				log.Error("failed to close Redis connection", slog.String("error", err.Error()))
+			}
+		}
+
+		log.Info("Worker shutdown completed")
+	},
+}
+
+// workerHealthHandler answers GET /health with 200 while the database and Redis respond, and 503
+// when one of them does not or the worker is shutting down
+func workerHealthHandler(db *gorm.DB, stopping *atomic.Bool) http.Handler {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.GET("/health", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), workerHealthTimeout)
+		defer cancel()
+
+		checks := map[string]string{"database": "ok", "redis": "ok"}
+		healthy := true
+
+		if sqlDB, err := db.DB(); err != nil {
+			checks["database"] = err.Error()
+			healthy = false
+		} else if err := sqlDB.PingContext(ctx); err != nil {
+			checks["database"] = err.Error()
+			healthy = false
+		}
+		if err := config.Rdb.Ping(ctx).Err(); err != nil {
+			checks["redis"] = err.Error()
+			healthy = false
+		}
+
+		if stopping.Load() {
+			response.SendServiceUnavailableError(c, "Worker is shutting down", checks)
+			return
+		}
+		if !healthy {
+			response.SendServiceUnavailableError(c, "Worker dependencies are unavailable", checks)
+			return
+		}
+		response.SendSuccess(c, http.StatusOK, "Worker is healthy", checks)
+	})
+	return r
+}
+
+func init() {
+	workerCmd.Flags().StringVar(&workerHealthAddr, "health-addr", ":8081", "Address of the health endpoint")
+	rootCmd.AddCommand(workerCmd)
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -26,11 +28,54 @@ type Config struct {
 	RedisDB       int    `mapstructure:"REDIS_DB"`
 	AppEnv        string `mapstructure:"APP_ENV"`
 
+	EventBusDriver    string        `mapstructure:"EVENT_BUS_DRIVER"` // redis or memory
+	EventConsumerName string        `mapstructure:"EVENT_CONSUMER_NAME"`
+	EventMaxAttempts  int           `mapstructure:"EVENT_MAX_ATTEMPTS"`
+	EventRetryBackoff time.Duration `mapstructure:"EVENT_RETRY_BACKOFF"`
+	EventClaimMinIdle time.Duration `mapstructure:"EVENT_CLAIM_MIN_IDLE"` // Messages of other consumers idle this long are taken over
+
+	JobWorkers           int           `mapstructure:"JOB_WORKERS"`
+	JobMaxAttempts       int           `mapstructure:"JOB_MAX_ATTEMPTS"`
+	JobRetryBackoff      time.Duration `mapstructure:"JOB_RETRY_BACKOFF"`
+	JobVisibilityTimeout time.Duration `mapstructure:"JOB_VISIBILITY_TIMEOUT"` // Running jobs without a heartbeat for this long are run again
+	JobShutdownTimeout   time.Duration `mapstructure:"JOB_SHUTDOWN_TIMEOUT"`
+
 	MidtransServerKey string `mapstructure:"MIDTRANS_SERVER_KEY"`
 	MidtransEnv       string `mapstructure:"MIDTRANS_ENV"`
 
+	XenditSecretKey     string `mapstructure:"XENDIT_SECRET_KEY"`
+	XenditCallbackToken string `mapstructure:"XENDIT_CALLBACK_TOKEN"`
+	XenditBaseURL       string `mapstructure:"XENDIT_BASE_URL"`
+
+	PaymentReconcileInterval time.Duration `mapstructure:"PAYMENT_RECONCILE_INTERVAL"`
+	PaymentReconcileAfter    time.Duration `mapstructure:"PAYMENT_RECONCILE_AFTER"`
+
+	OrderHoldWindow    time.Duration `mapstructure:"ORDER_HOLD_WINDOW"`    // How long a new order reserves its quota before a payment is started
+	OrderHoldExtension time.Duration `mapstructure:"ORDER_HOLD_EXTENSION"` // Added once when the buyer extends the hold
+	PaymentDueDefault  time.Duration `mapstructure:"PAYMENT_DUE_DEFAULT"`
+	PaymentDueByMethod string        `mapstructure:"PAYMENT_DUE_BY_METHOD"` // Comma separated METHOD=duration overrides of PAYMENT_DUE_DEFAULT
+
+	OrderExpirationSchedule string        `mapstructure:"ORDER_EXPIRATION_SCHEDULE"`
+	SchedulerRunRetention   time.Duration `mapstructure:"SCHEDULER_RUN_RETENTION"` // How long recorded scheduler runs are kept
+
+	WebhookDispatchInterval time.Duration `mapstructure:"WEBHOOK_DISPATCH_INTERVAL"`
+	WebhookMaxAttempts      int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryBackoff     time.Duration `mapstructure:"WEBHOOK_RETRY_BACKOFF"`
+	WebhookDisableAfter     int           `mapstructure:"WEBHOOK_DISABLE_AFTER"` // Consecutive failed attempts before an endpoint is disabled
+
 	StorageQRPath string `mapstructure:"STORAGE_QR_PATH"`
 
+	TicketQRSecret        string `mapstructure:"TICKET_QR_SECRET"`
+	TicketAllowPlainCodes bool   `mapstructure:"TICKET_ALLOW_PLAIN_CODES"`
+
+	WaitingRoomSecret         string        `mapstructure:"WAITING_ROOM_SECRET"`
+	WaitingRoomAdmitPerMinute int           `mapstructure:"WAITING_ROOM_ADMIT_PER_MINUTE"` // Default rate of events that do not set their own
+	WaitingRoomAdmissionTTL   time.Duration `mapstructure:"WAITING_ROOM_ADMISSION_TTL"`    // How long an admitted user may start an order
+	WaitingRoomAdmitSchedule  string        `mapstructure:"WAITING_ROOM_ADMIT_SCHEDULE"`
+
+	WaitlistOfferWindow   time.Duration `mapstructure:"WAITLIST_OFFER_WINDOW"` // How long a waitlist offer holds its quota
+	WaitlistOfferSchedule string        `mapstructure:"WAITLIST_OFFER_SCHEDULE"`
+
 	SMTPHost      string `mapstructure:"SMTP_HOST"`
 	SMTPPort      int    `mapstructure:"SMTP_PORT"`
 	SMTPUser      string `mapstructure:"SMTP_USER"`
@@ -58,11 +103,54 @@ func InitConfig(logger *slog.Logger) {
 	v.SetDefault("REDIS_PASSWORD", "")
 	v.SetDefault("REDIS_DB", 0)
 
+	v.SetDefault("EVENT_BUS_DRIVER", "redis")
+	v.SetDefault("EVENT_CONSUMER_NAME", "")
+	v.SetDefault("EVENT_MAX_ATTEMPTS", 5)
+	v.SetDefault("EVENT_RETRY_BACKOFF", 5*time.Second)
+	v.SetDefault("EVENT_CLAIM_MIN_IDLE", 5*time.Minute)
+
+	v.SetDefault("JOB_WORKERS", 5)
+	v.SetDefault("JOB_MAX_ATTEMPTS", 5)
+	v.SetDefault("JOB_RETRY_BACKOFF", 10*time.Second)
+	v.SetDefault("JOB_VISIBILITY_TIMEOUT", 5*time.Minute)
+	v.SetDefault("JOB_SHUTDOWN_TIMEOUT", 30*time.Second)
+
 	v.SetDefault("MIDTRANS_SERVER_KEY", "")
 	v.SetDefault("MIDTRANS_ENV", "sandbox")
 
+	v.SetDefault("XENDIT_SECRET_KEY", "")
+	v.SetDefault("XENDIT_CALLBACK_TOKEN", "")
+	v.SetDefault("XENDIT_BASE_URL", "https://api.xendit.co")
+
+	v.SetDefault("PAYMENT_RECONCILE_INTERVAL", 10*time.Minute)
+	v.SetDefault("PAYMENT_RECONCILE_AFTER", 15*time.Minute)
+
+	v.SetDefault("ORDER_HOLD_WINDOW", 10*time.Minute)
+	v.SetDefault("ORDER_HOLD_EXTENSION", 5*time.Minute)
+	v.SetDefault("PAYMENT_DUE_DEFAULT", 24*time.Hour)
+	v.SetDefault("PAYMENT_DUE_BY_METHOD", "GOPAY=15m,CREDIT_CARD=1h,PAYPAL=1h")
+
+	v.SetDefault("ORDER_EXPIRATION_SCHEDULE", "@every 30s")
+	v.SetDefault("SCHEDULER_RUN_RETENTION", 7*24*time.Hour)
+
+	v.SetDefault("WEBHOOK_DISPATCH_INTERVAL", 5*time.Second)
+	v.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	v.SetDefault("WEBHOOK_RETRY_BACKOFF", 30*time.Second)
+	v.SetDefault("WEBHOOK_DISABLE_AFTER", 20)
+
 	v.SetDefault("STORAGE_QR_PATH", "./storage/qrcodes")
 
+	v.SetDefault("TICKET_QR_SECRET", "")
+	v.SetDefault("TICKET_ALLOW_PLAIN_CODES", true)
+
+	v.SetDefault("WAITING_ROOM_SECRET", "")
+	v.SetDefault("WAITING_ROOM_ADMIT_PER_MINUTE", 100)
+	v.SetDefault("WAITING_ROOM_ADMISSION_TTL", 10*time.Minute)
+	v.SetDefault("WAITING_ROOM_ADMIT_SCHEDULE", "@every 5s")
+
+	v.SetDefault("WAITLIST_OFFER_WINDOW", 30*time.Minute)
+	v.SetDefault("WAITLIST_OFFER_SCHEDULE", "@every 15s")
+
 	v.SetDefault("SMTP_HOST", "sandbox.smtp.mailtrap.io")
 	v.SetDefault("SMTP_PORT", 2525)
 	v.SetDefault("SMTP_USER", "")
@@ -83,5 +171,43 @@ func InitConfig(logger *slog.Logger) {
 		os.Exit(1)
 	}
 
+	dueByMethod, err := parsePaymentDueByMethod(AppConfig.PaymentDueByMethod)
+	if err != nil {
+		logger.Error("invalid PAYMENT_DUE_BY_METHOD", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	paymentDueByMethod = dueByMethod
+
 	logger.Info("Configuration loaded successfully")
 }
+
+// paymentDueByMethod holds the parsed PAYMENT_DUE_BY_METHOD
+var paymentDueByMethod map[string]time.Duration
+
+// PaymentDueFor returns how long a payment of the given method may stay pending
+func PaymentDueFor(method string) time.Duration {
+	if due, ok := paymentDueByMethod[method]; ok {
+		return due
+	}
+	return AppConfig.PaymentDueDefault
+}
+
+func parsePaymentDueByMethod(value string) (map[string]time.Duration, error) {
+	dueByMethod := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, duration, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not METHOD=duration", entry)
+		}
+		due, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || due <= 0 {
+			return nil, fmt.Errorf("invalid duration for %s: %q", method, duration)
+		}
+		dueByMethod[strings.ToUpper(strings.TrimSpace(method))] = due
+	}
+	return dueByMethod, nil
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"io"
+	"learn/internal/dto"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attendanceSummaryInterval is how often the attendance stream pushes a fresh summary,
+// which also keeps idle connections open through proxies
+const attendanceSummaryInterval = 15 * time.Second
+
+type AttendanceController interface {
+	GetAttendance(c *gin.Context)
+	StreamAttendance(c *gin.Context)
+}
+
+type attendanceController struct {
+	attendanceService service.AttendanceService
+	attendanceHub     *events.AttendanceHub
+	logger            *slog.Logger
+}
+
+func NewAttendanceController(attendanceService service.AttendanceService, attendanceHub *events.AttendanceHub, logger *slog.Logger) AttendanceController {
+	return &attendanceController{attendanceService: attendanceService, attendanceHub: attendanceHub, logger: logger}
+}
+
+func (ctrl *attendanceController) GetAttendance(c *gin.Context) {
+	event, err := ctrl.attendanceService.GetEvent(c.Param("slug"))
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "get attendance")
+		return
+	}
+
+	summary, err := ctrl.attendanceService.GetAttendanceSummary(event)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get attendance")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Attendance retrieved successfully", summary)
+}
+
+// StreamAttendance streams Server-Sent Events: a "summary" on connect and every
+// attendanceSummaryInterval, and a "scan" for every scan of the event
+func (ctrl *attendanceController) StreamAttendance(c *gin.Context) {
+	event, err := ctrl.attendanceService.GetEvent(c.Param("slug"))
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "stream attendance")
+		return
+	}
+
+	summary, err := ctrl.attendanceService.GetAttendanceSummary(event)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "stream attendance")
+		return
+	}
+
+	scans, unsubscribe := ctrl.attendanceHub.Subscribe(event.ID)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(attendanceSummaryInterval)
+	defer ticker.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("summary", summary)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case scan, ok := <-scans:
+			if !ok {
+				return false
+			}
+			c.SSEvent("scan", dto.ToAttendanceScanMessage(scan))
+			return true
+		case <-ticker.C:
+			summary, err := ctrl.attendanceService.GetAttendanceSummary(event)
+			if err != nil {
+				// Keep the stream open, the next tick retries
+				return true
+			}
+			c.SSEvent("summary", summary)
+			return true
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeadLetterController interface {
+	ListDeadLetters(c *gin.Context)
+	ReplayDeadLetter(c *gin.Context)
+	DiscardDeadLetter(c *gin.Context)
+}
+
+type deadLetterController struct {
+	deadLetterService service.DeadLetterService
+	logger            *slog.Logger
+}
+
+func NewDeadLetterController(deadLetterService service.DeadLetterService, logger *slog.Logger) DeadLetterController {
+	return &deadLetterController{deadLetterService: deadLetterService, logger: logger}
+}
+
+func (ctrl *deadLetterController) ListDeadLetters(c *gin.Context) {
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "50"))
+
+	deadLetters, err := ctrl.deadLetterService.ListDeadLetters(c.Query("after"), count)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "list dead letters")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Dead letters retrieved successfully", deadLetters)
+}
+
+func (ctrl *deadLetterController) ReplayDeadLetter(c *gin.Context) {
+	deadLetter, err := ctrl.deadLetterService.ReplayDeadLetter(c.Param("id"))
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "replay dead letter")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Dead letter replayed successfully", deadLetter)
+}
+
+func (ctrl *deadLetterController) DiscardDeadLetter(c *gin.Context) {
+	if err := ctrl.deadLetterService.DiscardDeadLetter(c.Param("id")); err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "discard dead letter")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Dead letter discarded successfully", nil)
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"learn/internal/model"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventCancellationController interface {
+	GetCancellation(c *gin.Context)
+	ResumeCancellation(c *gin.Context)
+}
+
+type eventCancellationController struct {
+	cancellationService service.EventCancellationService
+	logger              *slog.Logger
+}
+
+func NewEventCancellationController(cancellationService service.EventCancellationService, logger *slog.Logger) EventCancellationController {
+	return &eventCancellationController{cancellationService: cancellationService, logger: logger}
+}
+
+func (ctrl *eventCancellationController) GetCancellation(c *gin.Context) {
+	cancellation, err := ctrl.cancellationService.GetCancellation(c.Param("slug"))
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "get event cancellation")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Event cancellation retrieved successfully", cancellation)
+}
+
+func (ctrl *eventCancellationController) ResumeCancellation(c *gin.Context) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return
+	}
+
+	cancellation, err := ctrl.cancellationService.ResumeCancellation(c.Request.Context(), c.Param("slug"), user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "resume event cancellation")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusAccepted, "Event cancellation resumed", cancellation)
+}
@@ -157,15 +157,36 @@ func (ctrl *eventController) UpdateEvent(c *gin.Context) {
 		return
 	}
 
-	event, err := ctrl.eventService.UpdateEvent(slug, input)
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	event, err := ctrl.eventService.UpdateEvent(c.Request.Context(), slug, input, user.ID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			response.SendNotFoundError(c, "Event not found")
 			return
 		}
-		response.SendInternalServerError(c, ctrl.logger, err)
+		response.HandleAppError(c, err, ctrl.logger, "update event")
 		return
 	}
 
 	response.SendSuccess(c, http.StatusOK, "Event updated successfully", dto.ToEventResponse(*event))
 }
+
+func (ctrl *eventController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FailedJobController interface {
+	GetStats(c *gin.Context)
+	ListFailedJobs(c *gin.Context)
+	RetryFailedJob(c *gin.Context)
+	DeleteFailedJob(c *gin.Context)
+}
+
+type failedJobController struct {
+	failedJobService service.FailedJobService
+	logger           *slog.Logger
+}
+
+func NewFailedJobController(failedJobService service.FailedJobService, logger *slog.Logger) FailedJobController {
+	return &failedJobController{failedJobService: failedJobService, logger: logger}
+}
+
+func (ctrl *failedJobController) GetStats(c *gin.Context) {
+	stats, err := ctrl.failedJobService.GetStats(c.Request.Context())
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get job queue stats")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Job queue stats retrieved successfully", stats)
+}
+
+func (ctrl *failedJobController) ListFailedJobs(c *gin.Context) {
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	count, _ := strconv.ParseInt(c.DefaultQuery("count", "50"), 10, 64)
+
+	jobs, err := ctrl.failedJobService.ListFailedJobs(c.Request.Context(), offset, count)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "list failed jobs")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Failed jobs retrieved successfully", jobs)
+}
+
+func (ctrl *failedJobController) RetryFailedJob(c *gin.Context) {
+	job, err := ctrl.failedJobService.RetryFailedJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "retry failed job")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Failed job queued again", job)
+}
+
+func (ctrl *failedJobController) DeleteFailedJob(c *gin.Context) {
+	if err := ctrl.failedJobService.DeleteFailedJob(c.Request.Context(), c.Param("id")); err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "delete failed job")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Failed job deleted successfully", nil)
+}
@@ -60,7 +60,7 @@ func (ctrl *orderCancellationController) CancelOrder(c *gin.Context) {
 		req.Reason = "Manual cancellation by user"
 	}
 
-	err = ctrl.orderCancellationService.CancelOrder(uint(orderID), userModel.ID, req.Reason)
+	err = ctrl.orderCancellationService.CancelOrder(c.Request.Context(), uint(orderID), userModel.ID, req.Reason)
 	if err != nil {
 		// Handle different types of errors appropriately
 		switch appErr := err.(type) {
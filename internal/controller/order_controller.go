@@ -3,25 +3,33 @@ package controller
 import (
 	"learn/internal/dto"
 	"learn/internal/model"
+	"learn/internal/pkg/filters"
+	"learn/internal/pkg/pagination"
 	"learn/internal/pkg/request"
 	"learn/internal/pkg/response"
 	"learn/internal/service"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type orderController struct {
 	orderService service.OrderService
 	logger       *slog.Logger
+	db           *gorm.DB
 }
 type OrderController interface {
 	CreateOrder(c *gin.Context)
+	GetMyOrders(c *gin.Context)
+	GetOrderByID(c *gin.Context)
+	ExtendHold(c *gin.Context)
 }
 
-func NewOrderController(orderService service.OrderService, logger *slog.Logger) OrderController {
-	return &orderController{orderService: orderService, logger: logger}
+func NewOrderController(orderService service.OrderService, logger *slog.Logger, db *gorm.DB) OrderController {
+	return &orderController{orderService: orderService, logger: logger, db: db}
 }
 
 func (ctrl *orderController) CreateOrder(c *gin.Context) {
@@ -36,7 +44,7 @@ func (ctrl *orderController) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	order, err := ctrl.orderService.CreateOrder(input, user.(model.User).ID)
+	order, err := ctrl.orderService.CreateOrder(c.Request.Context(), input, user.(model.User).ID)
 	if err != nil {
 		// Handle different types of errors appropriately
 		response.HandleAppError(c, err, ctrl.logger, "create order")
@@ -45,3 +53,78 @@ func (ctrl *orderController) CreateOrder(c *gin.Context) {
 
 	response.SendSuccess(c, http.StatusCreated, "Order created successfully", dto.ToOrderResponse(*order))
 }
+
+func (ctrl *orderController) GetMyOrders(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	var orders []model.Order
+	db := ctrl.db.Where("user_id = ?", user.(model.User).ID).
+		Preload("OrderLineItems.EventPrice.Event").
+		Order("created_at DESC")
+
+	filterFuncs := []filters.FilterFunc{
+		filters.WithStatus(),
+		filters.WithDataRange("created_at"),
+	}
+
+	db = filters.ApplyFilter(db, c, filterFuncs...)
+
+	paginatedResult, err := pagination.Paginate(c, db, &model.Order{}, &orders)
+	if err != nil {
+		response.SendInternalServerError(c, ctrl.logger, err)
+		return
+	}
+
+	paginatedResult.Data = dto.ToOrderSummaryResponses(orders)
+
+	response.SendSuccess(c, http.StatusOK, "Orders retrieved successfully", paginatedResult)
+}
+
+func (ctrl *orderController) GetOrderByID(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid order ID")
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	order, err := ctrl.orderService.GetOrderDetail(uint(orderID), user.(model.User).ID)
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "get order detail")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Order retrieved successfully", order)
+}
+
+// ExtendHold extends the reservation of a pending order once, before a payment is started
+func (ctrl *orderController) ExtendHold(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid order ID")
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	order, err := ctrl.orderService.ExtendHold(c.Request.Context(), uint(orderID), user.(model.User).ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "extend order hold")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Order hold extended", dto.ToOrderResponse(*order))
+}
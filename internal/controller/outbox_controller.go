@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OutboxController interface {
+	GetStats(c *gin.Context)
+}
+
+type outboxController struct {
+	outboxService service.OutboxService
+	logger        *slog.Logger
+}
+
+func NewOutboxController(outboxService service.OutboxService, logger *slog.Logger) OutboxController {
+	return &outboxController{outboxService: outboxService, logger: logger}
+}
+
+func (ctrl *outboxController) GetStats(c *gin.Context) {
+	stats, err := ctrl.outboxService.GetStats()
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get outbox stats")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Outbox stats retrieved successfully", stats)
+}
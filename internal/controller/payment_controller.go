@@ -2,6 +2,8 @@ package controller
 
 import (
 	"learn/internal/dto"
+	"learn/internal/gateway/midtrans"
+	"learn/internal/gateway/xendit"
 	"learn/internal/model"
 	"learn/internal/pkg/request"
 	"learn/internal/pkg/response"
@@ -25,7 +27,8 @@ type PaymentController interface {
 	UpdatePayment(c *gin.Context)
 	UpdatePaymentStatus(c *gin.Context)
 	DeletePayment(c *gin.Context)
-	HandleNotification(c *gin.Context)
+	HandleMidtransNotification(c *gin.Context)
+	HandleXenditNotification(c *gin.Context)
 }
 
 func NewPaymentController(paymentService service.PaymentService, logger *slog.Logger) PaymentController {
@@ -47,7 +50,7 @@ func (ctrl *paymentController) CreatePayment(c *gin.Context) {
 		return
 	}
 
-	payment, err := ctrl.paymentService.CreatePayment(&req, user.(model.User).ID)
+	payment, err := ctrl.paymentService.CreatePayment(c.Request.Context(), &req, user.(model.User).ID)
 	if err != nil {
 		// Handle different types of errors appropriately
 		response.HandleAppError(c, err, ctrl.logger, "create payment")
@@ -179,7 +182,7 @@ func (ctrl *paymentController) UpdatePaymentStatus(c *gin.Context) {
 		return
 	}
 
-	payment, err := ctrl.paymentService.UpdatePaymentStatus(uint(paymentID), req.Status)
+	payment, err := ctrl.paymentService.UpdatePaymentStatus(c.Request.Context(), uint(paymentID), req.Status)
 	if err != nil {
 		// Handle different types of errors appropriately
 		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "update payment status")
@@ -222,19 +225,26 @@ func (ctrl *paymentController) DeletePayment(c *gin.Context) {
 	response.SendSuccess(c, http.StatusOK, "Payment deleted successfully", nil)
 }
 
-func (ctrl *paymentController) HandleNotification(c *gin.Context) {
-	var notificationPayload map[string]interface{}
-	if !request.BindJSONOrError(c, &notificationPayload, ctrl.logger, "handle notification") {
+func (ctrl *paymentController) HandleMidtransNotification(c *gin.Context) {
+	ctrl.handleNotification(c, midtrans.ProviderName)
+}
+
+func (ctrl *paymentController) HandleXenditNotification(c *gin.Context) {
+	ctrl.handleNotification(c, xendit.ProviderName)
+}
+
+// handleNotification passes the raw webhook to the provider, which verifies the signature over it
+func (ctrl *paymentController) handleNotification(c *gin.Context, provider string) {
+	body, err := c.GetRawData()
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid notification body")
 		return
 	}
 
-	err := ctrl.paymentService.HandleNotification(notificationPayload)
+	err = ctrl.paymentService.HandleNotification(c.Request.Context(), provider, c.Request.Header, body)
 	if err != nil {
-		// Log error but generally return OK to Midtrans unless it's a critical error where retry is needed
-		// But usually we return 200 OK after processing
-		// If application error, we might want to return 500 to trigger retry?
-		// Midtrans retries on non-2xx.
-		response.HandleAppError(c, err, ctrl.logger, "handle notification")
+		// Providers retry on non-2xx, so only errors worth retrying should end up as 5xx
+		response.HandleAppError(c, err, ctrl.logger, "handle "+provider+" notification")
 		return
 	}
 
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"learn/internal/config"
+	"learn/internal/dto"
+	"learn/internal/model"
+	"learn/internal/pkg/filters"
+	"learn/internal/pkg/pagination"
+	"learn/internal/pkg/request"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PaymentReconciliationController interface {
+	RunReconciliation(c *gin.Context)
+	GetIssues(c *gin.Context)
+	ResolveIssue(c *gin.Context)
+}
+
+type paymentReconciliationController struct {
+	reconciliationService service.PaymentReconciliationService
+	logger                *slog.Logger
+	db                    *gorm.DB
+}
+
+func NewPaymentReconciliationController(reconciliationService service.PaymentReconciliationService, logger *slog.Logger, db *gorm.DB) PaymentReconciliationController {
+	return &paymentReconciliationController{reconciliationService: reconciliationService, logger: logger, db: db}
+}
+
+// RunReconciliation reconciles pending payments on demand with the configured age threshold
+func (ctrl *paymentReconciliationController) RunReconciliation(c *gin.Context) {
+	summary, err := ctrl.reconciliationService.Reconcile(config.AppConfig.PaymentReconcileAfter)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "run payment reconciliation")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Payment reconciliation completed", summary)
+}
+
+// GetIssues paginates the review queue, newest first, filtered by status and creation date
+func (ctrl *paymentReconciliationController) GetIssues(c *gin.Context) {
+	var issues []model.PaymentReconciliationIssue
+	db := ctrl.db.Preload("Payment").Order("created_at DESC")
+
+	filterFuncs := []filters.FilterFunc{
+		filters.WithStatus(),
+		filters.WithDataRange("created_at"),
+	}
+
+	db = filters.ApplyFilter(db, c, filterFuncs...)
+
+	paginatedResult, err := pagination.Paginate(c, db, &model.PaymentReconciliationIssue{}, &issues)
+	if err != nil {
+		response.SendInternalServerError(c, ctrl.logger, err)
+		return
+	}
+
+	paginatedResult.Data = dto.ToReconciliationIssueResponses(issues)
+
+	response.SendSuccess(c, http.StatusOK, "Reconciliation issues retrieved successfully", paginatedResult)
+}
+
+func (ctrl *paymentReconciliationController) ResolveIssue(c *gin.Context) {
+	issueID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid reconciliation issue ID")
+		return
+	}
+
+	var input dto.ResolveReconciliationIssueRequest
+	if !request.BindJSONOrError(c, &input, ctrl.logger, "resolve reconciliation issue") {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	issue, err := ctrl.reconciliationService.ResolveIssue(uint(issueID), input, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "resolve reconciliation issue")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Reconciliation issue resolved successfully", issue)
+}
+
+func (ctrl *paymentReconciliationController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"learn/internal/dto"
+	"learn/internal/pkg/request"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentSimulatorController interface {
+	SimulateNotification(c *gin.Context)
+}
+
+type paymentSimulatorController struct {
+	simulatorService service.PaymentSimulatorService
+	logger           *slog.Logger
+}
+
+func NewPaymentSimulatorController(simulatorService service.PaymentSimulatorService, logger *slog.Logger) PaymentSimulatorController {
+	return &paymentSimulatorController{simulatorService: simulatorService, logger: logger}
+}
+
+func (ctrl *paymentSimulatorController) SimulateNotification(c *gin.Context) {
+	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid payment ID")
+		return
+	}
+
+	var req dto.SimulatePaymentNotificationRequest
+	if !request.BindJSONOrError(c, &req, ctrl.logger, "simulate payment notification") {
+		return
+	}
+
+	payment, err := ctrl.simulatorService.SimulateNotification(c.Request.Context(), uint(paymentID), req.TransactionStatus)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "simulate payment notification")
+		return
+	}
+
+	paymentResponse := dto.PaymentResponse{
+		PaymentID:            payment.ID,
+		OrderID:              payment.OrderID,
+		PaymentMethod:        payment.PaymentMethod,
+		TransactionID:        payment.TransactionID,
+		Amount:               int64(payment.Order.TotalPrice),
+		PaymentStatus:        payment.PaymentStatus,
+		PaymentDate:          payment.PaymentDate,
+		PaymentURL:           payment.PaymentURL,
+		VirtualAccountNumber: payment.VirtualAccountNumber,
+		BillKey:              payment.BillKey,
+		BillerCode:           payment.BillerCode,
+		PaymentCode:          payment.PaymentCode,
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Simulated notification processed", paymentResponse)
+}
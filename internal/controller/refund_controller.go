@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"learn/internal/dto"
+	"learn/internal/model"
+	"learn/internal/pkg/filters"
+	"learn/internal/pkg/pagination"
+	"learn/internal/pkg/request"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RefundController interface {
+	RequestRefund(c *gin.Context)
+	GetMyRefunds(c *gin.Context)
+	GetAllRefunds(c *gin.Context)
+	GetRefundByID(c *gin.Context)
+	ApproveRefund(c *gin.Context)
+	RejectRefund(c *gin.Context)
+	SettleRefund(c *gin.Context)
+}
+
+type refundController struct {
+	refundService service.RefundService
+	logger        *slog.Logger
+	db            *gorm.DB
+}
+
+func NewRefundController(refundService service.RefundService, logger *slog.Logger, db *gorm.DB) RefundController {
+	return &refundController{refundService: refundService, logger: logger, db: db}
+}
+
+func (ctrl *refundController) RequestRefund(c *gin.Context) {
+	var input dto.CreateRefundRequest
+	if !request.BindJSONOrError(c, &input, ctrl.logger, "request refund") {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	refund, err := ctrl.refundService.RequestRefund(input, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "request refund")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusCreated, "Refund requested successfully", refund)
+}
+
+func (ctrl *refundController) GetMyRefunds(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	ctrl.listRefunds(c, ctrl.db.Where("requested_by_user_id = ?", user.ID))
+}
+
+func (ctrl *refundController) GetAllRefunds(c *gin.Context) {
+	ctrl.listRefunds(c, ctrl.db)
+}
+
+// listRefunds paginates refunds, newest first, filtered by status and creation date
+func (ctrl *refundController) listRefunds(c *gin.Context, db *gorm.DB) {
+	var refunds []model.Refund
+	db = db.Preload("Tickets.Ticket").Order("created_at DESC")
+
+	filterFuncs := []filters.FilterFunc{
+		filters.WithStatus(),
+		filters.WithDataRange("created_at"),
+	}
+
+	db = filters.ApplyFilter(db, c, filterFuncs...)
+
+	paginatedResult, err := pagination.Paginate(c, db, &model.Refund{}, &refunds)
+	if err != nil {
+		response.SendInternalServerError(c, ctrl.logger, err)
+		return
+	}
+
+	paginatedResult.Data = dto.ToRefundResponses(refunds)
+
+	response.SendSuccess(c, http.StatusOK, "Refunds retrieved successfully", paginatedResult)
+}
+
+func (ctrl *refundController) GetRefundByID(c *gin.Context) {
+	refundID, ok := ctrl.refundID(c)
+	if !ok {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	refund, err := ctrl.refundService.GetRefund(refundID, user)
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "get refund")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Refund retrieved successfully", refund)
+}
+
+func (ctrl *refundController) ApproveRefund(c *gin.Context) {
+	ctrl.review(c, "approve refund", "Refund approved successfully", func(refundID uint, input dto.ReviewRefundRequest, reviewerID uint) (*dto.RefundResponse, error) {
+		return ctrl.refundService.ApproveRefund(c.Request.Context(), refundID, input, reviewerID)
+	})
+}
+
+func (ctrl *refundController) RejectRefund(c *gin.Context) {
+	ctrl.review(c, "reject refund", "Refund rejected successfully", ctrl.refundService.RejectRefund)
+}
+
+func (ctrl *refundController) review(c *gin.Context, operation string, message string,
+	action func(refundID uint, input dto.ReviewRefundRequest, reviewerID uint) (*dto.RefundResponse, error)) {
+	refundID, ok := ctrl.refundID(c)
+	if !ok {
+		return
+	}
+
+	var input dto.ReviewRefundRequest
+	if c.Request.ContentLength > 0 && !request.BindJSONOrError(c, &input, ctrl.logger, operation) {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	refund, err := action(refundID, input, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, operation)
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, message, refund)
+}
+
+func (ctrl *refundController) SettleRefund(c *gin.Context) {
+	refundID, ok := ctrl.refundID(c)
+	if !ok {
+		return
+	}
+
+	var input dto.SettleRefundRequest
+	if !request.BindJSONOrError(c, &input, ctrl.logger, "settle refund") {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	refund, err := ctrl.refundService.SettleRefund(refundID, input, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "settle refund")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Refund settled successfully", refund)
+}
+
+func (ctrl *refundController) refundID(c *gin.Context) (uint, bool) {
+	refundID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid refund ID")
+		return 0, false
+	}
+	return uint(refundID), true
+}
+
+func (ctrl *refundController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
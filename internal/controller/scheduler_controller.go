@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"learn/internal/dto"
+	"learn/internal/model"
+	"learn/internal/pkg/filters"
+	"learn/internal/pkg/pagination"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SchedulerController interface {
+	ListJobs(c *gin.Context)
+	TriggerJob(c *gin.Context)
+	GetRuns(c *gin.Context)
+}
+
+type schedulerController struct {
+	schedulerService service.SchedulerService
+	logger           *slog.Logger
+	db               *gorm.DB
+}
+
+func NewSchedulerController(schedulerService service.SchedulerService, logger *slog.Logger, db *gorm.DB) SchedulerController {
+	return &schedulerController{schedulerService: schedulerService, logger: logger, db: db}
+}
+
+func (ctrl *schedulerController) ListJobs(c *gin.Context) {
+	jobs, err := ctrl.schedulerService.ListJobs(c.Request.Context())
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "list scheduler jobs")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Scheduler jobs retrieved successfully", jobs)
+}
+
+// TriggerJob asks for a run as soon as possible, the run itself shows up in the job's run history
+func (ctrl *schedulerController) TriggerJob(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.schedulerService.TriggerJob(c.Request.Context(), c.Param("name"), user.ID); err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "trigger scheduler job")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusAccepted, "Scheduler job triggered", nil)
+}
+
+// GetRuns paginates the recorded runs of a job, newest first, filtered by status and creation date
+func (ctrl *schedulerController) GetRuns(c *gin.Context) {
+	var runs []model.SchedulerRun
+	db := ctrl.db.Where("job_name = ?", c.Param("name")).Order("created_at DESC")
+
+	filterFuncs := []filters.FilterFunc{
+		filters.WithStatus(),
+		filters.WithDataRange("created_at"),
+	}
+
+	db = filters.ApplyFilter(db, c, filterFuncs...)
+
+	paginatedResult, err := pagination.Paginate(c, db, &model.SchedulerRun{}, &runs)
+	if err != nil {
+		response.SendInternalServerError(c, ctrl.logger, err)
+		return
+	}
+
+	paginatedResult.Data = dto.ToSchedulerRunResponses(runs)
+
+	response.SendSuccess(c, http.StatusOK, "Scheduler runs retrieved successfully", paginatedResult)
+}
+
+func (ctrl *schedulerController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
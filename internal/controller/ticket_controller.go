@@ -3,26 +3,39 @@ package controller
 import (
 	"learn/internal/dto"
 	"learn/internal/model"
+	"learn/internal/pkg/constants"
+	"learn/internal/pkg/filters"
+	"learn/internal/pkg/pagination"
 	"learn/internal/pkg/request"
 	"learn/internal/pkg/response"
 	"learn/internal/service"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type TicketController interface {
 	CheckInTicket(c *gin.Context)
+	GetMyTickets(c *gin.Context)
+	GetMyTicket(c *gin.Context)
+	GetTicketQRCode(c *gin.Context)
+	GetCheckInManifest(c *gin.Context)
+	SyncOfflineScans(c *gin.Context)
+	GetEventScans(c *gin.Context)
+	GetTicketScans(c *gin.Context)
 }
 
 type ticketController struct {
 	ticketService service.TicketService
 	logger        *slog.Logger
+	db            *gorm.DB
 }
 
-func NewTicketController(ticketService service.TicketService, logger *slog.Logger) TicketController {
-	return &ticketController{ticketService: ticketService, logger: logger}
+func NewTicketController(ticketService service.TicketService, logger *slog.Logger, db *gorm.DB) TicketController {
+	return &ticketController{ticketService: ticketService, logger: logger, db: db}
 }
 
 func (ctrl *ticketController) CheckInTicket(c *gin.Context) {
@@ -43,7 +56,7 @@ func (ctrl *ticketController) CheckInTicket(c *gin.Context) {
 		return
 	}
 
-	result, err := ctrl.ticketService.CheckInTicket(input, user.ID)
+	result, err := ctrl.ticketService.CheckInTicket(c.Request.Context(), input, user.ID)
 	if err != nil {
 		response.HandleAppError(c, err, ctrl.logger, "check in ticket")
 		return
@@ -51,3 +64,150 @@ func (ctrl *ticketController) CheckInTicket(c *gin.Context) {
 
 	response.SendSuccess(c, http.StatusOK, "Ticket checked in successfully", result)
 }
+
+func (ctrl *ticketController) GetMyTickets(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	groups, err := ctrl.ticketService.GetMyTickets(user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get my tickets")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Tickets retrieved successfully", groups)
+}
+
+func (ctrl *ticketController) GetMyTicket(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := ctrl.ticketService.GetMyTicket(c.Param("code"), user.ID)
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "get ticket")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Ticket retrieved successfully", ticket)
+}
+
+func (ctrl *ticketController) GetTicketQRCode(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	qrPath, err := ctrl.ticketService.GetTicketQRCodePath(c.Param("code"), user.ID)
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "get ticket QR code")
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Type", "image/png")
+	c.File(qrPath)
+}
+
+func (ctrl *ticketController) GetCheckInManifest(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	deviceID := c.Query("device_id")
+	if deviceID == "" {
+		response.SendBadRequestError(c, "device_id query parameter is required")
+		return
+	}
+
+	manifest, err := ctrl.ticketService.GetCheckInManifest(c.Param("event_slug"), deviceID, user.ID)
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "get check-in manifest")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Check-in manifest generated successfully", manifest)
+}
+
+func (ctrl *ticketController) SyncOfflineScans(c *gin.Context) {
+	var input dto.SyncOfflineScansRequest
+	if !request.BindJSONOrError(c, &input, ctrl.logger, "sync offline scans") {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	result, err := ctrl.ticketService.SyncOfflineScans(c.Request.Context(), input, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "sync offline scans")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Offline scans synced successfully", result)
+}
+
+func (ctrl *ticketController) GetEventScans(c *gin.Context) {
+	db := ctrl.db.
+		Joins("JOIN events ON events.id = ticket_scans.event_id").
+		Where("events.slug = ?", c.Param("event_slug"))
+
+	ctrl.listScans(c, db)
+}
+
+func (ctrl *ticketController) GetTicketScans(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("ticket_id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid ticket ID")
+		return
+	}
+
+	ctrl.listScans(c, ctrl.db.Where("ticket_scans.ticket_id = ?", ticketID))
+}
+
+// listScans paginates the scan audit log, newest scan first
+func (ctrl *ticketController) listScans(c *gin.Context, db *gorm.DB) {
+	var scans []model.TicketScan
+	db = db.Order("ticket_scans.scanned_at DESC")
+
+	filterFuncs := []filters.FilterFunc{
+		filters.WithEquals(constants.FilterScanResult, "ticket_scans.result"),
+		filters.WithEquals(constants.FilterScanDirection, "ticket_scans.direction"),
+		filters.WithEquals(constants.FilterScanGate, "ticket_scans.gate"),
+		filters.WithDataRange("ticket_scans.scanned_at"),
+	}
+
+	db = filters.ApplyFilter(db, c, filterFuncs...)
+
+	paginatedResult, err := pagination.Paginate(c, db, &model.TicketScan{}, &scans)
+	if err != nil {
+		response.SendInternalServerError(c, ctrl.logger, err)
+		return
+	}
+
+	paginatedResult.Data = dto.ToTicketScanResponses(scans)
+
+	response.SendSuccess(c, http.StatusOK, "Ticket scans retrieved successfully", paginatedResult)
+}
+
+func (ctrl *ticketController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"learn/internal/dto"
+	"learn/internal/model"
+	"learn/internal/pkg/request"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TicketTransferController interface {
+	StartTransfer(c *gin.Context)
+	CancelTransfer(c *gin.Context)
+	GetIncomingTransfers(c *gin.Context)
+	AcceptTransfer(c *gin.Context)
+}
+
+type ticketTransferController struct {
+	transferService service.TicketTransferService
+	logger          *slog.Logger
+}
+
+func NewTicketTransferController(transferService service.TicketTransferService, logger *slog.Logger) TicketTransferController {
+	return &ticketTransferController{transferService: transferService, logger: logger}
+}
+
+func (ctrl *ticketTransferController) StartTransfer(c *gin.Context) {
+	var input dto.StartTicketTransferRequest
+	if !request.BindJSONOrError(c, &input, ctrl.logger, "start ticket transfer") {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := ctrl.transferService.StartTransfer(c.Param("code"), input, user)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "start ticket transfer")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusCreated, "Ticket transfer started successfully", transfer)
+}
+
+func (ctrl *ticketTransferController) CancelTransfer(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.transferService.CancelTransfer(c.Param("code"), user.ID); err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "cancel ticket transfer")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Ticket transfer cancelled successfully", nil)
+}
+
+func (ctrl *ticketTransferController) GetIncomingTransfers(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	transfers, err := ctrl.transferService.GetIncomingTransfers(user)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get incoming ticket transfers")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Ticket transfers retrieved successfully", transfers)
+}
+
+func (ctrl *ticketTransferController) AcceptTransfer(c *gin.Context) {
+	transferID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid transfer ID")
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := ctrl.transferService.AcceptTransfer(c.Request.Context(), uint(transferID), user)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "accept ticket transfer")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Ticket transfer accepted successfully", ticket)
+}
+
+func (ctrl *ticketTransferController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"learn/internal/model"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WaitingRoomController interface {
+	Join(c *gin.Context)
+	GetStatus(c *gin.Context)
+}
+
+type waitingRoomController struct {
+	waitingRoomService service.WaitingRoomService
+	logger             *slog.Logger
+}
+
+func NewWaitingRoomController(waitingRoomService service.WaitingRoomService, logger *slog.Logger) WaitingRoomController {
+	return &waitingRoomController{waitingRoomService: waitingRoomService, logger: logger}
+}
+
+func (ctrl *waitingRoomController) Join(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := ctrl.waitingRoomService.Join(c.Request.Context(), c.Param("slug"), user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "join waiting room")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Joined waiting room successfully", status)
+}
+
+// GetStatus is polled by the client until the state is ADMITTED
+func (ctrl *waitingRoomController) GetStatus(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := ctrl.waitingRoomService.GetStatus(c.Request.Context(), c.Param("slug"), user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get waiting room status")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Waiting room status retrieved successfully", status)
+}
+
+func (ctrl *waitingRoomController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"learn/internal/dto"
+	"learn/internal/model"
+	"learn/internal/pkg/request"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WaitlistController interface {
+	Join(c *gin.Context)
+	GetMyEntries(c *gin.Context)
+	Leave(c *gin.Context)
+	GetSummary(c *gin.Context)
+}
+
+type waitlistController struct {
+	waitlistService service.WaitlistService
+	logger          *slog.Logger
+}
+
+func NewWaitlistController(waitlistService service.WaitlistService, logger *slog.Logger) WaitlistController {
+	return &waitlistController{waitlistService: waitlistService, logger: logger}
+}
+
+func (ctrl *waitlistController) Join(c *gin.Context) {
+	var input dto.JoinWaitlistRequest
+	if !request.BindJSONOrError(c, &input, ctrl.logger, "join waitlist") {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	entry, err := ctrl.waitlistService.Join(c.Request.Context(), input, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "join waitlist")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusCreated, "Joined waitlist successfully", dto.ToWaitlistEntryResponse(*entry))
+}
+
+func (ctrl *waitlistController) GetMyEntries(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	entries, err := ctrl.waitlistService.GetMyEntries(user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get waitlist entries")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Waitlist entries retrieved successfully", dto.ToWaitlistEntryResponses(entries))
+}
+
+func (ctrl *waitlistController) Leave(c *gin.Context) {
+	entryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid waitlist entry ID")
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	entry, err := ctrl.waitlistService.Leave(c.Request.Context(), uint(entryID), user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "leave waitlist")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Left waitlist successfully", dto.ToWaitlistEntryResponse(*entry))
+}
+
+func (ctrl *waitlistController) GetSummary(c *gin.Context) {
+	summary, err := ctrl.waitlistService.GetSummary(c.Param("slug"))
+	if err != nil {
+		response.HandleAppErrorWithNotFound(c, err, ctrl.logger, "get waitlist summary")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Waitlist summary retrieved successfully", summary)
+}
+
+func (ctrl *waitlistController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"learn/internal/dto"
+	"learn/internal/model"
+	"learn/internal/pkg/filters"
+	"learn/internal/pkg/pagination"
+	"learn/internal/pkg/request"
+	"learn/internal/pkg/response"
+	"learn/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookController interface {
+	CreateEndpoint(c *gin.Context)
+	GetEndpoints(c *gin.Context)
+	GetEndpoint(c *gin.Context)
+	UpdateEndpoint(c *gin.Context)
+	DeleteEndpoint(c *gin.Context)
+	GetDeliveries(c *gin.Context)
+	RedeliverDelivery(c *gin.Context)
+}
+
+type webhookController struct {
+	webhookService service.WebhookService
+	logger         *slog.Logger
+	db             *gorm.DB
+}
+
+func NewWebhookController(webhookService service.WebhookService, logger *slog.Logger, db *gorm.DB) WebhookController {
+	return &webhookController{webhookService: webhookService, logger: logger, db: db}
+}
+
+func (ctrl *webhookController) CreateEndpoint(c *gin.Context) {
+	var input dto.CreateWebhookEndpointRequest
+	if !request.BindJSONOrError(c, &input, ctrl.logger, "create webhook endpoint") {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	endpoint, err := ctrl.webhookService.CreateEndpoint(input, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "create webhook endpoint")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusCreated, "Webhook endpoint created successfully, store the secret now as it is not shown again", endpoint)
+}
+
+func (ctrl *webhookController) GetEndpoints(c *gin.Context) {
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	endpoints, err := ctrl.webhookService.GetEndpoints(user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get webhook endpoints")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Webhook endpoints retrieved successfully", endpoints)
+}
+
+func (ctrl *webhookController) GetEndpoint(c *gin.Context) {
+	endpointID, ok := ctrl.endpointID(c)
+	if !ok {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	endpoint, err := ctrl.webhookService.GetEndpoint(endpointID, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get webhook endpoint")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Webhook endpoint retrieved successfully", endpoint)
+}
+
+func (ctrl *webhookController) UpdateEndpoint(c *gin.Context) {
+	endpointID, ok := ctrl.endpointID(c)
+	if !ok {
+		return
+	}
+
+	var input dto.UpdateWebhookEndpointRequest
+	if !request.BindJSONOrError(c, &input, ctrl.logger, "update webhook endpoint") {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	endpoint, err := ctrl.webhookService.UpdateEndpoint(endpointID, input, user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "update webhook endpoint")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Webhook endpoint updated successfully", endpoint)
+}
+
+func (ctrl *webhookController) DeleteEndpoint(c *gin.Context) {
+	endpointID, ok := ctrl.endpointID(c)
+	if !ok {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.webhookService.DeleteEndpoint(endpointID, user.ID); err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "delete webhook endpoint")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Webhook endpoint deleted successfully", nil)
+}
+
+// GetDeliveries paginates the delivery log of an endpoint, newest first, filtered by status and creation date
+func (ctrl *webhookController) GetDeliveries(c *gin.Context) {
+	endpointID, ok := ctrl.endpointID(c)
+	if !ok {
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if _, err := ctrl.webhookService.GetEndpoint(endpointID, user.ID); err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "get webhook deliveries")
+		return
+	}
+
+	var deliveries []model.WebhookDelivery
+	db := ctrl.db.Where("endpoint_id = ?", endpointID).Order("created_at DESC")
+
+	filterFuncs := []filters.FilterFunc{
+		filters.WithStatus(),
+		filters.WithDataRange("created_at"),
+	}
+
+	db = filters.ApplyFilter(db, c, filterFuncs...)
+
+	paginatedResult, err := pagination.Paginate(c, db, &model.WebhookDelivery{}, &deliveries)
+	if err != nil {
+		response.SendInternalServerError(c, ctrl.logger, err)
+		return
+	}
+
+	paginatedResult.Data = dto.ToWebhookDeliveryResponses(deliveries)
+
+	response.SendSuccess(c, http.StatusOK, "Webhook deliveries retrieved successfully", paginatedResult)
+}
+
+func (ctrl *webhookController) RedeliverDelivery(c *gin.Context) {
+	endpointID, ok := ctrl.endpointID(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid webhook delivery ID")
+		return
+	}
+
+	user, ok := ctrl.currentUser(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := ctrl.webhookService.RedeliverDelivery(endpointID, uint(deliveryID), user.ID)
+	if err != nil {
+		response.HandleAppError(c, err, ctrl.logger, "redeliver webhook delivery")
+		return
+	}
+
+	response.SendSuccess(c, http.StatusOK, "Webhook delivery queued for redelivery", delivery)
+}
+
+func (ctrl *webhookController) endpointID(c *gin.Context) (uint, bool) {
+	endpointID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.SendBadRequestError(c, "Invalid webhook endpoint ID")
+		return 0, false
+	}
+	return uint(endpointID), true
+}
+
+func (ctrl *webhookController) currentUser(c *gin.Context) (model.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		response.SendUnauthorizedError(c, "User not authenticated")
+		return model.User{}, false
+	}
+
+	user, ok := userCtx.(model.User)
+	if !ok {
+		response.SendUnauthorizedError(c, "Invalid user context")
+		return model.User{}, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"learn/internal/pkg/events"
+	"time"
+)
+
+type TierAttendanceResponse struct {
+	EventPriceID uint   `json:"event_price_id"`
+	Name         string `json:"name"`
+	Quota        int    `json:"quota"`
+	Sold         int64  `json:"sold"`
+	CheckedIn    int64  `json:"checked_in"`
+	Inside       int64  `json:"inside"`
+}
+
+type ScanRateResponse struct {
+	Minute   time.Time `json:"minute"`
+	Accepted int64     `json:"accepted"`
+	Rejected int64     `json:"rejected"`
+}
+
+type AttendanceSummaryResponse struct {
+	EventID          uint                     `json:"event_id"`
+	EventSlug        string                   `json:"event_slug"`
+	EventName        string                   `json:"event_name"`
+	Sold             int64                    `json:"sold"`
+	CheckedIn        int64                    `json:"checked_in"`
+	Inside           int64                    `json:"inside"`
+	Tiers            []TierAttendanceResponse `json:"tiers"`
+	ScansPerMinute   []ScanRateResponse       `json:"scans_per_minute"` // Oldest minute first, minutes without scans are omitted
+	RecentRejections []TicketScanResponse     `json:"recent_rejections"`
+	GeneratedAt      time.Time                `json:"generated_at"`
+}
+
+// AttendanceScanMessage is the payload of a "scan" message on the attendance stream
+type AttendanceScanMessage struct {
+	ScanID       uint      `json:"scan_id"`
+	TicketID     uint      `json:"ticket_id,omitempty"`
+	EventPriceID uint      `json:"event_price_id,omitempty"`
+	Direction    string    `json:"direction"`
+	Result       string    `json:"result"`
+	Reason       string    `json:"reason,omitempty"`
+	Gate         string    `json:"gate,omitempty"`
+	DeviceID     string    `json:"device_id,omitempty"`
+	Offline      bool      `json:"offline"`
+	ScannedAt    time.Time `json:"scanned_at"`
+}
+
+func ToAttendanceScanMessage(event events.TicketScannedEvent) AttendanceScanMessage {
+	return AttendanceScanMessage{
+		ScanID:       event.ScanID,
+		TicketID:     event.TicketID,
+		EventPriceID: event.EventPriceID,
+		Direction:    string(event.Direction),
+		Result:       string(event.Result),
+		Reason:       event.Reason,
+		Gate:         event.Gate,
+		DeviceID:     event.DeviceID,
+		Offline:      event.Offline,
+		ScannedAt:    event.ScannedAt,
+	}
+}
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"learn/internal/pkg/events"
+	"time"
+)
+
+type DeadLetterResponse struct {
+	ID            string    `json:"id"`
+	EventType     string    `json:"event_type"`
+	Version       int       `json:"version"` // Schema version of payload, before upcasting
+	EventID       string    `json:"event_id"`
+	CorrelationID string    `json:"correlation_id"`
+	Payload       string    `json:"payload"`
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	OriginalID    string    `json:"original_id"` // Message ID in the event stream
+	Consumer      string    `json:"consumer"`
+	FailedAt      time.Time `json:"failed_at"`
+}
+
+// DeadLetterListResponse is a page of the dead-letter stream, continue with after=next_after
+type DeadLetterListResponse struct {
+	Total       int64                `json:"total"`
+	DeadLetters []DeadLetterResponse `json:"dead_letters"`
+	NextAfter   string               `json:"next_after,omitempty"`
+}
+
+func ToDeadLetterResponse(deadLetter events.DeadLetter) DeadLetterResponse {
+	return DeadLetterResponse{
+		ID:            deadLetter.ID,
+		EventType:     deadLetter.EventType,
+		Version:       deadLetter.Version,
+		EventID:       deadLetter.EventID,
+		CorrelationID: deadLetter.CorrelationID,
+		Payload:       deadLetter.Payload,
+		Error:         deadLetter.Error,
+		Attempts:      deadLetter.Attempts,
+		OriginalID:    deadLetter.OriginalID,
+		Consumer:      deadLetter.Consumer,
+		FailedAt:      deadLetter.FailedAt,
+	}
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"learn/internal/model"
+	"time"
+)
+
+type EventCancellationResponse struct {
+	ID                uint                          `json:"id"`
+	EventID           uint                          `json:"event_id"`
+	EventSlug         string                        `json:"event_slug"`
+	RequestedByUserID uint                          `json:"requested_by_user_id"`
+	Reason            string                        `json:"reason,omitempty"`
+	Status            model.EventCancellationStatus `json:"status"`
+	TotalOrders       int                           `json:"total_orders"`
+	ProcessedOrders   int                           `json:"processed_orders"`
+	CancelledOrders   int                           `json:"cancelled_orders"`
+	RefundedOrders    int                           `json:"refunded_orders"`
+	FailedOrders      int                           `json:"failed_orders"`
+	TicketsVoided     int                           `json:"tickets_voided"`
+	EmailsSent        int                           `json:"emails_sent"`
+	LastError         string                        `json:"last_error,omitempty"`
+	StartedAt         time.Time                     `json:"started_at"`
+	UpdatedAt         time.Time                     `json:"updated_at"`
+	CompletedAt       *time.Time                    `json:"completed_at,omitempty"`
+}
+
+func ToEventCancellationResponse(cancellation model.EventCancellation) EventCancellationResponse {
+	return EventCancellationResponse{
+		ID:                cancellation.ID,
+		EventID:           cancellation.EventID,
+		EventSlug:         cancellation.Event.Slug,
+		RequestedByUserID: cancellation.RequestedByUserID,
+		Reason:            cancellation.Reason,
+		Status:            cancellation.Status,
+		TotalOrders:       cancellation.TotalOrders,
+		ProcessedOrders:   cancellation.ProcessedOrders,
+		CancelledOrders:   cancellation.CancelledOrders,
+		RefundedOrders:    cancellation.RefundedOrders,
+		FailedOrders:      cancellation.FailedOrders,
+		TicketsVoided:     cancellation.TicketsVoided,
+		EmailsSent:        cancellation.EmailsSent,
+		LastError:         cancellation.LastError,
+		StartedAt:         cancellation.CreatedAt,
+		UpdatedAt:         cancellation.UpdatedAt,
+		CompletedAt:       cancellation.CompletedAt,
+	}
+}
@@ -17,27 +17,40 @@ type PriceInput struct {
 }
 
 type CreateEventInput struct {
-	VenueID        uint              `json:"venue_id" binding:"required"`
-	Name           string            `json:"name" binding:"required"`
-	Description    string            `json:"description"`
-	EventStartAt   time.Time         `json:"event_start_at" binding:"required"`
-	Status         model.EventStatus `json:"status,omitempty"`
-	SalesStartDate time.Time         `json:"sales_start_date,omitempty"`
-	SalesEndDate   time.Time         `json:"sales_end_date,omitempty"`
-	Guests         []GuestInput      `json:"guests"`
-	Prices         []PriceInput      `json:"prices"`
+	VenueID                   uint              `json:"venue_id" binding:"required"`
+	Name                      string            `json:"name" binding:"required"`
+	Description               string            `json:"description"`
+	EventStartAt              time.Time         `json:"event_start_at" binding:"required"`
+	Status                    model.EventStatus `json:"status,omitempty"`
+	SalesStartDate            time.Time         `json:"sales_start_date,omitempty"`
+	SalesEndDate              time.Time         `json:"sales_end_date,omitempty"`
+	AllowReentry              bool              `json:"allow_reentry"`
+	TransfersDisabled         bool              `json:"transfers_disabled"`
+	TransferCutoffHours       *int              `json:"transfer_cutoff_hours,omitempty" binding:"omitempty,min=0"` // Defaults to model.DefaultTransferCutoffHours
+	PaymentDueMinutes         *int              `json:"payment_due_minutes,omitempty" binding:"omitempty,min=1"`   // Caps the payment window of every payment method
+	WaitingRoomEnabled        bool              `json:"waiting_room_enabled"`
+	WaitingRoomAdmitPerMinute int               `json:"waiting_room_admit_per_minute,omitempty" binding:"omitempty,min=0"` // 0 uses the server default
+	Guests                    []GuestInput      `json:"guests"`
+	Prices                    []PriceInput      `json:"prices"`
 }
 
 type UpdateEventInput struct {
-	VenueID        *uint              `json:"venue_id,omitempty"`
-	Name           *string            `json:"name,omitempty"`
-	Description    *string            `json:"description,omitempty"`
-	EventStartAt   *time.Time         `json:"event_start_at,omitempty"`
-	Status         *model.EventStatus `json:"status,omitempty"`
-	SalesStartDate *time.Time         `json:"sales_start_date,omitempty"`
-	SalesEndDate   *time.Time         `json:"sales_end_date,omitempty"`
-	Guests         []GuestInput       `json:"guests"`
-	Prices         []PriceInput       `json:"prices"`
+	VenueID                   *uint              `json:"venue_id,omitempty"`
+	Name                      *string            `json:"name,omitempty"`
+	Description               *string            `json:"description,omitempty"`
+	EventStartAt              *time.Time         `json:"event_start_at,omitempty"`
+	Status                    *model.EventStatus `json:"status,omitempty"`
+	SalesStartDate            *time.Time         `json:"sales_start_date,omitempty"`
+	SalesEndDate              *time.Time         `json:"sales_end_date,omitempty"`
+	AllowReentry              *bool              `json:"allow_reentry,omitempty"`
+	TransfersDisabled         *bool              `json:"transfers_disabled,omitempty"`
+	TransferCutoffHours       *int               `json:"transfer_cutoff_hours,omitempty" binding:"omitempty,min=0"`
+	PaymentDueMinutes         *int               `json:"payment_due_minutes,omitempty" binding:"omitempty,min=0"` // 0 removes the cap
+	WaitingRoomEnabled        *bool              `json:"waiting_room_enabled,omitempty"`
+	WaitingRoomAdmitPerMinute *int               `json:"waiting_room_admit_per_minute,omitempty" binding:"omitempty,min=0"` // 0 uses the server default
+	CancellationReason        *string            `json:"cancellation_reason,omitempty"`                                     // Sent to ticket holders when status becomes CANCELLED
+	Guests                    []GuestInput       `json:"guests"`
+	Prices                    []PriceInput       `json:"prices"`
 }
 
 type EventGuestResponse struct {
@@ -53,16 +66,22 @@ type EventPriceResponse struct {
 }
 
 type EventResponseBase struct {
-	ID             uint                 `json:"id"`
-	Slug           string               `json:"slug"`
-	Name           string               `json:"name"`
-	Description    string               `json:"description"`
-	EventStartAt   time.Time            `json:"event_start_at"`
-	Status         model.EventStatus    `json:"status"`
-	SalesStartDate time.Time            `json:"sales_start_date"`
-	SalesEndDate   time.Time            `json:"sales_end_date"`
-	EventGuests    []EventGuestResponse `json:"guests"`
-	Prices         []EventPriceResponse `json:"prices"`
+	ID                        uint                 `json:"id"`
+	Slug                      string               `json:"slug"`
+	Name                      string               `json:"name"`
+	Description               string               `json:"description"`
+	EventStartAt              time.Time            `json:"event_start_at"`
+	Status                    model.EventStatus    `json:"status"`
+	SalesStartDate            time.Time            `json:"sales_start_date"`
+	SalesEndDate              time.Time            `json:"sales_end_date"`
+	AllowReentry              bool                 `json:"allow_reentry"`
+	TransfersDisabled         bool                 `json:"transfers_disabled"`
+	TransferCutoffHours       int                  `json:"transfer_cutoff_hours"`
+	PaymentDueMinutes         *int                 `json:"payment_due_minutes"`
+	WaitingRoomEnabled        bool                 `json:"waiting_room_enabled"`
+	WaitingRoomAdmitPerMinute int                  `json:"waiting_room_admit_per_minute"`
+	EventGuests               []EventGuestResponse `json:"guests"`
+	Prices                    []EventPriceResponse `json:"prices"`
 }
 
 type EventResponse struct {
@@ -122,16 +141,22 @@ func ToEventResponseBase(event model.Event) EventResponseBase {
 	}
 
 	return EventResponseBase{
-		ID:             event.ID,
-		Slug:           event.Slug,
-		Name:           event.Name,
-		Description:    event.Description,
-		EventStartAt:   event.EventStartAt,
-		Status:         event.Status,
-		SalesStartDate: event.SalesStartDate,
-		SalesEndDate:   event.SalesEndDate,
-		EventGuests:    eventGuestResponses,
-		Prices:         eventPriceResponses,
+		ID:                        event.ID,
+		Slug:                      event.Slug,
+		Name:                      event.Name,
+		Description:               event.Description,
+		EventStartAt:              event.EventStartAt,
+		Status:                    event.Status,
+		SalesStartDate:            event.SalesStartDate,
+		SalesEndDate:              event.SalesEndDate,
+		AllowReentry:              event.AllowReentry,
+		TransfersDisabled:         event.TransfersDisabled,
+		TransferCutoffHours:       event.TransferCutoffHours,
+		PaymentDueMinutes:         event.PaymentDueMinutes,
+		WaitingRoomEnabled:        event.WaitingRoomEnabled,
+		WaitingRoomAdmitPerMinute: event.WaitingRoomAdmitPerMinute,
+		EventGuests:               eventGuestResponses,
+		Prices:                    eventPriceResponses,
 	}
 }
 
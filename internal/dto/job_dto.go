@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"encoding/json"
+	"learn/internal/pkg/queue"
+	"time"
+)
+
+// JobQueueStatsResponse is the number of jobs in each state of the job queue
+type JobQueueStatsResponse struct {
+	Ready      int64 `json:"ready"`
+	Delayed    int64 `json:"delayed"` // Scheduled for later, including retries waiting for their backoff
+	Processing int64 `json:"processing"`
+	Failed     int64 `json:"failed"`
+}
+
+type FailedJobResponse struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+	FailedAt    *time.Time      `json:"failed_at,omitempty"`
+}
+
+// FailedJobListResponse is a page of the failed jobs store, most recently failed first
+type FailedJobListResponse struct {
+	Total  int64               `json:"total"`
+	Offset int64               `json:"offset"`
+	Jobs   []FailedJobResponse `json:"jobs"`
+}
+
+func ToJobQueueStatsResponse(stats queue.Stats) JobQueueStatsResponse {
+	return JobQueueStatsResponse{
+		Ready:      stats.Ready,
+		Delayed:    stats.Delayed,
+		Processing: stats.Processing,
+		Failed:     stats.Failed,
+	}
+}
+
+func ToFailedJobResponse(job queue.Job) FailedJobResponse {
+	return FailedJobResponse{
+		ID:          job.ID,
+		Type:        job.Type,
+		Payload:     job.Payload,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		LastError:   job.LastError,
+		EnqueuedAt:  job.EnqueuedAt,
+		FailedAt:    job.FailedAt,
+	}
+}
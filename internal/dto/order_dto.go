@@ -13,6 +13,7 @@ type TicketOrder struct {
 type NewOrderInput struct {
 	EventID        string        `json:"event_id" binding:"required"`
 	TicketsOrdered []TicketOrder `json:"tickets_ordered" binding:"required,min=1,max=10,dive"`
+	AdmissionToken string        `json:"admission_token"` // Required for events with the waiting room on
 }
 
 type TicketResponse struct {
@@ -27,9 +28,33 @@ type OrderResponse struct {
 	TotalPrice int64             `json:"total_price"` // Total price in smallest currency unit (e.g., cents)
 	Status     model.OrderStatus `json:"status"`
 	PaymentDue time.Time         `json:"payment_due"`
+	Hold       *OrderHoldResponse `json:"hold"`
 	Tickets    []TicketResponse  `json:"tickets"`
 }
 
+// OrderHoldResponse is the reservation of a pending order before a payment is started, for the
+// countdown of the frontend
+type OrderHoldResponse struct {
+	ExpiresAt  time.Time `json:"expires_at"`
+	ServerTime time.Time `json:"server_time"` // Lets the client correct its clock for the countdown
+	Extended   bool      `json:"extended"`
+	CanExtend  bool      `json:"can_extend"`
+}
+
+func ToOrderHoldResponse(order model.Order) *OrderHoldResponse {
+	if order.Status != model.OrderPending || order.HoldExpiresAt == nil {
+		return nil
+	}
+
+	now := time.Now()
+	return &OrderHoldResponse{
+		ExpiresAt:  *order.HoldExpiresAt,
+		ServerTime: now,
+		Extended:   order.HoldExtended,
+		CanExtend:  !order.HoldExtended && now.Before(*order.HoldExpiresAt),
+	}
+}
+
 func ToOrderResponse(order model.Order) OrderResponse {
 	var ticketResponses []TicketResponse
 	for _, ticket := range order.Tickets {
@@ -46,6 +71,164 @@ func ToOrderResponse(order model.Order) OrderResponse {
 		TotalPrice: order.TotalPrice,
 		Status:     order.Status,
 		PaymentDue: order.PaymentDue,
+		Hold:       ToOrderHoldResponse(order),
 		Tickets:    ticketResponses,
 	}
 }
+
+type OrderEventResponse struct {
+	ID           uint      `json:"id"`
+	Slug         string    `json:"slug"`
+	Name         string    `json:"name"`
+	EventStartAt time.Time `json:"event_start_at"`
+}
+
+type OrderLineItemResponse struct {
+	ID           uint   `json:"id"`
+	EventPriceID uint   `json:"event_price_id"`
+	PriceName    string `json:"price_name"`
+	Quantity     int    `json:"quantity"`
+	PricePerUnit int64  `json:"price_per_unit"` // Price in smallest currency unit (e.g., cents)
+	TotalPrice   int64  `json:"total_price"`
+}
+
+type OrderPaymentSummary struct {
+	PaymentID            uint                `json:"payment_id"`
+	PaymentMethod        model.PaymentMethod `json:"payment_method"`
+	PaymentStatus        model.PaymentStatus `json:"payment_status"`
+	TransactionID        string              `json:"transaction_id"`
+	PaymentDate          time.Time           `json:"payment_date"`
+	PaymentURL           string              `json:"payment_url,omitempty"`
+	VirtualAccountNumber string              `json:"virtual_account_number,omitempty"`
+	PaymentCode          string              `json:"payment_code,omitempty"`
+}
+
+type OrderSummaryResponse struct {
+	ID          uint                `json:"id"`
+	TotalPrice  int64               `json:"total_price"`
+	Status      model.OrderStatus   `json:"status"`
+	PaymentDue  time.Time           `json:"payment_due"`
+	Hold        *OrderHoldResponse  `json:"hold"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Event       *OrderEventResponse `json:"event,omitempty"`
+	TicketCount int                 `json:"ticket_count"`
+}
+
+type OrderDetailResponse struct {
+	ID         uint                    `json:"id"`
+	TotalPrice int64                   `json:"total_price"`
+	Status     model.OrderStatus       `json:"status"`
+	PaymentDue time.Time               `json:"payment_due"`
+	Hold       *OrderHoldResponse      `json:"hold"`
+	CreatedAt  time.Time               `json:"created_at"`
+	Event      *OrderEventResponse     `json:"event,omitempty"`
+	LineItems  []OrderLineItemResponse `json:"line_items"`
+	Payment    *OrderPaymentSummary    `json:"payment"`
+	Tickets    []TicketResponse        `json:"tickets"`
+}
+
+// orderEvent returns the event an order belongs to. Every line item of an
+// order points to the same event, so the first one is enough.
+func orderEvent(order model.Order) *OrderEventResponse {
+	if len(order.OrderLineItems) == 0 {
+		return nil
+	}
+
+	event := order.OrderLineItems[0].EventPrice.Event
+	if event.ID == 0 {
+		return nil
+	}
+
+	return &OrderEventResponse{
+		ID:           event.ID,
+		Slug:         event.Slug,
+		Name:         event.Name,
+		EventStartAt: event.EventStartAt,
+	}
+}
+
+func ToOrderSummaryResponse(order model.Order) OrderSummaryResponse {
+	ticketCount := 0
+	for _, lineItem := range order.OrderLineItems {
+		ticketCount += lineItem.Quantity
+	}
+
+	return OrderSummaryResponse{
+		ID:          order.ID,
+		TotalPrice:  order.TotalPrice,
+		Status:      order.Status,
+		PaymentDue:  order.PaymentDue,
+		Hold:        ToOrderHoldResponse(order),
+		CreatedAt:   order.CreatedAt,
+		Event:       orderEvent(order),
+		TicketCount: ticketCount,
+	}
+}
+
+func ToOrderSummaryResponses(orders []model.Order) []OrderSummaryResponse {
+	responses := make([]OrderSummaryResponse, 0, len(orders))
+	for _, order := range orders {
+		responses = append(responses, ToOrderSummaryResponse(order))
+	}
+	return responses
+}
+
+func ToOrderPaymentSummary(payment model.Payment) OrderPaymentSummary {
+	return OrderPaymentSummary{
+		PaymentID:            payment.ID,
+		PaymentMethod:        payment.PaymentMethod,
+		PaymentStatus:        payment.PaymentStatus,
+		TransactionID:        payment.TransactionID,
+		PaymentDate:          payment.PaymentDate,
+		PaymentURL:           payment.PaymentURL,
+		VirtualAccountNumber: payment.VirtualAccountNumber,
+		PaymentCode:          payment.PaymentCode,
+	}
+}
+
+func ToOrderDetailResponse(order model.Order, payment *model.Payment) OrderDetailResponse {
+	lineItems := make([]OrderLineItemResponse, 0, len(order.OrderLineItems))
+	for _, lineItem := range order.OrderLineItems {
+		lineItems = append(lineItems, OrderLineItemResponse{
+			ID:           lineItem.ID,
+			EventPriceID: lineItem.EventPriceID,
+			PriceName:    lineItem.EventPrice.Name,
+			Quantity:     lineItem.Quantity,
+			PricePerUnit: lineItem.PricePerUnit,
+			TotalPrice:   lineItem.TotalPrice,
+		})
+	}
+
+	tickets := make([]TicketResponse, 0, len(order.Tickets))
+	for _, ticket := range order.Tickets {
+		// Tickets transferred to someone else carry a code the buyer must not see
+		if ticket.HolderUserID != nil && *ticket.HolderUserID != order.UserID {
+			continue
+		}
+		tickets = append(tickets, TicketResponse{
+			ID:         ticket.ID,
+			Price:      ticket.Price,
+			Type:       ticket.Type,
+			TicketCode: ticket.TicketCode,
+		})
+	}
+
+	var paymentSummary *OrderPaymentSummary
+	if payment != nil {
+		summary := ToOrderPaymentSummary(*payment)
+		paymentSummary = &summary
+	}
+
+	return OrderDetailResponse{
+		ID:         order.ID,
+		TotalPrice: order.TotalPrice,
+		Status:     order.Status,
+		PaymentDue: order.PaymentDue,
+		Hold:       ToOrderHoldResponse(order),
+		CreatedAt:  order.CreatedAt,
+		Event:      orderEvent(order),
+		LineItems:  lineItems,
+		Payment:    paymentSummary,
+		Tickets:    tickets,
+	}
+}
@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+// OutboxStatsResponse is the backlog of domain events not yet relayed to the event stream
+type OutboxStatsResponse struct {
+	Pending                 int64      `json:"pending"`
+	Retrying                int64      `json:"retrying"` // Pending events whose publish already failed
+	OldestPendingAt         *time.Time `json:"oldest_pending_at,omitempty"`
+	OldestPendingAgeSeconds int64      `json:"oldest_pending_age_seconds"`
+	SentLastHour            int64      `json:"sent_last_hour"`
+}
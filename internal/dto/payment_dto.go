@@ -41,3 +41,8 @@ type UpdatePaymentRequest struct {
 type UpdatePaymentStatusRequest struct {
 	Status model.PaymentStatus `json:"status" binding:"required"`
 }
+
+// SimulatePaymentNotificationRequest selects the Midtrans transaction_status the fake gateway notifies
+type SimulatePaymentNotificationRequest struct {
+	TransactionStatus string `json:"transaction_status" binding:"required,oneof=settlement pending deny cancel expire refund"`
+}
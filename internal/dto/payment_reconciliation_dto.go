@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"learn/internal/model"
+	"time"
+)
+
+type ResolveReconciliationIssueRequest struct {
+	Note string `json:"note" binding:"required,max=500"`
+}
+
+// ReconciliationSummary reports what a reconciliation run did with the pending payments it checked
+type ReconciliationSummary struct {
+	Checked   int       `json:"checked"`
+	Applied   int       `json:"applied"` // Missed status transitions applied to the payment
+	Unchanged int       `json:"unchanged"`
+	Flagged   int       `json:"flagged"` // New issues queued for admin review
+	Failed    int       `json:"failed"`
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+}
+
+type ReconciliationIssueResponse struct {
+	ID               uint                            `json:"id"`
+	PaymentID        uint                            `json:"payment_id"`
+	OrderID          uint                            `json:"order_id"`
+	Type             model.ReconciliationIssueType   `json:"type"`
+	Status           model.ReconciliationIssueStatus `json:"status"`
+	Provider         string                          `json:"provider,omitempty"`
+	TransactionID    string                          `json:"transaction_id,omitempty"`
+	GatewayStatus    string                          `json:"gateway_status"`
+	ExpectedAmount   int64                           `json:"expected_amount"`
+	GatewayAmount    int64                           `json:"gateway_amount"`
+	Detail           string                          `json:"detail"`
+	ResolvedByUserID *uint                           `json:"resolved_by_user_id,omitempty"`
+	ResolutionNote   string                          `json:"resolution_note,omitempty"`
+	CreatedAt        time.Time                       `json:"created_at"`
+	ResolvedAt       *time.Time                      `json:"resolved_at,omitempty"`
+}
+
+func ToReconciliationIssueResponse(issue model.PaymentReconciliationIssue) ReconciliationIssueResponse {
+	return ReconciliationIssueResponse{
+		ID:               issue.ID,
+		PaymentID:        issue.PaymentID,
+		OrderID:          issue.OrderID,
+		Type:             issue.Type,
+		Status:           issue.Status,
+		Provider:         issue.Payment.Provider,
+		TransactionID:    issue.Payment.TransactionID,
+		GatewayStatus:    issue.GatewayStatus,
+		ExpectedAmount:   issue.ExpectedAmount,
+		GatewayAmount:    issue.GatewayAmount,
+		Detail:           issue.Detail,
+		ResolvedByUserID: issue.ResolvedByUserID,
+		ResolutionNote:   issue.ResolutionNote,
+		CreatedAt:        issue.CreatedAt,
+		ResolvedAt:       issue.ResolvedAt,
+	}
+}
+
+func ToReconciliationIssueResponses(issues []model.PaymentReconciliationIssue) []ReconciliationIssueResponse {
+	responses := make([]ReconciliationIssueResponse, 0, len(issues))
+	for _, issue := range issues {
+		responses = append(responses, ToReconciliationIssueResponse(issue))
+	}
+	return responses
+}
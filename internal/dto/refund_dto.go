@@ -0,0 +1,77 @@
+package dto
+
+import (
+	"learn/internal/model"
+	"time"
+)
+
+type CreateRefundRequest struct {
+	OrderID   uint   `json:"order_id" binding:"required"`
+	TicketIDs []uint `json:"ticket_ids" binding:"omitempty,max=50"` // Empty refunds every remaining ticket of the order
+	Reason    string `json:"reason" binding:"required,max=500"`
+}
+
+type ReviewRefundRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+type SettleRefundRequest struct {
+	Reference string `json:"reference" binding:"required,max=255"`
+}
+
+type RefundTicketResponse struct {
+	TicketID uint   `json:"ticket_id"`
+	Type     string `json:"type"`
+	Amount   int64  `json:"amount"`
+}
+
+type RefundResponse struct {
+	ID                  uint                   `json:"id"`
+	OrderID             uint                   `json:"order_id"`
+	PaymentID           uint                   `json:"payment_id"`
+	Type                model.RefundType       `json:"type"`
+	Status              model.RefundStatus     `json:"status"`
+	Amount              int64                  `json:"amount"` // Amount in smallest currency unit (e.g., cents)
+	Reason              string                 `json:"reason"`
+	ReviewNote          string                 `json:"review_note,omitempty"`
+	SettlementReference string                 `json:"settlement_reference,omitempty"`
+	Tickets             []RefundTicketResponse `json:"tickets"`
+	CreatedAt           time.Time              `json:"created_at"`
+	ReviewedAt          *time.Time             `json:"reviewed_at,omitempty"`
+	CompletedAt         *time.Time             `json:"completed_at,omitempty"`
+}
+
+func ToRefundResponse(refund model.Refund) RefundResponse {
+	tickets := make([]RefundTicketResponse, 0, len(refund.Tickets))
+	for _, item := range refund.Tickets {
+		tickets = append(tickets, RefundTicketResponse{
+			TicketID: item.TicketID,
+			Type:     item.Ticket.Type,
+			Amount:   item.Amount,
+		})
+	}
+
+	return RefundResponse{
+		ID:                  refund.ID,
+		OrderID:             refund.OrderID,
+		PaymentID:           refund.PaymentID,
+		Type:                refund.Type,
+		Status:              refund.Status,
+		Amount:              refund.Amount,
+		Reason:              refund.Reason,
+		ReviewNote:          refund.ReviewNote,
+		SettlementReference: refund.SettlementReference,
+		Tickets:             tickets,
+		CreatedAt:           refund.CreatedAt,
+		ReviewedAt:          refund.ReviewedAt,
+		CompletedAt:         refund.CompletedAt,
+	}
+}
+
+func ToRefundResponses(refunds []model.Refund) []RefundResponse {
+	responses := make([]RefundResponse, 0, len(refunds))
+	for _, refund := range refunds {
+		responses = append(responses, ToRefundResponse(refund))
+	}
+	return responses
+}
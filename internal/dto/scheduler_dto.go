@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"learn/internal/model"
+	"learn/internal/pkg/cron"
+	"time"
+)
+
+type SchedulerRunResponse struct {
+	ID                uint                      `json:"id"`
+	JobName           string                    `json:"job_name"`
+	Trigger           model.SchedulerRunTrigger `json:"trigger"`
+	TriggeredByUserID *uint                     `json:"triggered_by_user_id,omitempty"`
+	Node              string                    `json:"node"`
+	Status            model.SchedulerRunStatus  `json:"status"`
+	StartedAt         time.Time                 `json:"started_at"`
+	FinishedAt        *time.Time                `json:"finished_at,omitempty"`
+	DurationMs        int64                     `json:"duration_ms"`
+	Result            string                    `json:"result"`
+	Error             string                    `json:"error,omitempty"`
+}
+
+// SchedulerJobResponse is a registered job with its shared schedule state and last recorded run
+type SchedulerJobResponse struct {
+	Name        string                `json:"name"`
+	Spec        string                `json:"spec"`
+	NextRunAt   *time.Time            `json:"next_run_at"`
+	RunningOn   string                `json:"running_on,omitempty"`   // Node holding the job lock
+	TriggeredAt *time.Time            `json:"triggered_at,omitempty"` // Manual run waiting to be picked up
+	LastRun     *SchedulerRunResponse `json:"last_run"`
+}
+
+func ToSchedulerRunResponse(run model.SchedulerRun) SchedulerRunResponse {
+	return SchedulerRunResponse{
+		ID:                run.ID,
+		JobName:           run.JobName,
+		Trigger:           run.Trigger,
+		TriggeredByUserID: run.TriggeredByUserID,
+		Node:              run.Node,
+		Status:            run.Status,
+		StartedAt:         run.StartedAt,
+		FinishedAt:        run.FinishedAt,
+		DurationMs:        run.DurationMs,
+		Result:            run.Result,
+		Error:             run.Error,
+	}
+}
+
+func ToSchedulerRunResponses(runs []model.SchedulerRun) []SchedulerRunResponse {
+	responses := make([]SchedulerRunResponse, 0, len(runs))
+	for _, run := range runs {
+		responses = append(responses, ToSchedulerRunResponse(run))
+	}
+	return responses
+}
+
+func ToSchedulerJobResponse(status cron.JobStatus, lastRun *model.SchedulerRun) SchedulerJobResponse {
+	response := SchedulerJobResponse{
+		Name:        status.Name,
+		Spec:        status.Spec,
+		NextRunAt:   status.NextRunAt,
+		RunningOn:   status.RunningOn,
+		TriggeredAt: status.TriggeredAt,
+	}
+	if lastRun != nil {
+		run := ToSchedulerRunResponse(*lastRun)
+		response.LastRun = &run
+	}
+	return response
+}
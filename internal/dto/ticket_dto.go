@@ -1,9 +1,16 @@
 package dto
 
-import "learn/internal/model"
+import (
+	"learn/internal/model"
+	"time"
+)
 
 type CheckInTicketRequest struct {
 	TicketCode string `json:"ticket_code" binding:"required"`
+	Direction  string `json:"direction" binding:"omitempty,oneof=IN OUT in out"` // Defaults to IN
+	Gate       string `json:"gate"`
+	DeviceID   string `json:"device_id"`
+	EventSlug  string `json:"event_slug"` // Event the gate is scanning for, attached to scans rejected before a ticket is found
 }
 
 type CheckInTicketResponse struct {
@@ -13,6 +20,7 @@ type CheckInTicketResponse struct {
 	OwnerName   string `json:"owner_name"`
 	OwnerEmail  string `json:"owner_email"`
 	IsScanned   bool   `json:"is_scanned"`
+	IsInside    bool   `json:"is_inside"`
 	OrderID     uint   `json:"order_id"`
 	OrderStatus string `json:"order_status"`
 }
@@ -25,7 +33,217 @@ func ToCheckInTicketResponse(ticket model.Ticket, order model.Order) CheckInTick
 		OwnerName:   ticket.OwnerName,
 		OwnerEmail:  ticket.OwnerEmail,
 		IsScanned:   ticket.IsScanned,
+		IsInside:    ticket.IsInside,
 		OrderID:     ticket.OrderID,
 		OrderStatus: string(order.Status),
 	}
 }
+
+type TicketEventResponse struct {
+	ID           uint      `json:"id"`
+	Slug         string    `json:"slug"`
+	Name         string    `json:"name"`
+	EventStartAt time.Time `json:"event_start_at"`
+}
+
+type WalletTicketResponse struct {
+	ID         uint   `json:"id"`
+	TicketCode string `json:"ticket_code"`
+	Type       string `json:"type"`
+	Price      int64  `json:"price"` // Price in smallest currency unit (e.g., cents)
+	SeatNumber string `json:"seat_number,omitempty"`
+	OwnerName  string `json:"owner_name"`
+	OwnerEmail string `json:"owner_email"`
+	IsScanned  bool   `json:"is_scanned"`
+	IsVoided   bool   `json:"is_voided"`
+	OrderID    uint   `json:"order_id"`
+	QRCodeURL  string `json:"qr_code_url"`
+}
+
+type TicketDetailResponse struct {
+	WalletTicketResponse
+	Event TicketEventResponse `json:"event"`
+}
+
+type TicketWalletGroup struct {
+	Event   TicketEventResponse    `json:"event"`
+	Tickets []WalletTicketResponse `json:"tickets"`
+}
+
+func ToTicketEventResponse(event model.Event) TicketEventResponse {
+	return TicketEventResponse{
+		ID:           event.ID,
+		Slug:         event.Slug,
+		Name:         event.Name,
+		EventStartAt: event.EventStartAt,
+	}
+}
+
+func ToWalletTicketResponse(ticket model.Ticket) WalletTicketResponse {
+	return WalletTicketResponse{
+		ID:         ticket.ID,
+		TicketCode: ticket.TicketCode,
+		Type:       ticket.Type,
+		Price:      ticket.Price,
+		SeatNumber: ticket.SeatNumber,
+		OwnerName:  ticket.OwnerName,
+		OwnerEmail: ticket.OwnerEmail,
+		IsScanned:  ticket.IsScanned,
+		IsVoided:   ticket.VoidedAt != nil,
+		OrderID:    ticket.OrderID,
+		QRCodeURL:  "/api/v1/tickets/" + ticket.TicketCode + "/qr.png",
+	}
+}
+
+func ToTicketDetailResponse(ticket model.Ticket) TicketDetailResponse {
+	return TicketDetailResponse{
+		WalletTicketResponse: ToWalletTicketResponse(ticket),
+		Event:                ToTicketEventResponse(ticket.EventPrice.Event),
+	}
+}
+
+// ToTicketWalletGroups groups tickets by event, keeping the order in which events first appear
+func ToTicketWalletGroups(tickets []model.Ticket) []TicketWalletGroup {
+	groups := make([]TicketWalletGroup, 0)
+	groupIndex := make(map[uint]int)
+
+	for _, ticket := range tickets {
+		event := ticket.EventPrice.Event
+		idx, ok := groupIndex[event.ID]
+		if !ok {
+			groups = append(groups, TicketWalletGroup{
+				Event:   ToTicketEventResponse(event),
+				Tickets: make([]WalletTicketResponse, 0),
+			})
+			idx = len(groups) - 1
+			groupIndex[event.ID] = idx
+		}
+		groups[idx].Tickets = append(groups[idx].Tickets, ToWalletTicketResponse(ticket))
+	}
+
+	return groups
+}
+
+type CheckInManifestEntry struct {
+	TicketHash string `json:"ticket_hash"`         // SHA-256 of the signed QR content
+	CodeHash   string `json:"code_hash,omitempty"` // SHA-256 of the plain ticket code, only while plain codes are accepted
+	Type       string `json:"type"`
+	IsScanned  bool   `json:"is_scanned"`
+}
+
+type CheckInManifestResponse struct {
+	EventID     uint                   `json:"event_id"`
+	EventSlug   string                 `json:"event_slug"`
+	DeviceID    string                 `json:"device_id"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Tickets     []CheckInManifestEntry `json:"tickets"`
+}
+
+const (
+	OfflineScanAccepted = "accepted"
+	OfflineScanRejected = "rejected"
+)
+
+type OfflineScanInput struct {
+	ScanID     string    `json:"scan_id" binding:"required"`
+	TicketCode string    `json:"ticket_code" binding:"required"`
+	ScannedAt  time.Time `json:"scanned_at" binding:"required"`
+	Gate       string    `json:"gate"`
+	Direction  string    `json:"direction" binding:"omitempty,oneof=IN OUT in out"` // Defaults to IN
+}
+
+type SyncOfflineScansRequest struct {
+	DeviceID  string             `json:"device_id" binding:"required"`
+	EventSlug string             `json:"event_slug"` // Event of the manifest the device scanned with
+	Scans     []OfflineScanInput `json:"scans" binding:"required,min=1,max=500,dive"`
+}
+
+type OfflineScanResult struct {
+	ScanID   string `json:"scan_id"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"`
+	TicketID uint   `json:"ticket_id,omitempty"`
+}
+
+type SyncOfflineScansResponse struct {
+	DeviceID string              `json:"device_id"`
+	Accepted int                 `json:"accepted"`
+	Rejected int                 `json:"rejected"`
+	Results  []OfflineScanResult `json:"results"`
+}
+
+type TicketScanResponse struct {
+	ID            uint      `json:"id"`
+	TicketID      *uint     `json:"ticket_id"`
+	EventID       *uint     `json:"event_id"`
+	ScannerUserID uint      `json:"scanner_user_id"`
+	DeviceID      string    `json:"device_id,omitempty"`
+	Gate          string    `json:"gate,omitempty"`
+	Direction     string    `json:"direction"`
+	Result        string    `json:"result"`
+	Reason        string    `json:"reason,omitempty"`
+	Offline       bool      `json:"offline"`
+	ScannedAt     time.Time `json:"scanned_at"`
+}
+
+func ToTicketScanResponse(scan model.TicketScan) TicketScanResponse {
+	return TicketScanResponse{
+		ID:            scan.ID,
+		TicketID:      scan.TicketID,
+		EventID:       scan.EventID,
+		ScannerUserID: scan.ScannerUserID,
+		DeviceID:      scan.DeviceID,
+		Gate:          scan.Gate,
+		Direction:     string(scan.Direction),
+		Result:        string(scan.Result),
+		Reason:        scan.Reason,
+		Offline:       scan.Offline,
+		ScannedAt:     scan.ScannedAt,
+	}
+}
+
+func ToTicketScanResponses(scans []model.TicketScan) []TicketScanResponse {
+	var responses []TicketScanResponse
+	for _, scan := range scans {
+		responses = append(responses, ToTicketScanResponse(scan))
+	}
+	return responses
+}
+
+type StartTicketTransferRequest struct {
+	RecipientEmail string `json:"recipient_email" binding:"required,email"`
+}
+
+type TicketTransferResponse struct {
+	ID         uint                `json:"id"`
+	TicketID   uint                `json:"ticket_id"`
+	TicketType string              `json:"ticket_type"`
+	Event      TicketEventResponse `json:"event"`
+	FromUserID uint                `json:"from_user_id"`
+	ToEmail    string              `json:"to_email"`
+	Status     string              `json:"status"`
+	CreatedAt  time.Time           `json:"created_at"`
+	AcceptedAt *time.Time          `json:"accepted_at,omitempty"`
+}
+
+func ToTicketTransferResponse(transfer model.TicketTransfer) TicketTransferResponse {
+	return TicketTransferResponse{
+		ID:         transfer.ID,
+		TicketID:   transfer.TicketID,
+		TicketType: transfer.Ticket.Type,
+		Event:      ToTicketEventResponse(transfer.Ticket.EventPrice.Event),
+		FromUserID: transfer.FromUserID,
+		ToEmail:    transfer.ToEmail,
+		Status:     string(transfer.Status),
+		CreatedAt:  transfer.CreatedAt,
+		AcceptedAt: transfer.AcceptedAt,
+	}
+}
+
+func ToTicketTransferResponses(transfers []model.TicketTransfer) []TicketTransferResponse {
+	responses := make([]TicketTransferResponse, 0, len(transfers))
+	for _, transfer := range transfers {
+		responses = append(responses, ToTicketTransferResponse(transfer))
+	}
+	return responses
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type WaitingRoomState string
+
+const (
+	WaitingRoomWaiting    WaitingRoomState = "WAITING"
+	WaitingRoomAdmitted   WaitingRoomState = "ADMITTED"
+	WaitingRoomNotInQueue WaitingRoomState = "NOT_IN_QUEUE" // Never joined, or the admission expired
+)
+
+// WaitingRoomStatusResponse is where the user stands in the waiting room of an event. The admission
+// token is only set once the user is admitted and goes into the admission_token of the order.
+type WaitingRoomStatusResponse struct {
+	EventID              uint             `json:"event_id"`
+	State                WaitingRoomState `json:"state"`
+	Position             int64            `json:"position,omitempty"` // 1-based, while waiting
+	QueueLength          int64            `json:"queue_length"`
+	AdmitPerMinute       int              `json:"admit_per_minute"`
+	EstimatedWaitSeconds int64            `json:"estimated_wait_seconds"`
+	SalesStartAt         time.Time        `json:"sales_start_at"`
+	ServerTime           time.Time        `json:"server_time"`
+	AdmissionToken       string           `json:"admission_token,omitempty"`
+	AdmissionExpiresAt   *time.Time       `json:"admission_expires_at,omitempty"`
+}
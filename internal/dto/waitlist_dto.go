@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"learn/internal/model"
+	"time"
+)
+
+type JoinWaitlistRequest struct {
+	EventPriceID uint `json:"event_price_id" binding:"required"`
+	Quantity     int  `json:"quantity" binding:"required,min=1,max=4"`
+}
+
+type WaitlistEntryResponse struct {
+	ID             uint                 `json:"id"`
+	EventID        uint                 `json:"event_id"`
+	EventSlug      string               `json:"event_slug"`
+	EventName      string               `json:"event_name"`
+	EventPriceID   uint                 `json:"event_price_id"`
+	PriceName      string               `json:"price_name"`
+	Quantity       int                  `json:"quantity"`
+	Status         model.WaitlistStatus `json:"status"`
+	OfferedAt      *time.Time           `json:"offered_at,omitempty"`
+	OfferExpiresAt *time.Time           `json:"offer_expires_at,omitempty"` // Order before this to use the offer
+	OrderID        *uint                `json:"order_id,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+type WaitlistTierResponse struct {
+	EventPriceID    uint    `json:"event_price_id"`
+	Name            string  `json:"name"`
+	Waiting         int64   `json:"waiting"`
+	WaitingQuantity int64   `json:"waiting_quantity"` // Tickets asked for by the waiting users
+	Offered         int64   `json:"offered"`          // Open offers
+	Converted       int64   `json:"converted"`
+	Expired         int64   `json:"expired"`
+	Left            int64   `json:"left"`
+	ConversionRate  float64 `json:"conversion_rate"` // Converted out of the offers that were used or expired
+}
+
+type WaitlistSummaryResponse struct {
+	EventID   uint                   `json:"event_id"`
+	EventSlug string                 `json:"event_slug"`
+	Waiting   int64                  `json:"waiting"`
+	Converted int64                  `json:"converted"`
+	Tiers     []WaitlistTierResponse `json:"tiers"`
+}
+
+func ToWaitlistEntryResponse(entry model.WaitlistEntry) WaitlistEntryResponse {
+	return WaitlistEntryResponse{
+		ID:             entry.ID,
+		EventID:        entry.EventPrice.EventID,
+		EventSlug:      entry.EventPrice.Event.Slug,
+		EventName:      entry.EventPrice.Event.Name,
+		EventPriceID:   entry.EventPriceID,
+		PriceName:      entry.EventPrice.Name,
+		Quantity:       entry.Quantity,
+		Status:         entry.Status,
+		OfferedAt:      entry.OfferedAt,
+		OfferExpiresAt: entry.OfferExpiresAt,
+		OrderID:        entry.OrderID,
+		CreatedAt:      entry.CreatedAt,
+	}
+}
+
+func ToWaitlistEntryResponses(entries []model.WaitlistEntry) []WaitlistEntryResponse {
+	responses := make([]WaitlistEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		responses = append(responses, ToWaitlistEntryResponse(entry))
+	}
+	return responses
+}
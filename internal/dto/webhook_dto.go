@@ -0,0 +1,112 @@
+package dto
+
+import (
+	"encoding/json"
+	"learn/internal/model"
+	"time"
+)
+
+type CreateWebhookEndpointRequest struct {
+	URL         string                   `json:"url" binding:"required,url,max=500"`
+	EventTypes  []model.WebhookEventType `json:"event_types" binding:"required,min=1,dive,oneof=order.paid ticket.checked_in event.cancelled"`
+	EventSlug   string                   `json:"event_slug"` // Only deliver events of this event, empty for every event
+	Description string                   `json:"description" binding:"max=255"`
+}
+
+type UpdateWebhookEndpointRequest struct {
+	URL         *string                  `json:"url,omitempty" binding:"omitempty,url,max=500"`
+	EventTypes  []model.WebhookEventType `json:"event_types,omitempty" binding:"omitempty,min=1,dive,oneof=order.paid ticket.checked_in event.cancelled"`
+	EventSlug   *string                  `json:"event_slug,omitempty"` // An empty string removes the event filter
+	Description *string                  `json:"description,omitempty" binding:"omitempty,max=255"`
+	Active      *bool                    `json:"active,omitempty"` // true re-enables a disabled endpoint and resets its failure count
+}
+
+type WebhookEndpointResponse struct {
+	ID                  uint                     `json:"id"`
+	URL                 string                   `json:"url"`
+	EventTypes          []model.WebhookEventType `json:"event_types"`
+	EventSlug           string                   `json:"event_slug,omitempty"`
+	Description         string                   `json:"description"`
+	Active              bool                     `json:"active"`
+	ConsecutiveFailures int                      `json:"consecutive_failures"`
+	DisabledAt          *time.Time               `json:"disabled_at,omitempty"`
+	DisabledReason      string                   `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time                `json:"created_at"`
+}
+
+// WebhookEndpointCreatedResponse is only returned on creation, the secret is never shown again
+type WebhookEndpointCreatedResponse struct {
+	WebhookEndpointResponse
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             uint                        `json:"id"`
+	EventType      model.WebhookEventType      `json:"event_type"`
+	EventKey       string                      `json:"event_key"`
+	Status         model.WebhookDeliveryStatus `json:"status"`
+	Attempts       int                         `json:"attempts"`
+	NextAttemptAt  *time.Time                  `json:"next_attempt_at,omitempty"` // Only while the delivery is pending
+	LastAttemptAt  *time.Time                  `json:"last_attempt_at,omitempty"`
+	LastStatusCode int                         `json:"last_status_code,omitempty"`
+	LastError      string                      `json:"last_error,omitempty"`
+	LastResponse   string                      `json:"last_response,omitempty"`
+	DeliveredAt    *time.Time                  `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage             `json:"payload"`
+	CreatedAt      time.Time                   `json:"created_at"`
+}
+
+func ToWebhookEndpointResponse(endpoint model.WebhookEndpoint) WebhookEndpointResponse {
+	response := WebhookEndpointResponse{
+		ID:                  endpoint.ID,
+		URL:                 endpoint.URL,
+		EventTypes:          endpoint.WebhookEventTypes(),
+		Description:         endpoint.Description,
+		Active:              endpoint.Active,
+		ConsecutiveFailures: endpoint.ConsecutiveFailures,
+		DisabledAt:          endpoint.DisabledAt,
+		DisabledReason:      endpoint.DisabledReason,
+		CreatedAt:           endpoint.CreatedAt,
+	}
+	if endpoint.Event != nil {
+		response.EventSlug = endpoint.Event.Slug
+	}
+	return response
+}
+
+func ToWebhookEndpointResponses(endpoints []model.WebhookEndpoint) []WebhookEndpointResponse {
+	responses := make([]WebhookEndpointResponse, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		responses = append(responses, ToWebhookEndpointResponse(endpoint))
+	}
+	return responses
+}
+
+func ToWebhookDeliveryResponse(delivery model.WebhookDelivery) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventType:      delivery.EventType,
+		EventKey:       delivery.EventKey,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		LastResponse:   delivery.LastResponse,
+		DeliveredAt:    delivery.DeliveredAt,
+		Payload:        json.RawMessage(delivery.Payload),
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == model.WebhookDeliveryPending {
+		response.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return response
+}
+
+func ToWebhookDeliveryResponses(deliveries []model.WebhookDelivery) []WebhookDeliveryResponse {
+	responses := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		responses = append(responses, ToWebhookDeliveryResponse(delivery))
+	}
+	return responses
+}
@@ -0,0 +1,220 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learn/internal/gateway"
+	"learn/internal/gateway/midtrans"
+	"learn/internal/model"
+	"learn/internal/pkg/random"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	hexChars   = "0123456789abcdef"
+	digitChars = "0123456789"
+
+	// transactionTTL is how long a simulated transaction is kept after its last change
+	transactionTTL = 30 * 24 * time.Hour
+)
+
+// ErrTransactionNotFound is returned for a transaction the fake gateway never charged or forgot
+var ErrTransactionNotFound = errors.New("fake gateway: transaction not found")
+
+// Gateway stands in for Midtrans when MIDTRANS_ENV=fake. It registers under the Midtrans
+// provider name, so payments and webhooks go through the regular Midtrans paths. Transactions are
+// kept in Redis, so they survive restarts and every instance, API or worker, sees the same state.
+type Gateway struct {
+	redisClient *redis.Client
+	logger      *slog.Logger
+}
+
+type transaction struct {
+	reference string
+	method    model.PaymentMethod
+	amount    int64
+	status    string    // Midtrans transaction_status
+	expiresAt time.Time // Pending transactions read as expired afterwards, like Midtrans custom_expiry
+}
+
+func NewFakeGateway(redisClient *redis.Client, logger *slog.Logger) *Gateway {
+	return &Gateway{
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+func transactionKey(transactionID string) string {
+	return "fake_gateway:transaction:" + transactionID
+}
+
+// FromRegistry returns the fake gateway when it is the registered Midtrans provider
+func FromRegistry(registry *gateway.Registry) (*Gateway, bool) {
+	provider, ok := registry.ByName(midtrans.ProviderName)
+	if !ok {
+		return nil, false
+	}
+	fakeGateway, ok := provider.(*Gateway)
+	return fakeGateway, ok
+}
+
+func (g *Gateway) Name() string {
+	return midtrans.ProviderName
+}
+
+// Charge returns payment instructions shaped like the Midtrans sandbox ones without calling out
+func (g *Gateway) Charge(req gateway.ChargeRequest) (*gateway.ChargeResult, error) {
+	transactionID := newTransactionID()
+	result := &gateway.ChargeResult{TransactionID: transactionID}
+
+	switch req.Method {
+	case model.PaymentMethodBankTransferBCA:
+		result.VirtualAccountNumber = "12345" + random.StringWithCharset(6, digitChars)
+	case model.PaymentMethodBankTransferBNI:
+		result.VirtualAccountNumber = "988" + random.StringWithCharset(13, digitChars)
+	case model.PaymentMethodBankTransferBRI:
+		result.VirtualAccountNumber = "12345" + random.StringWithCharset(13, digitChars)
+	case model.PaymentMethodGopay:
+		result.PaymentURL = "http://fake-midtrans.local/v2/gopay/" + transactionID + "/qr-code"
+	case model.PaymentMethodIndomaret:
+		result.PaymentCode = random.StringWithCharset(14, digitChars)
+	default:
+		return nil, errors.New("fake gateway does not support payment method " + string(req.Method))
+	}
+
+	err := g.save(transactionID, &transaction{
+		reference: req.Reference,
+		method:    req.Method,
+		amount:    req.Amount,
+		status:    "pending",
+		expiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fake gateway: storing transaction: %w", err)
+	}
+
+	g.logger.Info("fake gateway charged payment",
+		slog.String("transaction_id", transactionID),
+		slog.String("reference", req.Reference),
+		slog.String("method", string(req.Method)))
+
+	return result, nil
+}
+
+func (g *Gateway) GetStatus(transactionID string) (*gateway.TransactionStatus, error) {
+	tx, err := g.load(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rawStatus := tx.status
+	if rawStatus == "pending" && !tx.expiresAt.IsZero() && time.Now().After(tx.expiresAt) {
+		rawStatus = "expire"
+	}
+
+	status, _ := midtrans.MapTransactionStatus(rawStatus, "accept")
+	return &gateway.TransactionStatus{
+		TransactionID: transactionID,
+		Reference:     tx.reference,
+		Status:        status,
+		RawStatus:     rawStatus,
+		Amount:        tx.amount,
+	}, nil
+}
+
+// Refund mirrors Midtrans: Gopay and cards are refunded directly, other methods need a manual settlement
+func (g *Gateway) Refund(req gateway.RefundRequest) (*gateway.RefundResult, error) {
+	switch req.Method {
+	case model.PaymentMethodGopay, model.PaymentMethodCreditCard:
+		if err := g.setStatus(req.TransactionID, "refund"); err != nil {
+			return nil, err
+		}
+		return &gateway.RefundResult{
+			Mode:         gateway.RefundModeDirect,
+			RefundKey:    req.RefundKey,
+			RefundAmount: strconv.FormatInt(req.Amount, 10) + ".00",
+		}, nil
+	case model.PaymentMethodVirtualAccount, model.PaymentMethodBankTransferBCA, model.PaymentMethodBankTransferBNI,
+		model.PaymentMethodBankTransferBRI, model.PaymentMethodIndomaret:
+		return &gateway.RefundResult{Mode: gateway.RefundModeManual, RefundKey: req.RefundKey}, nil
+	}
+	return nil, errors.New("refund is not supported for payment method " + string(req.Method))
+}
+
+func (g *Gateway) Cancel(transactionID string) error {
+	return g.setStatus(transactionID, "cancel")
+}
+
+func (g *Gateway) save(transactionID string, tx *transaction) error {
+	ctx := context.Background()
+	key := transactionKey(transactionID)
+
+	var expiresAt int64
+	if !tx.expiresAt.IsZero() {
+		expiresAt = tx.expiresAt.UnixMilli()
+	}
+
+	_, err := g.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, map[string]interface{}{
+			"reference":  tx.reference,
+			"method":     string(tx.method),
+			"amount":     tx.amount,
+			"status":     tx.status,
+			"expires_at": expiresAt,
+		})
+		pipe.Expire(ctx, key, transactionTTL)
+		return nil
+	})
+	return err
+}
+
+func (g *Gateway) load(transactionID string) (*transaction, error) {
+	values, err := g.redisClient.HGetAll(context.Background(), transactionKey(transactionID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+
+	amount, _ := strconv.ParseInt(values["amount"], 10, 64)
+	tx := &transaction{
+		reference: values["reference"],
+		method:    model.PaymentMethod(values["method"]),
+		amount:    amount,
+		status:    values["status"],
+	}
+	if expiresAt, _ := strconv.ParseInt(values["expires_at"], 10, 64); expiresAt > 0 {
+		tx.expiresAt = time.UnixMilli(expiresAt)
+	}
+	return tx, nil
+}
+
+// setStatus moves a known transaction to a new Midtrans transaction_status, unknown transactions
+// are left alone
+func (g *Gateway) setStatus(transactionID string, status string) error {
+	tx, err := g.load(transactionID)
+	if errors.Is(err, ErrTransactionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	tx.status = status
+	return g.save(transactionID, tx)
+}
+
+func newTransactionID() string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s",
+		random.StringWithCharset(8, hexChars),
+		random.StringWithCharset(4, hexChars),
+		random.StringWithCharset(4, hexChars),
+		random.StringWithCharset(4, hexChars),
+		random.StringWithCharset(12, hexChars))
+}
@@ -0,0 +1,86 @@
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"learn/internal/gateway"
+	"learn/internal/gateway/midtrans"
+	"learn/internal/model"
+	"net/http"
+	"time"
+)
+
+// statusCodes are the status_code values Midtrans sends with each simulated transaction_status
+var statusCodes = map[string]string{
+	"settlement": "200",
+	"pending":    "201",
+	"deny":       "202",
+	"cancel":     "202",
+	"expire":     "407",
+	"refund":     "200",
+}
+
+// IsSupportedStatus reports whether a notification can be simulated for the transaction status
+func IsSupportedStatus(transactionStatus string) bool {
+	_, ok := statusCodes[transactionStatus]
+	return ok
+}
+
+// VerifyNotification accepts the same signed payloads as Midtrans, including simulated ones
+func (g *Gateway) VerifyNotification(header http.Header, body []byte) (*gateway.Notification, error) {
+	return midtrans.ParseNotification(body)
+}
+
+// BuildNotification returns a Midtrans notification body for the payment, signed with the
+// configured server key, and moves the stored transaction to the new status. Payments whose
+// transaction is no longer stored fall back to a reference derived from the order.
+func (g *Gateway) BuildNotification(payment model.Payment, amount int64, transactionStatus string) ([]byte, error) {
+	statusCode, ok := statusCodes[transactionStatus]
+	if !ok {
+		return nil, errors.New("unsupported transaction status " + transactionStatus)
+	}
+
+	reference := fmt.Sprintf("ORDER-%d", payment.OrderID)
+	tx, err := g.load(payment.TransactionID)
+	switch {
+	case err == nil:
+		reference = tx.reference
+		tx.status = transactionStatus
+		if err := g.save(payment.TransactionID, tx); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, ErrTransactionNotFound):
+		return nil, err
+	}
+
+	grossAmount := fmt.Sprintf("%d.00", amount)
+	payload := map[string]string{
+		"transaction_time":   time.Now().Format("2006-01-02 15:04:05"),
+		"transaction_status": transactionStatus,
+		"transaction_id":     payment.TransactionID,
+		"status_message":     "midtrans payment notification",
+		"status_code":        statusCode,
+		"signature_key":      midtrans.Signature(reference, statusCode, grossAmount),
+		"payment_type":       paymentType(payment.PaymentMethod),
+		"order_id":           reference,
+		"merchant_id":        "FAKE-MERCHANT",
+		"gross_amount":       grossAmount,
+		"fraud_status":       "accept",
+		"currency":           "IDR",
+	}
+
+	return json.Marshal(payload)
+}
+
+func paymentType(method model.PaymentMethod) string {
+	switch method {
+	case model.PaymentMethodGopay:
+		return "gopay"
+	case model.PaymentMethodIndomaret:
+		return "cstore"
+	case model.PaymentMethodCreditCard:
+		return "credit_card"
+	}
+	return "bank_transfer"
+}
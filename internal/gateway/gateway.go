@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"errors"
+	"learn/internal/model"
+	"net/http"
+	"time"
+)
+
+// ErrInvalidSignature is returned when a webhook does not carry a valid provider signature
+var ErrInvalidSignature = errors.New("invalid notification signature")
+
+// PaymentGateway is implemented by every payment provider adapter
+type PaymentGateway interface {
+	// Name identifies the provider, it is stored on payments and used in webhook routes
+	Name() string
+	Charge(req ChargeRequest) (*ChargeResult, error)
+	GetStatus(transactionID string) (*TransactionStatus, error)
+	Refund(req RefundRequest) (*RefundResult, error)
+	Cancel(transactionID string) error
+	// VerifyNotification checks the provider signature of a webhook and translates its payload
+	VerifyNotification(header http.Header, body []byte) (*Notification, error)
+}
+
+type ChargeRequest struct {
+	Reference   string // Unique merchant reference sent to the provider, e.g. ORDER-<id>-<unix>
+	Amount      int64
+	Method      model.PaymentMethod
+	Description string
+	PayerEmail  string
+	ExpiresAt   time.Time // Payment due of the order, the provider stops accepting the payment after it
+}
+
+// ChargeResult carries the instructions the buyer needs to complete the payment
+type ChargeResult struct {
+	TransactionID        string
+	PaymentURL           string
+	VirtualAccountNumber string
+	PaymentCode          string
+	BillKey              string
+	BillerCode           string
+}
+
+type TransactionStatus struct {
+	TransactionID string
+	Reference     string
+	Status        model.PaymentStatus
+	RawStatus     string // Status as reported by the provider
+	Amount        int64  // Gross amount of the transaction, 0 when the provider does not report it
+}
+
+type RefundRequest struct {
+	TransactionID string
+	Method        model.PaymentMethod
+	RefundKey     string // Makes retries of the same refund idempotent on the provider side
+	Amount        int64
+	Reason        string
+}
+
+type RefundMode string
+
+const (
+	RefundModeDirect RefundMode = "DIRECT" // Money returned by the provider
+	RefundModeManual RefundMode = "MANUAL" // The provider cannot refund the method, money must be returned manually
+)
+
+// RefundResult describes how a refund was carried out
+type RefundResult struct {
+	Mode         RefundMode
+	RefundKey    string
+	RefundAmount string
+}
+
+// Notification is a verified webhook translated to a payment status. Ignore is set for
+// provider statuses that must not change the payment, such as partial refunds.
+type Notification struct {
+	TransactionID string
+	Reference     string
+	Status        model.PaymentStatus
+	RawStatus     string
+	Ignore        bool
+}
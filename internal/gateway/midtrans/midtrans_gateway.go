@@ -3,25 +3,35 @@ package midtrans
 import (
 	"errors"
 	"learn/internal/config"
+	"learn/internal/gateway"
+	"learn/internal/model"
 	"log/slog"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/coreapi"
 )
 
+// ProviderName is stored on payments charged through Midtrans
+const ProviderName = "midtrans"
+
+// Methods lists the payment methods charged through Midtrans
+var Methods = []model.PaymentMethod{
+	model.PaymentMethodBankTransferBCA,
+	model.PaymentMethodBankTransferBNI,
+	model.PaymentMethodBankTransferBRI,
+	model.PaymentMethodGopay,
+	model.PaymentMethodIndomaret,
+}
+
 type midtransGateway struct {
 	client coreapi.Client
 	logger *slog.Logger
 }
 
-type MidtransGateway interface {
-	ChargeBankTransfer(orderID string, amount int64, bank string) (*coreapi.ChargeResponse, error)
-	ChargeGopay(orderID string, amount int64) (*coreapi.ChargeResponse, error)
-	ChargeIndomaret(orderID string, amount int64, message string) (*coreapi.ChargeResponse, error)
-	VerifyPaymentNotification(payload map[string]interface{}) (bool, error)
-}
-
-func NewMidtransGateway(logger *slog.Logger) MidtransGateway {
+func NewMidtransGateway(logger *slog.Logger) gateway.PaymentGateway {
 	c := coreapi.Client{}
 
 	env := midtrans.Sandbox
@@ -37,7 +47,70 @@ func NewMidtransGateway(logger *slog.Logger) MidtransGateway {
 	}
 }
 
-func (g *midtransGateway) ChargeBankTransfer(orderID string, amount int64, bank string) (*coreapi.ChargeResponse, error) {
+func (g *midtransGateway) Name() string {
+	return ProviderName
+}
+
+func (g *midtransGateway) Charge(req gateway.ChargeRequest) (*gateway.ChargeResult, error) {
+	var resp *coreapi.ChargeResponse
+	var err error
+
+	expiry := customExpiry(req.ExpiresAt)
+	switch req.Method {
+	case model.PaymentMethodBankTransferBCA:
+		resp, err = g.chargeBankTransfer(req.Reference, req.Amount, "bca", expiry)
+	case model.PaymentMethodBankTransferBNI:
+		resp, err = g.chargeBankTransfer(req.Reference, req.Amount, "bni", expiry)
+	case model.PaymentMethodBankTransferBRI:
+		resp, err = g.chargeBankTransfer(req.Reference, req.Amount, "bri", expiry)
+	case model.PaymentMethodGopay:
+		resp, err = g.chargeGopay(req.Reference, req.Amount, expiry)
+	case model.PaymentMethodIndomaret:
+		resp, err = g.chargeIndomaret(req.Reference, req.Amount, req.Description, expiry)
+	default:
+		return nil, errors.New("midtrans does not support payment method " + string(req.Method))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &gateway.ChargeResult{TransactionID: resp.TransactionID}
+	if len(resp.VaNumbers) > 0 {
+		result.VirtualAccountNumber = resp.VaNumbers[0].VANumber
+	}
+	for _, action := range resp.Actions {
+		if action.Name == "generate-qr-code" {
+			result.PaymentURL = action.URL
+		}
+	}
+	if resp.PaymentType == "indomaret" {
+		result.PaymentCode = resp.PaymentCode
+	}
+
+	return result, nil
+}
+
+// customExpiry makes Midtrans expire the transaction at the payment due of the order instead of
+// after its own default, so a buyer cannot pay an order that was already cancelled. Midtrans counts
+// in whole minutes, the duration is rounded up.
+func customExpiry(expiresAt time.Time) *coreapi.CustomExpiry {
+	if expiresAt.IsZero() {
+		return nil
+	}
+
+	now := time.Now()
+	minutes := int(math.Ceil(expiresAt.Sub(now).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	return &coreapi.CustomExpiry{
+		OrderTime:      now.Format("2006-01-02 15:04:05 -0700"),
+		ExpiryDuration: minutes,
+		Unit:           "minute",
+	}
+}
+
+func (g *midtransGateway) chargeBankTransfer(orderID string, amount int64, bank string, expiry *coreapi.CustomExpiry) (*coreapi.ChargeResponse, error) {
 	req := &coreapi.ChargeReq{
 		PaymentType: coreapi.PaymentTypeBankTransfer,
 		TransactionDetails: midtrans.TransactionDetails{
@@ -47,6 +120,7 @@ func (g *midtransGateway) ChargeBankTransfer(orderID string, amount int64, bank
 		BankTransfer: &coreapi.BankTransferDetails{
 			Bank: midtrans.Bank(bank),
 		},
+		CustomExpiry: expiry,
 	}
 
 	resp, err := g.client.ChargeTransaction(req)
@@ -58,13 +132,14 @@ func (g *midtransGateway) ChargeBankTransfer(orderID string, amount int64, bank
 	return resp, nil
 }
 
-func (g *midtransGateway) ChargeGopay(orderID string, amount int64) (*coreapi.ChargeResponse, error) {
+func (g *midtransGateway) chargeGopay(orderID string, amount int64, expiry *coreapi.CustomExpiry) (*coreapi.ChargeResponse, error) {
 	req := &coreapi.ChargeReq{
 		PaymentType: coreapi.PaymentTypeGopay,
 		TransactionDetails: midtrans.TransactionDetails{
 			OrderID:  orderID,
 			GrossAmt: amount,
 		},
+		CustomExpiry: expiry,
 	}
 
 	resp, err := g.client.ChargeTransaction(req)
@@ -76,7 +151,7 @@ func (g *midtransGateway) ChargeGopay(orderID string, amount int64) (*coreapi.Ch
 	return resp, nil
 }
 
-func (g *midtransGateway) ChargeIndomaret(orderID string, amount int64, message string) (*coreapi.ChargeResponse, error) {
+func (g *midtransGateway) chargeIndomaret(orderID string, amount int64, message string, expiry *coreapi.CustomExpiry) (*coreapi.ChargeResponse, error) {
 	req := &coreapi.ChargeReq{
 		PaymentType: coreapi.PaymentTypeConvenienceStore,
 		TransactionDetails: midtrans.TransactionDetails{
@@ -87,6 +162,7 @@ func (g *midtransGateway) ChargeIndomaret(orderID string, amount int64, message
 			Store:   "indomaret",
 			Message: message,
 		},
+		CustomExpiry: expiry,
 	}
 
 	resp, err := g.client.ChargeTransaction(req)
@@ -97,3 +173,91 @@ func (g *midtransGateway) ChargeIndomaret(orderID string, amount int64, message
 
 	return resp, nil
 }
+
+func (g *midtransGateway) GetStatus(transactionID string) (*gateway.TransactionStatus, error) {
+	resp, err := g.client.CheckTransaction(transactionID)
+	if err != nil {
+		g.logger.Error("Midtrans Status Error", slog.String("error", err.Message), slog.String("transaction_id", transactionID))
+		return nil, errors.New("midtrans status check failed: " + err.Message)
+	}
+
+	status, _ := MapTransactionStatus(resp.TransactionStatus, resp.FraudStatus)
+	amount, _ := strconv.ParseFloat(resp.GrossAmount, 64) // Midtrans reports "10000.00"
+	return &gateway.TransactionStatus{
+		TransactionID: resp.TransactionID,
+		Reference:     resp.OrderID,
+		Status:        status,
+		RawStatus:     resp.TransactionStatus,
+		Amount:        int64(amount),
+	}, nil
+}
+
+// Refund returns money for a settled transaction. Gopay is refunded directly and cards
+// through the online refund API. Bank transfer and convenience store payments cannot be
+// refunded by Midtrans, so they only yield a manual settlement result. The refund key
+// makes retries of the same refund idempotent on the Midtrans side.
+func (g *midtransGateway) Refund(req gateway.RefundRequest) (*gateway.RefundResult, error) {
+	refundReq := &coreapi.RefundReq{
+		RefundKey: req.RefundKey,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+	}
+
+	var resp *coreapi.RefundResponse
+	var err *midtrans.Error
+
+	switch req.Method {
+	case model.PaymentMethodGopay:
+		resp, err = g.client.DirectRefundTransaction(req.TransactionID, refundReq)
+	case model.PaymentMethodCreditCard:
+		resp, err = g.client.RefundTransaction(req.TransactionID, refundReq)
+	case model.PaymentMethodVirtualAccount, model.PaymentMethodBankTransferBCA, model.PaymentMethodBankTransferBNI,
+		model.PaymentMethodBankTransferBRI, model.PaymentMethodIndomaret:
+		return &gateway.RefundResult{Mode: gateway.RefundModeManual, RefundKey: req.RefundKey}, nil
+	default:
+		return nil, errors.New("refund is not supported for payment method " + string(req.Method))
+	}
+
+	if err != nil {
+		g.logger.Error("Midtrans Refund Error", slog.String("error", err.Message), slog.String("refund_key", req.RefundKey))
+		return nil, errors.New("midtrans refund failed: " + err.Message)
+	}
+
+	return &gateway.RefundResult{
+		Mode:         gateway.RefundModeDirect,
+		RefundKey:    resp.RefundKey,
+		RefundAmount: resp.RefundAmount,
+	}, nil
+}
+
+// Cancel stops a transaction that has not been settled yet
+func (g *midtransGateway) Cancel(transactionID string) error {
+	if _, err := g.client.CancelTransaction(transactionID); err != nil {
+		g.logger.Error("Midtrans Cancel Error", slog.String("error", err.Message), slog.String("transaction_id", transactionID))
+		return errors.New("midtrans cancel failed: " + err.Message)
+	}
+	return nil
+}
+
+// MapTransactionStatus translates a Midtrans transaction status. The bool is false for
+// statuses that must not change the payment.
+func MapTransactionStatus(transactionStatus, fraudStatus string) (model.PaymentStatus, bool) {
+	switch transactionStatus {
+	case "capture":
+		if fraudStatus == "challenge" {
+			return model.PaymentStatusPending, true // Admin need to review
+		} else if fraudStatus == "accept" {
+			return model.PaymentStatusSuccess, true
+		}
+	case "settlement":
+		return model.PaymentStatusSuccess, true
+	case "deny", "cancel", "expire":
+		return model.PaymentStatusFailed, true
+	case "refund":
+		return model.PaymentStatusRefunded, true
+	case "pending":
+		return model.PaymentStatusPending, true
+	}
+	// Partial refunds are tracked by refund records, the payment still covers the remaining tickets
+	return "", false
+}
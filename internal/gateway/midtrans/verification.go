@@ -2,38 +2,70 @@ package midtrans
 
 import (
 	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"learn/internal/config"
+	"learn/internal/gateway"
+	"net/http"
 )
 
-func (g *midtransGateway) VerifyPaymentNotification(payload map[string]interface{}) (bool, error) {
-	orderID, exists := payload["order_id"].(string)
-	if !exists {
-		return false, errors.New("invalid notification payload: order_id missing")
+type notificationPayload struct {
+	OrderID           string `json:"order_id"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"`
+	SignatureKey      string `json:"signature_key"`
+	TransactionID     string `json:"transaction_id"`
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+}
+
+func (g *midtransGateway) VerifyNotification(header http.Header, body []byte) (*gateway.Notification, error) {
+	return ParseNotification(body)
+}
+
+// Signature is the signature_key Midtrans puts in notifications, a SHA-512 of
+// order_id + status_code + gross_amount + server key
+func Signature(orderID, statusCode, grossAmount string) string {
+	hash := sha512.Sum512([]byte(orderID + statusCode + grossAmount + config.AppConfig.MidtransServerKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// ParseNotification verifies the signature of a Midtrans notification body and translates its status
+func ParseNotification(body []byte) (*gateway.Notification, error) {
+	var payload notificationPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("invalid notification payload: " + err.Error())
 	}
-	statusCode, exists := payload["status_code"].(string)
-	if !exists {
-		return false, errors.New("invalid notification payload: status_code missing")
+	if payload.OrderID == "" {
+		return nil, errors.New("invalid notification payload: order_id missing")
 	}
-	grossAmount, exists := payload["gross_amount"].(string)
-	if !exists {
-		return false, errors.New("invalid notification payload: gross_amount missing")
+	if payload.StatusCode == "" {
+		return nil, errors.New("invalid notification payload: status_code missing")
 	}
-	signatureKey, exists := payload["signature_key"].(string)
-	if !exists {
-		return false, errors.New("invalid notification payload: signature_key missing")
+	if payload.GrossAmount == "" {
+		return nil, errors.New("invalid notification payload: gross_amount missing")
+	}
+	if payload.SignatureKey == "" {
+		return nil, errors.New("invalid notification payload: signature_key missing")
 	}
 
-	serverKey := config.AppConfig.MidtransServerKey
-	input := orderID + statusCode + grossAmount + serverKey
-
-	hash := sha512.Sum512([]byte(input))
-	expectedSignature := hex.EncodeToString(hash[:])
+	expectedSignature := Signature(payload.OrderID, payload.StatusCode, payload.GrossAmount)
+	if subtle.ConstantTimeCompare([]byte(payload.SignatureKey), []byte(expectedSignature)) != 1 {
+		return nil, gateway.ErrInvalidSignature
+	}
 
-	if signatureKey != expectedSignature {
-		return false, nil
+	if payload.TransactionID == "" || payload.TransactionStatus == "" {
+		return nil, errors.New("invalid notification payload: missing transaction fields")
 	}
 
-	return true, nil
+	status, ok := MapTransactionStatus(payload.TransactionStatus, payload.FraudStatus)
+	return &gateway.Notification{
+		TransactionID: payload.TransactionID,
+		Reference:     payload.OrderID,
+		Status:        status,
+		RawStatus:     payload.TransactionStatus,
+		Ignore:        !ok,
+	}, nil
 }
@@ -0,0 +1,31 @@
+package providers
+
+import (
+	"learn/internal/config"
+	"learn/internal/gateway"
+	"learn/internal/gateway/fake"
+	"learn/internal/gateway/midtrans"
+	"learn/internal/gateway/xendit"
+	"log/slog"
+)
+
+// NewRegistry registers every configured payment provider with the payment methods it charges.
+// MIDTRANS_ENV=fake swaps Midtrans for the fake gateway kept in Redis, and Xendit is only
+// registered when its secret key is set.
+func NewRegistry(logger *slog.Logger) *gateway.Registry {
+	registry := gateway.NewRegistry()
+	if config.AppConfig.MidtransEnv == "fake" {
+		logger.Warn("MIDTRANS_ENV=fake, Midtrans payments are simulated and never charged")
+		registry.Register(fake.NewFakeGateway(config.Rdb, logger), midtrans.Methods...)
+	} else {
+		registry.Register(midtrans.NewMidtransGateway(logger), midtrans.Methods...)
+	}
+
+	if config.AppConfig.XenditSecretKey != "" {
+		registry.Register(xendit.NewXenditGateway(logger), xendit.Methods...)
+	} else {
+		logger.Info("XENDIT_SECRET_KEY not set, Xendit payment methods are disabled")
+	}
+
+	return registry
+}
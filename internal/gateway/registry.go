@@ -0,0 +1,36 @@
+package gateway
+
+import "learn/internal/model"
+
+// Registry resolves the provider for a payment method when charging, and by name for
+// payments that already exist
+type Registry struct {
+	byMethod map[model.PaymentMethod]PaymentGateway
+	byName   map[string]PaymentGateway
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		byMethod: make(map[model.PaymentMethod]PaymentGateway),
+		byName:   make(map[string]PaymentGateway),
+	}
+}
+
+// Register adds a provider and routes the given payment methods to it. A method registered
+// twice goes to the provider registered last.
+func (r *Registry) Register(gateway PaymentGateway, methods ...model.PaymentMethod) {
+	r.byName[gateway.Name()] = gateway
+	for _, method := range methods {
+		r.byMethod[method] = gateway
+	}
+}
+
+func (r *Registry) ForMethod(method model.PaymentMethod) (PaymentGateway, bool) {
+	gateway, ok := r.byMethod[method]
+	return gateway, ok
+}
+
+func (r *Registry) ByName(name string) (PaymentGateway, bool) {
+	gateway, ok := r.byName[name]
+	return gateway, ok
+}
@@ -0,0 +1,35 @@
+package xendit
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"learn/internal/gateway"
+	"net/http"
+)
+
+// VerifyNotification checks the x-callback-token header Xendit sends with every invoice
+// callback against the verification token of the account
+func (g *xenditGateway) VerifyNotification(header http.Header, body []byte) (*gateway.Notification, error) {
+	token := header.Get("x-callback-token")
+	if g.callbackToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(g.callbackToken)) != 1 {
+		return nil, gateway.ErrInvalidSignature
+	}
+
+	var payload invoice
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("invalid notification payload: " + err.Error())
+	}
+	if payload.ID == "" || payload.Status == "" {
+		return nil, errors.New("invalid notification payload: id or status missing")
+	}
+
+	status, ok := mapInvoiceStatus(payload.Status)
+	return &gateway.Notification{
+		TransactionID: payload.ID,
+		Reference:     payload.ExternalID,
+		Status:        status,
+		RawStatus:     payload.Status,
+		Ignore:        !ok,
+	}, nil
+}
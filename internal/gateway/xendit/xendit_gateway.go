@@ -0,0 +1,230 @@
+package xendit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"learn/internal/config"
+	"learn/internal/gateway"
+	"learn/internal/model"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProviderName is stored on payments charged through Xendit
+const ProviderName = "xendit"
+
+// channelCodes maps the payment methods charged through Xendit to invoice payment channels
+var channelCodes = map[model.PaymentMethod]string{
+	model.PaymentMethodBankTransferMandiri: "MANDIRI",
+	model.PaymentMethodOVO:                 "OVO",
+	model.PaymentMethodAlfamart:            "ALFAMART",
+}
+
+// Methods lists the payment methods charged through Xendit
+var Methods = []model.PaymentMethod{
+	model.PaymentMethodBankTransferMandiri,
+	model.PaymentMethodOVO,
+	model.PaymentMethodAlfamart,
+}
+
+type xenditGateway struct {
+	httpClient    *http.Client
+	baseURL       string
+	secretKey     string
+	callbackToken string
+	logger        *slog.Logger
+}
+
+// NewXenditGateway charges through Xendit invoices, which give the buyer a hosted payment
+// page restricted to the chosen channel
+func NewXenditGateway(logger *slog.Logger) gateway.PaymentGateway {
+	return &xenditGateway{
+		httpClient:    &http.Client{Timeout: 15 * time.Second},
+		baseURL:       strings.TrimRight(config.AppConfig.XenditBaseURL, "/"),
+		secretKey:     config.AppConfig.XenditSecretKey,
+		callbackToken: config.AppConfig.XenditCallbackToken,
+		logger:        logger,
+	}
+}
+
+func (g *xenditGateway) Name() string {
+	return ProviderName
+}
+
+type invoice struct {
+	ID         string `json:"id"`
+	ExternalID string `json:"external_id"`
+	Status     string `json:"status"`
+	InvoiceURL string `json:"invoice_url"`
+	Amount     int64  `json:"amount"`
+}
+
+func (g *xenditGateway) Charge(req gateway.ChargeRequest) (*gateway.ChargeResult, error) {
+	channel, ok := channelCodes[req.Method]
+	if !ok {
+		return nil, errors.New("xendit does not support payment method " + string(req.Method))
+	}
+
+	body := map[string]interface{}{
+		"external_id":     req.Reference,
+		"amount":          req.Amount,
+		"description":     req.Description,
+		"payment_methods": []string{channel},
+	}
+	if req.PayerEmail != "" {
+		body["payer_email"] = req.PayerEmail
+	}
+	// The invoice expires with the order instead of after the default 24 hours
+	if !req.ExpiresAt.IsZero() {
+		seconds := int64(math.Ceil(time.Until(req.ExpiresAt).Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		body["invoice_duration"] = seconds
+	}
+
+	var resp invoice
+	if err := g.do(http.MethodPost, "/v2/invoices", body, nil, &resp); err != nil {
+		g.logger.Error("Xendit Charge Error", slog.String("error", err.Error()), slog.String("reference", req.Reference))
+		return nil, errors.New("xendit charge failed: " + err.Error())
+	}
+
+	return &gateway.ChargeResult{
+		TransactionID: resp.ID,
+		PaymentURL:    resp.InvoiceURL,
+	}, nil
+}
+
+func (g *xenditGateway) GetStatus(transactionID string) (*gateway.TransactionStatus, error) {
+	var resp invoice
+	if err := g.do(http.MethodGet, "/v2/invoices/"+url.PathEscape(transactionID), nil, nil, &resp); err != nil {
+		g.logger.Error("Xendit Status Error", slog.String("error", err.Error()), slog.String("transaction_id", transactionID))
+		return nil, errors.New("xendit status check failed: " + err.Error())
+	}
+
+	status, _ := mapInvoiceStatus(resp.Status)
+	return &gateway.TransactionStatus{
+		TransactionID: resp.ID,
+		Reference:     resp.ExternalID,
+		Status:        status,
+		RawStatus:     resp.Status,
+		Amount:        resp.Amount,
+	}, nil
+}
+
+// Refund returns money of e-wallet payments through the Xendit refund API. Bank transfer
+// and retail outlet payments cannot be refunded by Xendit and yield a manual settlement result.
+func (g *xenditGateway) Refund(req gateway.RefundRequest) (*gateway.RefundResult, error) {
+	switch req.Method {
+	case model.PaymentMethodOVO:
+	case model.PaymentMethodBankTransferMandiri, model.PaymentMethodAlfamart:
+		return &gateway.RefundResult{Mode: gateway.RefundModeManual, RefundKey: req.RefundKey}, nil
+	default:
+		return nil, errors.New("refund is not supported for payment method " + string(req.Method))
+	}
+
+	body := map[string]interface{}{
+		"invoice_id": req.TransactionID,
+		"amount":     req.Amount,
+		"reason":     "REQUESTED_BY_CUSTOMER",
+		"metadata":   map[string]string{"note": req.Reason},
+	}
+	header := http.Header{}
+	header.Set("Idempotency-key", req.RefundKey)
+
+	var resp struct {
+		ID     string `json:"id"`
+		Amount int64  `json:"amount"`
+	}
+	if err := g.do(http.MethodPost, "/refunds", body, header, &resp); err != nil {
+		g.logger.Error("Xendit Refund Error", slog.String("error", err.Error()), slog.String("refund_key", req.RefundKey))
+		return nil, errors.New("xendit refund failed: " + err.Error())
+	}
+
+	return &gateway.RefundResult{
+		Mode:         gateway.RefundModeDirect,
+		RefundKey:    resp.ID,
+		RefundAmount: fmt.Sprintf("%d", resp.Amount),
+	}, nil
+}
+
+// Cancel expires an unpaid invoice
+func (g *xenditGateway) Cancel(transactionID string) error {
+	if err := g.do(http.MethodPost, "/invoices/"+url.PathEscape(transactionID)+"/expire!", nil, nil, nil); err != nil {
+		g.logger.Error("Xendit Cancel Error", slog.String("error", err.Error()), slog.String("transaction_id", transactionID))
+		return errors.New("xendit cancel failed: " + err.Error())
+	}
+	return nil
+}
+
+// do sends an authenticated request to the Xendit API and decodes the JSON response into out
+func (g *xenditGateway) do(method, path string, body interface{}, header http.Header, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, g.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(g.secretKey, "")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			ErrorCode string `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.ErrorCode != "" {
+			return fmt.Errorf("%s: %s", apiErr.ErrorCode, apiErr.Message)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// mapInvoiceStatus translates a Xendit invoice status. The bool is false for statuses
+// that must not change the payment.
+func mapInvoiceStatus(status string) (model.PaymentStatus, bool) {
+	switch strings.ToUpper(status) {
+	case "PAID", "SETTLED":
+		return model.PaymentStatusSuccess, true
+	case "EXPIRED":
+		return model.PaymentStatusFailed, true
+	case "PENDING":
+		return model.PaymentStatusPending, true
+	}
+	return "", false
+}
@@ -1,9 +1,9 @@
 package middleware
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"learn/internal/pkg/requestid"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,8 +20,7 @@ func RequestIDMiddleware() gin.HandlerFunc {
 
 		c.Set(requestIDKey, requestID)
 		c.Header("X-Request-ID", requestID)
-		ctx := context.WithValue(c.Request.Context(), requestIDKey, requestID)
-		c.Request = c.Request.WithContext(ctx)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), requestID))
 
 		c.Next()
 	}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EventCancellationStatus string
+
+const (
+	EventCancellationRunning   EventCancellationStatus = "RUNNING"
+	EventCancellationCompleted EventCancellationStatus = "COMPLETED"
+	EventCancellationPartial   EventCancellationStatus = "PARTIAL" // Finished, but some orders failed and need a resume
+)
+
+// EventCancellation tracks the background cancel-and-refund run started when an event is cancelled.
+// Orders are processed in ID order and LastOrderID is the cursor a resumed run continues from.
+type EventCancellation struct {
+	gorm.Model
+	EventID           uint `gorm:"not null;uniqueIndex"`
+	Event             Event
+	RequestedByUserID uint `gorm:"not null"`
+	Reason            string
+	Status            EventCancellationStatus `gorm:"type:varchar(20);not null;default:'RUNNING'"`
+	LastOrderID       uint
+	TotalOrders       int
+	ProcessedOrders   int
+	CancelledOrders   int // Pending orders cancelled with their quota restored
+	RefundedOrders    int // Paid orders whose refund was completed or moved to manual settlement
+	FailedOrders      int
+	TicketsVoided     int
+	EmailsSent        int
+	LastError         string
+	CompletedAt       *time.Time
+}
+
+// IsFinished reports whether the run has nothing left to process
+func (c EventCancellation) IsFinished() bool {
+	return c.Status == EventCancellationCompleted
+}
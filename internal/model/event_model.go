@@ -16,17 +16,23 @@ const (
 
 type Event struct {
 	gorm.Model
-	VenueID        uint `gorm:"not null"`
-	Venue          Venue
-	Name           string `gorm:"not null"`
-	Slug           string `gorm:"uniqueIndex;not null"`
-	Description    string
-	EventStartAt   time.Time   `gorm:"not null"`
-	Status         EventStatus `gorm:"default:'DRAFT'"`
-	SalesStartDate time.Time
-	SalesEndDate   time.Time
-	EventGuests    []EventGuest `gorm:"foreignKey:EventID"`
-	Prices         []EventPrice `gorm:"foreignKey:EventID"`
+	VenueID                   uint `gorm:"not null"`
+	Venue                     Venue
+	Name                      string `gorm:"not null"`
+	Slug                      string `gorm:"uniqueIndex;not null"`
+	Description               string
+	EventStartAt              time.Time   `gorm:"not null"`
+	Status                    EventStatus `gorm:"default:'DRAFT'"`
+	SalesStartDate            time.Time
+	SalesEndDate              time.Time
+	AllowReentry              bool         `gorm:"default:false"` // Checked-out guests may check in again
+	TransfersDisabled         bool         `gorm:"default:false"`
+	TransferCutoffHours       int          // Transfers close this many hours before EventStartAt
+	PaymentDueMinutes         *int         // Caps the payment window of every payment method, nil keeps the method defaults
+	WaitingRoomEnabled        bool         `gorm:"default:false"` // Buyers queue and need an admission token to order
+	WaitingRoomAdmitPerMinute int          // Admissions per minute, 0 uses WAITING_ROOM_ADMIT_PER_MINUTE
+	EventGuests               []EventGuest `gorm:"foreignKey:EventID"`
+	Prices                    []EventPrice `gorm:"foreignKey:EventID"`
 }
 
 type EventGuest struct {
@@ -39,9 +45,18 @@ type EventGuest struct {
 
 type EventPrice struct {
 	gorm.Model
-	EventID uint   `gorm:"not null"`
+	EventID uint `gorm:"not null"`
+	Event   Event
 	Name    string `gorm:"not null"`
 	Price   int64  `gorm:"not null"`
 	Quota   int    `gorm:"not null"`
-	Tickets []Ticket
+	// WaitlistQuota is the part of Quota set aside for the waitlist: returned quota while users are
+	// waiting, and the quota of open offers. Only Quota - WaitlistQuota is sold to everyone else.
+	WaitlistQuota int `gorm:"not null;default:0"`
+	Tickets       []Ticket
+}
+
+// TransferDeadline is the last moment tickets of the event can be transferred
+func (e Event) TransferDeadline() time.Time {
+	return e.EventStartAt.Add(-time.Duration(e.TransferCutoffHours) * time.Hour)
 }
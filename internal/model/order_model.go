@@ -12,6 +12,7 @@ const (
 	OrderPending   OrderStatus = "PENDING"
 	OrderPaid      OrderStatus = "PAID"
 	OrderCancelled OrderStatus = "CANCELLED"
+	OrderRefunded  OrderStatus = "REFUNDED" // Every ticket of the order was refunded
 )
 
 type Order struct {
@@ -20,7 +21,9 @@ type Order struct {
 	User       User
 	TotalPrice int64       `gorm:"not null"` // Total price in smallest currency unit (e.g., cents)
 	Status     OrderStatus `gorm:"not null;default:'PENDING'"`
-	PaymentDue time.Time
+	PaymentDue time.Time // Unpaid orders are cancelled after this, the end of the hold until a payment is started
+	HoldExpiresAt *time.Time // End of the reservation, nil once a payment is started
+	HoldExtended  bool       `gorm:"not null;default:false"` // A hold is extended at most once
 	OrderLineItems []OrderLineItem `gorm:"foreignKey:OrderID"` // Added
 	Tickets    []Ticket
 }
@@ -0,0 +1,16 @@
+package model
+
+// CanTransitionOrderStatus reports whether an order may move from one status to another. Orders
+// are paid or cancelled only while PENDING, and only paid orders are refunded.
+func CanTransitionOrderStatus(from, to OrderStatus) bool {
+	switch from {
+	case OrderPending:
+		return to == OrderPaid || to == OrderCancelled
+	case OrderPaid:
+		return to == OrderRefunded
+	case OrderCancelled, OrderRefunded:
+		return false
+	default:
+		return false
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// OutboxEvent is a domain event stored in the same transaction as the state change it describes.
+// The outbox relay publishes unsent rows to the Redis stream and sets SentAt, so an event is
+// delivered at least once even if Redis is unavailable when the change commits.
+type OutboxEvent struct {
+	ID            uint   `gorm:"primarykey"`
+	EventID       string `gorm:"type:varchar(64)"` // Empty for rows written before events carried an ID
+	EventType     string `gorm:"type:varchar(100);not null"`
+	Version       int    `gorm:"not null;default:1"` // Schema version of Payload
+	CorrelationID string `gorm:"type:varchar(64)"`
+	Payload       string `gorm:"type:jsonb;not null"`
+	Attempts      int    `gorm:"not null;default:0"` // Failed publish attempts
+	LastError     string
+	CreatedAt     time.Time
+	SentAt        *time.Time `gorm:"index:idx_outbox_events_unsent,where:sent_at IS NULL"`
+}
@@ -11,9 +11,10 @@ type Payment struct {
 	gorm.Model
 	OrderID       uint          `gorm:"unique;not null" json:"order_id"` // Foreign Key ke Order (1:1 relationship)
 	PaymentMethod PaymentMethod `gorm:"type:varchar(50);not null" json:"payment_method"`
-	TransactionID string        `gorm:"unique;not null" json:"transaction_id"` // ID from payment gateway
+	TransactionID string        `gorm:"unique;not null" json:"transaction_id"`                        // ID from payment gateway
+	Provider      string        `gorm:"type:varchar(20);not null;default:'midtrans'" json:"provider"` // Payment gateway that charged the payment
 
-	// Midtrans Response Fields
+	// Payment Gateway Response Fields
 	PaymentURL           string `json:"payment_url"`
 	VirtualAccountNumber string `json:"virtual_account_number"`
 	BillKey              string `json:"bill_key"`
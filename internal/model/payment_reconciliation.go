@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReconciliationIssueType string
+
+const (
+	ReconciliationAmountMismatch        ReconciliationIssueType = "AMOUNT_MISMATCH"         // Gateway amount differs from Order.TotalPrice
+	ReconciliationPaidAfterCancellation ReconciliationIssueType = "PAID_AFTER_CANCELLATION" // Settled at the gateway after the order was closed
+)
+
+type ReconciliationIssueStatus string
+
+const (
+	ReconciliationIssueOpen     ReconciliationIssueStatus = "OPEN"
+	ReconciliationIssueResolved ReconciliationIssueStatus = "RESOLVED"
+)
+
+// PaymentReconciliationIssue is a mismatch between a payment and its gateway transaction that
+// the reconciler refused to apply automatically. Admins review and resolve them by hand.
+type PaymentReconciliationIssue struct {
+	gorm.Model
+	PaymentID        uint `gorm:"not null;uniqueIndex:idx_reconciliation_issue_payment_type"`
+	Payment          Payment
+	OrderID          uint                      `gorm:"not null;index"`
+	Type             ReconciliationIssueType   `gorm:"type:varchar(30);not null;uniqueIndex:idx_reconciliation_issue_payment_type"`
+	Status           ReconciliationIssueStatus `gorm:"type:varchar(20);not null;default:'OPEN'"`
+	GatewayStatus    string                    `gorm:"type:varchar(30)"` // Status as reported by the provider
+	ExpectedAmount   int64
+	GatewayAmount    int64
+	Detail           string
+	ResolvedByUserID *uint
+	ResolutionNote   string
+	ResolvedAt       *time.Time
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefundStatus string
+
+const (
+	RefundRequested        RefundStatus = "REQUESTED"
+	RefundProcessing       RefundStatus = "PROCESSING"        // Approved, gateway refund in progress
+	RefundManualSettlement RefundStatus = "MANUAL_SETTLEMENT" // Approved, money must be returned outside the gateway
+	RefundCompleted        RefundStatus = "COMPLETED"
+	RefundRejected         RefundStatus = "REJECTED"
+)
+
+type RefundType string
+
+const (
+	RefundFull    RefundType = "FULL"
+	RefundPartial RefundType = "PARTIAL"
+)
+
+// Refund is a request to return the money paid for some or all tickets of an order
+type Refund struct {
+	gorm.Model
+	OrderID             uint `gorm:"not null;index"`
+	Order               Order
+	PaymentID           uint         `gorm:"not null;index"`
+	RequestedByUserID   uint         `gorm:"not null;index"`
+	ReviewedByUserID    *uint        `gorm:"index"`
+	Type                RefundType   `gorm:"type:varchar(20);not null"`
+	Status              RefundStatus `gorm:"type:varchar(20);not null;default:'REQUESTED'"`
+	Amount              int64        `gorm:"not null"` // Amount in smallest currency unit (e.g., cents)
+	Reason              string
+	ReviewNote          string
+	GatewayRefundKey    string `gorm:"type:varchar(100)"`
+	SettlementReference string `gorm:"type:varchar(255)"` // Proof of a manual bank transfer back to the buyer
+	ReviewedAt          *time.Time
+	CompletedAt         *time.Time
+	Tickets             []RefundTicket `gorm:"foreignKey:RefundID"`
+}
+
+type RefundTicket struct {
+	RefundID uint `gorm:"primaryKey"`
+	TicketID uint `gorm:"primaryKey"`
+	Ticket   Ticket
+	Amount   int64 `gorm:"not null"`
+}
+
+// IsOpen reports whether the refund still holds its tickets
+func (r Refund) IsOpen() bool {
+	return r.Status == RefundRequested || r.Status == RefundProcessing
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SchedulerRunStatus string
+
+const (
+	SchedulerRunRunning   SchedulerRunStatus = "RUNNING"
+	SchedulerRunSucceeded SchedulerRunStatus = "SUCCEEDED"
+	SchedulerRunFailed    SchedulerRunStatus = "FAILED"
+)
+
+type SchedulerRunTrigger string
+
+const (
+	SchedulerTriggerSchedule SchedulerRunTrigger = "SCHEDULE"
+	SchedulerTriggerManual   SchedulerRunTrigger = "MANUAL"
+)
+
+// SchedulerRun is one run of a scheduled job, on the node that held the job lock
+type SchedulerRun struct {
+	gorm.Model
+	JobName           string              `gorm:"type:varchar(100);not null;index"`
+	Trigger           SchedulerRunTrigger `gorm:"type:varchar(20);not null"`
+	TriggeredByUserID *uint               // Admin that triggered a manual run
+	Node              string              `gorm:"type:varchar(255)"`
+	Status            SchedulerRunStatus  `gorm:"type:varchar(20);not null;default:'RUNNING';index"`
+	StartedAt         time.Time           `gorm:"not null"`
+	FinishedAt        *time.Time
+	DurationMs        int64
+	Result            string `gorm:"type:text"` // Summary returned by the job
+	Error             string `gorm:"type:text"`
+}
@@ -1,18 +1,26 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Ticket struct {
 	gorm.Model
-	EventPriceID uint   `gorm:"not null"`
+	EventPriceID uint `gorm:"not null"`
 	EventPrice   EventPrice
 	OrderID      uint   `gorm:"not null"`
-	Price        int64 `gorm:"not null"` // Price in smallest currency unit (e.g., cents)
+	Price        int64  `gorm:"not null"` // Price in smallest currency unit (e.g., cents)
 	Type         string `gorm:"not null"`
 	SeatNumber   string
-	TicketCode   string `gorm:"not null;unique"`
-	QrCodePath   string `gorm:"type:varchar(255)"`
-	IsScanned    bool   `gorm:"default:false"`
+	TicketCode   string    `gorm:"not null;unique"`
+	QrCodePath   string    `gorm:"type:varchar(255)"`
+	QrIssuedAt   time.Time // Issue time signed into the QR token; bumping it revokes older QR codes
+	IsScanned    bool      `gorm:"default:false"` // Checked in at least once
+	IsInside     bool      `gorm:"default:false"` // Currently checked in and not checked out
 	OwnerName    string
 	OwnerEmail   string
+	HolderUserID *uint      `gorm:"index"` // Set once the ticket is transferred; nil while the buyer holds it
+	VoidedAt     *time.Time // Set when the ticket is refunded; voided tickets cannot be used
 }
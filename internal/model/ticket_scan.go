@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ScanDirection string
+
+const (
+	ScanIn  ScanDirection = "IN"
+	ScanOut ScanDirection = "OUT"
+)
+
+type ScanResult string
+
+const (
+	ScanAccepted ScanResult = "ACCEPTED"
+	ScanRejected ScanResult = "REJECTED"
+)
+
+// TicketScan is an audit record of every scan attempt at the gate, including rejected ones
+type TicketScan struct {
+	gorm.Model
+	TicketID      *uint         `gorm:"index"` // Nil when the scanned content did not resolve to a ticket
+	EventID       *uint         `gorm:"index"`
+	ScannerUserID uint          `gorm:"not null;index"`
+	DeviceID      string        `gorm:"type:varchar(100)"`
+	Gate          string        `gorm:"type:varchar(100)"`
+	Direction     ScanDirection `gorm:"type:varchar(10);not null"`
+	Result        ScanResult    `gorm:"type:varchar(20);not null"`
+	Reason        string        `gorm:"type:varchar(100)"`
+	Offline       bool          `gorm:"default:false"` // Recorded by a scanner device while offline and synced later
+	ScannedAt     time.Time     `gorm:"not null"`
+}
+
+func (d ScanDirection) IsValid() bool {
+	return d == ScanIn || d == ScanOut
+}
+
+// ScanRejection returns the rule that prevents scanning the ticket in the given direction,
+// or an empty string when the scan is allowed. refundPending tells whether the ticket is part
+// of a refund that is still requested or processing.
+func ScanRejection(ticket Ticket, orderStatus OrderStatus, direction ScanDirection, allowReentry bool, refundPending bool) string {
+	if ticket.VoidedAt != nil {
+		return "ticket_voided"
+	}
+
+	if orderStatus != OrderPaid {
+		return "ticket_order_status"
+	}
+
+	switch direction {
+	case ScanIn:
+		if refundPending {
+			return "ticket_refund_pending"
+		}
+		if ticket.IsInside {
+			return "ticket_already_inside"
+		}
+		if ticket.IsScanned && !allowReentry {
+			return "ticket_already_scanned"
+		}
+	case ScanOut:
+		if !ticket.IsInside {
+			return "ticket_not_inside"
+		}
+	default:
+		return "scan_direction"
+	}
+
+	return ""
+}
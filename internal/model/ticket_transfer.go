@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTransferCutoffHours is used when an organizer does not set a transfer cutoff
+const DefaultTransferCutoffHours = 24
+
+type TicketTransferStatus string
+
+const (
+	TransferPending   TicketTransferStatus = "PENDING"
+	TransferAccepted  TicketTransferStatus = "ACCEPTED"
+	TransferCancelled TicketTransferStatus = "CANCELLED"
+)
+
+// TicketTransfer is an offer from the current holder of a ticket to hand it over to
+// the user registered with ToEmail
+type TicketTransfer struct {
+	gorm.Model
+	TicketID   uint `gorm:"not null;index"`
+	Ticket     Ticket
+	FromUserID uint                 `gorm:"not null;index"`
+	ToEmail    string               `gorm:"type:varchar(255);not null;index"`
+	ToUserID   *uint                `gorm:"index"`
+	Status     TicketTransferStatus `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	AcceptedAt *time.Time
+}
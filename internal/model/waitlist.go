@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting   WaitlistStatus = "WAITING"
+	WaitlistOffered   WaitlistStatus = "OFFERED"   // Quota is held for the user until OfferExpiresAt
+	WaitlistConverted WaitlistStatus = "CONVERTED" // The user ordered with the offer
+	WaitlistExpired   WaitlistStatus = "EXPIRED"   // The offer was not used in time and passed down the list
+	WaitlistLeft      WaitlistStatus = "LEFT"
+)
+
+// WaitlistEntry is a user waiting for quota of a sold-out price tier. Entries are served in ID
+// order, skipping those that ask for more than the quota that came back.
+type WaitlistEntry struct {
+	gorm.Model
+	EventPriceID   uint `gorm:"not null;index"`
+	EventPrice     EventPrice
+	UserID         uint `gorm:"not null;index"`
+	User           User
+	Quantity       int            `gorm:"not null"`
+	Status         WaitlistStatus `gorm:"type:varchar(20);not null;default:'WAITING';index"`
+	OfferedAt      *time.Time
+	OfferExpiresAt *time.Time
+	OrderID        *uint `gorm:"index"` // Order placed with the offer
+}
+
+// HasOpenOffer reports whether the entry holds quota for its user at now
+func (e WaitlistEntry) HasOpenOffer(now time.Time) bool {
+	return e.Status == WaitlistOffered && e.OfferExpiresAt != nil && now.Before(*e.OfferExpiresAt)
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookEventType string
+
+const (
+	WebhookOrderPaid       WebhookEventType = "order.paid"
+	WebhookTicketCheckedIn WebhookEventType = "ticket.checked_in"
+	WebhookEventCancelled  WebhookEventType = "event.cancelled"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED" // Gave up after the maximum attempts
+)
+
+// WebhookEndpoint is a URL of an organizer's system that receives the chosen event types,
+// signed with Secret. It is disabled after too many consecutive failed attempts.
+type WebhookEndpoint struct {
+	gorm.Model
+	UserID              uint `gorm:"not null;index"`
+	User                User
+	URL                 string `gorm:"not null"`
+	Secret              string `gorm:"type:varchar(64);not null"`
+	EventTypes          string `gorm:"type:varchar(255);not null"` // Comma separated WebhookEventType values
+	EventID             *uint  `gorm:"index"`                      // Only events of this event are delivered, nil for every event
+	Event               *Event
+	Description         string
+	Active              bool `gorm:"not null;default:true"`
+	ConsecutiveFailures int  `gorm:"not null;default:0"`
+	DisabledAt          *time.Time
+	DisabledReason      string
+}
+
+// WebhookEventTypes returns the subscribed event types
+func (e WebhookEndpoint) WebhookEventTypes() []WebhookEventType {
+	var types []WebhookEventType
+	for _, t := range strings.Split(e.EventTypes, ",") {
+		if t != "" {
+			types = append(types, WebhookEventType(t))
+		}
+	}
+	return types
+}
+
+// RecordFailure counts a failed attempt and disables the endpoint once disableAfter attempts in a
+// row failed. It reports whether this failure disabled the endpoint.
+func (e *WebhookEndpoint) RecordFailure(at time.Time, reason string, disableAfter int) bool {
+	e.ConsecutiveFailures++
+	if !e.Active || e.ConsecutiveFailures < disableAfter {
+		return false
+	}
+
+	e.Active = false
+	e.DisabledAt = &at
+	e.DisabledReason = "too many consecutive failed deliveries: " + reason
+	return true
+}
+
+// WebhookDelivery is one event sent to one endpoint. Payload is the exact body, so retries and
+// manual redeliveries send the same content. The last attempt is kept as the delivery log.
+type WebhookDelivery struct {
+	gorm.Model
+	EndpointID     uint `gorm:"not null;uniqueIndex:idx_webhook_delivery_endpoint_key"`
+	Endpoint       WebhookEndpoint
+	EventType      WebhookEventType      `gorm:"type:varchar(50);not null"`
+	EventKey       string                `gorm:"type:varchar(100);not null;uniqueIndex:idx_webhook_delivery_endpoint_key"` // Identifies the event, a redelivered bus message never creates a second delivery
+	Payload        string                `gorm:"type:jsonb;not null"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	Attempts       int                   `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time             `gorm:"not null;index"`
+	LastAttemptAt  *time.Time
+	LastStatusCode int
+	LastError      string
+	LastResponse   string // Start of the response body of the last attempt
+	DeliveredAt    *time.Time
+}
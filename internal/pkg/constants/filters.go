@@ -26,3 +26,10 @@ const (
 	FilterVenueState       = "state"
 	FilterVenueMinCapacity = "min_capacity"
 )
+
+// Ticket Scan Filter Keys
+const (
+	FilterScanResult    = "result"
+	FilterScanDirection = "direction"
+	FilterScanGate      = "gate"
+)
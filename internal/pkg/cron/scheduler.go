@@ -0,0 +1,379 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	stateKeyPrefix = "scheduler:job:"  // Hash per job with its spec, next run time and pending manual trigger
+	lockKeyPrefix  = "scheduler:lock:" // Held by the node running the job
+
+	tickInterval   = time.Second
+	lockTTL        = 30 * time.Second
+	lockRenewEvery = 10 * time.Second
+	defaultTimeout = 10 * time.Minute
+)
+
+// ErrUnknownJob is returned when no job is registered under a name
+var ErrUnknownJob = errors.New("unknown scheduler job")
+
+// unlockScript deletes the lock only while this node still holds it
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// renewScript extends the lock only while this node still holds it
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// JobFunc runs a scheduled job and returns a short summary of what it did
+type JobFunc func(ctx context.Context) (string, error)
+
+type job struct {
+	name     string
+	spec     string
+	timeout  time.Duration
+	schedule Schedule
+	run      JobFunc
+}
+
+// JobStatus is the schedule state of a job shared by every node
+type JobStatus struct {
+	Name        string
+	Spec        string
+	NextRunAt   *time.Time
+	RunningOn   string // Node holding the job lock, empty when the job is not running
+	TriggeredAt *time.Time
+}
+
+// Scheduler runs named jobs on interval or cron specs across every node running it. The next run
+// time of each job is kept in Redis and a run takes a per-job lock, so each run happens on exactly
+// one node. Runs are recorded in the scheduler_runs table.
+type Scheduler struct {
+	redisClient *redis.Client
+	runRepo     repository.SchedulerRunRepository
+	jobs        []*job
+	jobsByName  map[string]*job
+	nodeID      string
+	logger      *slog.Logger
+	running     map[string]bool // Jobs this node is running
+	mutex       sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler whose node ID is the hostname and process ID
+func NewScheduler(redisClient *redis.Client, runRepo repository.SchedulerRunRepository, logger *slog.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "scheduler"
+	}
+
+	return &Scheduler{
+		redisClient: redisClient,
+		runRepo:     runRepo,
+		jobsByName:  make(map[string]*job),
+		nodeID:      fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		logger:      logger,
+		running:     make(map[string]bool),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Register adds a job. timeout bounds a single run, 0 means 10 minutes. Jobs must be registered
+// before Start.
+func (s *Scheduler) Register(name string, spec string, timeout time.Duration, run JobFunc) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("register %s: %w", name, err)
+	}
+	if _, exists := s.jobsByName[name]; exists {
+		return fmt.Errorf("register %s: job already registered", name)
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	j := &job{name: name, spec: spec, timeout: timeout, schedule: schedule, run: run}
+	s.jobs = append(s.jobs, j)
+	s.jobsByName[name] = j
+	return nil
+}
+
+// Start checks every second which jobs are due until Stop is called
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+
+		s.logger.Info("Scheduler started", slog.String("node", s.nodeID), slog.Int("jobs", len(s.jobs)))
+
+		for {
+			select {
+			case <-ticker.C:
+				s.tick()
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops scheduling, cancels the context of running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	s.logger.Info("Scheduler stopped")
+}
+
+// Status returns the shared schedule state of every job, in registration order
+func (s *Scheduler) Status(ctx context.Context) ([]JobStatus, error) {
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		state, err := s.redisClient.HGetAll(ctx, stateKeyPrefix+j.name).Result()
+		if err != nil {
+			return nil, err
+		}
+		holder, err := s.redisClient.Get(ctx, lockKeyPrefix+j.name).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+
+		status := JobStatus{Name: j.name, Spec: j.spec, RunningOn: holder}
+		if state["spec"] == j.spec {
+			status.NextRunAt = parseMillis(state["next_run_at"])
+		}
+		status.TriggeredAt = parseMillis(state["triggered_at"])
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Trigger asks for a run of the job as soon as possible, on whichever node sees it first
+func (s *Scheduler) Trigger(ctx context.Context, name string, userID uint) error {
+	if _, ok := s.jobsByName[name]; !ok {
+		return ErrUnknownJob
+	}
+	return s.redisClient.HSet(ctx, stateKeyPrefix+name,
+		"triggered_at", time.Now().UnixMilli(),
+		"triggered_by", userID).Err()
+}
+
+// tick starts the jobs that are due and not already running on this node
+func (s *Scheduler) tick() {
+	for _, j := range s.jobs {
+		s.mutex.Lock()
+		busy := s.running[j.name]
+		s.mutex.Unlock()
+		if busy {
+			continue
+		}
+
+		state, err := s.redisClient.HGetAll(s.ctx, stateKeyPrefix+j.name).Result()
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.logger.Error("failed to read scheduler job state", slog.String("job", j.name), slog.String("error", err.Error()))
+			}
+			return
+		}
+		if _, _, due := s.due(j, state, time.Now()); !due {
+			continue
+		}
+
+		s.mutex.Lock()
+		s.running[j.name] = true
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+		go func(j *job) {
+			defer s.wg.Done()
+			defer func() {
+				s.mutex.Lock()
+				delete(s.running, j.name)
+				s.mutex.Unlock()
+			}()
+			s.runLocked(j)
+		}(j)
+	}
+}
+
+// due reports whether the job should run now and why. A job seen for the first time, or whose spec
+// changed, gets its next run time and is not due until then.
+func (s *Scheduler) due(j *job, state map[string]string, now time.Time) (model.SchedulerRunTrigger, *uint, bool) {
+	if state["triggered_at"] != "" {
+		var userID *uint
+		if value, err := strconv.ParseUint(state["triggered_by"], 10, 32); err == nil && value > 0 {
+			id := uint(value)
+			userID = &id
+		}
+		return model.SchedulerTriggerManual, userID, true
+	}
+
+	nextRunAt := parseMillis(state["next_run_at"])
+	if state["spec"] != j.spec || nextRunAt == nil {
+		s.setNextRun(j, now)
+		return "", nil, false
+	}
+	return model.SchedulerTriggerSchedule, nil, !now.Before(*nextRunAt)
+}
+
+// runLocked runs the job if this node gets its lock and the job is still due once it holds it
+func (s *Scheduler) runLocked(j *job) {
+	lockKey := lockKeyPrefix + j.name
+	acquired, err := s.redisClient.SetNX(s.ctx, lockKey, s.nodeID, lockTTL).Result()
+	if err != nil || !acquired {
+		return
+	}
+	defer func() {
+		if err := unlockScript.Run(context.Background(), s.redisClient, []string{lockKey}, s.nodeID).Err(); err != nil {
+			s.logger.Error("failed to release scheduler lock", slog.String("job", j.name), slog.String("error", err.Error()))
+		}
+	}()
+
+	// Another node may have run the job between reading the state and taking the lock
+	state, err := s.redisClient.HGetAll(s.ctx, stateKeyPrefix+j.name).Result()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	trigger, userID, due := s.due(j, state, now)
+	if !due {
+		return
+	}
+	if trigger == model.SchedulerTriggerManual {
+		s.redisClient.HDel(s.ctx, stateKeyPrefix+j.name, "triggered_at", "triggered_by")
+	}
+	if nextRunAt := parseMillis(state["next_run_at"]); trigger == model.SchedulerTriggerSchedule || (nextRunAt != nil && !now.Before(*nextRunAt)) {
+		// A manual run also covers a scheduled run that is due at the same time
+		s.setNextRun(j, now)
+	}
+
+	stopRenew := s.renewLock(j.name)
+	defer stopRenew()
+
+	s.execute(j, trigger, userID)
+}
+
+// execute runs the job with its timeout and records the run
+func (s *Scheduler) execute(j *job, trigger model.SchedulerRunTrigger, userID *uint) {
+	run := model.SchedulerRun{
+		JobName:           j.name,
+		Trigger:           trigger,
+		TriggeredByUserID: userID,
+		Node:              s.nodeID,
+		Status:            model.SchedulerRunRunning,
+		StartedAt:         time.Now(),
+	}
+	if err := s.runRepo.CreateRun(&run); err != nil {
+		s.logger.Error("failed to record scheduler run", slog.String("job", j.name), slog.String("error", err.Error()))
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, j.timeout)
+	result, err := runJob(ctx, j.run)
+	cancel()
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Result = result
+	run.Status = model.SchedulerRunSucceeded
+	if err != nil {
+		run.Status = model.SchedulerRunFailed
+		run.Error = err.Error()
+		s.logger.Error("Scheduled job failed",
+			slog.String("job", j.name),
+			slog.String("trigger", string(trigger)),
+			slog.String("error", err.Error()))
+	} else {
+		s.logger.Info("Scheduled job completed",
+			slog.String("job", j.name),
+			slog.String("trigger", string(trigger)),
+			slog.Int64("duration_ms", run.DurationMs),
+			slog.String("result", result))
+	}
+
+	if run.ID != 0 {
+		if err := s.runRepo.FinishRun(&run); err != nil {
+			s.logger.Error("failed to record scheduler run result", slog.String("job", j.name), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// renewLock keeps the job lock alive while the job runs, until the returned function is called
+func (s *Scheduler) renewLock(name string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lockRenewEvery)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				err := renewScript.Run(context.Background(), s.redisClient,
+					[]string{lockKeyPrefix + name}, s.nodeID, lockTTL.Milliseconds()).Err()
+				if err != nil {
+					s.logger.Error("failed to renew scheduler lock", slog.String("job", name), slog.String("error", err.Error()))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (s *Scheduler) setNextRun(j *job, now time.Time) {
+	next := j.schedule.Next(now)
+	if next.IsZero() {
+		s.logger.Error("scheduler job spec never matches", slog.String("job", j.name), slog.String("spec", j.spec))
+		return
+	}
+	if err := s.redisClient.HSet(s.ctx, stateKeyPrefix+j.name, "spec", j.spec, "next_run_at", next.UnixMilli()).Err(); err != nil && s.ctx.Err() == nil {
+		s.logger.Error("failed to save next scheduler run", slog.String("job", j.name), slog.String("error", err.Error()))
+	}
+}
+
+// runJob calls run and turns a panic into an error so it is recorded as a failed run
+func runJob(ctx context.Context, run JobFunc) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("scheduler job panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return run(ctx)
+}
+
+func parseMillis(value string) *time.Time {
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	t := time.UnixMilli(millis)
+	return &t
+}
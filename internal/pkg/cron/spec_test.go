@@ -0,0 +1,85 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRejectsInvalidSpecs(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@every",
+		"@every soon",
+		"@every 500ms",
+		"@yearly",
+	}
+
+	for _, spec := range specs {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := Parse(spec); err == nil {
+				t.Fatalf("Parse(%q) succeeded, want an error", spec)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute, second int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"every interval", "@every 90s", at(2026, 1, 1, 10, 0, 30), at(2026, 1, 1, 10, 2, 0)},
+		{"step within the hour", "*/15 * * * *", at(2026, 1, 1, 10, 7, 30), at(2026, 1, 1, 10, 15, 0)},
+		{"strictly after a matching minute", "*/15 * * * *", at(2026, 1, 1, 10, 15, 0), at(2026, 1, 1, 10, 30, 0)},
+		{"step rolls over the hour", "*/15 * * * *", at(2026, 1, 1, 10, 50, 0), at(2026, 1, 1, 11, 0, 0)},
+		{"range with step", "10-30/10 * * * *", at(2026, 1, 1, 10, 31, 0), at(2026, 1, 1, 11, 10, 0)},
+		{"list", "5,45 * * * *", at(2026, 1, 1, 10, 6, 0), at(2026, 1, 1, 10, 45, 0)},
+		{"hourly", "@hourly", at(2026, 1, 1, 10, 0, 0), at(2026, 1, 1, 11, 0, 0)},
+		{"daily rolls over the day", "@daily", at(2026, 1, 1, 10, 0, 0), at(2026, 1, 2, 0, 0, 0)},
+		{"month rollover", "0 0 1 * *", at(2026, 1, 31, 12, 0, 0), at(2026, 2, 1, 0, 0, 0)},
+		{"year rollover", "30 23 31 12 *", at(2026, 12, 31, 23, 30, 0), at(2027, 12, 31, 23, 30, 0)},
+		{"skips months without the day", "0 0 31 * *", at(2026, 4, 1, 0, 0, 0), at(2026, 5, 31, 0, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(2026, 3, 1, 0, 0, 0), at(2028, 2, 29, 0, 0, 0)},
+		{"day of week only", "0 9 * * 1", at(2026, 1, 1, 0, 0, 0), at(2026, 1, 5, 9, 0, 0)},
+		{"weekly on sunday", "@weekly", at(2026, 1, 1, 0, 0, 0), at(2026, 1, 4, 0, 0, 0)},
+		{"7 is sunday", "0 0 * * 7", at(2026, 1, 1, 0, 0, 0), at(2026, 1, 4, 0, 0, 0)},
+		{"day of month only", "0 9 13 * *", at(2026, 1, 1, 0, 0, 0), at(2026, 1, 13, 9, 0, 0)},
+		// With both day fields restricted a day matching either one runs the job
+		{"either day field, weekday first", "0 9 13 * 5", at(2026, 1, 1, 0, 0, 0), at(2026, 1, 2, 9, 0, 0)},
+		{"either day field, date first", "0 9 13 * 5", at(2026, 1, 10, 0, 0, 0), at(2026, 1, 13, 9, 0, 0)},
+		{"never matches", "0 0 30 2 *", at(2026, 1, 1, 0, 0, 0), time.Time{}},
+		{"never matches on the 31st of a short month", "0 0 31 4,6,9,11 *", at(2026, 1, 1, 0, 0, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+
+			got := schedule.Next(tt.after)
+			if !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) of %q = %s, want %s", tt.after, tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// attendanceSubscriberBuffer is how many scans a slow dashboard may lag behind before
+// further scans are dropped for it
+const attendanceSubscriberBuffer = 64
+
+// attendanceFeedRetryDelay is how long the feed waits before reading the stream again after an error
+const attendanceFeedRetryDelay = 5 * time.Second
+
+// AttendanceHub fans ticket scans out to the live attendance dashboards of this instance, keyed by
+// event ID. Every instance reads all scans itself, see Start.
+type AttendanceHub struct {
+	subscribers map[uint]map[chan TicketScannedEvent]struct{}
+	mutex       sync.RWMutex
+	logger      *slog.Logger
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// NewAttendanceHub creates a new AttendanceHub
+func NewAttendanceHub(logger *slog.Logger) *AttendanceHub {
+	return &AttendanceHub{
+		subscribers: make(map[uint]map[chan TicketScannedEvent]struct{}),
+		logger:      logger,
+	}
+}
+
+// Start feeds the hub from bus. On Redis Streams the hub tails the stream outside the consumer group,
+// so every instance sees every scan whether or not it runs the event consumers. Events of the
+// in-memory bus never leave the process, there the hub is subscribed as a handler.
+func (h *AttendanceHub) Start(bus Bus) {
+	eventBus, ok := bus.(*EventBus)
+	if !ok {
+		bus.Subscribe("ticket.scanned", h, TicketScannedEvent{})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		h.follow(ctx, eventBus)
+	}()
+}
+
+// Stop stops reading the stream
+func (h *AttendanceHub) Stop() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.wg.Wait()
+}
+
+// follow tails the stream from the moment the hub started and resumes after the last message read
+// when Redis fails
+func (h *AttendanceHub) follow(ctx context.Context, eventBus *EventBus) {
+	lastID := "$"
+	for {
+		err := eventBus.TailStream(ctx, lastID, func(message StreamMessage) {
+			lastID = message.ID
+			h.dispatch(message)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			h.logger.Error("failed to read ticket scans from the event stream", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-time.After(attendanceFeedRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatch decodes a ticket scan read from the stream and hands it to the subscribers of its event
+func (h *AttendanceHub) dispatch(message StreamMessage) {
+	if message.Envelope == nil || message.Envelope.Type != "ticket.scanned" {
+		return
+	}
+
+	// Upcasting rewrites the envelope, keep the message as it was read
+	envelope := *message.Envelope
+	var scannedEvent TicketScannedEvent
+	if err := envelope.UpcastTo(scannedEvent.GetEventVersion()); err != nil {
+		h.logger.Error("failed to upcast ticket scan", slog.String("message_id", message.ID), slog.String("error", err.Error()))
+		return
+	}
+	if err := json.Unmarshal(envelope.Payload, &scannedEvent); err != nil {
+		h.logger.Error("failed to decode ticket scan", slog.String("message_id", message.ID), slog.String("error", err.Error()))
+		return
+	}
+
+	_ = h.Handle(scannedEvent)
+}
+
+// Subscribe returns a channel receiving the scans of an event and a function that
+// must be called to stop receiving them
+func (h *AttendanceHub) Subscribe(eventID uint) (<-chan TicketScannedEvent, func()) {
+	ch := make(chan TicketScannedEvent, attendanceSubscriberBuffer)
+
+	h.mutex.Lock()
+	if h.subscribers[eventID] == nil {
+		h.subscribers[eventID] = make(map[chan TicketScannedEvent]struct{})
+	}
+	h.subscribers[eventID][ch] = struct{}{}
+	h.mutex.Unlock()
+
+	unsubscribe := func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+
+		if _, ok := h.subscribers[eventID][ch]; !ok {
+			return
+		}
+		delete(h.subscribers[eventID], ch)
+		if len(h.subscribers[eventID]) == 0 {
+			delete(h.subscribers, eventID)
+		}
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// Handle processes the TicketScannedEvent
+func (h *AttendanceHub) Handle(event Event) error {
+	scannedEvent, ok := event.(TicketScannedEvent)
+	if !ok {
+		return nil
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for ch := range h.subscribers[scannedEvent.EventID] {
+		select {
+		case ch <- scannedEvent:
+		default:
+			// Never block the event bus on a slow dashboard; it catches up on the next summary
+			h.logger.Warn("dropped ticket scan for slow attendance subscriber",
+				slog.Uint64("event_id", uint64(scannedEvent.EventID)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"learn/internal/config"
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Event bus drivers selectable with EVENT_BUS_DRIVER
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+)
+
+// Event interface defines the contract for events
+type Event interface {
+	GetEventType() string
+	GetEventVersion() int
+}
+
+// EventHandler interface defines the contract for event handlers. A returned error makes the bus
+// retry the message, so handlers must be safe to run more than once for the same event.
+type EventHandler interface {
+	Handle(event Event) error
+}
+
+// EventPublisher is what services depend on to publish events
+type EventPublisher interface {
+	// Publish wraps the event in an envelope correlated with the request ID of ctx and sends it.
+	// Failures are logged, events that must not be lost go through the outbox instead.
+	Publish(ctx context.Context, event Event)
+	// PublishEnvelope sends an already wrapped event and reports whether it was accepted
+	PublishEnvelope(envelope *Envelope) error
+}
+
+// EventSubscriber registers the handlers of an event type
+type EventSubscriber interface {
+	Subscribe(eventType string, handler EventHandler, eventPrototype interface{})
+}
+
+// Bus is an event bus implementation: EventBus on Redis Streams or MemoryBus inside the process
+type Bus interface {
+	EventPublisher
+	EventSubscriber
+	Start()
+	Stop()
+}
+
+var (
+	_ Bus = (*EventBus)(nil)
+	_ Bus = (*MemoryBus)(nil)
+)
+
+// NewBus creates the bus selected by EVENT_BUS_DRIVER. The memory driver is meant for a single
+// instance: events are lost on restart and never reach other instances.
+func NewBus(redisClient *redis.Client, logger *slog.Logger) Bus {
+	if config.AppConfig.EventBusDriver == DriverMemory {
+		logger.Info("Using in-memory event bus")
+		return NewMemoryBus(logger, memoryBusBuffer)
+	}
+	return NewEventBus(redisClient, logger)
+}
+
+// handlerRegistry holds the handlers and payload prototypes of the subscribed event types
+type handlerRegistry struct {
+	handlers   map[string][]EventHandler
+	eventTypes map[string]reflect.Type
+	mutex      sync.RWMutex
+}
+
+func newHandlerRegistry() handlerRegistry {
+	return handlerRegistry{
+		handlers:   make(map[string][]EventHandler),
+		eventTypes: make(map[string]reflect.Type),
+	}
+}
+
+// Subscribe registers an event handler for a specific event type
+func (r *handlerRegistry) Subscribe(eventType string, handler EventHandler, eventPrototype interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.handlers[eventType] = append(r.handlers[eventType], handler)
+	r.eventTypes[eventType] = reflect.TypeOf(eventPrototype)
+}
+
+// decode upcasts the payload to the version of the registered prototype of its event type,
+// unmarshals it and returns the handlers subscribed to it, or ErrNoHandlers when nobody listens
+// to the type
+func (r *handlerRegistry) decode(envelope *Envelope) (Event, []EventHandler, error) {
+	r.mutex.RLock()
+	handlers, handlersExist := r.handlers[envelope.Type]
+	proto, protoExist := r.eventTypes[envelope.Type]
+	r.mutex.RUnlock()
+
+	if !handlersExist || !protoExist {
+		return nil, nil, ErrNoHandlers
+	}
+
+	// Create a new instance of the event type
+	eventPtr := reflect.New(proto).Interface()
+
+	// The reflect.New returns a pointer, but our handlers might expect the value
+	// If the prototype was a struct, reflect.New(proto).Interface() is *Struct
+	current := reflect.Indirect(reflect.ValueOf(eventPtr)).Interface().(Event).GetEventVersion()
+	if err := envelope.UpcastTo(current); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(envelope.Payload, eventPtr); err != nil {
+		return nil, nil, err
+	}
+
+	return reflect.Indirect(reflect.ValueOf(eventPtr)).Interface().(Event), handlers, nil
+}
+
+// runHandler turns a handler panic into an error so the event is retried instead of lost
+func runHandler(logger *slog.Logger, handler EventHandler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("event handler panicked", slog.Any("panic", r))
+			err = fmt.Errorf("event handler panicked: %v", r)
+		}
+	}()
+	return handler.Handle(event)
+}
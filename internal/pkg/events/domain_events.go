@@ -1,5 +1,10 @@
 package events
 
+// Events are published inside an Envelope carrying their schema version. Version 1 was the
+// untagged payload with Go field names, version 2 introduced the snake_case JSON tags. Bump the
+// version when a payload changes incompatibly and register an upcaster from the previous version
+// in upcasters.go.
+
 import (
 	"learn/internal/model"
 	"time"
@@ -7,72 +12,174 @@ import (
 
 // OrderCreatedEvent is triggered when an order is created
 type OrderCreatedEvent struct {
-	OrderID    uint
-	UserID     uint
-	TotalPrice int64
-	CreatedAt  time.Time
+	OrderID    uint      `json:"order_id"`
+	UserID     uint      `json:"user_id"`
+	TotalPrice int64     `json:"total_price"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 func (e OrderCreatedEvent) GetEventType() string {
 	return "order.created"
 }
 
+func (e OrderCreatedEvent) GetEventVersion() int {
+	return 2
+}
+
 // OrderPaidEvent is triggered when an order is paid
 type OrderPaidEvent struct {
-	OrderID    uint
-	UserID     uint
-	TotalPrice int64
-	PaidAt     time.Time
+	OrderID    uint      `json:"order_id"`
+	UserID     uint      `json:"user_id"`
+	TotalPrice int64     `json:"total_price"`
+	PaidAt     time.Time `json:"paid_at"`
 }
 
 func (e OrderPaidEvent) GetEventType() string {
 	return "order.paid"
 }
 
+func (e OrderPaidEvent) GetEventVersion() int {
+	return 2
+}
+
 // OrderCancelledEvent is triggered when an order is cancelled
 type OrderCancelledEvent struct {
-	OrderID     uint
-	UserID      uint
-	Reason      string
-	CancelledAt time.Time
+	OrderID     uint      `json:"order_id"`
+	UserID      uint      `json:"user_id"`
+	Reason      string    `json:"reason"`
+	CancelledAt time.Time `json:"cancelled_at"`
 }
 
 func (e OrderCancelledEvent) GetEventType() string {
 	return "order.cancelled"
 }
 
+func (e OrderCancelledEvent) GetEventVersion() int {
+	return 2
+}
+
 // PaymentCreatedEvent is triggered when a payment is created
 type PaymentCreatedEvent struct {
-	PaymentID uint
-	OrderID   uint
-	Method    model.PaymentMethod
-	Amount    int64
-	CreatedAt time.Time
+	PaymentID uint                `json:"payment_id"`
+	OrderID   uint                `json:"order_id"`
+	Method    model.PaymentMethod `json:"method"`
+	Amount    int64               `json:"amount"`
+	CreatedAt time.Time           `json:"created_at"`
 }
 
 func (e PaymentCreatedEvent) GetEventType() string {
 	return "payment.created"
 }
 
+func (e PaymentCreatedEvent) GetEventVersion() int {
+	return 2
+}
+
 // PaymentStatusUpdatedEvent is triggered when a payment status is updated
 type PaymentStatusUpdatedEvent struct {
-	PaymentID uint
-	OrderID   uint
-	Status    model.PaymentStatus
-	UpdatedAt time.Time
+	PaymentID uint                `json:"payment_id"`
+	OrderID   uint                `json:"order_id"`
+	Status    model.PaymentStatus `json:"status"`
+	UpdatedAt time.Time           `json:"updated_at"`
 }
 
 func (e PaymentStatusUpdatedEvent) GetEventType() string {
 	return "payment.status.updated"
 }
 
+func (e PaymentStatusUpdatedEvent) GetEventVersion() int {
+	return 2
+}
+
 // TicketsGeneratedEvent is triggered when tickets are generated for an order
 type TicketsGeneratedEvent struct {
-	OrderID     uint
-	TicketCodes []string
-	GeneratedAt time.Time
+	OrderID     uint      `json:"order_id"`
+	TicketCodes []string  `json:"ticket_codes"`
+	GeneratedAt time.Time `json:"generated_at"`
 }
 
 func (e TicketsGeneratedEvent) GetEventType() string {
 	return "tickets.generated"
 }
+
+func (e TicketsGeneratedEvent) GetEventVersion() int {
+	return 2
+}
+
+// TicketScannedEvent is triggered for every scan attempt at the gate, accepted or rejected
+type TicketScannedEvent struct {
+	ScanID       uint                `json:"scan_id"`
+	TicketID     uint                `json:"ticket_id"`
+	EventID      uint                `json:"event_id"`
+	EventPriceID uint                `json:"event_price_id"`
+	Direction    model.ScanDirection `json:"direction"`
+	Result       model.ScanResult    `json:"result"`
+	Reason       string              `json:"reason"`
+	Gate         string              `json:"gate"`
+	DeviceID     string              `json:"device_id"`
+	Offline      bool                `json:"offline"`
+	ScannedAt    time.Time           `json:"scanned_at"`
+}
+
+func (e TicketScannedEvent) GetEventType() string {
+	return "ticket.scanned"
+}
+
+func (e TicketScannedEvent) GetEventVersion() int {
+	return 2
+}
+
+// TicketTransferredEvent is triggered when a recipient accepts a ticket transfer
+type TicketTransferredEvent struct {
+	TransferID    uint      `json:"transfer_id"`
+	TicketID      uint      `json:"ticket_id"`
+	EventID       uint      `json:"event_id"`
+	FromUserID    uint      `json:"from_user_id"`
+	ToUserID      uint      `json:"to_user_id"`
+	TransferredAt time.Time `json:"transferred_at"`
+}
+
+func (e TicketTransferredEvent) GetEventType() string {
+	return "ticket.transferred"
+}
+
+func (e TicketTransferredEvent) GetEventVersion() int {
+	return 2
+}
+
+// OrderRefundedEvent is triggered when a refund is approved and its tickets are voided
+type OrderRefundedEvent struct {
+	RefundID      uint      `json:"refund_id"`
+	OrderID       uint      `json:"order_id"`
+	UserID        uint      `json:"user_id"`
+	Amount        int64     `json:"amount"`
+	TicketIDs     []uint    `json:"ticket_ids"`
+	FullyRefunded bool      `json:"fully_refunded"`
+	Manual        bool      `json:"manual"` // The money still has to be returned outside the payment gateway
+	RefundedAt    time.Time `json:"refunded_at"`
+}
+
+func (e OrderRefundedEvent) GetEventType() string {
+	return "order.refunded"
+}
+
+func (e OrderRefundedEvent) GetEventVersion() int {
+	return 2
+}
+
+// EventCancelledEvent is triggered when an event is cancelled and its orders start being cancelled and refunded
+type EventCancelledEvent struct {
+	EventID        uint      `json:"event_id"`
+	CancellationID uint      `json:"cancellation_id"`
+	UserID         uint      `json:"user_id"`
+	Reason         string    `json:"reason"`
+	CancelledAt    time.Time `json:"cancelled_at"`
+}
+
+func (e EventCancelledEvent) GetEventType() string {
+	return "event.cancelled"
+}
+
+func (e EventCancelledEvent) GetEventVersion() int {
+	return 2
+}
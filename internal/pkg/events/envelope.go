@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"learn/internal/pkg/random"
+	"learn/internal/pkg/requestid"
+	"time"
+)
+
+var (
+	// ErrMissingEventType is returned for a stream message whose envelope has no event type
+	ErrMissingEventType = errors.New("message missing event type")
+	// ErrMissingPayload is returned for a stream message that carries neither an envelope nor a payload
+	ErrMissingPayload = errors.New("message missing payload")
+)
+
+// Envelope wraps every event on the stream with the metadata needed to decode it later. Payload
+// holds the event in the schema of Version and is upcast to the current version on read.
+type Envelope struct {
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	EventID       string          `json:"event_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// Upcaster migrates a payload from one schema version to the next
+type Upcaster func(payload json.RawMessage) (json.RawMessage, error)
+
+// upcasters holds the registered upcasters by event type and the version they migrate from
+var upcasters = make(map[string]map[int]Upcaster)
+
+// RegisterUpcaster registers the migration of eventType payloads from fromVersion to fromVersion+1.
+// It is meant to be called from init functions.
+func RegisterUpcaster(eventType string, fromVersion int, upcaster Upcaster) {
+	if upcasters[eventType] == nil {
+		upcasters[eventType] = make(map[int]Upcaster)
+	}
+	upcasters[eventType][fromVersion] = upcaster
+}
+
+// NewEnvelope wraps an event with a new event ID and the request ID of ctx as correlation ID
+func NewEnvelope(ctx context.Context, event Event) (*Envelope, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Type:          event.GetEventType(),
+		Version:       event.GetEventVersion(),
+		EventID:       NewEventID(),
+		OccurredAt:    time.Now(),
+		CorrelationID: requestid.FromContext(ctx),
+		Payload:       payload,
+	}, nil
+}
+
+// NewEventID returns a random ID identifying an event across redeliveries and replays
+func NewEventID() string {
+	return random.StringWithCharset(32, "0123456789abcdef")
+}
+
+// UpcastTo migrates the payload to targetVersion one version at a time
+func (e *Envelope) UpcastTo(targetVersion int) error {
+	if e.Version > targetVersion {
+		return fmt.Errorf("%s version %d is newer than the supported version %d", e.Type, e.Version, targetVersion)
+	}
+
+	for e.Version < targetVersion {
+		upcaster, ok := upcasters[e.Type][e.Version]
+		if !ok {
+			return fmt.Errorf("no upcaster registered for %s version %d", e.Type, e.Version)
+		}
+
+		payload, err := upcaster(e.Payload)
+		if err != nil {
+			return fmt.Errorf("upcasting %s version %d: %w", e.Type, e.Version, err)
+		}
+		e.Payload = payload
+		e.Version++
+	}
+	return nil
+}
+
+// envelopeFromValues reads the envelope of a stream message. Messages published before envelopes
+// existed only carry type and payload and are read as version 1.
+func envelopeFromValues(values map[string]interface{}) (*Envelope, error) {
+	var envelope Envelope
+	if raw, ok := values["envelope"].(string); ok {
+		if err := json.Unmarshal([]byte(raw), &envelope); err != nil {
+			return nil, err
+		}
+	} else {
+		payload, ok := values["payload"].(string)
+		if !ok {
+			return nil, ErrMissingPayload
+		}
+		envelope.Type, _ = values["type"].(string)
+		envelope.Version = 1
+		envelope.Payload = json.RawMessage(payload)
+	}
+
+	if envelope.Type == "" {
+		return nil, ErrMissingEventType
+	}
+	return &envelope, nil
+}
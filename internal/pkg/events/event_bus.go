@@ -3,8 +3,12 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"learn/internal/config"
 	"log/slog"
-	"reflect"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -12,80 +16,132 @@ import (
 )
 
 const (
-	StreamName   = "events_stream"
-	GroupName    = "events_group"
-	ConsumerName = "event_consumer"
+	StreamName           = "events_stream"
+	GroupName            = "events_group"
+	DeadLetterStreamName = "events_stream:dead_letter"
+
+	// Failed attempts and the earliest next retry of pending messages, keyed by message ID
+	attemptsKey = "events_stream:attempts"
+	retryAtKey  = "events_stream:retry_at"
+
+	maxRetryBackoff     = 5 * time.Minute
+	reclaimBatchSize    = 50
+	consumerCleanupIdle = 24 * time.Hour // Consumers of stopped instances are removed once idle this long
 )
 
-// EventBus manages event subscriptions and dispatching using Redis Streams
-type EventBus struct {
-	redisClient *redis.Client
-	handlers    map[string][]EventHandler
-	eventTypes  map[string]reflect.Type
-	mutex       sync.RWMutex
-	logger      *slog.Logger
-	ctx         context.Context
-	cancel      context.CancelFunc
-}
+// ErrDeadLetterNotFound is returned when a dead-lettered message does not exist (anymore)
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// ErrNoHandlers is returned when no handler is subscribed to an event type
+var ErrNoHandlers = errors.New("no handlers registered for event type")
 
-// Event interface defines the contract for events
-type Event interface {
-	GetEventType() string
+// EventBus manages event subscriptions and dispatching using Redis Streams.
+// A message is acknowledged only after all of its handlers succeeded. Failed messages stay in the
+// pending entries list and are retried by their consumer after an exponential backoff. Messages
+// left behind by crashed or stuck instances are taken over with XAUTOCLAIM once they are idle for
+// claimMinIdle. After maxAttempts failures a message is moved to the dead-letter stream.
+type EventBus struct {
+	handlerRegistry
+	redisClient  *redis.Client
+	logger       *slog.Logger
+	ctx          context.Context
+	cancel       context.CancelFunc
+	consumerName string
+	maxAttempts  int
+	retryBackoff time.Duration
+	claimMinIdle time.Duration
+	wg           sync.WaitGroup
 }
 
-// EventHandler interface defines the contract for event handlers
-type EventHandler interface {
-	Handle(event Event)
+// DeadLetter is a message that failed maxAttempts times
+type DeadLetter struct {
+	ID            string
+	EventType     string
+	Version       int
+	EventID       string
+	CorrelationID string
+	Payload       string
+	Error         string
+	Attempts      int
+	OriginalID    string
+	Consumer      string
+	FailedAt      time.Time
 }
 
-// NewEventBus creates a new event bus instance
+// NewEventBus creates a new event bus instance. Every instance consumes under its own name,
+// EVENT_CONSUMER_NAME or hostname-pid, so replicas never share pending entries.
 func NewEventBus(redisClient *redis.Client, logger *slog.Logger) *EventBus {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &EventBus{
-		redisClient: redisClient,
-		handlers:    make(map[string][]EventHandler),
-		eventTypes:  make(map[string]reflect.Type),
-		logger:      logger,
-		ctx:         ctx,
-		cancel:      cancel,
+
+	consumerName := config.AppConfig.EventConsumerName
+	if consumerName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "event_consumer"
+		}
+		consumerName = fmt.Sprintf("%s-%d", hostname, os.Getpid())
 	}
-}
 
-// Subscribe registers an event handler for a specific event type
-func (eb *EventBus) Subscribe(eventType string, handler EventHandler, eventPrototype interface{}) {
-	eb.mutex.Lock()
-	defer eb.mutex.Unlock()
+	maxAttempts := config.AppConfig.EventMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	retryBackoff := config.AppConfig.EventRetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = 5 * time.Second
+	}
+	claimMinIdle := config.AppConfig.EventClaimMinIdle
+	if claimMinIdle <= 0 {
+		claimMinIdle = 5 * time.Minute
+	}
 
-	eb.handlers[eventType] = append(eb.handlers[eventType], handler)
-	eb.eventTypes[eventType] = reflect.TypeOf(eventPrototype)
+	return &EventBus{
+		handlerRegistry: newHandlerRegistry(),
+		redisClient:     redisClient,
+		logger:          logger,
+		ctx:             ctx,
+		cancel:          cancel,
+		consumerName:    consumerName,
+		maxAttempts:     maxAttempts,
+		retryBackoff:    retryBackoff,
+		claimMinIdle:    claimMinIdle,
+	}
 }
 
-// Publish sends an event to Redis Stream
-func (eb *EventBus) Publish(event Event) {
-	eventType := event.GetEventType()
-
-	data, err := json.Marshal(event)
+// Publish sends an event to Redis Stream, correlated with the request ID of ctx
+func (eb *EventBus) Publish(ctx context.Context, event Event) {
+	envelope, err := NewEnvelope(ctx, event)
 	if err != nil {
 		eb.logger.Error("failed to marshal event", slog.String("error", err.Error()))
 		return
 	}
 
-	err = eb.redisClient.XAdd(eb.ctx, &redis.XAddArgs{
+	if err := eb.PublishEnvelope(envelope); err != nil {
+		eb.logger.Error("failed to publish event to Redis",
+			slog.String("type", envelope.Type),
+			slog.String("event_id", envelope.EventID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// PublishEnvelope sends an already wrapped event to Redis Stream and reports whether it was stored
+func (eb *EventBus) PublishEnvelope(envelope *Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	return eb.redisClient.XAdd(eb.ctx, &redis.XAddArgs{
 		Stream: StreamName,
 		Values: map[string]interface{}{
-			"type":    eventType,
-			"payload": data,
+			"type":     envelope.Type,
+			"version":  envelope.Version,
+			"envelope": data,
 		},
 	}).Err()
-
-	if err != nil {
-		eb.logger.Error("failed to publish event to Redis",
-			slog.String("type", eventType),
-			slog.String("error", err.Error()))
-	}
 }
 
-// Start initiates the consumer worker
+// Start initiates the consumer worker and the reclaimer of failed and stuck messages
 func (eb *EventBus) Start() {
 	// Create consumer group if it doesn't exist
 	err := eb.redisClient.XGroupCreateMkStream(eb.ctx, StreamName, GroupName, "0").Err()
@@ -93,16 +149,21 @@ func (eb *EventBus) Start() {
 		eb.logger.Warn("failed to create consumer group", slog.String("error", err.Error()))
 	}
 
+	eb.wg.Add(2)
 	go eb.worker()
+	go eb.reclaimer()
 }
 
-// Stop stops the consumer worker
+// Stop stops reading new messages and waits for the messages being handled. Results of those are
+// still recorded, so a message handled during shutdown is not handled again by another instance.
 func (eb *EventBus) Stop() {
 	eb.cancel()
+	eb.wg.Wait()
 }
 
 func (eb *EventBus) worker() {
-	eb.logger.Info("Event bus worker started")
+	defer eb.wg.Done()
+	eb.logger.Info("Event bus worker started", slog.String("consumer", eb.consumerName))
 	for {
 		select {
 		case <-eb.ctx.Done():
@@ -110,14 +171,14 @@ func (eb *EventBus) worker() {
 		default:
 			streams, err := eb.redisClient.XReadGroup(eb.ctx, &redis.XReadGroupArgs{
 				Group:    GroupName,
-				Consumer: ConsumerName,
+				Consumer: eb.consumerName,
 				Streams:  []string{StreamName, ">"},
 				Count:    10,
 				Block:    time.Second * 2,
 			}).Result()
 
 			if err != nil {
-				if err != redis.Nil {
+				if err != redis.Nil && eb.ctx.Err() == nil {
 					eb.logger.Error("failed to read from redis stream", slog.String("error", err.Error()))
 				}
 				continue
@@ -132,52 +193,359 @@ func (eb *EventBus) worker() {
 	}
 }
 
+// reclaimer periodically retries the failed messages of this consumer and takes over the messages
+// other consumers did not acknowledge within claimMinIdle
+func (eb *EventBus) reclaimer() {
+	defer eb.wg.Done()
+	ticker := time.NewTicker(eb.retryBackoff)
+	defer ticker.Stop()
+	cleanupTicker := time.NewTicker(time.Hour)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-eb.ctx.Done():
+			return
+		case <-ticker.C:
+			eb.reclaimPending()
+		case <-cleanupTicker.C:
+			eb.removeIdleConsumers()
+		}
+	}
+}
+
+func (eb *EventBus) reclaimPending() {
+	eb.retryFailed()
+	eb.claimAbandoned()
+}
+
+// retryFailed handles the pending messages of this consumer again once their backoff passed.
+// Messages without a retry time are still being handled by the worker and are left alone.
+func (eb *EventBus) retryFailed() {
+	start := "0"
+	for {
+		streams, err := eb.redisClient.XReadGroup(eb.ctx, &redis.XReadGroupArgs{
+			Group:    GroupName,
+			Consumer: eb.consumerName,
+			Streams:  []string{StreamName, start},
+			Count:    reclaimBatchSize,
+		}).Result()
+		if err != nil {
+			if err != redis.Nil && eb.ctx.Err() == nil {
+				eb.logger.Error("failed to read failed messages", slog.String("error", err.Error()))
+			}
+			return
+		}
+		if len(streams) == 0 || len(streams[0].Messages) == 0 {
+			return
+		}
+
+		for _, msg := range streams[0].Messages {
+			if eb.ctx.Err() != nil {
+				return
+			}
+			start = msg.ID
+			if retryAt, ok := eb.retryAt(msg.ID); !ok || time.Now().UnixMilli() < retryAt {
+				continue
+			}
+			eb.processMessage(msg)
+		}
+	}
+}
+
+// claimAbandoned takes over the messages that stayed unacknowledged for claimMinIdle, usually
+// because the consumer that read them died. claimMinIdle has to stay well above the slowest
+// handler, otherwise a message still being handled is handled twice.
+func (eb *EventBus) claimAbandoned() {
+	start := "0-0"
+	for {
+		messages, next, err := eb.redisClient.XAutoClaim(eb.ctx, &redis.XAutoClaimArgs{
+			Stream:   StreamName,
+			Group:    GroupName,
+			MinIdle:  eb.claimMinIdle,
+			Start:    start,
+			Count:    reclaimBatchSize,
+			Consumer: eb.consumerName,
+		}).Result()
+		if err != nil {
+			if eb.ctx.Err() == nil {
+				eb.logger.Error("failed to reclaim pending messages", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		for _, msg := range messages {
+			if eb.ctx.Err() != nil {
+				return
+			}
+			if eb.waitingForRetry(msg.ID) {
+				continue
+			}
+			eb.processMessage(msg)
+		}
+
+		if next == "0-0" || len(messages) == 0 {
+			return
+		}
+		start = next
+	}
+}
+
+// waitingForRetry reports whether a failed message is still inside its backoff window
+func (eb *EventBus) waitingForRetry(messageID string) bool {
+	retryAt, ok := eb.retryAt(messageID)
+	return ok && time.Now().UnixMilli() < retryAt
+}
+
+// retryAt returns the earliest next attempt of a failed message in unix milliseconds, ok is false
+// when the message never failed
+func (eb *EventBus) retryAt(messageID string) (int64, bool) {
+	retryAt, err := eb.redisClient.HGet(eb.ctx, retryAtKey, messageID).Int64()
+	if err != nil {
+		return 0, false
+	}
+	return retryAt, true
+}
+
+func (eb *EventBus) removeIdleConsumers() {
+	consumers, err := eb.redisClient.XInfoConsumers(eb.ctx, StreamName, GroupName).Result()
+	if err != nil {
+		eb.logger.Warn("failed to list stream consumers", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, consumer := range consumers {
+		idle := time.Duration(consumer.Idle) * time.Millisecond
+		if consumer.Name == eb.consumerName || consumer.Pending > 0 || idle < consumerCleanupIdle {
+			continue
+		}
+		if err := eb.redisClient.XGroupDelConsumer(eb.ctx, StreamName, GroupName, consumer.Name).Err(); err != nil {
+			eb.logger.Warn("failed to remove idle stream consumer", slog.String("consumer", consumer.Name), slog.String("error", err.Error()))
+		}
+	}
+}
+
 func (eb *EventBus) processMessage(msg redis.XMessage) {
-	eventType, ok := msg.Values["type"].(string)
-	if !ok {
-		eb.logger.Warn("message missing event type", slog.String("id", msg.ID))
+	envelope, err := envelopeFromValues(msg.Values)
+	if err != nil {
+		eb.logger.Warn("failed to read message envelope", slog.String("id", msg.ID), slog.String("error", err.Error()))
+		eb.deadLetter(msg, nil, 1, err)
 		return
 	}
 
-	payloadStr, ok := msg.Values["payload"].(string)
-	if !ok {
-		eb.logger.Warn("message missing payload", slog.String("id", msg.ID))
+	event, handlers, err := eb.decode(envelope)
+	if errors.Is(err, ErrNoHandlers) {
+		// Nobody listens to this type, acknowledge it so it does not sit in the pending entries list
+		eb.ack(msg.ID)
+		return
+	}
+	if err != nil {
+		eb.logger.Error("failed to decode event payload",
+			slog.String("type", envelope.Type),
+			slog.Int("version", envelope.Version),
+			slog.String("error", err.Error()))
+		// Retrying cannot fix a payload that does not decode
+		eb.deadLetter(msg, envelope, 1, err)
 		return
 	}
 
-	eb.mutex.RLock()
-	handlers, handlersExist := eb.handlers[eventType]
-	proto, protoExist := eb.eventTypes[eventType]
-	eb.mutex.RUnlock()
+	var handleErr error
+	for _, handler := range handlers {
+		if err := runHandler(eb.logger, handler, event); err != nil && handleErr == nil {
+			handleErr = err
+		}
+	}
 
-	if !handlersExist || !protoExist {
+	if handleErr == nil {
+		eb.ack(msg.ID)
 		return
 	}
 
-	// Create a new instance of the event type
-	eventPtr := reflect.New(proto).Interface()
-	if err := json.Unmarshal([]byte(payloadStr), eventPtr); err != nil {
-		eb.logger.Error("failed to unmarshal event payload",
-			slog.String("type", eventType),
-			slog.String("error", err.Error()))
+	eb.retryLater(msg, envelope, handleErr)
+}
+
+// retryLater records a failed attempt and schedules the next one, or dead-letters the message
+// once it failed maxAttempts times
+func (eb *EventBus) retryLater(msg redis.XMessage, envelope *Envelope, handleErr error) {
+	attempts, err := eb.redisClient.HIncrBy(context.Background(), attemptsKey, msg.ID, 1).Result()
+	if err != nil {
+		eb.logger.Error("failed to record event attempt", slog.String("id", msg.ID), slog.String("error", err.Error()))
 		return
 	}
 
-	// The reflect.New returns a pointer, but our handlers might expect the value
-	// If the prototype was a struct, reflect.New(proto).Interface() is *Struct
-	event := reflect.Indirect(reflect.ValueOf(eventPtr)).Interface().(Event)
+	if int(attempts) >= eb.maxAttempts {
+		eb.deadLetter(msg, envelope, int(attempts), handleErr)
+		return
+	}
 
-	for _, handler := range handlers {
-		go func(h EventHandler, e Event) {
-			defer func() {
-				if r := recover(); r != nil {
-					eb.logger.Error("event handler panicked", slog.Any("panic", r))
-				}
-			}()
-			h.Handle(e)
-		}(handler, event)
+	backoff := eb.retryBackoff << (attempts - 1)
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	eb.redisClient.HSet(context.Background(), retryAtKey, msg.ID, time.Now().Add(backoff).UnixMilli())
+
+	eb.logger.Warn("event handler failed, message will be retried",
+		slog.String("id", msg.ID),
+		slog.String("type", envelope.Type),
+		slog.String("correlation_id", envelope.CorrelationID),
+		slog.Int64("attempt", attempts),
+		slog.Duration("backoff", backoff),
+		slog.String("error", handleErr.Error()))
+}
+
+// deadLetter moves a message to the dead-letter stream and acknowledges it. Without an envelope
+// the fields of the unreadable message are copied as they are.
+func (eb *EventBus) deadLetter(msg redis.XMessage, envelope *Envelope, attempts int, cause error) {
+	values := map[string]interface{}{
+		"error":       cause.Error(),
+		"attempts":    attempts,
+		"original_id": msg.ID,
+		"consumer":    eb.consumerName,
+		"failed_at":   time.Now().Format(time.RFC3339),
+	}
+
+	eventType := ""
+	if envelope != nil {
+		data, err := json.Marshal(envelope)
+		if err != nil {
+			eb.logger.Error("failed to marshal envelope of dead letter", slog.String("id", msg.ID), slog.String("error", err.Error()))
+			return
+		}
+		eventType = envelope.Type
+		values["type"] = envelope.Type
+		values["envelope"] = data
+	} else {
+		for _, field := range []string{"type", "envelope", "payload"} {
+			if value, ok := msg.Values[field]; ok {
+				values[field] = value
+			}
+		}
+	}
+
+	err := eb.redisClient.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: DeadLetterStreamName,
+		Values: values,
+	}).Err()
+	if err != nil {
+		// Keep the message pending, it is dead-lettered on the next reclaim
+		eb.logger.Error("failed to dead-letter message", slog.String("id", msg.ID), slog.String("error", err.Error()))
+		return
+	}
+
+	eb.logger.Error("event moved to dead-letter stream",
+		slog.String("id", msg.ID),
+		slog.String("type", eventType),
+		slog.Int("attempts", attempts),
+		slog.String("error", cause.Error()))
+	eb.ack(msg.ID)
+}
+
+func (eb *EventBus) ack(messageID string) {
+	if err := eb.redisClient.XAck(context.Background(), StreamName, GroupName, messageID).Err(); err != nil {
+		eb.logger.Error("failed to acknowledge message", slog.String("id", messageID), slog.String("error", err.Error()))
+		return
+	}
+	eb.redisClient.HDel(context.Background(), attemptsKey, messageID)
+	eb.redisClient.HDel(context.Background(), retryAtKey, messageID)
+}
+
+// ListDeadLetters returns up to count dead-lettered messages, oldest first, starting after afterID
+func (eb *EventBus) ListDeadLetters(afterID string, count int64) ([]DeadLetter, int64, error) {
+	start := "-"
+	if afterID != "" {
+		start = "(" + afterID
+	}
+
+	messages, err := eb.redisClient.XRangeN(eb.ctx, DeadLetterStreamName, start, "+", count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := eb.redisClient.XLen(eb.ctx, DeadLetterStreamName).Result()
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Acknowledge the message
-	eb.redisClient.XAck(eb.ctx, StreamName, GroupName, msg.ID)
+	deadLetters := make([]DeadLetter, 0, len(messages))
+	for _, msg := range messages {
+		deadLetters = append(deadLetters, toDeadLetter(msg))
+	}
+	return deadLetters, total, nil
+}
+
+// ReplayDeadLetter publishes a dead-lettered message to the event stream again with a fresh
+// attempt count and removes it from the dead-letter stream. The envelope is kept as it was, so
+// the event ID and correlation ID survive the replay.
+func (eb *EventBus) ReplayDeadLetter(id string) (*DeadLetter, error) {
+	msg, err := eb.getDeadLetterMessage(id)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := envelopeFromValues(msg.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := eb.PublishEnvelope(envelope); err != nil {
+		return nil, err
+	}
+	if err := eb.redisClient.XDel(eb.ctx, DeadLetterStreamName, id).Err(); err != nil {
+		return nil, err
+	}
+
+	deadLetter := toDeadLetter(*msg)
+	eb.logger.Info("dead letter replayed", slog.String("id", id), slog.String("type", deadLetter.EventType))
+	return &deadLetter, nil
+}
+
+// DiscardDeadLetter drops a dead-lettered message for good
+func (eb *EventBus) DiscardDeadLetter(id string) error {
+	deleted, err := eb.redisClient.XDel(eb.ctx, DeadLetterStreamName, id).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrDeadLetterNotFound
+	}
+
+	eb.logger.Info("dead letter discarded", slog.String("id", id))
+	return nil
+}
+
+func (eb *EventBus) getDeadLetterMessage(id string) (*redis.XMessage, error) {
+	messages, err := eb.redisClient.XRangeN(eb.ctx, DeadLetterStreamName, id, id, 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	return &messages[0], nil
+}
+
+func toDeadLetter(msg redis.XMessage) DeadLetter {
+	field := func(name string) string {
+		value, _ := msg.Values[name].(string)
+		return value
+	}
+
+	attempts, _ := strconv.Atoi(field("attempts"))
+	failedAt, _ := time.Parse(time.RFC3339, field("failed_at"))
+	deadLetter := DeadLetter{
+		ID:         msg.ID,
+		EventType:  field("type"),
+		Payload:    field("envelope"),
+		Error:      field("error"),
+		Attempts:   attempts,
+		OriginalID: field("original_id"),
+		Consumer:   field("consumer"),
+		FailedAt:   failedAt,
+	}
+
+	if envelope, err := envelopeFromValues(msg.Values); err == nil {
+		deadLetter.Version = envelope.Version
+		deadLetter.EventID = envelope.EventID
+		deadLetter.CorrelationID = envelope.CorrelationID
+		deadLetter.Payload = string(envelope.Payload)
+	}
+	return deadLetter
 }
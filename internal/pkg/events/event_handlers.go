@@ -1,12 +1,13 @@
 package events
 
 import (
-	"learn/internal/config"
+	"context"
 	"learn/internal/model"
-	"learn/internal/pkg/qrcode"
 	"learn/internal/pkg/random"
+	"learn/internal/pkg/ticketqr"
 	"learn/internal/repository"
 	"log/slog"
+	"time"
 )
 
 // OrderPaidEventHandler handles the OrderPaidEvent
@@ -24,29 +25,25 @@ func NewOrderPaidEventHandler(orderRepo repository.OrderRepository, logger *slog
 }
 
 // Handle processes the OrderPaidEvent
-func (h *OrderPaidEventHandler) Handle(event Event) {
+func (h *OrderPaidEventHandler) Handle(event Event) error {
 	if orderPaidEvent, ok := event.(OrderPaidEvent); ok {
-		order, err := h.orderRepo.GetOrderByID(orderPaidEvent.OrderID)
+		// Usually a no-op, the payment transaction already marked the order paid while it was
+		// pending. A cancelled order is never marked paid.
+		_, paid, err := h.orderRepo.TransitionOrderStatus(context.Background(), orderPaidEvent.OrderID, model.OrderPending, model.OrderPaid, nil)
 		if err != nil {
-			h.logger.Error("Failed to get order for payment processing",
+			h.logger.Error("Failed to update order status to paid",
 				slog.Uint64("order_id", uint64(orderPaidEvent.OrderID)),
 				slog.String("error", err.Error()))
-			return
+			return err
 		}
 
-		// Update order status to paid
-		order.Status = model.OrderPaid
-		if err := h.orderRepo.UpdateOrder(order); err != nil {
-			h.logger.Error("Failed to update order status to paid",
+		if paid {
+			h.logger.Info("Order status updated to paid",
 				slog.Uint64("order_id", uint64(orderPaidEvent.OrderID)),
-				slog.String("error", err.Error()))
-			return
+				slog.Int64("total_price", orderPaidEvent.TotalPrice))
 		}
-
-		h.logger.Info("Order status updated to paid",
-			slog.Uint64("order_id", uint64(orderPaidEvent.OrderID)),
-			slog.Int64("total_price", orderPaidEvent.TotalPrice))
 	}
+	return nil
 }
 
 // OrderCancelledEventHandler handles the OrderCancelledEvent
@@ -64,29 +61,25 @@ func NewOrderCancelledEventHandler(orderRepo repository.OrderRepository, logger
 }
 
 // Handle processes the OrderCancelledEvent
-func (h *OrderCancelledEventHandler) Handle(event Event) {
+func (h *OrderCancelledEventHandler) Handle(event Event) error {
 	if orderCancelledEvent, ok := event.(OrderCancelledEvent); ok {
-		order, err := h.orderRepo.GetOrderByID(orderCancelledEvent.OrderID)
+		// Usually a no-op, the event is recorded by the transaction that cancelled the order.
+		// The conditional transition restores the quota only if the order was still pending.
+		_, cancelled, err := h.orderRepo.TransitionOrderStatus(context.Background(), orderCancelledEvent.OrderID, model.OrderPending, model.OrderCancelled, nil)
 		if err != nil {
-			h.logger.Error("Failed to get order for cancellation",
+			h.logger.Error("Failed to update order status to cancelled",
 				slog.Uint64("order_id", uint64(orderCancelledEvent.OrderID)),
 				slog.String("error", err.Error()))
-			return
+			return err
 		}
 
-		// Update order status to cancelled
-		order.Status = model.OrderCancelled
-		if err := h.orderRepo.UpdateOrder(order); err != nil {
-			h.logger.Error("Failed to update order status to cancelled",
+		if cancelled {
+			h.logger.Info("Order status updated to cancelled",
 				slog.Uint64("order_id", uint64(orderCancelledEvent.OrderID)),
-				slog.String("error", err.Error()))
-			return
+				slog.String("reason", orderCancelledEvent.Reason))
 		}
-
-		h.logger.Info("Order status updated to cancelled",
-			slog.Uint64("order_id", uint64(orderCancelledEvent.OrderID)),
-			slog.String("reason", orderCancelledEvent.Reason))
 	}
+	return nil
 }
 
 // PaymentStatusUpdatedEventHandler handles the PaymentStatusUpdatedEvent
@@ -115,8 +108,9 @@ func NewPaymentStatusUpdatedEventHandler(
 	}
 }
 
-// Handle processes the PaymentStatusUpdatedEvent
-func (h *PaymentStatusUpdatedEventHandler) Handle(event Event) {
+// Handle processes the PaymentStatusUpdatedEvent. The payment transaction already moved the order,
+// so retrying is safe: ticket generation skips orders that already have tickets.
+func (h *PaymentStatusUpdatedEventHandler) Handle(event Event) error {
 	if paymentStatusEvent, ok := event.(PaymentStatusUpdatedEvent); ok {
 		h.logger.Info("Processing payment status update",
 			slog.Uint64("payment_id", uint64(paymentStatusEvent.PaymentID)),
@@ -130,7 +124,7 @@ func (h *PaymentStatusUpdatedEventHandler) Handle(event Event) {
 				h.logger.Error("Failed to get order by ID with line items for status update",
 					slog.Uint64("order_id", uint64(paymentStatusEvent.OrderID)),
 					slog.String("error", err.Error()))
-				return
+				return err
 			}
 
 			// Publish OrderPaidEvent
@@ -144,42 +138,51 @@ func (h *PaymentStatusUpdatedEventHandler) Handle(event Event) {
 			// In a real implementation, you would publish this event to the event bus
 			// For now, we'll handle it directly
 			orderPaidHandler := NewOrderPaidEventHandler(h.orderRepo, h.logger)
-			orderPaidHandler.Handle(orderPaidEvent)
+			if err := orderPaidHandler.Handle(orderPaidEvent); err != nil {
+				return err
+			}
 
-			// Generate tickets
-			h.generateTicketsForOrder(order)
-		case model.PaymentStatusFailed:
-			// Get order
-			order, err := h.orderRepo.GetOrderByID(paymentStatusEvent.OrderID)
+			// A payment settled after the order was cancelled leaves the order cancelled, its
+			// quota may already be sold again. The payment transaction flagged it for admin review.
+			order, err = h.orderRepo.GetOrderByIDWithLineItems(order.ID)
 			if err != nil {
-				h.logger.Error("Failed to get order for failed payment",
-					slog.Uint64("order_id", uint64(paymentStatusEvent.OrderID)),
-					slog.String("error", err.Error()))
-				return
+				return err
 			}
-
-			// Restore quotas
-			h.restoreQuotasForOrder(order)
-
-			// Publish OrderCancelledEvent
-			orderCancelledEvent := OrderCancelledEvent{
-				OrderID:     order.ID,
-				UserID:      order.UserID,
-				Reason:      "Payment failed",
-				CancelledAt: paymentStatusEvent.UpdatedAt,
+			if order.Status != model.OrderPaid {
+				h.logger.Warn("Payment succeeded for an order that is not paid, no tickets generated",
+					slog.Uint64("order_id", uint64(order.ID)),
+					slog.String("order_status", string(order.Status)))
+				return nil
 			}
 
-			// In a real implementation, you would publish this event to the event bus
-			// For now, we'll handle it directly
-			orderCancelledHandler := NewOrderCancelledEventHandler(h.orderRepo, h.logger)
-			orderCancelledHandler.Handle(orderCancelledEvent)
+			// Generate tickets
+			return h.generateTicketsForOrder(order)
+		case model.PaymentStatusFailed:
+			// The payment transaction cancelled the order and restored its quota if it was
+			// still pending
+			h.logger.Info("Order cancelled after failed payment",
+				slog.Uint64("order_id", uint64(paymentStatusEvent.OrderID)))
 		}
 	}
+	return nil
 }
 
-// generateTicketsForOrder generates tickets for a successful order
-func (h *PaymentStatusUpdatedEventHandler) generateTicketsForOrder(order *model.Order) {
+// generateTicketsForOrder generates tickets for a successful order. Orders that already have
+// tickets are skipped, so a redelivered event never issues a second set.
+func (h *PaymentStatusUpdatedEventHandler) generateTicketsForOrder(order *model.Order) error {
+	existing, err := h.ticketRepo.CountTicketsByOrderID(order.ID)
+	if err != nil {
+		h.logger.Error("failed to count tickets of order", slog.Uint64("order_id", uint64(order.ID)), slog.String("error", err.Error()))
+		return err
+	}
+	if existing > 0 {
+		h.logger.Info("tickets already generated for order", slog.Uint64("order_id", uint64(order.ID)))
+		return nil
+	}
+
 	var ticketsToCreate []model.Ticket
+	eventIDs := make(map[uint]uint) // event price ID -> event ID
+	issuedAt := time.Now()
 
 	for _, lineItem := range order.OrderLineItems {
 		// Fetch EventPrice details to get Type (Name)
@@ -188,25 +191,18 @@ func (h *PaymentStatusUpdatedEventHandler) generateTicketsForOrder(order *model.
 			h.logger.Error("failed to get event price for ticket generation",
 				slog.Uint64("event_price_id", uint64(lineItem.EventPriceID)),
 				slog.String("error", err.Error()))
-			continue
+			return err
 		}
+		eventIDs[lineItem.EventPriceID] = eventPrice.EventID
 
 		for i := 0; i < lineItem.Quantity; i++ {
-			ticketCode := random.String(10)
-			qrPath, qrErr := qrcode.GenerateQRCodePNG(config.AppConfig.StorageQRPath, ticketCode)
-			if qrErr != nil {
-				h.logger.Error("failed to generate QR code for ticket",
-					slog.String("ticket_code", ticketCode),
-					slog.String("error", qrErr.Error()))
-			}
-
 			ticket := model.Ticket{
 				OrderID:      order.ID,
 				EventPriceID: lineItem.EventPriceID,
 				Price:        lineItem.PricePerUnit,
-				Type:         eventPrice.Name,   // Use EventPrice Name as Ticket Type
-				TicketCode:   ticketCode,
-				QrCodePath:   qrPath,
+				Type:         eventPrice.Name, // Use EventPrice Name as Ticket Type
+				TicketCode:   random.String(10),
+				QrIssuedAt:   issuedAt,
 				OwnerName:    order.User.Name,
 				OwnerEmail:   order.User.Email,
 			}
@@ -217,7 +213,23 @@ func (h *PaymentStatusUpdatedEventHandler) generateTicketsForOrder(order *model.
 	if len(ticketsToCreate) > 0 {
 		if err := h.ticketRepo.CreateTickets(ticketsToCreate); err != nil {
 			h.logger.Error("failed to create tickets", slog.String("error", err.Error()))
-			return
+			return err
+		}
+
+		// The signed QR token carries the ticket ID, so images are written once the tickets exist
+		for _, ticket := range ticketsToCreate {
+			qrPath, qrErr := ticketqr.GenerateTicketQRCode(ticket, eventIDs[ticket.EventPriceID])
+			if qrErr != nil {
+				h.logger.Error("failed to generate QR code for ticket",
+					slog.Uint64("ticket_id", uint64(ticket.ID)),
+					slog.String("error", qrErr.Error()))
+				continue
+			}
+			if err := h.ticketRepo.UpdateQRCodePath(ticket.ID, qrPath); err != nil {
+				h.logger.Error("failed to save QR code path for ticket",
+					slog.Uint64("ticket_id", uint64(ticket.ID)),
+					slog.String("error", err.Error()))
+			}
 		}
 
 		// Extract ticket codes for the event
@@ -237,30 +249,5 @@ func (h *PaymentStatusUpdatedEventHandler) generateTicketsForOrder(order *model.
 			slog.Uint64("order_id", uint64(order.ID)),
 			slog.Int("count", len(ticketsToCreate)))
 	}
-}
-
-// restoreQuotasForOrder restores the quotas for a failed order
-func (h *PaymentStatusUpdatedEventHandler) restoreQuotasForOrder(order *model.Order) {
-	// Get order line items to determine which quotas to restore
-	orderWithLineItems, err := h.orderRepo.GetOrderByIDWithLineItems(order.ID)
-	if err != nil {
-		h.logger.Error("Failed to get order with line items for quota restoration",
-			slog.Uint64("order_id", uint64(order.ID)),
-			slog.String("error", err.Error()))
-		return
-	}
-
-	// Restore quotas for each line item
-	for _, lineItem := range orderWithLineItems.OrderLineItems {
-		err := h.orderRepo.RestoreQuota(lineItem.EventPriceID, lineItem.Quantity)
-		if err != nil {
-			h.logger.Error("Failed to restore quota",
-				slog.Uint64("event_price_id", uint64(lineItem.EventPriceID)),
-				slog.Int("quantity", lineItem.Quantity),
-				slog.String("error", err.Error()))
-		}
-	}
-
-	h.logger.Info("Quotas restored for cancelled order",
-		slog.Uint64("order_id", uint64(order.ID)))
+	return nil
 }
@@ -0,0 +1,166 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrStreamMessageNotFound is returned when a message does not exist on the event stream (anymore)
+var ErrStreamMessageNotFound = errors.New("stream message not found")
+
+// StreamMessage is a message as stored on the event stream, independent of the consumer group.
+// Envelope is nil when the message cannot be read, Payload then holds the raw message fields.
+type StreamMessage struct {
+	ID          string
+	EventType   string
+	Payload     string
+	PublishedAt time.Time
+	Envelope    *Envelope
+}
+
+// PendingInfo describes a message the consumer group has read but not acknowledged yet
+type PendingInfo struct {
+	Consumer string
+	Idle     time.Duration
+	Attempts int
+}
+
+// StreamIDAt returns the first stream ID that can have been added at or after t, for use as
+// a range boundary
+func StreamIDAt(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10) + "-0"
+}
+
+// ReadStream returns up to count messages between the stream IDs from and to, both inclusive.
+// Use "-" and "+" for the start and the end of the stream and prefix an ID with "(" to exclude it.
+func (eb *EventBus) ReadStream(from string, to string, count int64) ([]StreamMessage, error) {
+	messages, err := eb.redisClient.XRangeN(eb.ctx, StreamName, from, to, count).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]StreamMessage, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, toStreamMessage(msg))
+	}
+	return result, nil
+}
+
+// GetStreamMessage returns a single message of the event stream
+func (eb *EventBus) GetStreamMessage(id string) (*StreamMessage, error) {
+	messages, err := eb.ReadStream(id, id, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, ErrStreamMessageNotFound
+	}
+	return &messages[0], nil
+}
+
+// GetPendingInfo reports whether the consumer group still holds a message unacknowledged.
+// It returns nil when the message was acknowledged or never delivered.
+func (eb *EventBus) GetPendingInfo(id string) (*PendingInfo, error) {
+	pending, err := eb.redisClient.XPendingExt(eb.ctx, &redis.XPendingExtArgs{
+		Stream: StreamName,
+		Group:  GroupName,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	attempts, err := eb.redisClient.HGet(eb.ctx, attemptsKey, id).Int()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return &PendingInfo{Consumer: pending[0].Consumer, Idle: pending[0].Idle, Attempts: attempts}, nil
+}
+
+// TailStream calls handle for every message added to the event stream after fromID until ctx is
+// done. Use "$" to only follow new messages. The consumer group is not involved, so tailing never
+// takes messages away from the workers.
+func (eb *EventBus) TailStream(ctx context.Context, fromID string, handle func(StreamMessage)) error {
+	lastID := fromID
+	for {
+		streams, err := eb.redisClient.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{StreamName, lastID},
+			Count:   100,
+			Block:   time.Second * 2,
+		}).Result()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				handle(toStreamMessage(msg))
+				lastID = msg.ID
+			}
+		}
+	}
+}
+
+// Redeliver runs the registered handlers for a message read from the stream outside the consumer
+// group, so its acknowledgement, attempts and dead-lettering are left untouched. With dryRun the
+// payload is only decoded. It returns the number of handlers the message is (or would be) dispatched to.
+func (eb *EventBus) Redeliver(message StreamMessage, dryRun bool) (int, error) {
+	if message.Envelope == nil {
+		return 0, fmt.Errorf("message %s has no readable envelope", message.ID)
+	}
+
+	// Upcasting rewrites the envelope, keep the message as it was read
+	envelope := *message.Envelope
+	event, handlers, err := eb.decode(&envelope)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return len(handlers), nil
+	}
+
+	for _, handler := range handlers {
+		if err := runHandler(eb.logger, handler, event); err != nil {
+			return len(handlers), err
+		}
+	}
+	return len(handlers), nil
+}
+
+func toStreamMessage(msg redis.XMessage) StreamMessage {
+	message := StreamMessage{ID: msg.ID}
+	if millis, err := strconv.ParseInt(strings.SplitN(msg.ID, "-", 2)[0], 10, 64); err == nil {
+		message.PublishedAt = time.UnixMilli(millis)
+	}
+
+	envelope, err := envelopeFromValues(msg.Values)
+	if err != nil {
+		message.EventType, _ = msg.Values["type"].(string)
+		raw, _ := json.Marshal(msg.Values)
+		message.Payload = string(raw)
+		return message
+	}
+
+	message.EventType = envelope.Type
+	message.Payload = string(envelope.Payload)
+	message.Envelope = envelope
+	return message
+}
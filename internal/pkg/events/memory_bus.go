@@ -0,0 +1,173 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"learn/internal/config"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// memoryBusBuffer is how many events the in-memory bus queues before publishing fails
+const memoryBusBuffer = 1024
+
+// ErrMemoryBusFull is returned when the queue of the in-memory bus is full
+var ErrMemoryBusFull = errors.New("in-memory event bus queue is full")
+
+// memoryDelivery is a queued event with the number of attempts already made
+type memoryDelivery struct {
+	envelope *Envelope
+	attempts int
+}
+
+// MemoryBus dispatches events inside the process, for tests and single-instance deployments
+// without Redis Streams. With a buffer of 0 it is synchronous: Publish runs the handlers before it
+// returns and failures are only logged. Otherwise events are queued for a worker that retries
+// failed handlers with the same backoff as EventBus and drops the event after maxAttempts.
+// Queued events are lost when the process stops.
+type MemoryBus struct {
+	handlerRegistry
+	logger       *slog.Logger
+	queue        chan memoryDelivery
+	maxAttempts  int
+	retryBackoff time.Duration
+	stopChan     chan struct{}
+	stopOnce     sync.Once
+}
+
+// NewMemoryBus creates an in-memory bus queueing up to buffer events, 0 makes it synchronous
+func NewMemoryBus(logger *slog.Logger, buffer int) *MemoryBus {
+	maxAttempts := config.AppConfig.EventMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	retryBackoff := config.AppConfig.EventRetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = 5 * time.Second
+	}
+
+	bus := &MemoryBus{
+		handlerRegistry: newHandlerRegistry(),
+		logger:          logger,
+		maxAttempts:     maxAttempts,
+		retryBackoff:    retryBackoff,
+		stopChan:        make(chan struct{}),
+	}
+	if buffer > 0 {
+		bus.queue = make(chan memoryDelivery, buffer)
+	}
+	return bus
+}
+
+// Publish wraps the event in an envelope correlated with the request ID of ctx and dispatches it
+func (b *MemoryBus) Publish(ctx context.Context, event Event) {
+	envelope, err := NewEnvelope(ctx, event)
+	if err != nil {
+		b.logger.Error("failed to marshal event", slog.String("error", err.Error()))
+		return
+	}
+
+	if err := b.PublishEnvelope(envelope); err != nil {
+		b.logger.Error("failed to publish event",
+			slog.String("type", envelope.Type),
+			slog.String("event_id", envelope.EventID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// PublishEnvelope dispatches an already wrapped event, or queues it when the bus is buffered
+func (b *MemoryBus) PublishEnvelope(envelope *Envelope) error {
+	delivery := memoryDelivery{envelope: envelope}
+	if b.queue == nil {
+		b.dispatch(delivery)
+		return nil
+	}
+
+	select {
+	case b.queue <- delivery:
+		return nil
+	default:
+		return ErrMemoryBusFull
+	}
+}
+
+// Start starts the worker of a buffered bus
+func (b *MemoryBus) Start() {
+	if b.queue == nil {
+		return
+	}
+
+	go func() {
+		b.logger.Info("In-memory event bus worker started")
+		for {
+			select {
+			case delivery := <-b.queue:
+				b.dispatch(delivery)
+			case <-b.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the worker, events still queued are dropped
+func (b *MemoryBus) Stop() {
+	b.stopOnce.Do(func() { close(b.stopChan) })
+}
+
+// dispatch runs the handlers of an event and schedules a retry when one of them fails
+func (b *MemoryBus) dispatch(delivery memoryDelivery) {
+	// Upcasting rewrites the envelope, a retry has to start from the published one
+	envelope := *delivery.envelope
+	event, handlers, err := b.decode(&envelope)
+	if errors.Is(err, ErrNoHandlers) {
+		return
+	}
+	if err != nil {
+		b.logger.Error("failed to decode event payload, event dropped",
+			slog.String("type", envelope.Type),
+			slog.Int("version", envelope.Version),
+			slog.String("error", err.Error()))
+		return
+	}
+
+	var handleErr error
+	for _, handler := range handlers {
+		if err := runHandler(b.logger, handler, event); err != nil && handleErr == nil {
+			handleErr = err
+		}
+	}
+	if handleErr == nil {
+		return
+	}
+
+	delivery.attempts++
+	if b.queue == nil || delivery.attempts >= b.maxAttempts {
+		b.logger.Error("event handler failed, event dropped",
+			slog.String("type", envelope.Type),
+			slog.String("event_id", envelope.EventID),
+			slog.String("correlation_id", envelope.CorrelationID),
+			slog.Int("attempts", delivery.attempts),
+			slog.String("error", handleErr.Error()))
+		return
+	}
+
+	backoff := b.retryBackoff << (delivery.attempts - 1)
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	b.logger.Warn("event handler failed, event will be retried",
+		slog.String("type", envelope.Type),
+		slog.String("event_id", envelope.EventID),
+		slog.Int("attempt", delivery.attempts),
+		slog.Duration("backoff", backoff),
+		slog.String("error", handleErr.Error()))
+
+	time.AfterFunc(backoff, func() {
+		select {
+		case <-b.stopChan:
+		case b.queue <- delivery:
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"log/slog"
+	"time"
+)
+
+const (
+	outboxRelayInterval  = time.Second
+	outboxBatchSize      = 100
+	outboxMonitorEvery   = time.Minute
+	outboxCleanupEvery   = time.Hour
+	outboxRetention      = 7 * 24 * time.Hour
+	outboxBacklogWarnAge = time.Minute // Pending events older than this are logged as a stuck backlog
+)
+
+// OutboxRelay publishes events committed to the outbox table to the Redis stream. A row is marked
+// sent only after XAdd succeeded, so delivery is at least once and handlers must stay idempotent.
+type OutboxRelay struct {
+	outboxRepo repository.OutboxRepository
+	eventBus   EventPublisher
+	logger     *slog.Logger
+	stopChan   chan struct{}
+}
+
+func NewOutboxRelay(outboxRepo repository.OutboxRepository, eventBus EventPublisher, logger *slog.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		eventBus:   eventBus,
+		logger:     logger,
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start relays pending events every second, checks the backlog every minute and purges delivered
+// events every hour until Stop is called
+func (r *OutboxRelay) Start() {
+	relayTicker := time.NewTicker(outboxRelayInterval)
+	defer relayTicker.Stop()
+	monitorTicker := time.NewTicker(outboxMonitorEvery)
+	defer monitorTicker.Stop()
+	cleanupTicker := time.NewTicker(outboxCleanupEvery)
+	defer cleanupTicker.Stop()
+
+	r.logger.Info("Outbox relay started")
+
+	for {
+		select {
+		case <-relayTicker.C:
+			r.relay()
+		case <-monitorTicker.C:
+			r.checkBacklog()
+		case <-cleanupTicker.C:
+			r.cleanup()
+		case <-r.stopChan:
+			r.logger.Info("Outbox relay stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the relay, events still pending are published after the next start
+func (r *OutboxRelay) Stop() {
+	close(r.stopChan)
+}
+
+// relay drains the backlog batch by batch until it is empty or a publish fails
+func (r *OutboxRelay) relay() {
+	for {
+		sent, err := r.outboxRepo.PublishPending(outboxBatchSize, func(event model.OutboxEvent) error {
+			return r.eventBus.PublishEnvelope(outboxEnvelope(event))
+		})
+		if err != nil {
+			r.logger.Error("failed to relay outbox events", slog.String("error", err.Error()))
+			return
+		}
+		if sent < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// outboxEnvelope wraps an outbox row, rows written before events carried an ID get one derived
+// from the row so redeliveries still share it
+func outboxEnvelope(event model.OutboxEvent) *Envelope {
+	eventID := event.EventID
+	if eventID == "" {
+		eventID = fmt.Sprintf("outbox-%d", event.ID)
+	}
+
+	return &Envelope{
+		Type:          event.EventType,
+		Version:       event.Version,
+		EventID:       eventID,
+		OccurredAt:    event.CreatedAt,
+		CorrelationID: event.CorrelationID,
+		Payload:       json.RawMessage(event.Payload),
+	}
+}
+
+func (r *OutboxRelay) cleanup() {
+	deleted, err := r.outboxRepo.DeleteSentBefore(time.Now().Add(-outboxRetention))
+	if err != nil {
+		r.logger.Error("failed to purge sent outbox events", slog.String("error", err.Error()))
+	} else if deleted > 0 {
+		r.logger.Info("purged sent outbox events", slog.Int64("deleted", deleted))
+	}
+}
+
+func (r *OutboxRelay) checkBacklog() {
+	stats, err := r.outboxRepo.GetStats()
+	if err != nil {
+		r.logger.Error("failed to get outbox stats", slog.String("error", err.Error()))
+		return
+	}
+	if stats.OldestPendingAt != nil && time.Since(*stats.OldestPendingAt) > outboxBacklogWarnAge {
+		r.logger.Warn("outbox backlog is not draining",
+			slog.Int64("pending", stats.Pending),
+			slog.Int64("retrying", stats.Retrying),
+			slog.Time("oldest_pending_at", *stats.OldestPendingAt))
+	}
+}
@@ -0,0 +1,103 @@
+package events
+
+import "encoding/json"
+
+// Upcasters migrate payloads already on the stream, in the outbox or in the dead-letter stream to
+// the current schema of their event. Register one per version bump and never change a registered
+// one, messages of that version may still be around.
+func init() {
+	// Version 1 payloads used the Go field names
+	RegisterUpcaster("order.created", 1, renameFields(map[string]string{
+		"OrderID":    "order_id",
+		"UserID":     "user_id",
+		"TotalPrice": "total_price",
+		"CreatedAt":  "created_at",
+	}))
+	RegisterUpcaster("order.paid", 1, renameFields(map[string]string{
+		"OrderID":    "order_id",
+		"UserID":     "user_id",
+		"TotalPrice": "total_price",
+		"PaidAt":     "paid_at",
+	}))
+	RegisterUpcaster("order.cancelled", 1, renameFields(map[string]string{
+		"OrderID":     "order_id",
+		"UserID":      "user_id",
+		"Reason":      "reason",
+		"CancelledAt": "cancelled_at",
+	}))
+	RegisterUpcaster("payment.created", 1, renameFields(map[string]string{
+		"PaymentID": "payment_id",
+		"OrderID":   "order_id",
+		"Method":    "method",
+		"Amount":    "amount",
+		"CreatedAt": "created_at",
+	}))
+	RegisterUpcaster("payment.status.updated", 1, renameFields(map[string]string{
+		"PaymentID": "payment_id",
+		"OrderID":   "order_id",
+		"Status":    "status",
+		"UpdatedAt": "updated_at",
+	}))
+	RegisterUpcaster("tickets.generated", 1, renameFields(map[string]string{
+		"OrderID":     "order_id",
+		"TicketCodes": "ticket_codes",
+		"GeneratedAt": "generated_at",
+	}))
+	RegisterUpcaster("ticket.scanned", 1, renameFields(map[string]string{
+		"ScanID":       "scan_id",
+		"TicketID":     "ticket_id",
+		"EventID":      "event_id",
+		"EventPriceID": "event_price_id",
+		"Direction":    "direction",
+		"Result":       "result",
+		"Reason":       "reason",
+		"Gate":         "gate",
+		"DeviceID":     "device_id",
+		"Offline":      "offline",
+		"ScannedAt":    "scanned_at",
+	}))
+	RegisterUpcaster("ticket.transferred", 1, renameFields(map[string]string{
+		"TransferID":    "transfer_id",
+		"TicketID":      "ticket_id",
+		"EventID":       "event_id",
+		"FromUserID":    "from_user_id",
+		"ToUserID":      "to_user_id",
+		"TransferredAt": "transferred_at",
+	}))
+	RegisterUpcaster("order.refunded", 1, renameFields(map[string]string{
+		"RefundID":      "refund_id",
+		"OrderID":       "order_id",
+		"UserID":        "user_id",
+		"Amount":        "amount",
+		"TicketIDs":     "ticket_ids",
+		"FullyRefunded": "fully_refunded",
+		"Manual":        "manual",
+		"RefundedAt":    "refunded_at",
+	}))
+	RegisterUpcaster("event.cancelled", 1, renameFields(map[string]string{
+		"EventID":        "event_id",
+		"CancellationID": "cancellation_id",
+		"UserID":         "user_id",
+		"Reason":         "reason",
+		"CancelledAt":    "cancelled_at",
+	}))
+}
+
+// renameFields returns an upcaster renaming top-level payload fields, fields not in renames are kept
+func renameFields(renames map[string]string) Upcaster {
+	return func(payload json.RawMessage) (json.RawMessage, error) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return nil, err
+		}
+
+		renamed := make(map[string]json.RawMessage, len(fields))
+		for name, value := range fields {
+			if newName, ok := renames[name]; ok {
+				name = newName
+			}
+			renamed[name] = value
+		}
+		return json.Marshal(renamed)
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"learn/internal/model"
+	"learn/internal/pkg/webhook"
+	"learn/internal/repository"
+	"log/slog"
+	"time"
+)
+
+// WebhookEventHandler turns domain events into deliveries for the organizer webhook endpoints
+// subscribed to them. Sending is left to the webhook dispatcher, so a slow endpoint never holds up
+// the bus. Deliveries are keyed by event, a redelivered message does not create duplicates.
+type WebhookEventHandler struct {
+	webhookRepo repository.WebhookRepository
+	orderRepo   repository.OrderRepository
+	eventRepo   repository.EventRepository
+	logger      *slog.Logger
+}
+
+// NewWebhookEventHandler creates a new WebhookEventHandler
+func NewWebhookEventHandler(webhookRepo repository.WebhookRepository, orderRepo repository.OrderRepository, eventRepo repository.EventRepository, logger *slog.Logger) *WebhookEventHandler {
+	return &WebhookEventHandler{
+		webhookRepo: webhookRepo,
+		orderRepo:   orderRepo,
+		eventRepo:   eventRepo,
+		logger:      logger,
+	}
+}
+
+// Handle processes PaymentStatusUpdatedEvent, TicketScannedEvent and EventCancelledEvent
+func (h *WebhookEventHandler) Handle(event Event) error {
+	switch e := event.(type) {
+	case PaymentStatusUpdatedEvent:
+		if e.Status != model.PaymentStatusSuccess {
+			return nil
+		}
+		return h.orderPaid(e)
+	case TicketScannedEvent:
+		if e.Result != model.ScanAccepted || e.Direction != model.ScanIn {
+			return nil
+		}
+		return h.enqueue(model.WebhookTicketCheckedIn, fmt.Sprintf("scan-%d", e.ScanID), e.EventID, e.ScannedAt, webhook.TicketCheckedInData{
+			TicketID:     e.TicketID,
+			EventID:      e.EventID,
+			EventPriceID: e.EventPriceID,
+			Gate:         e.Gate,
+			DeviceID:     e.DeviceID,
+			Offline:      e.Offline,
+			CheckedInAt:  e.ScannedAt,
+		})
+	case EventCancelledEvent:
+		return h.enqueue(model.WebhookEventCancelled, fmt.Sprintf("cancellation-%d", e.CancellationID), e.EventID, e.CancelledAt, webhook.EventCancelledData{
+			EventID:     e.EventID,
+			Reason:      e.Reason,
+			CancelledAt: e.CancelledAt,
+		})
+	}
+	return nil
+}
+
+func (h *WebhookEventHandler) orderPaid(e PaymentStatusUpdatedEvent) error {
+	order, err := h.orderRepo.GetOrderByIDWithLineItems(e.OrderID)
+	if err != nil {
+		h.logger.Error("failed to get order for webhook", slog.Uint64("order_id", uint64(e.OrderID)), slog.String("error", err.Error()))
+		return err
+	}
+	// A payment settled after the order was closed does not make it paid, see PAID_AFTER_CANCELLATION
+	if order.Status != model.OrderPaid {
+		return nil
+	}
+	if len(order.OrderLineItems) == 0 {
+		return errors.New("order has no line items")
+	}
+
+	// Every line item of an order belongs to the same event
+	price, err := h.eventRepo.GetEventPriceByID(order.OrderLineItems[0].EventPriceID)
+	if err != nil {
+		h.logger.Error("failed to get event price for webhook", slog.Uint64("order_id", uint64(e.OrderID)), slog.String("error", err.Error()))
+		return err
+	}
+
+	ticketCount := 0
+	for _, lineItem := range order.OrderLineItems {
+		ticketCount += lineItem.Quantity
+	}
+
+	return h.enqueue(model.WebhookOrderPaid, fmt.Sprintf("order-%d", order.ID), price.EventID, e.UpdatedAt, webhook.OrderPaidData{
+		OrderID:     order.ID,
+		EventID:     price.EventID,
+		TotalPrice:  order.TotalPrice,
+		TicketCount: ticketCount,
+		PaidAt:      e.UpdatedAt,
+	})
+}
+
+// enqueue creates a pending delivery of the event for every endpoint subscribed to it
+func (h *WebhookEventHandler) enqueue(eventType model.WebhookEventType, key string, eventID uint, occurredAt time.Time, data interface{}) error {
+	endpoints, err := h.webhookRepo.GetActiveEndpointsFor(eventType, eventID)
+	if err != nil {
+		h.logger.Error("failed to get webhook endpoints", slog.String("type", string(eventType)), slog.String("error", err.Error()))
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	eventKey := string(eventType) + ":" + key
+	body, err := json.Marshal(webhook.Payload{ID: eventKey, Type: eventType, CreatedAt: occurredAt, Data: data})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]model.WebhookDelivery, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		deliveries = append(deliveries, model.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventType:     eventType,
+			EventKey:      eventKey,
+			Payload:       string(body),
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+
+	if err := h.webhookRepo.CreateDeliveries(deliveries); err != nil {
+		h.logger.Error("failed to create webhook deliveries", slog.String("event_key", eventKey), slog.String("error", err.Error()))
+		return err
+	}
+
+	h.logger.Info("Webhook deliveries queued", slog.String("event_key", eventKey), slog.Int("endpoints", len(deliveries)))
+	return nil
+}
@@ -59,3 +59,12 @@ func WithDataRange(column string) FilterFunc {
 		return db
 	}
 }
+
+func WithEquals(param string, column string) FilterFunc {
+	return func(db *gorm.DB, c *gin.Context) *gorm.DB {
+		if value := c.Query(param); value != "" {
+			return db.Where("LOWER("+column+") = ?", strings.ToLower(value))
+		}
+		return db
+	}
+}
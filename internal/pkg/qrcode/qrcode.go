@@ -8,15 +8,16 @@ import (
 	goqrcode "github.com/skip2/go-qrcode"
 )
 
-func GenerateQRCodePNG(basePath, ticketCode string) (string, error) {
+// GenerateQRCodePNG encodes content into <basePath>/<name>.png and returns the file path
+func GenerateQRCodePNG(basePath, name, content string) (string, error) {
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		return "", fmt.Errorf("failed to create QR storage directory: %w", err)
 	}
 
-	filename := ticketCode + ".png"
+	filename := name + ".png"
 	fullPath := filepath.Join(basePath, filename)
 
-	if err := goqrcode.WriteFile(ticketCode, goqrcode.Medium, 256, fullPath); err != nil {
+	if err := goqrcode.WriteFile(content, goqrcode.Medium, 256, fullPath); err != nil {
 		return "", fmt.Errorf("failed to generate QR code: %w", err)
 	}
 
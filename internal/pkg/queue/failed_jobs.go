@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrJobNotFound is returned when a job ID is not in the failed jobs store
+var ErrJobNotFound = errors.New("job not found")
+
+// retryFailedScript queues a failed job again with the given body, if it is still failed
+var retryFailedScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('LPUSH', KEYS[3], ARGV[1])
+return 1
+`)
+
+// Stats is the number of jobs in each state
+type Stats struct {
+	Ready      int64
+	Delayed    int64 // Scheduled for later, including retries waiting for their backoff
+	Processing int64
+	Failed     int64
+}
+
+// Stats counts the jobs in each state
+func (jq *JobQueue) Stats(ctx context.Context) (Stats, error) {
+	var ready, delayed, processing, failed *redis.IntCmd
+	_, err := jq.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		ready = pipe.LLen(ctx, jobsReadyKey)
+		delayed = pipe.ZCard(ctx, jobsDelayedKey)
+		processing = pipe.ZCard(ctx, jobsProcessingKey)
+		failed = pipe.ZCard(ctx, jobsFailedKey)
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return Stats{
+		Ready:      ready.Val(),
+		Delayed:    delayed.Val(),
+		Processing: processing.Val(),
+		Failed:     failed.Val(),
+	}, nil
+}
+
+// FailedJobs returns a page of the failed jobs store, most recently failed first, and its size
+func (jq *JobQueue) FailedJobs(ctx context.Context, offset, count int64) ([]Job, int64, error) {
+	total, err := jq.redisClient.ZCard(ctx, jobsFailedKey).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, err := jq.redisClient.ZRevRange(ctx, jobsFailedKey, offset, offset+count-1).Result()
+	if err != nil || len(ids) == 0 {
+		return []Job{}, total, err
+	}
+
+	values, err := jq.redisClient.HMGet(ctx, jobsDataKey, ids...).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	jobs := make([]Job, 0, len(values))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			job = Job{LastError: "decode job: " + err.Error()}
+		}
+		job.ID = ids[i]
+		jobs = append(jobs, job)
+	}
+	return jobs, total, nil
+}
+
+// RetryFailed queues a failed job again with a fresh attempt count
+func (jq *JobQueue) RetryFailed(ctx context.Context, id string) (*Job, error) {
+	data, err := jq.redisClient.HGet(ctx, jobsDataKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, err
+	}
+	job.Attempts = 0
+	job.FailedAt = nil
+	job.RunAt = time.Now()
+
+	body, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	retried, err := retryFailedScript.Run(ctx, jq.redisClient,
+		[]string{jobsFailedKey, jobsDataKey, jobsReadyKey}, id, body).Int()
+	if err != nil {
+		return nil, err
+	}
+	if retried == 0 {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+// DeleteFailed removes a job from the failed jobs store for good
+func (jq *JobQueue) DeleteFailed(ctx context.Context, id string) error {
+	removed, err := jq.redisClient.ZRem(ctx, jobsFailedKey, id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrJobNotFound
+	}
+	return jq.redisClient.HDel(ctx, jobsDataKey, id).Err()
+}
@@ -2,113 +2,558 @@ package queue
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"learn/internal/config"
+	"learn/internal/pkg/random"
 	"log/slog"
+	"runtime/debug"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
-// JobQueue represents a job queue for processing tasks asynchronously
-type JobQueue struct {
-	jobs    chan Job
-	workers int
-	logger  *slog.Logger
-	ctx     context.Context
-	cancel  context.CancelFunc
-	wg      sync.WaitGroup
-}
+// Redis keys of the queue. Job bodies live in the data hash, the other keys only hold job IDs.
+const (
+	jobsDataKey       = "jobs:data"       // Hash of job ID -> Job JSON
+	jobsReadyKey      = "jobs:ready"      // List of job IDs ready to run, pushed left and popped right
+	jobsDelayedKey    = "jobs:delayed"    // Sorted set of job IDs by run time
+	jobsProcessingKey = "jobs:processing" // Sorted set of running job IDs by visibility deadline
+	jobsFailedKey     = "jobs:failed"     // Sorted set of job IDs that exhausted their attempts, by failure time
+)
+
+const (
+	jobPollInterval    = time.Second
+	jobPromoteInterval = time.Second
+	jobPromoteBatch    = 100
+	jobMaxBackoff      = time.Hour
+	// jobCancelGrace is how long Stop waits for handlers to return after their context is cancelled
+	jobCancelGrace = 5 * time.Second
+)
+
+// enqueueScript stores a job and queues it, unless a job with the same ID is still queued or running.
+// A failed job with the same ID is replaced.
+var enqueueScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	if not redis.call('ZSCORE', KEYS[4], ARGV[1]) then
+		return 0
+	end
+	redis.call('ZREM', KEYS[4], ARGV[1])
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+if tonumber(ARGV[3]) > 0 then
+	redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+else
+	redis.call('LPUSH', KEYS[2], ARGV[1])
+end
+return 1
+`)
+
+// dequeueScript moves the next ready job to the processing set with its visibility deadline
+var dequeueScript = redis.NewScript(`
+local id = redis.call('RPOP', KEYS[1])
+if not id then
+	return false
+end
+redis.call('ZADD', KEYS[2], ARGV[1], id)
+return {id, redis.call('HGET', KEYS[3], id)}
+`)
+
+// promoteScript queues delayed jobs that are due and reclaims running jobs past their visibility
+// deadline, whose worker died or lost its connection
+var promoteScript = redis.NewScript(`
+local counts = {}
+for i, key in ipairs({KEYS[1], KEYS[2]}) do
+	local ids = redis.call('ZRANGEBYSCORE', key, '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+	for _, id in ipairs(ids) do
+		redis.call('ZREM', key, id)
+		redis.call('LPUSH', KEYS[3], id)
+	end
+	counts[i] = #ids
+end
+return counts
+`)
 
-// Job represents a single job to be processed
+// Job is a unit of work stored in Redis. Payload is the JSON of the value given to Enqueue.
 type Job struct {
-	ID       string
-	Task     func() error
-	RetryMax int
-	Retry    int
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   string          `json:"last_error,omitempty"`
+	FailedAt    *time.Time      `json:"failed_at,omitempty"`
 }
 
-// NewJobQueue creates a new job queue with specified number of workers
-func NewJobQueue(workers int, logger *slog.Logger) *JobQueue {
+// Decode unmarshals the payload of the job into v
+func (j Job) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Handler runs a job. Jobs are delivered at least once: a job whose worker died is run again after
+// the visibility timeout, so handlers must be idempotent.
+type Handler func(ctx context.Context, job Job) error
+
+// EnqueueOption customizes a job before it is queued
+type EnqueueOption func(*Job)
+
+// WithDelay runs the job no earlier than delay from now
+func WithDelay(delay time.Duration) EnqueueOption {
+	return func(j *Job) {
+		j.RunAt = j.EnqueuedAt.Add(delay)
+	}
+}
+
+// WithRunAt runs the job no earlier than runAt
+func WithRunAt(runAt time.Time) EnqueueOption {
+	return func(j *Job) {
+		j.RunAt = runAt
+	}
+}
+
+// WithMaxAttempts overrides JOB_MAX_ATTEMPTS for the job
+func WithMaxAttempts(maxAttempts int) EnqueueOption {
+	return func(j *Job) {
+		if maxAttempts > 0 {
+			j.MaxAttempts = maxAttempts
+		}
+	}
+}
+
+// WithJobID sets the job ID instead of a random one. Enqueue is skipped while a job with the same
+// ID is queued or running, which makes it safe to enqueue the same work more than once.
+func WithJobID(id string) EnqueueOption {
+	return func(j *Job) {
+		j.ID = id
+	}
+}
+
+// JobQueue is a durable job queue on Redis. Jobs survive restarts, can be delayed, are retried with
+// exponential backoff and end up in the failed jobs store once their attempts are exhausted.
+type JobQueue struct {
+	redisClient       *redis.Client
+	handlers          map[string]Handler
+	mutex             sync.RWMutex
+	workers           int
+	maxAttempts       int
+	retryBackoff      time.Duration
+	visibilityTimeout time.Duration
+	shutdownTimeout   time.Duration
+	logger            *slog.Logger
+	ctx               context.Context // Cancelled by Stop, workers stop taking jobs
+	cancel            context.CancelFunc
+	jobCtx            context.Context // Passed to handlers, cancelled when draining times out
+	jobCancel         context.CancelFunc
+	wg                sync.WaitGroup
+}
+
+// NewJobQueue creates a job queue configured by the JOB_* settings
+func NewJobQueue(redisClient *redis.Client, logger *slog.Logger) *JobQueue {
 	ctx, cancel := context.WithCancel(context.Background())
+	jobCtx, jobCancel := context.WithCancel(context.Background())
+
+	workers := config.AppConfig.JobWorkers
+	if workers <= 0 {
+		workers = 5
+	}
+	maxAttempts := config.AppConfig.JobMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	retryBackoff := config.AppConfig.JobRetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = 10 * time.Second
+	}
+	visibilityTimeout := config.AppConfig.JobVisibilityTimeout
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = 5 * time.Minute
+	}
+	shutdownTimeout := config.AppConfig.JobShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
 
 	return &JobQueue{
-		jobs:    make(chan Job, 100), // Buffered channel
-		workers: workers,
-		logger:  logger,
-		ctx:     ctx,
-		cancel:  cancel,
+		redisClient:       redisClient,
+		handlers:          make(map[string]Handler),
+		workers:           workers,
+		maxAttempts:       maxAttempts,
+		retryBackoff:      retryBackoff,
+		visibilityTimeout: visibilityTimeout,
+		shutdownTimeout:   shutdownTimeout,
+		logger:            logger,
+		ctx:               ctx,
+		cancel:            cancel,
+		jobCtx:            jobCtx,
+		jobCancel:         jobCancel,
+	}
+}
+
+// Register sets the handler of a job type. Handlers must be registered before Start.
+func (jq *JobQueue) Register(jobType string, handler Handler) {
+	jq.mutex.Lock()
+	defer jq.mutex.Unlock()
+
+	jq.handlers[jobType] = handler
+}
+
+// Enqueue stores a job with the JSON of payload and returns its ID
+func (jq *JobQueue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts ...EnqueueOption) (string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("marshal %s payload: %w", jobType, err)
+	}
+
+	now := time.Now()
+	job := Job{
+		ID:          NewJobID(),
+		Type:        jobType,
+		Payload:     body,
+		MaxAttempts: jq.maxAttempts,
+		EnqueuedAt:  now,
+		RunAt:       now,
+	}
+	for _, opt := range opts {
+		opt(&job)
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return "", fmt.Errorf("marshal %s job: %w", jobType, err)
+	}
+
+	var runAt int64
+	if job.RunAt.After(now) {
+		runAt = job.RunAt.UnixMilli()
+	}
+
+	queued, err := enqueueScript.Run(ctx, jq.redisClient,
+		[]string{jobsDataKey, jobsReadyKey, jobsDelayedKey, jobsFailedKey},
+		job.ID, data, runAt).Int()
+	if err != nil {
+		return "", fmt.Errorf("enqueue %s job: %w", jobType, err)
+	}
+	if queued == 0 {
+		jq.logger.Info("Job already queued, skipping", slog.String("job_id", job.ID), slog.String("job_type", jobType))
+		return job.ID, nil
 	}
+
+	jq.logger.Info("Job enqueued",
+		slog.String("job_id", job.ID),
+		slog.String("job_type", jobType),
+		slog.Time("run_at", job.RunAt))
+	return job.ID, nil
 }
 
-// Start starts the job queue workers
+// Start starts the workers and the scheduler moving delayed and stuck jobs to the ready list
 func (jq *JobQueue) Start() {
+	jq.wg.Add(1)
+	go jq.promoter()
+
 	for i := 0; i < jq.workers; i++ {
 		jq.wg.Add(1)
-		go jq.worker(i)
+		go jq.worker()
 	}
+
+	jq.logger.Info("Job queue started",
+		slog.Int("workers", jq.workers),
+		slog.Int("max_attempts", jq.maxAttempts),
+		slog.Duration("visibility_timeout", jq.visibilityTimeout))
 }
 
-// Stop stops the job queue and waits for all jobs to complete
+// Stop stops taking jobs and waits up to JOB_SHUTDOWN_TIMEOUT for running jobs to finish. Handlers
+// still running after that have their context cancelled; jobs that stop because of it are queued
+// again without using an attempt, the others are reclaimed after the visibility timeout.
 func (jq *JobQueue) Stop() {
-	close(jq.jobs)
 	jq.cancel()
-	jq.wg.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		jq.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		jq.logger.Info("Job queue stopped")
+		return
+	case <-time.After(jq.shutdownTimeout):
+	}
+
+	jq.jobCancel()
+	select {
+	case <-done:
+		jq.logger.Warn("Job queue stopped after cancelling running jobs")
+	case <-time.After(jobCancelGrace):
+		jq.logger.Warn("Job queue stopped with jobs still running, they are picked up again after the visibility timeout")
+	}
 }
 
-// worker runs in a goroutine and processes jobs
-func (jq *JobQueue) worker(workerID int) {
+func (jq *JobQueue) worker() {
 	defer jq.wg.Done()
 
-	for {
-		select {
-		case job, ok := <-jq.jobs:
-			if !ok {
-				return // Channel closed
+	for jq.ctx.Err() == nil {
+		job, err := jq.dequeue()
+		if err != nil {
+			jq.logger.Error("failed to dequeue job", slog.String("error", err.Error()))
+		}
+		if job == nil {
+			select {
+			case <-jq.ctx.Done():
+			case <-time.After(jobPollInterval):
 			}
-
-			jq.processJob(job)
-		case <-jq.ctx.Done():
-			return // Context cancelled
+			continue
 		}
+
+		jq.process(job)
 	}
 }
 
-// processJob handles the execution of a single job with retry logic
-func (jq *JobQueue) processJob(job Job) {
-	var err error
+// dequeue takes the next ready job and counts the attempt, or returns nil when none is ready
+func (jq *JobQueue) dequeue() (*Job, error) {
+	ctx := context.Background()
+	deadline := time.Now().Add(jq.visibilityTimeout).UnixMilli()
 
-	for job.Retry <= job.RetryMax {
-		err = job.Task()
-		if err == nil {
-			// Success
-			return
+	result, err := dequeueScript.Run(ctx, jq.redisClient,
+		[]string{jobsReadyKey, jobsProcessingKey, jobsDataKey}, deadline).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := result[0].(string)
+	data, ok := result[1].(string)
+	if !ok {
+		// Deleted while queued, nothing left to run
+		return nil, jq.redisClient.ZRem(ctx, jobsProcessingKey, id).Err()
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		job = Job{ID: id, Attempts: 1, EnqueuedAt: time.Now()}
+		job.LastError = "decode job: " + err.Error()
+		return nil, jq.fail(&job)
+	}
+	job.ID = id
+	job.Attempts++
+
+	if err := jq.save(ctx, jq.redisClient, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// process runs the handler of a job and records the outcome
+func (jq *JobQueue) process(job *Job) {
+	jq.mutex.RLock()
+	handler, ok := jq.handlers[job.Type]
+	jq.mutex.RUnlock()
+
+	if !ok {
+		job.LastError = "no handler registered for job type " + job.Type
+		jq.recordFailure(job)
+		return
+	}
+
+	stopHeartbeat := jq.heartbeat(job.ID)
+	start := time.Now()
+	err := runHandler(jq.jobCtx, handler, *job)
+	stopHeartbeat()
+
+	if err == nil {
+		if err := jq.ack(job); err != nil {
+			jq.logger.Error("failed to acknowledge job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
 		}
+		jq.logger.Info("Job completed",
+			slog.String("job_id", job.ID),
+			slog.String("job_type", job.Type),
+			slog.Int("attempt", job.Attempts),
+			slog.Duration("duration", time.Since(start)))
+		return
+	}
 
-		job.Retry++
+	if jq.jobCtx.Err() != nil {
+		// Interrupted by shutdown, the attempt does not count
+		job.Attempts--
+		if err := jq.requeue(job); err != nil {
+			jq.logger.Error("failed to requeue interrupted job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+		}
+		return
+	}
 
-		if job.Retry <= job.RetryMax {
-			// Wait before retrying (exponential backoff)
-			waitTime := time.Duration(job.Retry) * time.Second
-			time.Sleep(waitTime)
+	job.LastError = err.Error()
+	jq.recordFailure(job)
+}
 
-			jq.logger.Info("Retrying job",
-				slog.String("job_id", job.ID),
-				slog.Int("retry_attempt", job.Retry),
-				slog.String("error", err.Error()))
+// recordFailure schedules a retry with exponential backoff, or moves the job to the failed jobs
+// store once it used all its attempts
+func (jq *JobQueue) recordFailure(job *Job) {
+	if job.Attempts >= job.MaxAttempts || !jq.hasHandler(job.Type) {
+		if err := jq.fail(job); err != nil {
+			jq.logger.Error("failed to store failed job", slog.String("job_id", job.ID), slog.String("error", err.Error()))
 		}
+		jq.logger.Error("Job failed after all attempts",
+			slog.String("job_id", job.ID),
+			slog.String("job_type", job.Type),
+			slog.Int("attempts", job.Attempts),
+			slog.String("error", job.LastError))
+		return
+	}
+
+	backoff := jq.retryBackoff << (job.Attempts - 1)
+	if backoff <= 0 || backoff > jobMaxBackoff {
+		backoff = jobMaxBackoff
 	}
+	job.RunAt = time.Now().Add(backoff)
 
-	// Log failure after all retries
-	jq.logger.Error("Job failed after all retries",
+	if err := jq.retry(job); err != nil {
+		jq.logger.Error("failed to schedule job retry", slog.String("job_id", job.ID), slog.String("error", err.Error()))
+		return
+	}
+	jq.logger.Warn("Job failed, retrying",
 		slog.String("job_id", job.ID),
-		slog.String("error", err.Error()))
+		slog.String("job_type", job.Type),
+		slog.Int("attempt", job.Attempts),
+		slog.Duration("backoff", backoff),
+		slog.String("error", job.LastError))
 }
 
-// Enqueue adds a job to the queue
-func (jq *JobQueue) Enqueue(job Job) {
-	select {
-	case jq.jobs <- job:
-		// Successfully added to queue
-	default:
-		// Queue is full, handle appropriately
-		jq.logger.Error("Job queue is full, dropping job", slog.String("job_id", job.ID))
+func (jq *JobQueue) hasHandler(jobType string) bool {
+	jq.mutex.RLock()
+	defer jq.mutex.RUnlock()
+
+	_, ok := jq.handlers[jobType]
+	return ok
+}
+
+// heartbeat keeps pushing the visibility deadline of a running job back until the returned
+// function is called, so long jobs are not reclaimed while their worker is alive
+func (jq *JobQueue) heartbeat(id string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jq.visibilityTimeout / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				deadline := time.Now().Add(jq.visibilityTimeout).UnixMilli()
+				if err := jq.redisClient.ZAddXX(context.Background(), jobsProcessingKey, &redis.Z{Score: float64(deadline), Member: id}).Err(); err != nil {
+					jq.logger.Error("failed to extend job visibility", slog.String("job_id", id), slog.String("error", err.Error()))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (jq *JobQueue) ack(job *Job) error {
+	ctx := context.Background()
+	_, err := jq.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, jobsProcessingKey, job.ID)
+		pipe.HDel(ctx, jobsDataKey, job.ID)
+		return nil
+	})
+	return err
+}
+
+func (jq *JobQueue) retry(job *Job) error {
+	ctx := context.Background()
+	_, err := jq.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, jobsProcessingKey, job.ID)
+		if err := jq.save(ctx, pipe, job); err != nil {
+			return err
+		}
+		pipe.ZAdd(ctx, jobsDelayedKey, &redis.Z{Score: float64(job.RunAt.UnixMilli()), Member: job.ID})
+		return nil
+	})
+	return err
+}
+
+func (jq *JobQueue) requeue(job *Job) error {
+	ctx := context.Background()
+	_, err := jq.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, jobsProcessingKey, job.ID)
+		if err := jq.save(ctx, pipe, job); err != nil {
+			return err
+		}
+		// Pushed on the popping side, so it runs first after the restart
+		pipe.RPush(ctx, jobsReadyKey, job.ID)
+		return nil
+	})
+	return err
+}
+
+func (jq *JobQueue) fail(job *Job) error {
+	ctx := context.Background()
+	now := time.Now()
+	job.FailedAt = &now
+
+	_, err := jq.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, jobsProcessingKey, job.ID)
+		if err := jq.save(ctx, pipe, job); err != nil {
+			return err
+		}
+		pipe.ZAdd(ctx, jobsFailedKey, &redis.Z{Score: float64(now.UnixMilli()), Member: job.ID})
+		return nil
+	})
+	return err
+}
+
+func (jq *JobQueue) save(ctx context.Context, client redis.Cmdable, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return client.HSet(ctx, jobsDataKey, job.ID, data).Err()
+}
+
+// promoter moves delayed jobs that are due and jobs past their visibility deadline to the ready list
+func (jq *JobQueue) promoter() {
+	defer jq.wg.Done()
+
+	ticker := time.NewTicker(jobPromoteInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			counts, err := promoteScript.Run(context.Background(), jq.redisClient,
+				[]string{jobsDelayedKey, jobsProcessingKey, jobsReadyKey},
+				strconv.FormatInt(time.Now().UnixMilli(), 10), jobPromoteBatch).Int64Slice()
+			if err != nil {
+				jq.logger.Error("failed to promote due jobs", slog.String("error", err.Error()))
+				continue
+			}
+			if len(counts) == 2 && counts[1] > 0 {
+				jq.logger.Warn("Reclaimed jobs past their visibility timeout", slog.Int64("count", counts[1]))
+			}
+		case <-jq.ctx.Done():
+			return
+		}
 	}
 }
+
+// runHandler calls handler and turns a panic into an error so the job is retried
+func runHandler(ctx context.Context, handler Handler, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// NewJobID returns a random job ID
+func NewJobID() string {
+	return random.StringWithCharset(32, "0123456789abcdef")
+}
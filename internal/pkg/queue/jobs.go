@@ -0,0 +1,11 @@
+package queue
+
+// Job types, each with the payload it is enqueued with
+const (
+	JobEventCancellation = "event.cancellation"
+)
+
+// EventCancellationPayload is the payload of an event.cancellation job
+type EventCancellationPayload struct {
+	CancellationID uint `json:"cancellation_id"`
+}
@@ -111,3 +111,7 @@ func SendNotFoundError(c *gin.Context, message string) {
 func SendTooManyRequestsError(c *gin.Context, message string) {
 	sendError(c, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", message, nil)
 }
+
+func SendServiceUnavailableError(c *gin.Context, message string, details interface{}) {
+	sendError(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", message, details)
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"learn/internal/service"
+	"log/slog"
+	"time"
+)
+
+// OrderExpirationJob handles automatic cancellation of expired orders
+type OrderExpirationJob struct {
+	orderRepo         repository.OrderRepository
+	orderStateMachine service.OrderStateMachine
+	logger            *slog.Logger
+}
+
+// NewOrderExpirationJob creates a new order expiration job
+func NewOrderExpirationJob(orderRepo repository.OrderRepository, orderStateMachine service.OrderStateMachine, logger *slog.Logger) *OrderExpirationJob {
+	return &OrderExpirationJob{
+		orderRepo:         orderRepo,
+		orderStateMachine: orderStateMachine,
+		logger:            logger,
+	}
+}
+
+// Run finds and cancels orders that have exceeded their payment due time
+func (j *OrderExpirationJob) Run(ctx context.Context) (string, error) {
+	now := time.Now()
+
+	// Find orders that are pending and have exceeded their payment due time
+	var expiredOrders []model.Order
+	err := j.orderRepo.GetDB().WithContext(ctx).Where("status = ? AND payment_due < ?", model.OrderPending, now).Find(&expiredOrders).Error
+	if err != nil {
+		return "", fmt.Errorf("find expired orders: %w", err)
+	}
+
+	if len(expiredOrders) == 0 {
+		return "no expired orders", nil
+	}
+
+	j.logger.Info("Found expired orders", slog.Int("count", len(expiredOrders)))
+
+	cancelled, failed := 0, 0
+	for _, order := range expiredOrders {
+		if ctx.Err() != nil {
+			break
+		}
+
+		reason := "Expired - Payment not received within deadline"
+		if order.HoldExpiresAt != nil {
+			reason = "Expired - Hold ended before a payment was started"
+		}
+
+		// Orders paid since they were loaded stay paid, the transition only applies to PENDING
+		ok, err := j.orderStateMachine.Cancel(ctx, order.ID, reason)
+		if err != nil {
+			failed++
+			j.logger.Error("Failed to cancel expired order",
+				slog.Uint64("order_id", uint64(order.ID)),
+				slog.String("error", err.Error()))
+		} else if ok {
+			cancelled++
+			j.logger.Info("Successfully cancelled expired order",
+				slog.Uint64("order_id", uint64(order.ID)))
+		}
+	}
+
+	result := fmt.Sprintf("cancelled %d of %d expired orders", cancelled, len(expiredOrders))
+	if failed > 0 {
+		return result, fmt.Errorf("%d expired orders could not be cancelled", failed)
+	}
+	return result, ctx.Err()
+}
@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"learn/internal/service"
+	"log/slog"
+	"time"
+)
+
+// WebhookDispatchScheduler periodically sends the organizer webhook deliveries that are due
+type WebhookDispatchScheduler struct {
+	webhookService service.WebhookService
+	interval       time.Duration
+	logger         *slog.Logger
+	stopChan       chan struct{}
+}
+
+// NewWebhookDispatchScheduler creates a new WebhookDispatchScheduler
+func NewWebhookDispatchScheduler(webhookService service.WebhookService, interval time.Duration, logger *slog.Logger) *WebhookDispatchScheduler {
+	return &WebhookDispatchScheduler{
+		webhookService: webhookService,
+		interval:       interval,
+		logger:         logger,
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// Start dispatches due deliveries every interval until Stop is called
+func (s *WebhookDispatchScheduler) Start() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Webhook dispatch scheduler started", slog.Duration("interval", s.interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.webhookService.DispatchDue(); err != nil {
+				s.logger.Error("Webhook dispatch failed", slog.String("error", err.Error()))
+			}
+		case <-s.stopChan:
+			s.logger.Info("Webhook dispatch scheduler stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduled task
+func (s *WebhookDispatchScheduler) Stop() {
+	close(s.stopChan)
+}
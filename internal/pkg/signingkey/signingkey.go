@@ -0,0 +1,27 @@
+package signingkey
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"learn/internal/config"
+)
+
+// Purposes of the keys derived from the JWT secret. A label must never change, tokens signed with
+// the old key would stop verifying.
+const (
+	TicketQR    = "ticket-qr"
+	WaitingRoom = "waiting-room"
+)
+
+// For returns the key signing the tokens of purpose. A dedicated secret is used as it is. Without
+// one the key is derived from the JWT secret as HMAC-SHA256(JWT secret, "learn/" + purpose), so
+// every purpose has its own key and none of them is the key that signs access tokens.
+func For(purpose string, dedicatedSecret string) []byte {
+	if dedicatedSecret != "" {
+		return []byte(dedicatedSecret)
+	}
+
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWTSecretKey))
+	mac.Write([]byte("learn/" + purpose))
+	return mac.Sum(nil)
+}
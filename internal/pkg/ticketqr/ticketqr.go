@@ -0,0 +1,29 @@
+package ticketqr
+
+import (
+	"learn/internal/config"
+	"learn/internal/model"
+	"learn/internal/pkg/qrcode"
+	"learn/internal/pkg/signingkey"
+)
+
+// SigningKey returns the key used to sign ticket tokens: TICKET_QR_SECRET, or a key derived
+// from the JWT secret for ticket QR codes only
+func SigningKey() []byte {
+	return signingkey.For(signingkey.TicketQR, config.AppConfig.TicketQRSecret)
+}
+
+// TokenForTicket builds the signed QR content for a ticket. The issue time comes from
+// the ticket itself, so regenerating a lost image yields the same token.
+func TokenForTicket(ticket model.Ticket, eventID uint) string {
+	return Sign(Claims{
+		TicketID: ticket.ID,
+		EventID:  eventID,
+		IssuedAt: ticket.QrIssuedAt,
+	}, SigningKey())
+}
+
+// GenerateTicketQRCode writes the signed QR image for a ticket and returns its path
+func GenerateTicketQRCode(ticket model.Ticket, eventID uint) (string, error) {
+	return qrcode.GenerateQRCodePNG(config.AppConfig.StorageQRPath, ticket.TicketCode, TokenForTicket(ticket, eventID))
+}
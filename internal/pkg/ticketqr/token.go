@@ -0,0 +1,113 @@
+package ticketqr
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// tokenPrefix marks QR content as a signed ticket token rather than a plain ticket code
+const tokenPrefix = "TQ1."
+
+// macSize is the number of HMAC-SHA256 bytes kept in the token. 128 bits is plenty
+// for an online check and keeps the QR code small.
+const macSize = 16
+
+// encoding rejects non-canonical base64 so a token has exactly one valid spelling
+var encoding = base64.RawURLEncoding.Strict()
+
+var (
+	ErrMalformedToken   = errors.New("malformed ticket token")
+	ErrInvalidSignature = errors.New("invalid ticket token signature")
+)
+
+// Claims is the data carried inside a signed ticket token
+type Claims struct {
+	TicketID uint
+	EventID  uint
+	IssuedAt time.Time
+}
+
+// IsSigned reports whether the scanned content looks like a signed ticket token
+func IsSigned(content string) bool {
+	return strings.HasPrefix(content, tokenPrefix)
+}
+
+// Sign encodes the claims into a compact "TQ1.<payload>.<mac>" token
+func Sign(claims Claims, key []byte) string {
+	payload := make([]byte, 0, 3*binary.MaxVarintLen64)
+	payload = binary.AppendUvarint(payload, uint64(claims.TicketID))
+	payload = binary.AppendUvarint(payload, uint64(claims.EventID))
+	payload = binary.AppendVarint(payload, claims.IssuedAt.Unix())
+
+	encodedPayload := encoding.EncodeToString(payload)
+	signature := encoding.EncodeToString(sign(encodedPayload, key))
+
+	return tokenPrefix + encodedPayload + "." + signature
+}
+
+// Verify checks the token signature and returns its claims. It never touches the database.
+func Verify(token string, key []byte) (*Claims, error) {
+	if !IsSigned(token) {
+		return nil, ErrMalformedToken
+	}
+
+	parts := strings.Split(strings.TrimPrefix(token, tokenPrefix), ".")
+	if len(parts) != 2 {
+		return nil, ErrMalformedToken
+	}
+
+	signature, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if !hmac.Equal(signature, sign(parts[0], key)) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	ticketID, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return nil, ErrMalformedToken
+	}
+	payload = payload[n:]
+
+	eventID, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return nil, ErrMalformedToken
+	}
+	payload = payload[n:]
+
+	issuedAt, n := binary.Varint(payload)
+	if n <= 0 || n != len(payload) {
+		return nil, ErrMalformedToken
+	}
+
+	return &Claims{
+		TicketID: uint(ticketID),
+		EventID:  uint(eventID),
+		IssuedAt: time.Unix(issuedAt, 0),
+	}, nil
+}
+
+// Hash returns the hex SHA-256 of scanned QR content. Check-in manifests only carry these
+// hashes, so a lost scanner device does not leak usable tickets.
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func sign(encodedPayload string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(tokenPrefix + encodedPayload))
+	return mac.Sum(nil)[:macSize]
+}
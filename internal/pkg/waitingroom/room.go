@@ -0,0 +1,217 @@
+package waitingroom
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Position is where a user stands in the waiting room of an event
+type Position struct {
+	Admitted           bool
+	AdmissionExpiresAt time.Time // Set when admitted
+	Position           int64     // 1-based place in the queue while waiting, 0 when not queued
+	QueueLength        int64
+}
+
+// Stats is the size of the waiting room of an event
+type Stats struct {
+	Waiting  int64
+	Admitted int64 // Admissions that have not expired
+}
+
+// joinScript queues the user at the back unless already queued or admitted. An expired admission
+// joins again at the back. Returns {admitted, rank, admission expiry ms, queue length}.
+var joinScript = redis.NewScript(`
+local expiry = redis.call('ZSCORE', KEYS[3], ARGV[1])
+if expiry and tonumber(expiry) > tonumber(ARGV[2]) then
+	return {1, -1, expiry, redis.call('ZCARD', KEYS[1])}
+end
+
+local rank = redis.call('ZRANK', KEYS[1], ARGV[1])
+if not rank then
+	redis.call('ZREM', KEYS[3], ARGV[1])
+	local seq = redis.call('INCR', KEYS[2])
+	redis.call('ZADD', KEYS[1], seq, ARGV[1])
+	rank = redis.call('ZRANK', KEYS[1], ARGV[1])
+	for i = 1, 3 do
+		redis.call('EXPIRE', KEYS[i], ARGV[3])
+	end
+end
+return {0, rank, '0', redis.call('ZCARD', KEYS[1])}
+`)
+
+// admitScript moves up to ARGV[1] users from the front of the queue to the admitted set, where they
+// stay until ARGV[2] (ms). Expired admissions are dropped first. Returns the number admitted.
+var admitScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', ARGV[3])
+local popped = redis.call('ZPOPMIN', KEYS[1], ARGV[1])
+for i = 1, #popped, 2 do
+	redis.call('ZADD', KEYS[2], ARGV[2], popped[i])
+end
+if #popped > 0 then
+	redis.call('EXPIRE', KEYS[2], ARGV[4])
+end
+return #popped / 2
+`)
+
+// Room keeps the per-event queues of the waiting room in Redis
+type Room struct {
+	redisClient *redis.Client
+}
+
+func NewRoom(redisClient *redis.Client) *Room {
+	return &Room{redisClient: redisClient}
+}
+
+func queueKey(eventID uint) string    { return fmt.Sprintf("waitingroom:%d:queue", eventID) }
+func seqKey(eventID uint) string      { return fmt.Sprintf("waitingroom:%d:seq", eventID) }
+func admittedKey(eventID uint) string { return fmt.Sprintf("waitingroom:%d:admitted", eventID) }
+func stateKey(eventID uint) string    { return fmt.Sprintf("waitingroom:%d:state", eventID) }
+
+// usedTokenKey marks an admission token that was spent on an order, stored by hash
+func usedTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "waitingroom:used:" + hex.EncodeToString(sum[:])
+}
+
+// ConsumeAdmission spends a verified admission token on an order. It returns false when the token
+// was already spent. The mark lives as long as the token, afterwards the token is expired anyway.
+func (r *Room) ConsumeAdmission(ctx context.Context, token string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+	return r.redisClient.SetNX(ctx, usedTokenKey(token), 1, ttl).Result()
+}
+
+// ReleaseAdmission makes a spent token usable again when the order it was spent on failed
+func (r *Room) ReleaseAdmission(ctx context.Context, token string) error {
+	return r.redisClient.Del(ctx, usedTokenKey(token)).Err()
+}
+
+// Join queues the user, or returns the current position of a user who already joined. keep is how
+// long the keys of the room live after the last join, it should outlast the sales period.
+func (r *Room) Join(ctx context.Context, eventID uint, userID uint, keep time.Duration) (Position, error) {
+	values, err := joinScript.Run(ctx, r.redisClient,
+		[]string{queueKey(eventID), seqKey(eventID), admittedKey(eventID)},
+		userID, time.Now().UnixMilli(), int64(keep.Seconds())).Slice()
+	if err != nil {
+		return Position{}, err
+	}
+	if len(values) != 4 {
+		return Position{}, errors.New("unexpected waiting room join reply")
+	}
+
+	admitted, _ := values[0].(int64)
+	rank, _ := values[1].(int64)
+	queueLength, _ := values[3].(int64)
+	if admitted == 1 {
+		expiry, _ := values[2].(string)
+		return Position{Admitted: true, AdmissionExpiresAt: parseMillis(expiry), QueueLength: queueLength}, nil
+	}
+	return Position{Position: rank + 1, QueueLength: queueLength}, nil
+}
+
+// Position returns where the user stands without joining. Position is 0 and Admitted false for a
+// user who never joined or whose admission expired.
+func (r *Room) Position(ctx context.Context, eventID uint, userID uint) (Position, error) {
+	member := strconv.FormatUint(uint64(userID), 10)
+
+	var expiry *redis.FloatCmd
+	var rank, queueLength *redis.IntCmd
+	_, err := r.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		expiry = pipe.ZScore(ctx, admittedKey(eventID), member)
+		rank = pipe.ZRank(ctx, queueKey(eventID), member)
+		queueLength = pipe.ZCard(ctx, queueKey(eventID))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return Position{}, err
+	}
+
+	position := Position{QueueLength: queueLength.Val()}
+	if expiry.Err() == nil {
+		expiresAt := time.UnixMilli(int64(expiry.Val()))
+		if time.Now().Before(expiresAt) {
+			position.Admitted = true
+			position.AdmissionExpiresAt = expiresAt
+			return position, nil
+		}
+	}
+	if rank.Err() == nil {
+		position.Position = rank.Val() + 1
+	}
+	return position, nil
+}
+
+// Admit lets up to count users from the front of the queue in until now plus ttl. keep is how long
+// the admitted set lives, like in Join.
+func (r *Room) Admit(ctx context.Context, eventID uint, count int64, ttl time.Duration, keep time.Duration) (int64, error) {
+	if count <= 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	return admitScript.Run(ctx, r.redisClient,
+		[]string{queueKey(eventID), admittedKey(eventID)},
+		count, now.Add(ttl).UnixMilli(), now.UnixMilli(), int64(keep.Seconds())).Int64()
+}
+
+// AdmissionCredit adds the admissions earned since the previous call at perMinute and returns the
+// whole number that may be admitted now. The fraction carries over to the next call. The first call
+// only starts the clock, and credit accrues for up to maxElapsed, so a paused admitter does not let
+// a burst in.
+func (r *Room) AdmissionCredit(ctx context.Context, eventID uint, perMinute int, maxElapsed time.Duration, keep time.Duration) (int64, error) {
+	state, err := r.redisClient.HGetAll(ctx, stateKey(eventID)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var elapsed time.Duration
+	if last := parseMillis(state["last_admit_at"]); !last.IsZero() {
+		elapsed = now.Sub(last)
+		if elapsed > maxElapsed {
+			elapsed = maxElapsed
+		}
+	}
+	credit, _ := strconv.ParseFloat(state["credit"], 64)
+	credit += float64(perMinute) * elapsed.Minutes()
+
+	whole := int64(credit)
+	if err := r.redisClient.HSet(ctx, stateKey(eventID),
+		"last_admit_at", now.UnixMilli(),
+		"credit", strconv.FormatFloat(credit-float64(whole), 'f', 6, 64)).Err(); err != nil {
+		return 0, err
+	}
+	r.redisClient.Expire(ctx, stateKey(eventID), keep)
+	return whole, nil
+}
+
+// Stats counts the waiting and admitted users of an event
+func (r *Room) Stats(ctx context.Context, eventID uint) (Stats, error) {
+	var waiting, admitted *redis.IntCmd
+	_, err := r.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		waiting = pipe.ZCard(ctx, queueKey(eventID))
+		admitted = pipe.ZCount(ctx, admittedKey(eventID), strconv.FormatInt(time.Now().UnixMilli(), 10), "+inf")
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{Waiting: waiting.Val(), Admitted: admitted.Val()}, nil
+}
+
+func parseMillis(value string) time.Time {
+	millis, err := strconv.ParseFloat(value, 64)
+	if err != nil || millis <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(millis))
+}
@@ -0,0 +1,125 @@
+package waitingroom
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"learn/internal/config"
+	"learn/internal/pkg/signingkey"
+	"strings"
+	"time"
+)
+
+// tokenPrefix marks an admission token of the waiting room
+const tokenPrefix = "WR1."
+
+// macSize is the number of HMAC-SHA256 bytes kept in the token
+const macSize = 16
+
+// encoding rejects non-canonical base64 so a token has exactly one valid spelling
+var encoding = base64.RawURLEncoding.Strict()
+
+var (
+	ErrMalformedToken   = errors.New("malformed admission token")
+	ErrInvalidSignature = errors.New("invalid admission token signature")
+	ErrTokenExpired     = errors.New("admission token expired")
+	ErrTokenMismatch    = errors.New("admission token belongs to another event or user")
+)
+
+// Claims is the data carried inside an admission token
+type Claims struct {
+	EventID   uint
+	UserID    uint
+	ExpiresAt time.Time
+}
+
+// SigningKey returns the key used to sign admission tokens: WAITING_ROOM_SECRET, or a key derived
+// from the JWT secret for the waiting room only
+func SigningKey() []byte {
+	return signingkey.For(signingkey.WaitingRoom, config.AppConfig.WaitingRoomSecret)
+}
+
+// Sign encodes the claims into a compact "WR1.<payload>.<mac>" token
+func Sign(claims Claims, key []byte) string {
+	payload := make([]byte, 0, 3*binary.MaxVarintLen64)
+	payload = binary.AppendUvarint(payload, uint64(claims.EventID))
+	payload = binary.AppendUvarint(payload, uint64(claims.UserID))
+	payload = binary.AppendVarint(payload, claims.ExpiresAt.Unix())
+
+	encodedPayload := encoding.EncodeToString(payload)
+	signature := encoding.EncodeToString(sign(encodedPayload, key))
+
+	return tokenPrefix + encodedPayload + "." + signature
+}
+
+// Verify checks the token signature and returns its claims. It never touches Redis.
+func Verify(token string, key []byte) (*Claims, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrMalformedToken
+	}
+
+	parts := strings.Split(strings.TrimPrefix(token, tokenPrefix), ".")
+	if len(parts) != 2 {
+		return nil, ErrMalformedToken
+	}
+
+	signature, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if !hmac.Equal(signature, sign(parts[0], key)) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	eventID, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return nil, ErrMalformedToken
+	}
+	payload = payload[n:]
+
+	userID, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return nil, ErrMalformedToken
+	}
+	payload = payload[n:]
+
+	expiresAt, n := binary.Varint(payload)
+	if n <= 0 || n != len(payload) {
+		return nil, ErrMalformedToken
+	}
+
+	return &Claims{
+		EventID:   uint(eventID),
+		UserID:    uint(userID),
+		ExpiresAt: time.Unix(expiresAt, 0),
+	}, nil
+}
+
+// VerifyAdmission checks that the token admits the user to the event right now. Whether the token
+// was already spent on an order is tracked by Room.ConsumeAdmission.
+func VerifyAdmission(token string, eventID uint, userID uint) (*Claims, error) {
+	claims, err := Verify(token, SigningKey())
+	if err != nil {
+		return nil, err
+	}
+	if claims.EventID != eventID || claims.UserID != userID {
+		return nil, ErrTokenMismatch
+	}
+	if !time.Now().Before(claims.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+func sign(encodedPayload string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(tokenPrefix + encodedPayload))
+	return mac.Sum(nil)[:macSize]
+}
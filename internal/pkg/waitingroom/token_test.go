@@ -0,0 +1,123 @@
+package waitingroom
+
+import (
+	"errors"
+	"learn/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("waiting-room-test-key")
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims Claims
+	}{
+		{"small ids", Claims{EventID: 1, UserID: 2, ExpiresAt: time.Unix(1767225600, 0)}},
+		{"large ids", Claims{EventID: 4294967295, UserID: 4294967295, ExpiresAt: time.Unix(4102444800, 0)}},
+		{"zero values", Claims{ExpiresAt: time.Unix(0, 0)}},
+		{"expiry before 1970", Claims{EventID: 7, UserID: 8, ExpiresAt: time.Unix(-86400, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := Sign(tt.claims, testKey)
+			if !strings.HasPrefix(token, tokenPrefix) {
+				t.Fatalf("token %q does not start with %q", token, tokenPrefix)
+			}
+
+			claims, err := Verify(token, testKey)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.EventID != tt.claims.EventID || claims.UserID != tt.claims.UserID || !claims.ExpiresAt.Equal(tt.claims.ExpiresAt) {
+				t.Fatalf("Verify = %+v, want %+v", *claims, tt.claims)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsTamperedTokens(t *testing.T) {
+	token := Sign(Claims{EventID: 3, UserID: 42, ExpiresAt: time.Unix(1767225600, 0)}, testKey)
+	parts := strings.Split(strings.TrimPrefix(token, tokenPrefix), ".")
+	payload, signature := parts[0], parts[1]
+
+	// A token for another user signed with the same key, to splice its payload in
+	other := Sign(Claims{EventID: 3, UserID: 43, ExpiresAt: time.Unix(1767225600, 0)}, testKey)
+	otherPayload := strings.Split(strings.TrimPrefix(other, tokenPrefix), ".")[0]
+
+	flip := func(s string, i int) string {
+		replacement := byte('A')
+		if s[i] == 'A' {
+			replacement = 'B'
+		}
+		return s[:i] + string(replacement) + s[i+1:]
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		key   []byte
+		want  error
+	}{
+		{"other key", token, []byte("another-key"), ErrInvalidSignature},
+		{"payload changed", tokenPrefix + flip(payload, 0) + "." + signature, testKey, ErrInvalidSignature},
+		{"payload of another token", tokenPrefix + otherPayload + "." + signature, testKey, ErrInvalidSignature},
+		{"signature changed", tokenPrefix + payload + "." + flip(signature, 0), testKey, ErrInvalidSignature},
+		{"signature truncated", tokenPrefix + payload + "." + signature[:len(signature)-2], testKey, ErrInvalidSignature},
+		{"signature not base64", tokenPrefix + payload + ".!!!", testKey, ErrMalformedToken},
+		{"missing prefix", strings.TrimPrefix(token, tokenPrefix), testKey, ErrMalformedToken},
+		{"other prefix", "TQ1." + payload + "." + signature, testKey, ErrMalformedToken},
+		{"missing signature", tokenPrefix + payload, testKey, ErrMalformedToken},
+		{"extra part", token + ".x", testKey, ErrMalformedToken},
+		{"empty", "", testKey, ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := Verify(tt.token, tt.key)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify(%q) = %+v, %v, want error %v", tt.token, claims, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAdmission(t *testing.T) {
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+	config.AppConfig.WaitingRoomSecret = ""
+	config.AppConfig.JWTSecretKey = "jwt-test-secret"
+
+	valid := Claims{EventID: 3, UserID: 42, ExpiresAt: time.Now().Add(time.Minute)}
+
+	tests := []struct {
+		name    string
+		token   string
+		eventID uint
+		userID  uint
+		want    error
+	}{
+		{"admitted", Sign(valid, SigningKey()), 3, 42, nil},
+		{"other event", Sign(valid, SigningKey()), 4, 42, ErrTokenMismatch},
+		{"other user", Sign(valid, SigningKey()), 3, 43, ErrTokenMismatch},
+		{"expired", Sign(Claims{EventID: 3, UserID: 42, ExpiresAt: time.Now().Add(-time.Second)}, SigningKey()), 3, 42, ErrTokenExpired},
+		// The JWT secret itself must not sign admissions, only the key derived from it
+		{"signed with the jwt secret", Sign(valid, []byte(config.AppConfig.JWTSecretKey)), 3, 42, ErrInvalidSignature},
+		{"empty", "", 3, 42, ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := VerifyAdmission(tt.token, tt.eventID, tt.userID)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyAdmission = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (claims == nil || claims.UserID != tt.userID) {
+				t.Fatalf("VerifyAdmission claims = %+v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"learn/internal/model"
+	"time"
+)
+
+// Payload is the JSON body of every delivery. ID identifies the event, it stays the same across
+// retries and manual redeliveries so receivers can deduplicate on it.
+type Payload struct {
+	ID        string                 `json:"id"`
+	Type      model.WebhookEventType `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      interface{}            `json:"data"`
+}
+
+// OrderPaidData is the data of an order.paid delivery
+type OrderPaidData struct {
+	OrderID     uint      `json:"order_id"`
+	EventID     uint      `json:"event_id"`
+	TotalPrice  int64     `json:"total_price"`
+	TicketCount int       `json:"ticket_count"`
+	PaidAt      time.Time `json:"paid_at"`
+}
+
+// TicketCheckedInData is the data of a ticket.checked_in delivery
+type TicketCheckedInData struct {
+	TicketID     uint      `json:"ticket_id"`
+	EventID      uint      `json:"event_id"`
+	EventPriceID uint      `json:"event_price_id"`
+	Gate         string    `json:"gate,omitempty"`
+	DeviceID     string    `json:"device_id,omitempty"`
+	Offline      bool      `json:"offline"` // Scanned offline and synced later
+	CheckedInAt  time.Time `json:"checked_in_at"`
+}
+
+// EventCancelledData is the data of an event.cancelled delivery
+type EventCancelledData struct {
+	EventID     uint      `json:"event_id"`
+	Reason      string    `json:"reason,omitempty"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+var (
+	ErrMalformedSignature = errors.New("malformed webhook signature")
+	ErrInvalidSignature   = errors.New("invalid webhook signature")
+	ErrExpiredSignature   = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Sign returns the signature header value "t=<unix>,v1=<hex>", where v1 is the HMAC-SHA256 of
+// "<unix>.<body>" with the endpoint secret. Signing the timestamp lets receivers reject replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", unix, hex.EncodeToString(mac(secret, unix, body)))
+}
+
+// Verify checks a signature header against the body and rejects timestamps further than
+// tolerance from now
+func Verify(secret string, header string, body []byte, tolerance time.Duration) error {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+	if unix == "" || signature == "" {
+		return ErrMalformedSignature
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return ErrMalformedSignature
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrMalformedSignature
+	}
+	if !hmac.Equal(expected, mac(secret, unix, body)) {
+		return ErrInvalidSignature
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrExpiredSignature
+	}
+	return nil
+}
+
+func mac(secret string, unix string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(unix))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	body := []byte(`{"event":"order.paid","data":{"order_id":1}}`)
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		body      []byte
+	}{
+		{"now", time.Now(), body},
+		{"empty body", time.Now(), nil},
+		{"inside tolerance in the past", time.Now().Add(-4 * time.Minute), body},
+		{"inside tolerance in the future", time.Now().Add(4 * time.Minute), body},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := Sign(testSecret, tt.timestamp, tt.body)
+			if !strings.HasPrefix(header, fmt.Sprintf("t=%d,v1=", tt.timestamp.Unix())) {
+				t.Fatalf("header %q does not start with the timestamp", header)
+			}
+			if err := Verify(testSecret, header, tt.body, 5*time.Minute); err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsBadSignatures(t *testing.T) {
+	body := []byte(`{"event":"order.paid"}`)
+	now := time.Now()
+	header := Sign(testSecret, now, body)
+	signature := strings.SplitN(header, ",v1=", 2)[1]
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		want   error
+	}{
+		{"empty header", testSecret, "", body, ErrMalformedSignature},
+		{"part without value", testSecret, "t", body, ErrMalformedSignature},
+		{"missing timestamp", testSecret, "v1=" + signature, body, ErrMalformedSignature},
+		{"missing signature", testSecret, fmt.Sprintf("t=%d", now.Unix()), body, ErrMalformedSignature},
+		{"timestamp not a number", testSecret, "t=soon,v1=" + signature, body, ErrMalformedSignature},
+		{"signature not hex", testSecret, fmt.Sprintf("t=%d,v1=zz", now.Unix()), body, ErrMalformedSignature},
+		{"other secret", "whsec_other", header, body, ErrInvalidSignature},
+		{"changed body", testSecret, header, []byte(`{"event":"order.cancelled"}`), ErrInvalidSignature},
+		{"changed timestamp", testSecret, fmt.Sprintf("t=%d,v1=%s", now.Unix()+1, signature), body, ErrInvalidSignature},
+		{"too old", testSecret, Sign(testSecret, now.Add(-6*time.Minute), body), body, ErrExpiredSignature},
+		{"too far in the future", testSecret, Sign(testSecret, now.Add(6*time.Minute), body), body, ErrExpiredSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyIgnoresUnknownParts(t *testing.T) {
+	body := []byte(`{}`)
+	header := Sign(testSecret, time.Now(), body) + ",v0=deadbeef"
+	if err := Verify(testSecret, header, body, 5*time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
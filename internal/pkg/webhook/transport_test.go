@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"100.64.0.1", false},
+		{"192.0.0.8", false},
+		{"198.18.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:100.64.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := IsPublicAddress(net.ParseIP(tt.ip)); got != tt.want {
+				t.Fatalf("IsPublicAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		host      string
+		forbidden bool
+	}{
+		{"example.com", false},
+		{"hooks.example.com.", false},
+		{"93.184.216.34", false},
+		{"localhost", true},
+		{"LOCALHOST.", true},
+		{"api.localhost", true},
+		{"127.0.0.1", true},
+		{"169.254.169.254", true},
+		{"10.0.0.5", true},
+		{"::1", true},
+		// Hostnames are only checked once they resolve, when the delivery connects
+		{"internal.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := CheckHost(tt.host)
+			if tt.forbidden && !errors.Is(err, ErrForbiddenAddress) {
+				t.Fatalf("CheckHost(%s) = %v, want ErrForbiddenAddress", tt.host, err)
+			}
+			if !tt.forbidden && err != nil {
+				t.Fatalf("CheckHost(%s) = %v, want nil", tt.host, err)
+			}
+		})
+	}
+}
+
+func TestTransportRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the receiver on a loopback address was reached")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport()}
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Get = %v, want ErrForbiddenAddress", err)
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"learn/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TierAttendance holds the ticket counts of a single EventPrice tier
+type TierAttendance struct {
+	EventPriceID uint
+	Name         string
+	Quota        int
+	Sold         int64
+	CheckedIn    int64
+	Inside       int64
+}
+
+// ScanMinuteCount holds the number of scans recorded within one minute
+type ScanMinuteCount struct {
+	Minute   time.Time
+	Accepted int64
+	Rejected int64
+}
+
+type AttendanceRepository interface {
+	GetTierAttendance(eventID uint) ([]TierAttendance, error)
+	GetScansPerMinute(eventID uint, since time.Time) ([]ScanMinuteCount, error)
+	GetRecentRejectedScans(eventID uint, limit int) ([]model.TicketScan, error)
+}
+
+type attendanceRepository struct {
+	db *gorm.DB
+}
+
+func NewAttendanceRepository(db *gorm.DB) AttendanceRepository {
+	return &attendanceRepository{db: db}
+}
+
+// GetTierAttendance counts the paid, checked-in and currently inside tickets of every tier of an event
+func (r *attendanceRepository) GetTierAttendance(eventID uint) ([]TierAttendance, error) {
+	var tiers []TierAttendance
+	err := r.db.Table("event_prices").
+		Select(`event_prices.id AS event_price_id, event_prices.name, event_prices.quota,
+			COUNT(tickets.id) AS sold,
+			COUNT(tickets.id) FILTER (WHERE tickets.is_scanned) AS checked_in,
+			COUNT(tickets.id) FILTER (WHERE tickets.is_inside) AS inside`).
+		Joins(`LEFT JOIN tickets ON tickets.event_price_id = event_prices.id AND tickets.deleted_at IS NULL AND tickets.voided_at IS NULL
+			AND EXISTS (SELECT 1 FROM orders WHERE orders.id = tickets.order_id AND orders.status = ?)`, model.OrderPaid).
+		Where("event_prices.event_id = ? AND event_prices.deleted_at IS NULL", eventID).
+		Group("event_prices.id, event_prices.name, event_prices.quota").
+		Order("event_prices.id").
+		Scan(&tiers).Error
+	return tiers, err
+}
+
+func (r *attendanceRepository) GetScansPerMinute(eventID uint, since time.Time) ([]ScanMinuteCount, error) {
+	var counts []ScanMinuteCount
+	err := r.db.Model(&model.TicketScan{}).
+		Select(`date_trunc('minute', scanned_at) AS minute,
+			COUNT(*) FILTER (WHERE result = ?) AS accepted,
+			COUNT(*) FILTER (WHERE result = ?) AS rejected`, model.ScanAccepted, model.ScanRejected).
+		Where("event_id = ? AND scanned_at >= ?", eventID, since).
+		Group("minute").
+		Order("minute").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *attendanceRepository) GetRecentRejectedScans(eventID uint, limit int) ([]model.TicketScan, error) {
+	var scans []model.TicketScan
+	err := r.db.Where("event_id = ? AND result = ?", eventID, model.ScanRejected).
+		Order("scanned_at DESC").
+		Limit(limit).
+		Find(&scans).Error
+	return scans, err
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrEventCancellationExists is returned when a cancellation run was already started for the event
+var ErrEventCancellationExists = errors.New("event cancellation already started")
+
+type EventCancellationRepository interface {
+	CreateCancellation(cancellation *model.EventCancellation) error
+	GetCancellationByID(cancellationID uint) (*model.EventCancellation, error)
+	GetCancellationByEventID(eventID uint) (*model.EventCancellation, error)
+	GetUnfinishedCancellations() ([]model.EventCancellation, error)
+	CountOpenOrdersForEvent(eventID uint) (int64, error)
+	GetOpenOrdersForEvent(eventID uint, afterOrderID uint, limit int) ([]model.Order, error)
+	SaveCancellation(cancellation *model.EventCancellation) error
+}
+
+type eventCancellationRepository struct {
+	db *gorm.DB
+}
+
+func NewEventCancellationRepository(db *gorm.DB) EventCancellationRepository {
+	return &eventCancellationRepository{db: db}
+}
+
+func (r *eventCancellationRepository) CreateCancellation(cancellation *model.EventCancellation) error {
+	var existing int64
+	if err := r.db.Model(&model.EventCancellation{}).Where("event_id = ?", cancellation.EventID).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrEventCancellationExists
+	}
+	return r.db.Create(cancellation).Error
+}
+
+func (r *eventCancellationRepository) GetCancellationByID(cancellationID uint) (*model.EventCancellation, error) {
+	var cancellation model.EventCancellation
+	if err := r.db.Preload("Event").First(&cancellation, cancellationID).Error; err != nil {
+		return nil, err
+	}
+	return &cancellation, nil
+}
+
+func (r *eventCancellationRepository) GetCancellationByEventID(eventID uint) (*model.EventCancellation, error) {
+	var cancellation model.EventCancellation
+	if err := r.db.Preload("Event").Where("event_id = ?", eventID).First(&cancellation).Error; err != nil {
+		return nil, err
+	}
+	return &cancellation, nil
+}
+
+// GetUnfinishedCancellations returns runs that were interrupted before they completed
+func (r *eventCancellationRepository) GetUnfinishedCancellations() ([]model.EventCancellation, error) {
+	var cancellations []model.EventCancellation
+	err := r.db.Where("status = ?", model.EventCancellationRunning).Order("id ASC").Find(&cancellations).Error
+	return cancellations, err
+}
+
+// openOrdersForEvent selects pending and paid orders holding a price of the event
+func (r *eventCancellationRepository) openOrdersForEvent(eventID uint) *gorm.DB {
+	return r.db.Model(&model.Order{}).
+		Where("orders.status IN ?", []model.OrderStatus{model.OrderPending, model.OrderPaid}).
+		Where(`EXISTS (SELECT 1 FROM order_line_items
+			JOIN event_prices ON event_prices.id = order_line_items.event_price_id
+			WHERE order_line_items.order_id = orders.id AND event_prices.event_id = ?)`, eventID)
+}
+
+func (r *eventCancellationRepository) CountOpenOrdersForEvent(eventID uint) (int64, error) {
+	var count int64
+	err := r.openOrdersForEvent(eventID).Count(&count).Error
+	return count, err
+}
+
+// GetOpenOrdersForEvent returns the next batch of open orders after the cursor, with buyer and tickets
+func (r *eventCancellationRepository) GetOpenOrdersForEvent(eventID uint, afterOrderID uint, limit int) ([]model.Order, error) {
+	var orders []model.Order
+	err := r.openOrdersForEvent(eventID).
+		Preload("User").
+		Preload("Tickets").
+		Where("orders.id > ?", afterOrderID).
+		Order("orders.id ASC").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+func (r *eventCancellationRepository) SaveCancellation(cancellation *model.EventCancellation) error {
+	return r.db.Omit("Event").Save(cancellation).Error
+}
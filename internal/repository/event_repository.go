@@ -2,6 +2,7 @@ package repository
 
 import (
 	"learn/internal/model"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -14,6 +15,7 @@ type EventRepository interface {
 	CreateEventPrices(eventPrices []model.EventPrice) error
 	GetEventPriceByID(id uint) (*model.EventPrice, error)
 	GetEventsByGuestSlug(guestSlug string) ([]model.Event, error)
+	GetWaitingRoomEventsOnSale(now time.Time) ([]model.Event, error)
 	UpdateEvent(event *model.Event) error
 	UpdateEventGuests(eventID uint, eventGuests []model.EventGuest) error
 	UpdateEventPrices(eventID uint, eventPrices []model.EventPrice) error
@@ -57,6 +59,14 @@ func (r *eventRepository) GetEventsByGuestSlug(guestSlug string) ([]model.Event,
 	return events, err
 }
 
+// GetWaitingRoomEventsOnSale returns the published events with the waiting room on whose sales
+// period contains now
+func (r *eventRepository) GetWaitingRoomEventsOnSale(now time.Time) ([]model.Event, error) {
+	var events []model.Event
+	err := r.db.Where("waiting_room_enabled = ? AND status = ? AND sales_start_date <= ? AND sales_end_date > ?", true, model.Published, now, now).Find(&events).Error
+	return events, err
+}
+
 func (r *eventRepository) UpdateEvent(event *model.Event) error {
 	return r.db.Save(event).Error
 }
@@ -1,26 +1,38 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"learn/internal/model"
+	"time"
 
 	// Added this import
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// ErrOrderHoldExpired is returned when a payment is started for an order whose hold already ended
+var ErrOrderHoldExpired = errors.New("order hold expired")
+
+// ErrNotEnoughQuota is returned when a price tier cannot sell the ordered quantity to the buyer
+var ErrNotEnoughQuota = errors.New("not enough quota for ticket")
+
+// ErrOrderTransition is returned for an order status change model.CanTransitionOrderStatus does not allow
+var ErrOrderTransition = errors.New("invalid order status transition")
+
 type orderRepository struct {
 	db *gorm.DB
 }
 
 type OrderRepository interface {
-	CreateOrderInTransaction(order *model.Order, prices []model.EventPrice, priceUpdates map[uint]int) error
+	CreateOrderInTransaction(ctx context.Context, order *model.Order, prices []model.EventPrice, priceUpdates map[uint]int, orderEvent func(order *model.Order) DomainEvent) error
 	GetEventPricesByIDs(priceIDs []uint) ([]model.EventPrice, error)
 	GetEventByID(id uint) (*model.Event, error)
 	GetOrderByID(orderID uint) (*model.Order, error)
 	GetOrderByIDWithLineItems(orderID uint) (*model.Order, error) // Added
-	UpdateOrder(order *model.Order) error
-	RestoreQuota(eventPriceID uint, quantity int) error
+	GetOrderDetailByID(orderID uint) (*model.Order, error)
+	ExtendOrderHold(ctx context.Context, order *model.Order, extendTo time.Time) (bool, error)
+	TransitionOrderStatus(ctx context.Context, orderID uint, from model.OrderStatus, to model.OrderStatus, statusEvent func(order *model.Order) DomainEvent) (*model.Order, bool, error)
 	GetDB() *gorm.DB
 }
 
@@ -28,8 +40,11 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
-func (r *orderRepository) CreateOrderInTransaction(order *model.Order, prices []model.EventPrice, priceUpdates map[uint]int) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+// CreateOrderInTransaction reserves the quota and creates the order with its line items. Quota set
+// aside for the waitlist is only sold to users with an open offer, which the order uses up. The event
+// built by orderEvent is written to the outbox in the same transaction.
+func (r *orderRepository) CreateOrderInTransaction(ctx context.Context, order *model.Order, prices []model.EventPrice, priceUpdates map[uint]int, orderEvent func(order *model.Order) DomainEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 1. Lock the EventPrice records to prevent race conditions
 		var lockedPrices []model.EventPrice
 		priceIDs := make([]uint, 0, len(priceUpdates))
@@ -42,11 +57,23 @@ func (r *orderRepository) CreateOrderInTransaction(order *model.Order, prices []
 			return err
 		}
 
-		// 2. Verify quotas are sufficient before proceeding
+		// 2. Verify quotas are sufficient before proceeding, counting the waitlist offers of the buyer
+		var offers []model.WaitlistEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND event_price_id IN ? AND status = ? AND offer_expires_at > ?",
+				order.UserID, priceIDs, model.WaitlistOffered, time.Now()).
+			Find(&offers).Error; err != nil {
+			return err
+		}
+		offered := make(map[uint]int)
+		for _, offer := range offers {
+			offered[offer.EventPriceID] = offer.Quantity
+		}
+
 		for _, lockedPrice := range lockedPrices {
 			quantity := priceUpdates[lockedPrice.ID]
-			if lockedPrice.Quota < quantity {
-				return errors.New("not enough quota for ticket")
+			if lockedPrice.Quota-lockedPrice.WaitlistQuota+offered[lockedPrice.ID] < quantity {
+				return ErrNotEnoughQuota
 			}
 		}
 
@@ -83,18 +110,33 @@ func (r *orderRepository) CreateOrderInTransaction(order *model.Order, prices []
 			return err
 		}
 
-		// 5. Update quotas for each price
+		// 5. Update quotas for each price, an offer releases all the quota it set aside
 		for priceID, quantity := range priceUpdates {
-			result := tx.Model(&model.EventPrice{}).Where("id = ? AND quota >= ? ", priceID, quantity).UpdateColumn("quota", gorm.Expr("quota - ?", quantity))
+			result := tx.Model(&model.EventPrice{}).
+				Where("id = ? AND quota - waitlist_quota + ? >= ?", priceID, offered[priceID], quantity).
+				UpdateColumns(map[string]interface{}{
+					"quota":          gorm.Expr("quota - ?", quantity),
+					"waitlist_quota": gorm.Expr("waitlist_quota - ?", offered[priceID]),
+				})
 			if result.Error != nil {
 				return result.Error
 			}
 			if result.RowsAffected == 0 {
-				return errors.New("not enough quota or price not found")
+				return ErrNotEnoughQuota
+			}
+		}
+
+		for _, offer := range offers {
+			if err := tx.Model(&model.WaitlistEntry{}).Where("id = ?", offer.ID).Updates(map[string]interface{}{
+				"status":   model.WaitlistConverted,
+				"order_id": order.ID,
+			}).Error; err != nil {
+				return err
 			}
 		}
 
-		return nil
+		// 6. Record the domain event for the outbox relay
+		return addOutboxEvent(tx, orderEvent(order))
 	})
 }
 
@@ -118,10 +160,6 @@ func (r *orderRepository) GetOrderByID(orderID uint) (*model.Order, error) {
 	return &order, nil
 }
 
-func (r *orderRepository) UpdateOrder(order *model.Order) error {
-	return r.db.Save(order).Error
-}
-
 func (r *orderRepository) GetOrderByIDWithLineItems(orderID uint) (*model.Order, error) {
 	var order model.Order
 	if err := r.db.Preload("OrderLineItems").Preload("User").First(&order, orderID).Error; err != nil {
@@ -130,9 +168,109 @@ func (r *orderRepository) GetOrderByIDWithLineItems(orderID uint) (*model.Order,
 	return &order, nil
 }
 
-func (r *orderRepository) RestoreQuota(eventPriceID uint, quantity int) error {
-	result := r.db.Model(&model.EventPrice{}).Where("id = ?", eventPriceID).UpdateColumn("quota", gorm.Expr("quota + ?", quantity))
-	return result.Error
+func (r *orderRepository) GetOrderDetailByID(orderID uint) (*model.Order, error) {
+	var order model.Order
+	if err := r.db.Preload("OrderLineItems.EventPrice.Event").Preload("Tickets").First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ExtendOrderHold moves the end of the hold, and the payment due with it, to extendTo. It only
+// applies while the order is pending, not extended yet and its hold unchanged since it was read, so
+// concurrent requests extend it once. The returned bool is false when nothing was updated.
+func (r *orderRepository) ExtendOrderHold(ctx context.Context, order *model.Order, extendTo time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.Order{}).
+		Where("id = ? AND status = ? AND hold_extended = ? AND hold_expires_at = ?", order.ID, model.OrderPending, false, order.HoldExpiresAt).
+		Updates(map[string]interface{}{
+			"hold_expires_at": extendTo,
+			"payment_due":     extendTo,
+			"hold_extended":   true,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	order.HoldExpiresAt = &extendTo
+	order.PaymentDue = extendTo
+	order.HoldExtended = true
+	return true, nil
+}
+
+// TransitionOrderStatus moves the order from one status to another only while it still has the
+// from status. Cancelling restores the quota of its line items in the same transaction, and when
+// statusEvent is not nil its event is written to the outbox as well. The returned bool is false
+// when the order had already left the from status, the returned order is nil in that case.
+func (r *orderRepository) TransitionOrderStatus(ctx context.Context, orderID uint, from model.OrderStatus, to model.OrderStatus, statusEvent func(order *model.Order) DomainEvent) (*model.Order, bool, error) {
+	var order model.Order
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		moved, err := transitionOrderStatus(tx, orderID, from, to)
+		if err != nil || !moved {
+			return err
+		}
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return err
+		}
+
+		changed = true
+		if statusEvent == nil {
+			return nil
+		}
+		return addOutboxEvent(tx, statusEvent(&order))
+	})
+	if err != nil || !changed {
+		return nil, false, err
+	}
+	return &order, true, nil
+}
+
+// transitionOrderStatus is the conditional status update shared by every order transition, so an
+// order that was paid in the meantime is never cancelled and its quota is restored exactly once.
+// Transitions model.CanTransitionOrderStatus does not allow return ErrOrderTransition.
+func transitionOrderStatus(tx *gorm.DB, orderID uint, from model.OrderStatus, to model.OrderStatus) (bool, error) {
+	if !model.CanTransitionOrderStatus(from, to) {
+		return false, ErrOrderTransition
+	}
+
+	result := tx.Model(&model.Order{}).
+		Where("id = ? AND status = ?", orderID, from).
+		Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	if to != model.OrderCancelled {
+		return true, nil
+	}
+
+	var lineItems []model.OrderLineItem
+	if err := tx.Where("order_id = ?", orderID).Find(&lineItems).Error; err != nil {
+		return false, err
+	}
+	for _, lineItem := range lineItems {
+		if err := restoreQuota(tx, lineItem.EventPriceID, lineItem.Quantity); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// restoreQuota gives quantity back to the price tier. While users wait for the tier it is set aside
+// for the waitlist until the waitlist job offers it, so nobody else can buy it first.
+func restoreQuota(tx *gorm.DB, eventPriceID uint, quantity int) error {
+	waiting := tx.Session(&gorm.Session{NewDB: true}).Model(&model.WaitlistEntry{}).Select("1").
+		Where("event_price_id = ? AND status = ?", eventPriceID, model.WaitlistWaiting)
+	return tx.Model(&model.EventPrice{}).Where("id = ?", eventPriceID).
+		UpdateColumns(map[string]interface{}{
+			"quota":          gorm.Expr("quota + ?", quantity),
+			"waitlist_quota": gorm.Expr("waitlist_quota + CASE WHEN EXISTS (?) THEN ? ELSE 0 END", waiting, quantity),
+		}).Error
 }
 
 func (r *orderRepository) GetDB() *gorm.DB {
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"encoding/json"
+	"learn/internal/model"
+	"learn/internal/pkg/random"
+	"learn/internal/pkg/requestid"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DomainEvent is implemented by the events of the events package. Repositories write them to
+// the outbox inside the transaction of the state change instead of publishing them directly.
+type DomainEvent interface {
+	GetEventType() string
+	GetEventVersion() int
+}
+
+// OutboxStats describes the backlog of events waiting for the relay
+type OutboxStats struct {
+	Pending         int64
+	Retrying        int64 // Pending events whose publish already failed at least once
+	OldestPendingAt *time.Time
+	SentLastHour    int64
+}
+
+type OutboxRepository interface {
+	PublishPending(limit int, publish func(event model.OutboxEvent) error) (int, error)
+	GetStats() (*OutboxStats, error)
+	DeleteSentBefore(cutoff time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// addOutboxEvent stores a domain event as part of tx, the relay publishes it after the commit.
+// The request ID of the transaction context becomes the correlation ID of the event.
+func addOutboxEvent(tx *gorm.DB, event DomainEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.OutboxEvent{
+		EventID:       random.StringWithCharset(32, "0123456789abcdef"),
+		EventType:     event.GetEventType(),
+		Version:       event.GetEventVersion(),
+		CorrelationID: requestid.FromContext(tx.Statement.Context),
+		Payload:       string(payload),
+	}).Error
+}
+
+// outboxRelayLock is the key of the advisory lock held by the relay that is publishing
+const outboxRelayLock int64 = 0x6f7574626f78 // "outbox"
+
+// PublishPending hands unsent events to publish in creation order and marks them sent. Only one
+// relay publishes at a time: the others find the advisory lock taken and return without sending,
+// so events reach the stream in creation order however many relays run. The batch stops at the
+// first failure to keep events in order, the failed row is retried on the next call.
+func (r *outboxRepository) PublishPending(limit int, publish func(event model.OutboxEvent) error) (int, error) {
+	sent := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Released when the transaction ends, or with the connection when the relay dies
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLock).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		var pending []model.OutboxEvent
+		if err := tx.Where("sent_at IS NULL").
+			Order("id ASC").
+			Limit(limit).
+			Find(&pending).Error; err != nil {
+			return err
+		}
+
+		for _, event := range pending {
+			if err := publish(event); err != nil {
+				return tx.Model(&model.OutboxEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": err.Error(),
+				}).Error
+			}
+
+			if err := tx.Model(&model.OutboxEvent{}).Where("id = ?", event.ID).Update("sent_at", time.Now()).Error; err != nil {
+				return err
+			}
+			sent++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sent, nil
+}
+
+func (r *outboxRepository) GetStats() (*OutboxStats, error) {
+	var stats OutboxStats
+	if err := r.db.Model(&model.OutboxEvent{}).Where("sent_at IS NULL").Count(&stats.Pending).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&model.OutboxEvent{}).Where("sent_at IS NULL AND attempts > 0").Count(&stats.Retrying).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&model.OutboxEvent{}).Where("sent_at >= ?", time.Now().Add(-time.Hour)).Count(&stats.SentLastHour).Error; err != nil {
+		return nil, err
+	}
+
+	if stats.Pending > 0 {
+		var oldest model.OutboxEvent
+		if err := r.db.Where("sent_at IS NULL").Order("id ASC").First(&oldest).Error; err != nil {
+			return nil, err
+		}
+		stats.OldestPendingAt = &oldest.CreatedAt
+	}
+
+	return &stats, nil
+}
+
+// DeleteSentBefore purges delivered events, unsent ones are always kept
+func (r *outboxRepository) DeleteSentBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("sent_at IS NOT NULL AND sent_at < ?", cutoff).Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"learn/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReconciliationIssueResolved is returned when resolving an issue that is no longer open
+var ErrReconciliationIssueResolved = errors.New("reconciliation issue already resolved")
+
+type PaymentReconciliationRepository interface {
+	GetPendingPaymentsCreatedBefore(cutoff time.Time, afterPaymentID uint, limit int) ([]model.Payment, error)
+	CreateIssue(issue *model.PaymentReconciliationIssue) (bool, error)
+	GetIssueByID(issueID uint) (*model.PaymentReconciliationIssue, error)
+	ResolveIssue(issueID uint, resolverID uint, note string) (*model.PaymentReconciliationIssue, error)
+}
+
+type paymentReconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentReconciliationRepository(db *gorm.DB) PaymentReconciliationRepository {
+	return &paymentReconciliationRepository{db: db}
+}
+
+// GetPendingPaymentsCreatedBefore returns a batch of pending payments older than the cutoff, in ID order
+func (r *paymentReconciliationRepository) GetPendingPaymentsCreatedBefore(cutoff time.Time, afterPaymentID uint, limit int) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := r.db.Preload("Order").
+		Where("payment_status = ? AND created_at < ? AND id > ?", model.PaymentStatusPending, cutoff, afterPaymentID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&payments).Error
+	return payments, err
+}
+
+// CreateIssue flags a payment once per issue type. The bool is false when the issue was already
+// flagged by an earlier run, resolved or not.
+func (r *paymentReconciliationRepository) CreateIssue(issue *model.PaymentReconciliationIssue) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(issue)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *paymentReconciliationRepository) GetIssueByID(issueID uint) (*model.PaymentReconciliationIssue, error) {
+	var issue model.PaymentReconciliationIssue
+	if err := r.db.Preload("Payment").First(&issue, issueID).Error; err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+func (r *paymentReconciliationRepository) ResolveIssue(issueID uint, resolverID uint, note string) (*model.PaymentReconciliationIssue, error) {
+	result := r.db.Model(&model.PaymentReconciliationIssue{}).
+		Where("id = ? AND status = ?", issueID, model.ReconciliationIssueOpen).
+		Updates(map[string]interface{}{
+			"status":              model.ReconciliationIssueResolved,
+			"resolved_by_user_id": resolverID,
+			"resolution_note":     note,
+			"resolved_at":         time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrReconciliationIssueResolved
+	}
+	return r.GetIssueByID(issueID)
+}
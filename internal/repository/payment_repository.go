@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"context"
+	"fmt"
 	"learn/internal/config"
 	"learn/internal/model"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
@@ -11,12 +14,12 @@ import (
 
 type PaymentRepository interface {
 	CreatePayment(payment *model.Payment) error
-	CreatePaymentInTransaction(payment *model.Payment) error
+	CreatePaymentInTransaction(ctx context.Context, payment *model.Payment, paymentDue time.Time, paymentEvent func(payment *model.Payment) DomainEvent) error
 	GetPaymentByID(paymentID uint) (*model.Payment, error)
 	GetPaymentByOrderID(orderID uint) (*model.Payment, error)
 	GetPaymentByTransactionID(transactionID string) (*model.Payment, error)
 	UpdatePayment(payment *model.Payment) error
-	UpdatePaymentStatusInTransaction(paymentID uint, status model.PaymentStatus) (*model.Payment, bool, error)
+	UpdatePaymentStatusInTransaction(ctx context.Context, paymentID uint, status model.PaymentStatus, statusEvent func(payment *model.Payment) DomainEvent) (*model.Payment, bool, error)
 	DeletePayment(paymentID uint) error
 	GetRedisClient() *redis.Client
 }
@@ -37,8 +40,11 @@ func (r *paymentRepository) CreatePayment(payment *model.Payment) error {
 	return r.db.Create(payment).Error
 }
 
-func (r *paymentRepository) CreatePaymentInTransaction(payment *model.Payment) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+// CreatePaymentInTransaction creates the payment of a pending order whose hold has not ended, ends
+// the hold and gives the order paymentDue, and writes the event built by paymentEvent to the outbox
+// in the same transaction
+func (r *paymentRepository) CreatePaymentInTransaction(ctx context.Context, payment *model.Payment, paymentDue time.Time, paymentEvent func(payment *model.Payment) DomainEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var order model.Order
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, payment.OrderID).Error; err != nil {
 			return err
@@ -46,6 +52,9 @@ func (r *paymentRepository) CreatePaymentInTransaction(payment *model.Payment) e
 		if order.Status != model.OrderPending {
 			return gorm.ErrInvalidTransaction
 		}
+		if order.HoldExpiresAt != nil && !time.Now().Before(order.PaymentDue) {
+			return ErrOrderHoldExpired
+		}
 
 		var existing model.Payment
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", payment.OrderID).First(&existing).Error; err == nil {
@@ -54,7 +63,16 @@ func (r *paymentRepository) CreatePaymentInTransaction(payment *model.Payment) e
 			return err
 		}
 
-		return tx.Create(payment).Error
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&order).Updates(map[string]interface{}{
+			"payment_due":     paymentDue,
+			"hold_expires_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		return addOutboxEvent(tx, paymentEvent(payment))
 	})
 }
 
@@ -78,11 +96,14 @@ func (r *paymentRepository) UpdatePayment(payment *model.Payment) error {
 	return r.db.Save(payment).Error
 }
 
-func (r *paymentRepository) UpdatePaymentStatusInTransaction(paymentID uint, status model.PaymentStatus) (*model.Payment, bool, error) {
+// UpdatePaymentStatusInTransaction moves the payment and its order to the new status. When the status
+// changed and statusEvent is not nil, its event is written to the outbox in the same transaction.
+// A successful payment of an order that is no longer PENDING is flagged for reconciliation review.
+func (r *paymentRepository) UpdatePaymentStatusInTransaction(ctx context.Context, paymentID uint, status model.PaymentStatus, statusEvent func(payment *model.Payment) DomainEvent) (*model.Payment, bool, error) {
 	var payment model.Payment
 	changed := false
 
-	err := r.db.Transaction(func(tx *gorm.DB) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
 			return err
 		}
@@ -96,19 +117,30 @@ func (r *paymentRepository) UpdatePaymentStatusInTransaction(paymentID uint, sta
 			return err
 		}
 
+		// The order follows the payment only while it is still PENDING, a failed payment
+		// restores the quota of an order that was not cancelled already
 		switch status {
 		case model.PaymentStatusSuccess:
-			if err := tx.Model(&model.Order{}).Where("id = ?", payment.OrderID).Update("status", model.OrderPaid).Error; err != nil {
+			moved, err := transitionOrderStatus(tx, payment.OrderID, model.OrderPending, model.OrderPaid)
+			if err != nil {
 				return err
 			}
+			if !moved {
+				if err := flagPaidAfterCancellation(tx, &payment); err != nil {
+					return err
+				}
+			}
 		case model.PaymentStatusFailed:
-			if err := tx.Model(&model.Order{}).Where("id = ? AND status = ?", payment.OrderID, model.OrderPending).Update("status", model.OrderCancelled).Error; err != nil {
+			if _, err := transitionOrderStatus(tx, payment.OrderID, model.OrderPending, model.OrderCancelled); err != nil {
 				return err
 			}
 		}
 
 		changed = true
-		return nil
+		if statusEvent == nil {
+			return nil
+		}
+		return addOutboxEvent(tx, statusEvent(&payment))
 	})
 	if err != nil {
 		return nil, false, err
@@ -116,6 +148,26 @@ func (r *paymentRepository) UpdatePaymentStatusInTransaction(paymentID uint, sta
 	return &payment, changed, nil
 }
 
+// flagPaidAfterCancellation opens a reconciliation issue for a payment that succeeded after its order
+// was closed, so admins refund it instead of the money silently staying with us
+func flagPaidAfterCancellation(tx *gorm.DB, payment *model.Payment) error {
+	var order model.Order
+	if err := tx.First(&order, payment.OrderID).Error; err != nil {
+		return err
+	}
+
+	issue := model.PaymentReconciliationIssue{
+		PaymentID:      payment.ID,
+		OrderID:        order.ID,
+		Type:           model.ReconciliationPaidAfterCancellation,
+		Status:         model.ReconciliationIssueOpen,
+		GatewayStatus:  string(payment.PaymentStatus),
+		ExpectedAmount: order.TotalPrice,
+		Detail:         fmt.Sprintf("payment succeeded after the order became %s", order.Status),
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&issue).Error
+}
+
 func (r *paymentRepository) DeletePayment(paymentID uint) error {
 	return r.db.Delete(&model.Payment{}, paymentID).Error
 }
@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"errors"
+	"learn/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRefundOrderStatus is returned when refunds are requested for an order that is not paid
+	ErrRefundOrderStatus = errors.New("only paid orders can be refunded")
+	// ErrRefundTicketUnavailable is returned when a ticket is used, voided, transferred or already being refunded
+	ErrRefundTicketUnavailable = errors.New("ticket cannot be refunded")
+	// ErrRefundStatus is returned when a refund is not in the status an operation requires
+	ErrRefundStatus = errors.New("refund is not in the required status")
+)
+
+type RefundRepository interface {
+	CreateRefund(refund *model.Refund, ticketIDs []uint) error
+	CreateEventCancellationRefund(refund *model.Refund) error
+	GetRequestedRefundsByOrderID(orderID uint) ([]model.Refund, error)
+	GetRefundByID(refundID uint) (*model.Refund, error)
+	ClaimRefund(refundID uint, reviewerID uint, note string) (*model.Refund, error)
+	ReleaseRefund(refundID uint) error
+	ReleaseStaleRefunds(orderID uint, claimedBefore time.Time) (int64, error)
+	RejectRefund(refundID uint, reviewerID uint, note string) (*model.Refund, error)
+	ApplyRefund(refundID uint, status model.RefundStatus, gatewayRefundKey string) (*model.Refund, bool, error)
+	SettleRefund(refundID uint, reference string) (*model.Refund, error)
+}
+
+type refundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) RefundRepository {
+	return &refundRepository{db: db}
+}
+
+// CreateRefund stores a refund request for the given tickets of the order, or for every
+// remaining ticket when ticketIDs is empty. Type and Amount are filled in from the tickets.
+func (r *refundRepository) CreateRefund(refund *model.Refund, ticketIDs []uint) error {
+	return r.createRefund(refund, ticketIDs, false)
+}
+
+// CreateEventCancellationRefund stores a refund for every remaining ticket of the order that
+// is not already part of an open refund. Used and transferred tickets are included, because
+// the event they were bought for no longer takes place.
+func (r *refundRepository) CreateEventCancellationRefund(refund *model.Refund) error {
+	return r.createRefund(refund, nil, true)
+}
+
+func (r *refundRepository) GetRequestedRefundsByOrderID(orderID uint) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := r.db.Where("order_id = ? AND status = ?", orderID, model.RefundRequested).Order("id ASC").Find(&refunds).Error
+	return refunds, err
+}
+
+func (r *refundRepository) createRefund(refund *model.Refund, ticketIDs []uint, eventCancelled bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var order model.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, refund.OrderID).Error; err != nil {
+			return err
+		}
+		if order.Status != model.OrderPaid {
+			return ErrRefundOrderStatus
+		}
+
+		var remaining []model.Ticket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND voided_at IS NULL", order.ID).
+			Order("id ASC").
+			Find(&remaining).Error; err != nil {
+			return err
+		}
+
+		selected := remaining
+		if len(ticketIDs) > 0 {
+			byID := make(map[uint]model.Ticket, len(remaining))
+			for _, ticket := range remaining {
+				byID[ticket.ID] = ticket
+			}
+
+			selected = make([]model.Ticket, 0, len(ticketIDs))
+			seen := make(map[uint]bool, len(ticketIDs))
+			for _, id := range ticketIDs {
+				ticket, ok := byID[id]
+				if !ok {
+					return ErrRefundTicketUnavailable
+				}
+				if !seen[id] {
+					seen[id] = true
+					selected = append(selected, ticket)
+				}
+			}
+		}
+		if len(selected) == 0 {
+			return ErrRefundTicketUnavailable
+		}
+
+		ids := make([]uint, 0, len(selected))
+		for _, ticket := range selected {
+			if !eventCancelled && (ticket.IsScanned || (ticket.HolderUserID != nil && *ticket.HolderUserID != order.UserID)) {
+				return ErrRefundTicketUnavailable
+			}
+			ids = append(ids, ticket.ID)
+		}
+
+		open, err := openRefundTicketIDs(tx, ids)
+		if err != nil {
+			return err
+		}
+		if len(open) > 0 {
+			if !eventCancelled {
+				return ErrRefundTicketUnavailable
+			}
+
+			// Tickets already being refunded are left to their own refund
+			inOpenRefund := make(map[uint]bool, len(open))
+			for _, id := range open {
+				inOpenRefund[id] = true
+			}
+			available := make([]model.Ticket, 0, len(selected))
+			for _, ticket := range selected {
+				if !inOpenRefund[ticket.ID] {
+					available = append(available, ticket)
+				}
+			}
+			if len(available) == 0 {
+				return ErrRefundTicketUnavailable
+			}
+			selected = available
+		}
+
+		refund.Type = model.RefundPartial
+		if len(selected) == len(remaining) {
+			refund.Type = model.RefundFull
+		}
+		refund.Status = model.RefundRequested
+		refund.Amount = 0
+		refund.Tickets = make([]model.RefundTicket, 0, len(selected))
+		for _, ticket := range selected {
+			refund.Amount += ticket.Price
+			refund.Tickets = append(refund.Tickets, model.RefundTicket{TicketID: ticket.ID, Amount: ticket.Price})
+		}
+
+		return tx.Create(refund).Error
+	})
+}
+
+// openRefundTicketIDs returns the tickets among ticketIDs that belong to a REQUESTED or PROCESSING
+// refund. Check-in and transfers call it while holding the ticket lock, so a ticket is never used
+// or handed over while its refund is open.
+func openRefundTicketIDs(tx *gorm.DB, ticketIDs []uint) ([]uint, error) {
+	var open []uint
+	err := tx.Model(&model.RefundTicket{}).
+		Joins("JOIN refunds ON refunds.id = refund_tickets.refund_id AND refunds.deleted_at IS NULL").
+		Where("refund_tickets.ticket_id IN ? AND refunds.status IN ?", ticketIDs,
+			[]model.RefundStatus{model.RefundRequested, model.RefundProcessing}).
+		Pluck("refund_tickets.ticket_id", &open).Error
+	return open, err
+}
+
+// lockRefundTickets locks the tickets of a refund and checks that none of them was used or
+// transferred since the refund was requested. Tickets of a cancelled event are refunded regardless,
+// like in CreateEventCancellationRefund.
+func lockRefundTickets(tx *gorm.DB, refund model.Refund) ([]model.Ticket, error) {
+	var tickets []model.Ticket
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN (?)", tx.Model(&model.RefundTicket{}).Select("ticket_id").Where("refund_id = ?", refund.ID)).
+		Order("id ASC").
+		Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+
+	eventPriceIDs := make([]uint, 0, len(tickets))
+	for _, ticket := range tickets {
+		eventPriceIDs = append(eventPriceIDs, ticket.EventPriceID)
+	}
+	var cancelled int64
+	if err := tx.Model(&model.Event{}).
+		Joins("JOIN event_prices ON event_prices.event_id = events.id").
+		Where("event_prices.id IN ? AND events.status = ?", eventPriceIDs, model.Cancelled).
+		Count(&cancelled).Error; err != nil {
+		return nil, err
+	}
+	if cancelled > 0 {
+		return tickets, nil
+	}
+
+	var order model.Order
+	if err := tx.Select("id, user_id").First(&order, refund.OrderID).Error; err != nil {
+		return nil, err
+	}
+	for _, ticket := range tickets {
+		if ticket.IsScanned || (ticket.HolderUserID != nil && *ticket.HolderUserID != order.UserID) {
+			return nil, ErrRefundTicketUnavailable
+		}
+	}
+	return tickets, nil
+}
+
+func (r *refundRepository) GetRefundByID(refundID uint) (*model.Refund, error) {
+	var refund model.Refund
+	if err := r.db.Preload("Tickets.Ticket").First(&refund, refundID).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// ClaimRefund moves a requested refund to PROCESSING so only one reviewer can approve it.
+// ErrRefundTicketUnavailable is returned when one of its tickets was used or transferred.
+func (r *refundRepository) ClaimRefund(refundID uint, reviewerID uint, note string) (*model.Refund, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var refund model.Refund
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, refundID).Error; err != nil {
+			return err
+		}
+		if refund.Status != model.RefundRequested {
+			return ErrRefundStatus
+		}
+
+		if _, err := lockRefundTickets(tx, refund); err != nil {
+			return err
+		}
+
+		return tx.Model(&refund).Updates(map[string]interface{}{
+			"status":              model.RefundProcessing,
+			"reviewed_by_user_id": reviewerID,
+			"reviewed_at":         time.Now(),
+			"review_note":         note,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetRefundByID(refundID)
+}
+
+// ReleaseRefund puts a refund whose gateway call failed back into the review queue
+func (r *refundRepository) ReleaseRefund(refundID uint) error {
+	return r.db.Model(&model.Refund{}).
+		Where("id = ? AND status = ?", refundID, model.RefundProcessing).
+		Update("status", model.RefundRequested).Error
+}
+
+// ReleaseStaleRefunds puts the PROCESSING refunds of the order claimed before claimedBefore back
+// into the review queue. Their approval was interrupted, for example by a restart during the
+// gateway call; approving them again is safe because the gateway refund key is the refund ID.
+func (r *refundRepository) ReleaseStaleRefunds(orderID uint, claimedBefore time.Time) (int64, error) {
+	result := r.db.Model(&model.Refund{}).
+		Where("order_id = ? AND status = ? AND reviewed_at < ?", orderID, model.RefundProcessing, claimedBefore).
+		Update("status", model.RefundRequested)
+	return result.RowsAffected, result.Error
+}
+
+func (r *refundRepository) RejectRefund(refundID uint, reviewerID uint, note string) (*model.Refund, error) {
+	now := time.Now()
+	result := r.db.Model(&model.Refund{}).
+		Where("id = ? AND status = ?", refundID, model.RefundRequested).
+		Updates(map[string]interface{}{
+			"status":              model.RefundRejected,
+			"reviewed_by_user_id": reviewerID,
+			"reviewed_at":         now,
+			"review_note":         note,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrRefundStatus
+	}
+	return r.GetRefundByID(refundID)
+}
+
+// ApplyRefund voids the refunded tickets, restores their quota and finishes a processing
+// refund. When no usable ticket is left the payment and the order become REFUNDED, which
+// is reported by the returned bool. The tickets are checked again under their lock and
+// ErrRefundTicketUnavailable is returned when one of them was used or transferred.
+func (r *refundRepository) ApplyRefund(refundID uint, status model.RefundStatus, gatewayRefundKey string) (*model.Refund, bool, error) {
+	var refund model.Refund
+	fullyRefunded := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, refundID).Error; err != nil {
+			return err
+		}
+		if refund.Status != model.RefundProcessing {
+			return ErrRefundStatus
+		}
+
+		tickets, err := lockRefundTickets(tx, refund)
+		if err != nil {
+			return err
+		}
+		if err := tx.Preload("Ticket").Where("refund_id = ?", refund.ID).Find(&refund.Tickets).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		quotas := make(map[uint]int)
+		ticketIDs := make([]uint, 0, len(tickets))
+		for _, ticket := range tickets {
+			ticketIDs = append(ticketIDs, ticket.ID)
+			quotas[ticket.EventPriceID]++
+		}
+
+		if err := tx.Model(&model.Ticket{}).
+			Where("id IN ? AND voided_at IS NULL", ticketIDs).
+			Updates(map[string]interface{}{"voided_at": now, "is_inside": false}).Error; err != nil {
+			return err
+		}
+
+		for eventPriceID, quantity := range quotas {
+			if err := restoreQuota(tx, eventPriceID, quantity); err != nil {
+				return err
+			}
+		}
+
+		var remaining int64
+		if err := tx.Model(&model.Ticket{}).
+			Where("order_id = ? AND voided_at IS NULL", refund.OrderID).
+			Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining == 0 {
+			fullyRefunded = true
+			if err := tx.Model(&model.Payment{}).Where("id = ?", refund.PaymentID).
+				Update("payment_status", model.PaymentStatusRefunded).Error; err != nil {
+				return err
+			}
+			if _, err := transitionOrderStatus(tx, refund.OrderID, model.OrderPaid, model.OrderRefunded); err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]interface{}{
+			"status":             status,
+			"gateway_refund_key": gatewayRefundKey,
+		}
+		if status == model.RefundCompleted {
+			updates["completed_at"] = now
+			refund.CompletedAt = &now
+		}
+		refund.Status = status
+		refund.GatewayRefundKey = gatewayRefundKey
+		return tx.Model(&refund).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &refund, fullyRefunded, nil
+}
+
+// SettleRefund records that a manual settlement refund was paid out
+func (r *refundRepository) SettleRefund(refundID uint, reference string) (*model.Refund, error) {
+	result := r.db.Model(&model.Refund{}).
+		Where("id = ? AND status = ?", refundID, model.RefundManualSettlement).
+		Updates(map[string]interface{}{
+			"status":               model.RefundCompleted,
+			"settlement_reference": reference,
+			"completed_at":         time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrRefundStatus
+	}
+	return r.GetRefundByID(refundID)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"learn/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SchedulerRunRepository interface {
+	CreateRun(run *model.SchedulerRun) error
+	FinishRun(run *model.SchedulerRun) error
+	GetLastRuns(jobNames []string) (map[string]model.SchedulerRun, error)
+	DeleteRunsBefore(before time.Time) (int64, error)
+}
+
+type schedulerRunRepository struct {
+	db *gorm.DB
+}
+
+func NewSchedulerRunRepository(db *gorm.DB) SchedulerRunRepository {
+	return &schedulerRunRepository{db: db}
+}
+
+func (r *schedulerRunRepository) CreateRun(run *model.SchedulerRun) error {
+	return r.db.Create(run).Error
+}
+
+func (r *schedulerRunRepository) FinishRun(run *model.SchedulerRun) error {
+	return r.db.Model(run).Updates(map[string]interface{}{
+		"status":      run.Status,
+		"finished_at": run.FinishedAt,
+		"duration_ms": run.DurationMs,
+		"result":      run.Result,
+		"error":       run.Error,
+	}).Error
+}
+
+// GetLastRuns returns the most recent run of each job, jobs that never ran are missing from the map
+func (r *schedulerRunRepository) GetLastRuns(jobNames []string) (map[string]model.SchedulerRun, error) {
+	var runs []model.SchedulerRun
+	err := r.db.Where("id IN (?)",
+		r.db.Model(&model.SchedulerRun{}).Select("MAX(id)").Where("job_name IN ?", jobNames).Group("job_name"),
+	).Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	lastRuns := make(map[string]model.SchedulerRun, len(runs))
+	for _, run := range runs {
+		lastRuns[run.JobName] = run
+	}
+	return lastRuns, nil
+}
+
+// DeleteRunsBefore removes finished runs started before the given time
+func (r *schedulerRunRepository) DeleteRunsBefore(before time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("started_at < ? AND status <> ?", before, model.SchedulerRunRunning).Delete(&model.SchedulerRun{})
+	return result.RowsAffected, result.Error
+}
@@ -1,15 +1,36 @@
 package repository
 
 import (
+	"errors"
 	"learn/internal/model"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// ErrTicketQRMismatch is returned when a correctly signed QR token no longer matches its ticket,
+// for example after the ticket was reissued
+var ErrTicketQRMismatch = errors.New("ticket QR code does not match the current ticket")
+
+// CheckInResult is the outcome of a scan. Rejection holds the rule that blocked the scan
+// and is empty when the scan was applied.
+type CheckInResult struct {
+	Ticket    *model.Ticket
+	Order     *model.Order
+	Rejection string
+}
+
 type TicketRepository interface {
 	CreateTickets(tickets []model.Ticket) error
-	CheckInTicketByCode(ticketCode string) (*model.Ticket, *model.Order, bool, error)
+	CountTicketsByOrderID(orderID uint) (int64, error)
+	CheckInTicketByCode(ticketCode string, scan *model.TicketScan) (*CheckInResult, error)
+	CheckInTicketByQR(ticketID uint, eventID uint, issuedAt time.Time, scan *model.TicketScan) (*CheckInResult, error)
+	CreateTicketScan(scan *model.TicketScan) error
+	GetTicketsByUserID(userID uint) ([]model.Ticket, error)
+	GetTicketByCodeForUser(ticketCode string, userID uint) (*model.Ticket, error)
+	UpdateQRCodePath(ticketID uint, qrCodePath string) error
+	GetPaidTicketsByEventID(eventID uint) ([]model.Ticket, error)
 }
 
 type ticketRepository struct {
@@ -24,15 +45,95 @@ func (r *ticketRepository) CreateTickets(tickets []model.Ticket) error {
 	return r.db.Create(&tickets).Error
 }
 
-func (r *ticketRepository) CheckInTicketByCode(ticketCode string) (*model.Ticket, *model.Order, bool, error) {
+func (r *ticketRepository) CountTicketsByOrderID(orderID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Ticket{}).Where("order_id = ?", orderID).Count(&count).Error
+	return count, err
+}
+
+// GetTicketsByUserID returns every ticket the user currently holds: tickets from their own
+// orders that were not transferred away, and tickets transferred to them
+func (r *ticketRepository) GetTicketsByUserID(userID uint) ([]model.Ticket, error) {
+	var tickets []model.Ticket
+	err := r.db.Joins("JOIN orders ON orders.id = tickets.order_id").
+		Where("COALESCE(tickets.holder_user_id, orders.user_id) = ?", userID).
+		Preload("EventPrice.Event").
+		Order("tickets.created_at ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+// GetTicketByCodeForUser returns gorm.ErrRecordNotFound when the ticket does not belong to the user,
+// so callers cannot tell someone else's ticket apart from a non-existent one
+func (r *ticketRepository) GetTicketByCodeForUser(ticketCode string, userID uint) (*model.Ticket, error) {
+	var ticket model.Ticket
+	err := r.db.Joins("JOIN orders ON orders.id = tickets.order_id").
+		Where("tickets.ticket_code = ? AND COALESCE(tickets.holder_user_id, orders.user_id) = ?", ticketCode, userID).
+		Preload("EventPrice.Event").
+		First(&ticket).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+func (r *ticketRepository) GetPaidTicketsByEventID(eventID uint) ([]model.Ticket, error) {
+	var tickets []model.Ticket
+	err := r.db.Joins("JOIN event_prices ON event_prices.id = tickets.event_price_id").
+		Joins("JOIN orders ON orders.id = tickets.order_id").
+		Where("event_prices.event_id = ? AND orders.status = ? AND tickets.voided_at IS NULL", eventID, model.OrderPaid).
+		Order("tickets.id ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+func (r *ticketRepository) UpdateQRCodePath(ticketID uint, qrCodePath string) error {
+	return r.db.Model(&model.Ticket{}).Where("id = ?", ticketID).Update("qr_code_path", qrCodePath).Error
+}
+
+func (r *ticketRepository) CheckInTicketByCode(ticketCode string, scan *model.TicketScan) (*CheckInResult, error) {
+	return r.checkIn(scan, func(tx *gorm.DB, ticket *model.Ticket) error {
+		return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("ticket_code = ?", ticketCode).
+			First(ticket).Error
+	})
+}
+
+// CheckInTicketByQR scans the ticket referenced by a verified QR token. The token must
+// still match the ticket's event and current QR issue time, otherwise ErrTicketQRMismatch is returned.
+func (r *ticketRepository) CheckInTicketByQR(ticketID uint, eventID uint, issuedAt time.Time, scan *model.TicketScan) (*CheckInResult, error) {
+	return r.checkIn(scan, func(tx *gorm.DB, ticket *model.Ticket) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(ticket, ticketID).Error; err != nil {
+			return err
+		}
+
+		var price model.EventPrice
+		if err := tx.Select("event_id").First(&price, ticket.EventPriceID).Error; err != nil {
+			return err
+		}
+
+		if price.EventID != eventID || ticket.QrIssuedAt.Unix() != issuedAt.Unix() {
+			return ErrTicketQRMismatch
+		}
+		return nil
+	})
+}
+
+func (r *ticketRepository) CreateTicketScan(scan *model.TicketScan) error {
+	return r.db.Create(scan).Error
+}
+
+// checkIn locks the ticket selected by lockTicket together with its order, applies the scan
+// when the event's re-entry policy allows it and records the attempt in ticket_scans
+func (r *ticketRepository) checkIn(scan *model.TicketScan, lockTicket func(tx *gorm.DB, ticket *model.Ticket) error) (*CheckInResult, error) {
 	var ticket model.Ticket
 	var order model.Order
-	checkedIn := false
+	var event model.Event
+	var rejection string
 
 	err := r.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("ticket_code = ?", ticketCode).
-			First(&ticket).Error; err != nil {
+		if err := lockTicket(tx, &ticket); err != nil {
 			return err
 		}
 
@@ -41,21 +142,48 @@ func (r *ticketRepository) CheckInTicketByCode(ticketCode string) (*model.Ticket
 			return err
 		}
 
-		if order.Status != model.OrderPaid || ticket.IsScanned {
-			return nil
+		if err := tx.Select("events.id, events.allow_reentry").
+			Joins("JOIN event_prices ON event_prices.event_id = events.id").
+			Where("event_prices.id = ?", ticket.EventPriceID).
+			First(&event).Error; err != nil {
+			return err
+		}
+
+		// Entering is refused while a refund holds the ticket, so a refunded ticket is never used
+		refundPending := false
+		if scan.Direction == model.ScanIn {
+			open, err := openRefundTicketIDs(tx, []uint{ticket.ID})
+			if err != nil {
+				return err
+			}
+			refundPending = len(open) > 0
 		}
 
-		if err := tx.Model(&ticket).Update("is_scanned", true).Error; err != nil {
-			return err
+		rejection = model.ScanRejection(ticket, order.Status, scan.Direction, event.AllowReentry, refundPending)
+		if rejection == "" {
+			updates := map[string]interface{}{"is_inside": scan.Direction == model.ScanIn}
+			if scan.Direction == model.ScanIn {
+				updates["is_scanned"] = true
+			}
+			if err := tx.Model(&ticket).Updates(updates).Error; err != nil {
+				return err
+			}
+			ticket.IsInside = scan.Direction == model.ScanIn
+			ticket.IsScanned = ticket.IsScanned || ticket.IsInside
 		}
 
-		ticket.IsScanned = true
-		checkedIn = true
-		return nil
+		scan.TicketID = &ticket.ID
+		scan.EventID = &event.ID
+		scan.Result = model.ScanAccepted
+		scan.Reason = rejection
+		if rejection != "" {
+			scan.Result = model.ScanRejected
+		}
+		return tx.Create(scan).Error
 	})
 	if err != nil {
-		return nil, nil, false, err
+		return nil, err
 	}
 
-	return &ticket, &order, checkedIn, nil
+	return &CheckInResult{Ticket: &ticket, Order: &order, Rejection: rejection}, nil
 }
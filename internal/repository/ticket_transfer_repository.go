@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"errors"
+	"learn/internal/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrTicketTransferPending is returned when the ticket already has an open transfer
+	ErrTicketTransferPending = errors.New("ticket already has a pending transfer")
+	// ErrTicketTransferNotPending is returned when the transfer was already accepted or cancelled
+	ErrTicketTransferNotPending = errors.New("ticket transfer is no longer pending")
+	// ErrTicketTransferStale is returned when the ticket changed hands or was used after the transfer was offered
+	ErrTicketTransferStale = errors.New("ticket can no longer be transferred by the sender")
+	// ErrTicketRefundPending is returned when the ticket is part of a requested or processing refund
+	ErrTicketRefundPending = errors.New("ticket has a pending refund")
+)
+
+// TicketReissue holds the new identity given to a ticket when it changes hands
+type TicketReissue struct {
+	TicketCode string
+	QrIssuedAt time.Time
+}
+
+type TicketTransferRepository interface {
+	CreateTransfer(transfer *model.TicketTransfer) error
+	GetPendingTransferByTicketID(ticketID uint) (*model.TicketTransfer, error)
+	GetPendingTransfersByEmail(email string) ([]model.TicketTransfer, error)
+	GetTransferByID(transferID uint) (*model.TicketTransfer, error)
+	CancelTransfer(transferID uint) error
+	AcceptTransfer(transferID uint, recipient model.User, reissue TicketReissue) (*model.TicketTransfer, *model.Ticket, error)
+}
+
+type ticketTransferRepository struct {
+	db *gorm.DB
+}
+
+func NewTicketTransferRepository(db *gorm.DB) TicketTransferRepository {
+	return &ticketTransferRepository{db: db}
+}
+
+// CreateTransfer stores a pending transfer while holding the ticket lock, so a ticket
+// never has more than one pending transfer
+func (r *ticketTransferRepository) CreateTransfer(transfer *model.TicketTransfer) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var ticket model.Ticket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&ticket, transfer.TicketID).Error; err != nil {
+			return err
+		}
+
+		var pending int64
+		if err := tx.Model(&model.TicketTransfer{}).
+			Where("ticket_id = ? AND status = ?", transfer.TicketID, model.TransferPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrTicketTransferPending
+		}
+
+		openRefunds, err := openRefundTicketIDs(tx, []uint{ticket.ID})
+		if err != nil {
+			return err
+		}
+		if len(openRefunds) > 0 {
+			return ErrTicketRefundPending
+		}
+
+		transfer.Status = model.TransferPending
+		return tx.Create(transfer).Error
+	})
+}
+
+func (r *ticketTransferRepository) GetPendingTransferByTicketID(ticketID uint) (*model.TicketTransfer, error) {
+	var transfer model.TicketTransfer
+	err := r.db.Where("ticket_id = ? AND status = ?", ticketID, model.TransferPending).
+		First(&transfer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *ticketTransferRepository) GetPendingTransfersByEmail(email string) ([]model.TicketTransfer, error) {
+	var transfers []model.TicketTransfer
+	err := r.db.Where("LOWER(to_email) = ? AND status = ?", strings.ToLower(email), model.TransferPending).
+		Preload("Ticket.EventPrice.Event").
+		Order("created_at DESC").
+		Find(&transfers).Error
+	return transfers, err
+}
+
+func (r *ticketTransferRepository) GetTransferByID(transferID uint) (*model.TicketTransfer, error) {
+	var transfer model.TicketTransfer
+	err := r.db.Preload("Ticket.EventPrice.Event").First(&transfer, transferID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *ticketTransferRepository) CancelTransfer(transferID uint) error {
+	result := r.db.Model(&model.TicketTransfer{}).
+		Where("id = ? AND status = ?", transferID, model.TransferPending).
+		Update("status", model.TransferCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTicketTransferNotPending
+	}
+	return nil
+}
+
+// AcceptTransfer hands the ticket over to the recipient and reissues its code and QR,
+// which invalidates everything the sender still has
+func (r *ticketTransferRepository) AcceptTransfer(transferID uint, recipient model.User, reissue TicketReissue) (*model.TicketTransfer, *model.Ticket, error) {
+	var transfer model.TicketTransfer
+	var ticket model.Ticket
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&transfer, transferID).Error; err != nil {
+			return err
+		}
+		if transfer.Status != model.TransferPending {
+			return ErrTicketTransferNotPending
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&ticket, transfer.TicketID).Error; err != nil {
+			return err
+		}
+
+		var order model.Order
+		if err := tx.Select("id, user_id").First(&order, ticket.OrderID).Error; err != nil {
+			return err
+		}
+
+		holderID := order.UserID
+		if ticket.HolderUserID != nil {
+			holderID = *ticket.HolderUserID
+		}
+		if holderID != transfer.FromUserID || ticket.IsScanned || ticket.VoidedAt != nil {
+			return ErrTicketTransferStale
+		}
+
+		openRefunds, err := openRefundTicketIDs(tx, []uint{ticket.ID})
+		if err != nil {
+			return err
+		}
+		if len(openRefunds) > 0 {
+			return ErrTicketRefundPending
+		}
+
+		ticket.HolderUserID = &recipient.ID
+		ticket.OwnerName = recipient.Name
+		ticket.OwnerEmail = recipient.Email
+		ticket.TicketCode = reissue.TicketCode
+		ticket.QrIssuedAt = reissue.QrIssuedAt
+		ticket.QrCodePath = ""
+		if err := tx.Model(&ticket).Updates(map[string]interface{}{
+			"holder_user_id": recipient.ID,
+			"owner_name":     recipient.Name,
+			"owner_email":    recipient.Email,
+			"ticket_code":    reissue.TicketCode,
+			"qr_issued_at":   reissue.QrIssuedAt,
+			"qr_code_path":   "",
+		}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transfer.Status = model.TransferAccepted
+		transfer.ToUserID = &recipient.ID
+		transfer.AcceptedAt = &now
+		return tx.Model(&transfer).Updates(map[string]interface{}{
+			"status":      model.TransferAccepted,
+			"to_user_id":  recipient.ID,
+			"accepted_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &transfer, &ticket, nil
+}
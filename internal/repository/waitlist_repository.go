@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"learn/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrWaitlistEntryExists is returned when the user already waits for, or holds an offer of, the tier
+	ErrWaitlistEntryExists = errors.New("user is already on the waitlist of this price tier")
+	// ErrWaitlistNotSoldOut is returned when the tier still has enough quota for the requested quantity
+	ErrWaitlistNotSoldOut = errors.New("price tier is not sold out")
+	// ErrWaitlistEntryStatus is returned when the entry is no longer waiting or offered
+	ErrWaitlistEntryStatus = errors.New("waitlist entry is not active")
+)
+
+// WaitlistTierStats holds the waitlist counts of a single EventPrice tier
+type WaitlistTierStats struct {
+	EventPriceID    uint
+	Name            string
+	Waiting         int64
+	WaitingQuantity int64
+	Offered         int64 // Open offers
+	Converted       int64
+	Expired         int64
+	Left            int64
+}
+
+type WaitlistRepository interface {
+	CreateEntry(ctx context.Context, entry *model.WaitlistEntry) error
+	GetEntryByID(entryID uint) (*model.WaitlistEntry, error)
+	GetUserEntries(userID uint) ([]model.WaitlistEntry, error)
+	GetOpenOffers(userID uint, priceIDs []uint, now time.Time) ([]model.WaitlistEntry, error)
+	LeaveEntry(ctx context.Context, entryID uint) (*model.WaitlistEntry, error)
+	GetPriceIDsToOffer(now time.Time) ([]uint, error)
+	OfferQuota(ctx context.Context, eventPriceID uint, now time.Time, offerExpiresAt time.Time) ([]model.WaitlistEntry, error)
+	GetTierStats(eventID uint) ([]WaitlistTierStats, error)
+}
+
+type waitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) WaitlistRepository {
+	return &waitlistRepository{db: db}
+}
+
+// CreateEntry adds the user to the back of the waitlist while the tier cannot sell entry.Quantity
+func (r *waitlistRepository) CreateEntry(ctx context.Context, entry *model.WaitlistEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var price model.EventPrice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&price, entry.EventPriceID).Error; err != nil {
+			return err
+		}
+		if price.Quota-price.WaitlistQuota >= entry.Quantity {
+			return ErrWaitlistNotSoldOut
+		}
+
+		var active int64
+		if err := tx.Model(&model.WaitlistEntry{}).
+			Where("event_price_id = ? AND user_id = ? AND status IN ?", entry.EventPriceID, entry.UserID,
+				[]model.WaitlistStatus{model.WaitlistWaiting, model.WaitlistOffered}).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrWaitlistEntryExists
+		}
+
+		entry.Status = model.WaitlistWaiting
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *waitlistRepository) GetEntryByID(entryID uint) (*model.WaitlistEntry, error) {
+	var entry model.WaitlistEntry
+	if err := r.db.Preload("EventPrice.Event").First(&entry, entryID).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *waitlistRepository) GetUserEntries(userID uint) ([]model.WaitlistEntry, error) {
+	var entries []model.WaitlistEntry
+	err := r.db.Preload("EventPrice.Event").Where("user_id = ?", userID).Order("id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// GetOpenOffers returns the offers of the user for the given tiers that have not expired at now
+func (r *waitlistRepository) GetOpenOffers(userID uint, priceIDs []uint, now time.Time) ([]model.WaitlistEntry, error) {
+	var entries []model.WaitlistEntry
+	err := r.db.Where("user_id = ? AND event_price_id IN ? AND status = ? AND offer_expires_at > ?",
+		userID, priceIDs, model.WaitlistOffered, now).Find(&entries).Error
+	return entries, err
+}
+
+// LeaveEntry takes the entry off the waitlist. The quota of an open offer stays set aside and is
+// offered to the next users by OfferQuota.
+func (r *waitlistRepository) LeaveEntry(ctx context.Context, entryID uint) (*model.WaitlistEntry, error) {
+	var entry model.WaitlistEntry
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.WaitlistEntry{}).
+			Where("id = ? AND status IN ?", entryID, []model.WaitlistStatus{model.WaitlistWaiting, model.WaitlistOffered}).
+			Update("status", model.WaitlistLeft)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWaitlistEntryStatus
+		}
+		return tx.First(&entry, entryID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetPriceIDsToOffer returns the tiers of events on sale at now with quota set aside for the
+// waitlist, or with unsold quota and users waiting
+func (r *waitlistRepository) GetPriceIDsToOffer(now time.Time) ([]uint, error) {
+	var priceIDs []uint
+	err := r.db.Model(&model.EventPrice{}).
+		Joins("JOIN events ON events.id = event_prices.event_id AND events.deleted_at IS NULL").
+		Where("events.status = ? AND events.sales_end_date > ?", model.Published, now).
+		Where(`(event_prices.waitlist_quota > 0 OR (event_prices.quota > event_prices.waitlist_quota AND EXISTS (
+			SELECT 1 FROM waitlist_entries WHERE waitlist_entries.event_price_id = event_prices.id
+			AND waitlist_entries.status = ? AND waitlist_entries.deleted_at IS NULL)))`, model.WaitlistWaiting).
+		Pluck("event_prices.id", &priceIDs).Error
+	return priceIDs, err
+}
+
+// OfferQuota expires the unused offers of the tier and offers its unsold quota to the waiting users
+// in order, skipping those who asked for more than is left. Quota nobody on the list can use goes
+// back on sale, so afterwards the tier only sets aside the quota of its open offers. The new offers
+// are returned with their user, price and event.
+func (r *waitlistRepository) OfferQuota(ctx context.Context, eventPriceID uint, now time.Time, offerExpiresAt time.Time) ([]model.WaitlistEntry, error) {
+	var offered []model.WaitlistEntry
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var price model.EventPrice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&price, eventPriceID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.WaitlistEntry{}).
+			Where("event_price_id = ? AND status = ? AND offer_expires_at <= ?", eventPriceID, model.WaitlistOffered, now).
+			Update("status", model.WaitlistExpired).Error; err != nil {
+			return err
+		}
+
+		var openQuantity int64
+		if err := tx.Model(&model.WaitlistEntry{}).
+			Where("event_price_id = ? AND status = ?", eventPriceID, model.WaitlistOffered).
+			Select("COALESCE(SUM(quantity), 0)").Scan(&openQuantity).Error; err != nil {
+			return err
+		}
+
+		var waiting []model.WaitlistEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("event_price_id = ? AND status = ?", eventPriceID, model.WaitlistWaiting).
+			Order("id").Find(&waiting).Error; err != nil {
+			return err
+		}
+
+		// Users on the list are served before anyone else, so all unsold quota is theirs
+		free := price.Quota - int(openQuantity)
+		if len(waiting) == 0 {
+			free = 0
+		}
+		setAside := int(openQuantity)
+		for _, entry := range waiting {
+			if entry.Quantity > free {
+				continue
+			}
+			if err := tx.Model(&model.WaitlistEntry{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+				"status":           model.WaitlistOffered,
+				"offered_at":       now,
+				"offer_expires_at": offerExpiresAt,
+			}).Error; err != nil {
+				return err
+			}
+			free -= entry.Quantity
+			setAside += entry.Quantity
+			offered = append(offered, entry)
+		}
+
+		if setAside != price.WaitlistQuota {
+			if err := tx.Model(&model.EventPrice{}).Where("id = ?", eventPriceID).
+				UpdateColumn("waitlist_quota", setAside).Error; err != nil {
+				return err
+			}
+		}
+		if len(offered) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(offered))
+		for i, entry := range offered {
+			ids[i] = entry.ID
+		}
+		return tx.Preload("User").Preload("EventPrice.Event").Where("id IN ?", ids).Order("id").Find(&offered).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offered, nil
+}
+
+// GetTierStats counts the waitlist entries of every tier of an event by status
+func (r *waitlistRepository) GetTierStats(eventID uint) ([]WaitlistTierStats, error) {
+	var tiers []WaitlistTierStats
+	err := r.db.Table("event_prices").
+		Select(`event_prices.id AS event_price_id, event_prices.name,
+			COUNT(waitlist_entries.id) FILTER (WHERE waitlist_entries.status = ?) AS waiting,
+			COALESCE(SUM(waitlist_entries.quantity) FILTER (WHERE waitlist_entries.status = ?), 0) AS waiting_quantity,
+			COUNT(waitlist_entries.id) FILTER (WHERE waitlist_entries.status = ?) AS offered,
+			COUNT(waitlist_entries.id) FILTER (WHERE waitlist_entries.status = ?) AS converted,
+			COUNT(waitlist_entries.id) FILTER (WHERE waitlist_entries.status = ?) AS expired,
+			COUNT(waitlist_entries.id) FILTER (WHERE waitlist_entries.status = ?) AS "left"`,
+			model.WaitlistWaiting, model.WaitlistWaiting, model.WaitlistOffered,
+			model.WaitlistConverted, model.WaitlistExpired, model.WaitlistLeft).
+		Joins("LEFT JOIN waitlist_entries ON waitlist_entries.event_price_id = event_prices.id AND waitlist_entries.deleted_at IS NULL").
+		Where("event_prices.event_id = ? AND event_prices.deleted_at IS NULL", eventID).
+		Group("event_prices.id, event_prices.name").
+		Order("event_prices.id").
+		Scan(&tiers).Error
+	return tiers, err
+}
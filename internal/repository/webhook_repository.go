@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"learn/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookAttempt is the outcome of sending a delivery once
+type WebhookAttempt struct {
+	StatusCode int
+	Response   string
+	Error      string // Empty when the endpoint answered with a 2xx status
+	At         time.Time
+}
+
+type WebhookRepository interface {
+	CreateEndpoint(endpoint *model.WebhookEndpoint) error
+	GetEndpointsByUserID(userID uint) ([]model.WebhookEndpoint, error)
+	GetEndpointForUser(endpointID uint, userID uint) (*model.WebhookEndpoint, error)
+	UpdateEndpoint(endpoint *model.WebhookEndpoint) error
+	DeleteEndpoint(endpointID uint) error
+	GetActiveEndpointsFor(eventType model.WebhookEventType, eventID uint) ([]model.WebhookEndpoint, error)
+	CreateDeliveries(deliveries []model.WebhookDelivery) error
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]model.WebhookDelivery, error)
+	RecordAttempt(delivery *model.WebhookDelivery, attempt WebhookAttempt, nextAttemptAt *time.Time, disableAfter int) (bool, error)
+	GetDeliveryForEndpoint(deliveryID uint, endpointID uint) (*model.WebhookDelivery, error)
+	ResetDelivery(deliveryID uint) error
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateEndpoint(endpoint *model.WebhookEndpoint) error {
+	return r.db.Create(endpoint).Error
+}
+
+func (r *webhookRepository) GetEndpointsByUserID(userID uint) ([]model.WebhookEndpoint, error) {
+	var endpoints []model.WebhookEndpoint
+	err := r.db.Preload("Event").Where("user_id = ?", userID).Order("id ASC").Find(&endpoints).Error
+	return endpoints, err
+}
+
+// GetEndpointForUser returns gorm.ErrRecordNotFound for endpoints of other users
+func (r *webhookRepository) GetEndpointForUser(endpointID uint, userID uint) (*model.WebhookEndpoint, error) {
+	var endpoint model.WebhookEndpoint
+	if err := r.db.Preload("Event").Where("user_id = ?", userID).First(&endpoint, endpointID).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *webhookRepository) UpdateEndpoint(endpoint *model.WebhookEndpoint) error {
+	return r.db.Omit("User", "Event").Save(endpoint).Error
+}
+
+func (r *webhookRepository) DeleteEndpoint(endpointID uint) error {
+	return r.db.Delete(&model.WebhookEndpoint{}, endpointID).Error
+}
+
+// GetActiveEndpointsFor returns the enabled endpoints subscribed to the event type, for every
+// event or for the given one
+func (r *webhookRepository) GetActiveEndpointsFor(eventType model.WebhookEventType, eventID uint) ([]model.WebhookEndpoint, error) {
+	var endpoints []model.WebhookEndpoint
+	err := r.db.Where("active = ?", true).
+		Where("(',' || event_types || ',') LIKE ?", "%,"+string(eventType)+",%").
+		Where("event_id IS NULL OR event_id = ?", eventID).
+		Find(&endpoints).Error
+	return endpoints, err
+}
+
+// CreateDeliveries skips deliveries that already exist for the same endpoint and event key
+func (r *webhookRepository) CreateDeliveries(deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+// ClaimDueDeliveries returns pending deliveries of enabled endpoints that are due, oldest first, and
+// pushes their next attempt back by lease so concurrent dispatchers skip them while they are sent
+func (r *webhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	var ids []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&model.WebhookDelivery{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED", Table: clause.Table{Name: "webhook_deliveries"}}).
+			Joins("JOIN webhook_endpoints ON webhook_endpoints.id = webhook_deliveries.endpoint_id AND webhook_endpoints.deleted_at IS NULL").
+			Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ? AND webhook_endpoints.active = ?", model.WebhookDeliveryPending, now, true).
+			Order("webhook_deliveries.next_attempt_at ASC").
+			Limit(limit).
+			Pluck("webhook_deliveries.id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&model.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	err = r.db.Preload("Endpoint").Where("id IN ?", ids).Order("id ASC").Find(&deliveries).Error
+	return deliveries, err
+}
+
+// RecordAttempt stores the outcome of an attempt. A failed attempt is retried at nextAttemptAt, or
+// marks the delivery FAILED when nextAttemptAt is nil. The endpoint counts consecutive failures and
+// is disabled once they reach disableAfter; the bool reports whether this attempt disabled it.
+func (r *webhookRepository) RecordAttempt(delivery *model.WebhookDelivery, attempt WebhookAttempt, nextAttemptAt *time.Time, disableAfter int) (bool, error) {
+	disabled := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_attempt_at":  attempt.At,
+			"last_status_code": attempt.StatusCode,
+			"last_error":       attempt.Error,
+			"last_response":    attempt.Response,
+		}
+		switch {
+		case attempt.Error == "":
+			updates["status"] = model.WebhookDeliveryDelivered
+			updates["delivered_at"] = attempt.At
+		case nextAttemptAt != nil:
+			updates["next_attempt_at"] = *nextAttemptAt
+		default:
+			updates["status"] = model.WebhookDeliveryFailed
+		}
+		if err := tx.Model(&model.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if attempt.Error == "" {
+			return tx.Model(&model.WebhookEndpoint{}).Where("id = ?", delivery.EndpointID).Update("consecutive_failures", 0).Error
+		}
+
+		var endpoint model.WebhookEndpoint
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&endpoint, delivery.EndpointID).Error; err != nil {
+			return err
+		}
+		disabled = endpoint.RecordFailure(attempt.At, attempt.Error, disableAfter)
+		endpointUpdates := map[string]interface{}{"consecutive_failures": endpoint.ConsecutiveFailures}
+		if disabled {
+			endpointUpdates["active"] = false
+			endpointUpdates["disabled_at"] = endpoint.DisabledAt
+			endpointUpdates["disabled_reason"] = endpoint.DisabledReason
+		}
+		return tx.Model(&endpoint).Updates(endpointUpdates).Error
+	})
+	return disabled, err
+}
+
+func (r *webhookRepository) GetDeliveryForEndpoint(deliveryID uint, endpointID uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := r.db.Where("endpoint_id = ?", endpointID).First(&delivery, deliveryID).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ResetDelivery queues a delivery again with a fresh attempt count, whatever its status
+func (r *webhookRepository) ResetDelivery(deliveryID uint) error {
+	return r.db.Model(&model.WebhookDelivery{}).Where("id = ?", deliveryID).Updates(map[string]interface{}{
+		"status":          model.WebhookDeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	}).Error
+}
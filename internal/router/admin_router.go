@@ -4,6 +4,9 @@ import (
 	"learn/internal/controller"
 	"learn/internal/middleware"
 	"learn/internal/model"
+	"learn/internal/pkg/cron"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/queue"
 	"learn/internal/repository"
 	"learn/internal/service"
 	"log/slog"
@@ -12,11 +15,16 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupAdminRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
+func SetupAdminRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, eventBus events.Bus, jobQueue *queue.JobQueue, cronScheduler *cron.Scheduler) {
 	userRepo := repository.NewUserRepository(db)
 	emailService := service.NewEmailService(logger)
 	adminService := service.NewAdminService(userRepo, emailService, logger)
 	adminController := controller.NewAdminController(adminService, logger, db)
+	outboxService := service.NewOutboxService(repository.NewOutboxRepository(db), logger)
+	outboxController := controller.NewOutboxController(outboxService, logger)
+	failedJobController := controller.NewFailedJobController(service.NewFailedJobService(jobQueue, logger), logger)
+	schedulerService := service.NewSchedulerService(cronScheduler, repository.NewSchedulerRunRepository(db), logger)
+	schedulerController := controller.NewSchedulerController(schedulerService, logger, db)
 
 	adminRoutes := rg.Group("/admin")
 	adminRoutes.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(model.Administrator))
@@ -27,5 +35,21 @@ func SetupAdminRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
 		adminRoutes.POST("/users/unblock", adminController.UnblockUser)
 		adminRoutes.POST("/users/delete", adminController.DeleteUser)
 		adminRoutes.GET("/users", adminController.ListUsers)
+		adminRoutes.GET("/outbox/stats", outboxController.GetStats)
+		adminRoutes.GET("/jobs/stats", failedJobController.GetStats)
+		adminRoutes.GET("/jobs/failed", failedJobController.ListFailedJobs)
+		adminRoutes.POST("/jobs/failed/:id/retry", failedJobController.RetryFailedJob)
+		adminRoutes.DELETE("/jobs/failed/:id", failedJobController.DeleteFailedJob)
+		adminRoutes.GET("/scheduler/jobs", schedulerController.ListJobs)
+		adminRoutes.POST("/scheduler/jobs/:name/trigger", schedulerController.TriggerJob)
+		adminRoutes.GET("/scheduler/jobs/:name/runs", schedulerController.GetRuns)
+	}
+
+	// Dead letters only exist on the Redis Streams bus
+	if redisBus, ok := eventBus.(*events.EventBus); ok {
+		deadLetterController := controller.NewDeadLetterController(service.NewDeadLetterService(redisBus, logger), logger)
+		adminRoutes.GET("/events/dead-letters", deadLetterController.ListDeadLetters)
+		adminRoutes.POST("/events/dead-letters/:id/replay", deadLetterController.ReplayDeadLetter)
+		adminRoutes.DELETE("/events/dead-letters/:id", deadLetterController.DiscardDeadLetter)
 	}
 }
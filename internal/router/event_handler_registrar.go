@@ -7,9 +7,10 @@ import (
 )
 
 // RegisterEventHandlersWithRepos registers all event handlers to the event bus with provided repositories
-func RegisterEventHandlersWithRepos(eventBus *events.EventBus, orderRepo repository.OrderRepository,
+func RegisterEventHandlersWithRepos(eventBus events.EventSubscriber, orderRepo repository.OrderRepository,
 	paymentRepo repository.PaymentRepository, ticketRepo repository.TicketRepository,
-	eventRepo repository.EventRepository, logger *slog.Logger) {
+	eventRepo repository.EventRepository, webhookRepo repository.WebhookRepository,
+	logger *slog.Logger) {
 
 	// Register OrderPaidEvent handler
 	orderPaidHandler := events.NewOrderPaidEventHandler(orderRepo, logger)
@@ -28,4 +29,10 @@ func RegisterEventHandlersWithRepos(eventBus *events.EventBus, orderRepo reposit
 		logger,
 	)
 	eventBus.Subscribe("payment.status.updated", paymentStatusHandler, events.PaymentStatusUpdatedEvent{})
+
+	// Register organizer webhook handler, deliveries are sent by the webhook dispatcher
+	webhookHandler := events.NewWebhookEventHandler(webhookRepo, orderRepo, eventRepo, logger)
+	eventBus.Subscribe("payment.status.updated", webhookHandler, events.PaymentStatusUpdatedEvent{})
+	eventBus.Subscribe("ticket.scanned", webhookHandler, events.TicketScannedEvent{})
+	eventBus.Subscribe("event.cancelled", webhookHandler, events.EventCancelledEvent{})
 }
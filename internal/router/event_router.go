@@ -1,9 +1,14 @@
 package router
 
 import (
+	"learn/internal/config"
 	"learn/internal/controller"
+	"learn/internal/gateway"
 	"learn/internal/middleware"
 	"learn/internal/model"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/queue"
+	"learn/internal/pkg/waitingroom"
 	"learn/internal/repository"
 	"learn/internal/service"
 	"log/slog"
@@ -12,12 +17,41 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupEventRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
+// NewEventCancellationService wires the cancellation service for the event routes and the job handlers
+func NewEventCancellationService(db *gorm.DB, logger *slog.Logger, eventBus events.EventPublisher, jobQueue *queue.JobQueue, gateways *gateway.Registry) service.EventCancellationService {
+	orderRepo := repository.NewOrderRepository(db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	refundService := service.NewRefundService(refundRepo, orderRepo, paymentRepo, logger, eventBus, gateways)
+	return service.NewEventCancellationService(
+		repository.NewEventCancellationRepository(db),
+		repository.NewEventRepository(db),
+		orderRepo,
+		service.NewOrderStateMachine(orderRepo, logger),
+		paymentRepo,
+		refundRepo,
+		refundService,
+		service.NewEmailService(logger),
+		jobQueue,
+		logger,
+		eventBus,
+	)
+}
+
+func SetupEventRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, eventBus events.Bus, jobQueue *queue.JobQueue, gateways *gateway.Registry, attendanceHub *events.AttendanceHub) {
 	eventRepo := repository.NewEventRepository(db)
 	venueRepo := repository.NewVenueRepository(db)
 	guestRepo := repository.NewGuestRepository(db)
-	eventService := service.NewEventService(eventRepo, venueRepo, guestRepo, logger)
+	cancellationService := NewEventCancellationService(db, logger, eventBus, jobQueue, gateways)
+	cancellationController := controller.NewEventCancellationController(cancellationService, logger)
+	eventService := service.NewEventService(eventRepo, venueRepo, guestRepo, cancellationService, logger)
 	eventController := controller.NewEventController(eventService, logger, db)
+	attendanceRepo := repository.NewAttendanceRepository(db)
+	attendanceService := service.NewAttendanceService(attendanceRepo, eventRepo, logger)
+	attendanceController := controller.NewAttendanceController(attendanceService, attendanceHub, logger)
+	waitingRoomService := service.NewWaitingRoomService(eventRepo, waitingroom.NewRoom(config.Rdb), logger)
+	waitingRoomController := controller.NewWaitingRoomController(waitingRoomService, logger)
+	waitlistController := controller.NewWaitlistController(NewWaitlistService(db, logger), logger)
 
 	eventRoutes := rg.Group("/events")
 	{
@@ -30,6 +64,13 @@ func SetupEventRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
 		{
 			authenticated.POST("/", middleware.RoleMiddleware(model.Administrator, model.Organizer), eventController.CreateEvent)
 			authenticated.PATCH("/:slug", middleware.RoleMiddleware(model.Administrator, model.Organizer), eventController.UpdateEvent)
+			authenticated.GET("/:slug/attendance", middleware.RoleMiddleware(model.Administrator, model.Organizer), attendanceController.GetAttendance)
+			authenticated.GET("/:slug/attendance/stream", middleware.RoleMiddleware(model.Administrator, model.Organizer), attendanceController.StreamAttendance)
+			authenticated.GET("/:slug/cancellation", middleware.RoleMiddleware(model.Administrator, model.Organizer), cancellationController.GetCancellation)
+			authenticated.POST("/:slug/cancellation/resume", middleware.RoleMiddleware(model.Administrator, model.Organizer), cancellationController.ResumeCancellation)
+			authenticated.GET("/:slug/waitlist", middleware.RoleMiddleware(model.Administrator, model.Organizer), waitlistController.GetSummary)
+			authenticated.POST("/:slug/waiting-room/join", middleware.RoleMiddleware(model.Attendee), waitingRoomController.Join)
+			authenticated.GET("/:slug/waiting-room", middleware.RoleMiddleware(model.Attendee), waitingRoomController.GetStatus)
 		}
 	}
 }
@@ -19,7 +19,7 @@ func SetupGuestRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
 
 	eventRepo := repository.NewEventRepository(db)
 	venueRepo := repository.NewVenueRepository(db)
-	eventService := service.NewEventService(eventRepo, venueRepo, guestRepo, logger)
+	eventService := service.NewEventService(eventRepo, venueRepo, guestRepo, nil, logger)
 	eventController := controller.NewEventController(eventService, logger, db)
 
 	guestRoutes := rg.Group("/guests")
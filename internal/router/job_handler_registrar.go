@@ -0,0 +1,25 @@
+package router
+
+import (
+	"context"
+	"learn/internal/gateway"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/queue"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// RegisterJobHandlers registers the handler of every job type. It must run before the queue is
+// started, a job without a handler goes straight to the failed jobs store.
+func RegisterJobHandlers(jobQueue *queue.JobQueue, db *gorm.DB, logger *slog.Logger, eventBus events.EventPublisher, gateways *gateway.Registry) {
+	// Register event cancellation handler
+	cancellationService := NewEventCancellationService(db, logger, eventBus, jobQueue, gateways)
+	jobQueue.Register(queue.JobEventCancellation, func(ctx context.Context, job queue.Job) error {
+		var payload queue.EventCancellationPayload
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		return cancellationService.ProcessCancellation(ctx, payload.CancellationID)
+	})
+}
@@ -15,19 +15,24 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupOrderRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, eventBus *events.EventBus) {
+func SetupOrderRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, eventBus events.Bus) {
 	orderRepo := repository.NewOrderRepository(db)
-	orderService := service.NewOrderService(orderRepo, logger, eventBus)
-	orderController := controller.NewOrderController(orderService, logger)
+	paymentRepo := repository.NewPaymentRepository(db)
+	waitlistRepo := repository.NewWaitlistRepository(db)
+	orderService := service.NewOrderService(orderRepo, paymentRepo, waitlistRepo, logger)
+	orderController := controller.NewOrderController(orderService, logger, db)
 
 	// Order cancellation service and controller
-	orderCancellationService := service.NewOrderCancellationService(orderRepo, logger, eventBus)
+	orderCancellationService := service.NewOrderCancellationService(orderRepo, service.NewOrderStateMachine(orderRepo, logger), logger)
 	orderCancellationController := controller.NewOrderCancellationController(orderCancellationService, logger)
 
 	orderRoutes := rg.Group("/orders")
 	orderRoutes.Use(middleware.AuthMiddleware())
 	{
+		orderRoutes.GET("/", middleware.RoleMiddleware(model.Attendee), orderController.GetMyOrders)
+		orderRoutes.GET("/:id", middleware.RoleMiddleware(model.Attendee), orderController.GetOrderByID)
 		orderRoutes.POST("/", middleware.RoleMiddleware(model.Attendee), ratelimiter.Limit("order_create", 10, time.Minute), orderController.CreateOrder)
+		orderRoutes.POST("/:id/hold/extend", middleware.RoleMiddleware(model.Attendee), orderController.ExtendHold)
 		orderRoutes.DELETE("/:id", middleware.RoleMiddleware(model.Attendee), orderCancellationController.CancelOrder)
 	}
 }
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"learn/internal/controller"
+	"learn/internal/gateway"
+	"learn/internal/middleware"
+	"learn/internal/model"
+	"learn/internal/pkg/ratelimiter"
+	"learn/internal/repository"
+	"learn/internal/service"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NewPaymentReconciliationService wires the reconciler for the admin routes, the scheduler and the reconcile command
+func NewPaymentReconciliationService(db *gorm.DB, logger *slog.Logger, gateways *gateway.Registry) service.PaymentReconciliationService {
+	paymentRepository := repository.NewPaymentRepository(db)
+	orderRepository := repository.NewOrderRepository(db)
+	ticketRepository := repository.NewTicketRepository(db)
+	eventRepository := repository.NewEventRepository(db)
+	reconciliationRepository := repository.NewPaymentReconciliationRepository(db)
+	paymentService := service.NewPaymentService(paymentRepository, orderRepository, ticketRepository, eventRepository, logger, gateways)
+	return service.NewPaymentReconciliationService(reconciliationRepository, paymentRepository, paymentService, gateways, logger)
+}
+
+func SetupPaymentReconciliationRoutes(apiV1 *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, gateways *gateway.Registry) {
+	reconciliationService := NewPaymentReconciliationService(db, logger, gateways)
+	reconciliationController := controller.NewPaymentReconciliationController(reconciliationService, logger, db)
+
+	reconciliationRoutes := apiV1.Group("/admin/reconciliation")
+	reconciliationRoutes.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(model.Administrator))
+	{
+		reconciliationRoutes.POST("/run", ratelimiter.Limit("payment_reconciliation_run", 2, time.Minute), reconciliationController.RunReconciliation)
+		reconciliationRoutes.GET("/issues", reconciliationController.GetIssues)
+		reconciliationRoutes.POST("/issues/:id/resolve", reconciliationController.ResolveIssue)
+	}
+}
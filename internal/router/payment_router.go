@@ -2,8 +2,10 @@ package router
 
 import (
 	"learn/internal/controller"
+	"learn/internal/gateway"
+	"learn/internal/gateway/fake"
 	"learn/internal/middleware"
-	"learn/internal/pkg/events"
+	"learn/internal/model"
 	"learn/internal/pkg/ratelimiter"
 	"learn/internal/repository"
 	"learn/internal/service"
@@ -14,17 +16,18 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupPaymentRoutes(apiV1 *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, eventBus *events.EventBus) {
+func SetupPaymentRoutes(apiV1 *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, gateways *gateway.Registry) {
 	paymentRepository := repository.NewPaymentRepository(db)
 	orderRepository := repository.NewOrderRepository(db)
 	ticketRepository := repository.NewTicketRepository(db)
 	eventRepository := repository.NewEventRepository(db)
-	paymentService := service.NewPaymentService(paymentRepository, orderRepository, ticketRepository, eventRepository, logger, eventBus)
+	paymentService := service.NewPaymentService(paymentRepository, orderRepository, ticketRepository, eventRepository, logger, gateways)
 	paymentController := controller.NewPaymentController(paymentService, logger)
 
 	paymentRouter := apiV1.Group("/payments")
 	// Public Routes
-	paymentRouter.POST("/midtrans-notification", ratelimiter.Limit("payment_notification", 60, time.Minute), paymentController.HandleNotification)
+	paymentRouter.POST("/midtrans-notification", ratelimiter.Limit("payment_notification", 60, time.Minute), paymentController.HandleMidtransNotification)
+	paymentRouter.POST("/xendit-notification", ratelimiter.Limit("payment_notification_xendit", 60, time.Minute), paymentController.HandleXenditNotification)
 
 	paymentRouter.Use(middleware.AuthMiddleware())
 	{
@@ -35,4 +38,11 @@ func SetupPaymentRoutes(apiV1 *gin.RouterGroup, db *gorm.DB, logger *slog.Logger
 		paymentRouter.PATCH("/:id/status", ratelimiter.Limit("payment_status_update", 20, time.Minute), paymentController.UpdatePaymentStatus)
 		paymentRouter.DELETE("/:id", paymentController.DeletePayment)
 	}
+
+	// Simulated notifications only exist while the fake gateway stands in for Midtrans
+	if fakeGateway, ok := fake.FromRegistry(gateways); ok {
+		simulatorService := service.NewPaymentSimulatorService(fakeGateway, paymentRepository, orderRepository, paymentService, logger)
+		simulatorController := controller.NewPaymentSimulatorController(simulatorService, logger)
+		paymentRouter.POST("/:id/simulate-notification", middleware.RoleMiddleware(model.Administrator), simulatorController.SimulateNotification)
+	}
 }
@@ -0,0 +1,47 @@
+package router
+
+import (
+	"learn/internal/controller"
+	"learn/internal/gateway"
+	"learn/internal/middleware"
+	"learn/internal/model"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/ratelimiter"
+	"learn/internal/repository"
+	"learn/internal/service"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupRefundRoutes(apiV1 *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, eventBus events.Bus, gateways *gateway.Registry) {
+	refundRepository := repository.NewRefundRepository(db)
+	orderRepository := repository.NewOrderRepository(db)
+	paymentRepository := repository.NewPaymentRepository(db)
+	refundService := service.NewRefundService(refundRepository, orderRepository, paymentRepository, logger, eventBus, gateways)
+	refundController := controller.NewRefundController(refundService, logger, db)
+
+	refundRoutes := apiV1.Group("/refunds")
+	refundRoutes.Use(middleware.AuthMiddleware())
+	{
+		refundRoutes.POST(
+			"/",
+			middleware.RoleMiddleware(model.Attendee),
+			ratelimiter.Limit("refund_request", 5, time.Minute),
+			refundController.RequestRefund,
+		)
+		refundRoutes.GET("/mine", middleware.RoleMiddleware(model.Attendee), refundController.GetMyRefunds)
+		refundRoutes.GET("/", middleware.RoleMiddleware(model.Administrator, model.Organizer), refundController.GetAllRefunds)
+		refundRoutes.GET("/:id", refundController.GetRefundByID)
+		refundRoutes.POST(
+			"/:id/approve",
+			middleware.RoleMiddleware(model.Administrator, model.Organizer),
+			ratelimiter.Limit("refund_approve", 30, time.Minute),
+			refundController.ApproveRefund,
+		)
+		refundRoutes.POST("/:id/reject", middleware.RoleMiddleware(model.Administrator, model.Organizer), refundController.RejectRefund)
+		refundRoutes.POST("/:id/settle", middleware.RoleMiddleware(model.Administrator, model.Organizer), refundController.SettleRefund)
+	}
+}
@@ -1,9 +1,12 @@
 package router
 
 import (
+	"learn/internal/gateway"
 	"learn/internal/middleware"
 	"learn/internal/model"
+	"learn/internal/pkg/cron"
 	"learn/internal/pkg/events"
+	"learn/internal/pkg/queue"
 	"learn/internal/repository"
 	"log/slog"
 	"time"
@@ -45,7 +48,7 @@ func LoggerMiddleware(logger *slog.Logger) gin.HandlerFunc {
 	}
 }
 
-func SetupRouter(logger *slog.Logger, db *gorm.DB, eventBus *events.EventBus) *gin.Engine {
+func SetupRouter(logger *slog.Logger, db *gorm.DB, eventBus events.Bus, jobQueue *queue.JobQueue, cronScheduler *cron.Scheduler, gateways *gateway.Registry, attendanceHub *events.AttendanceHub) *gin.Engine {
 	r := gin.Default()
 
 	r.Use(middleware.RequestIDMiddleware())
@@ -73,9 +76,10 @@ func SetupRouter(logger *slog.Logger, db *gorm.DB, eventBus *events.EventBus) *g
 	paymentRepo := repository.NewPaymentRepository(db)
 	ticketRepo := repository.NewTicketRepository(db)
 	eventRepo := repository.NewEventRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
 
 	// Register event handlers
-	RegisterEventHandlersWithRepos(eventBus, orderRepo, paymentRepo, ticketRepo, eventRepo, logger)
+	RegisterEventHandlersWithRepos(eventBus, orderRepo, paymentRepo, ticketRepo, eventRepo, webhookRepo, logger)
 
 	// Buat grup utama untuk /api/v1
 	apiV1 := r.Group("/api/v1")
@@ -83,11 +87,15 @@ func SetupRouter(logger *slog.Logger, db *gorm.DB, eventBus *events.EventBus) *g
 		SetupAuthRoutes(apiV1, db, logger)
 		SetupVenueRoutes(apiV1, db, logger)
 		SetupGuestRoutes(apiV1, db, logger)
-		SetupEventRoutes(apiV1, db, logger)
+		SetupEventRoutes(apiV1, db, logger, eventBus, jobQueue, gateways, attendanceHub)
 		SetupOrderRoutes(apiV1, db, logger, eventBus)
-		SetupPaymentRoutes(apiV1, db, logger, eventBus)
-		SetupTicketRoutes(apiV1, db, logger)
-		SetupAdminRoutes(apiV1, db, logger)
+		SetupWaitlistRoutes(apiV1, db, logger)
+		SetupPaymentRoutes(apiV1, db, logger, gateways)
+		SetupTicketRoutes(apiV1, db, logger, eventBus)
+		SetupRefundRoutes(apiV1, db, logger, eventBus, gateways)
+		SetupAdminRoutes(apiV1, db, logger, eventBus, jobQueue, cronScheduler)
+		SetupPaymentReconciliationRoutes(apiV1, db, logger, gateways)
+		SetupWebhookRoutes(apiV1, db, logger)
 	}
 
 	return r
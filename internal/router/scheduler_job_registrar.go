@@ -0,0 +1,80 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"learn/internal/config"
+	"learn/internal/gateway"
+	"learn/internal/pkg/cron"
+	"learn/internal/pkg/scheduler"
+	"learn/internal/pkg/waitingroom"
+	"learn/internal/repository"
+	"learn/internal/service"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	JobOrderExpiration       = "order_expiration"
+	JobPaymentReconciliation = "payment_reconciliation"
+	JobSchedulerRunCleanup   = "scheduler_run_cleanup"
+	JobWaitingRoomAdmission  = "waiting_room_admission"
+	JobWaitlistOffers        = "waitlist_offers"
+)
+
+// NewCronScheduler creates the scheduler with every scheduled job registered. Each run happens on
+// one node only, so every replica can run it.
+func NewCronScheduler(db *gorm.DB, logger *slog.Logger, gateways *gateway.Registry) (*cron.Scheduler, error) {
+	runRepo := repository.NewSchedulerRunRepository(db)
+	cronScheduler := cron.NewScheduler(config.Rdb, runRepo, logger)
+
+	// Cancel pending orders that were not paid before their payment due
+	orderRepo := repository.NewOrderRepository(db)
+	expirationJob := scheduler.NewOrderExpirationJob(orderRepo, service.NewOrderStateMachine(orderRepo, logger), logger)
+	if err := cronScheduler.Register(JobOrderExpiration, config.AppConfig.OrderExpirationSchedule, 5*time.Minute, expirationJob.Run); err != nil {
+		return nil, err
+	}
+
+	// Reconcile payments whose webhook was lost
+	reconciliationService := NewPaymentReconciliationService(db, logger, gateways)
+	reconcileSpec := fmt.Sprintf("@every %s", config.AppConfig.PaymentReconcileInterval)
+	err := cronScheduler.Register(JobPaymentReconciliation, reconcileSpec, 0, func(ctx context.Context) (string, error) {
+		summary, err := reconciliationService.Reconcile(config.AppConfig.PaymentReconcileAfter)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("checked %d, applied %d, unchanged %d, flagged %d, failed %d",
+			summary.Checked, summary.Applied, summary.Unchanged, summary.Flagged, summary.Failed), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop recorded runs past their retention
+	err = cronScheduler.Register(JobSchedulerRunCleanup, "@daily", 0, func(ctx context.Context) (string, error) {
+		deleted, err := runRepo.DeleteRunsBefore(time.Now().Add(-config.AppConfig.SchedulerRunRetention))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("deleted %d runs", deleted), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Let queued buyers into events with the waiting room on
+	waitingRoomService := service.NewWaitingRoomService(repository.NewEventRepository(db), waitingroom.NewRoom(config.Rdb), logger)
+	if err := cronScheduler.Register(JobWaitingRoomAdmission, config.AppConfig.WaitingRoomAdmitSchedule, time.Minute, waitingRoomService.AdmitDue); err != nil {
+		return nil, err
+	}
+
+	// Offer quota that came back to the waitlist and pass unused offers down the list
+	waitlistService := NewWaitlistService(db, logger)
+	if err := cronScheduler.Register(JobWaitlistOffers, config.AppConfig.WaitlistOfferSchedule, time.Minute, waitlistService.OfferReturnedQuota); err != nil {
+		return nil, err
+	}
+
+	return cronScheduler, nil
+}
@@ -4,6 +4,7 @@ import (
 	"learn/internal/controller"
 	"learn/internal/middleware"
 	"learn/internal/model"
+	"learn/internal/pkg/events"
 	"learn/internal/pkg/ratelimiter"
 	"learn/internal/repository"
 	"learn/internal/service"
@@ -14,19 +15,56 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupTicketRoutes(apiV1 *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
+func SetupTicketRoutes(apiV1 *gin.RouterGroup, db *gorm.DB, logger *slog.Logger, eventBus events.Bus) {
 	ticketRepository := repository.NewTicketRepository(db)
-	ticketService := service.NewTicketService(ticketRepository, logger)
-	ticketController := controller.NewTicketController(ticketService, logger)
+	eventRepository := repository.NewEventRepository(db)
+	ticketService := service.NewTicketService(ticketRepository, eventRepository, logger, eventBus)
+	ticketController := controller.NewTicketController(ticketService, logger, db)
+	transferRepository := repository.NewTicketTransferRepository(db)
+	transferService := service.NewTicketTransferService(transferRepository, ticketRepository, service.NewEmailService(logger), logger, eventBus)
+	transferController := controller.NewTicketTransferController(transferService, logger)
 
 	ticketRoutes := apiV1.Group("/tickets")
 	ticketRoutes.Use(middleware.AuthMiddleware())
 	{
+		ticketRoutes.GET("/", middleware.RoleMiddleware(model.Attendee), ticketController.GetMyTickets)
+		ticketRoutes.GET("/:code", middleware.RoleMiddleware(model.Attendee), ticketController.GetMyTicket)
+		ticketRoutes.GET("/:code/qr.png", middleware.RoleMiddleware(model.Attendee), ticketController.GetTicketQRCode)
+		ticketRoutes.POST(
+			"/:code/transfer",
+			middleware.RoleMiddleware(model.Attendee),
+			ratelimiter.Limit("ticket_transfer", 10, time.Minute),
+			transferController.StartTransfer,
+		)
+		ticketRoutes.DELETE("/:code/transfer", middleware.RoleMiddleware(model.Attendee), transferController.CancelTransfer)
+		ticketRoutes.GET("/transfers/incoming", middleware.RoleMiddleware(model.Attendee), transferController.GetIncomingTransfers)
+		ticketRoutes.POST("/transfers/:id/accept", middleware.RoleMiddleware(model.Attendee), transferController.AcceptTransfer)
 		ticketRoutes.POST(
 			"/check-in",
 			middleware.RoleMiddleware(model.Administrator, model.Organizer),
 			ratelimiter.Limit("ticket_checkin", 120, time.Minute),
 			ticketController.CheckInTicket,
 		)
+		ticketRoutes.POST(
+			"/check-in/sync",
+			middleware.RoleMiddleware(model.Administrator, model.Organizer),
+			ratelimiter.Limit("ticket_checkin_sync", 30, time.Minute),
+			ticketController.SyncOfflineScans,
+		)
+		ticketRoutes.GET(
+			"/manifest/:event_slug",
+			middleware.RoleMiddleware(model.Administrator, model.Organizer),
+			ticketController.GetCheckInManifest,
+		)
+		ticketRoutes.GET(
+			"/scans/event/:event_slug",
+			middleware.RoleMiddleware(model.Administrator, model.Organizer),
+			ticketController.GetEventScans,
+		)
+		ticketRoutes.GET(
+			"/scans/ticket/:ticket_id",
+			middleware.RoleMiddleware(model.Administrator, model.Organizer),
+			ticketController.GetTicketScans,
+		)
 	}
 }
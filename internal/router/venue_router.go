@@ -18,7 +18,7 @@ func SetupVenueRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
 	venueController := controller.NewVenueController(venueService, logger, db)
 
 	eventRepo := repository.NewEventRepository(db)
-	eventService := service.NewEventService(eventRepo, venueRepo, nil, logger)
+	eventService := service.NewEventService(eventRepo, venueRepo, nil, nil, logger)
 	eventController := controller.NewEventController(eventService, logger, db)
 
 	venueRoutes := rg.Group("/venues")
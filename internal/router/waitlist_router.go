@@ -0,0 +1,35 @@
+package router
+
+import (
+	"learn/internal/controller"
+	"learn/internal/middleware"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"learn/internal/service"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NewWaitlistService wires the waitlist service for the routes and the offer job
+func NewWaitlistService(db *gorm.DB, logger *slog.Logger) service.WaitlistService {
+	return service.NewWaitlistService(
+		repository.NewWaitlistRepository(db),
+		repository.NewEventRepository(db),
+		service.NewEmailService(logger),
+		logger,
+	)
+}
+
+func SetupWaitlistRoutes(rg *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
+	waitlistController := controller.NewWaitlistController(NewWaitlistService(db, logger), logger)
+
+	waitlistRoutes := rg.Group("/waitlist")
+	waitlistRoutes.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(model.Attendee))
+	{
+		waitlistRoutes.POST("/", waitlistController.Join)
+		waitlistRoutes.GET("/", waitlistController.GetMyEntries)
+		waitlistRoutes.DELETE("/:id", waitlistController.Leave)
+	}
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"learn/internal/controller"
+	"learn/internal/middleware"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"learn/internal/service"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NewWebhookService wires the webhook service for the organizer routes and the dispatch scheduler
+func NewWebhookService(db *gorm.DB, logger *slog.Logger) service.WebhookService {
+	webhookRepository := repository.NewWebhookRepository(db)
+	eventRepository := repository.NewEventRepository(db)
+	return service.NewWebhookService(webhookRepository, eventRepository, logger)
+}
+
+func SetupWebhookRoutes(apiV1 *gin.RouterGroup, db *gorm.DB, logger *slog.Logger) {
+	webhookService := NewWebhookService(db, logger)
+	webhookController := controller.NewWebhookController(webhookService, logger, db)
+
+	webhookRoutes := apiV1.Group("/webhooks")
+	webhookRoutes.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(model.Administrator, model.Organizer))
+	{
+		webhookRoutes.POST("", webhookController.CreateEndpoint)
+		webhookRoutes.GET("", webhookController.GetEndpoints)
+		webhookRoutes.GET("/:id", webhookController.GetEndpoint)
+		webhookRoutes.PATCH("/:id", webhookController.UpdateEndpoint)
+		webhookRoutes.DELETE("/:id", webhookController.DeleteEndpoint)
+		webhookRoutes.GET("/:id/deliveries", webhookController.GetDeliveries)
+		webhookRoutes.POST("/:id/deliveries/:delivery_id/redeliver", webhookController.RedeliverDelivery)
+	}
+}
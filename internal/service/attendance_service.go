@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// attendanceScanWindow is how far back the scans per minute of the dashboard reach
+	attendanceScanWindow = 15 * time.Minute
+	// attendanceRecentRejections is how many of the latest rejected scans the dashboard shows
+	attendanceRecentRejections = 10
+)
+
+type AttendanceService interface {
+	GetEvent(eventSlug string) (*model.Event, error)
+	GetAttendanceSummary(event *model.Event) (*dto.AttendanceSummaryResponse, error)
+}
+
+type attendanceService struct {
+	attendanceRepo repository.AttendanceRepository
+	eventRepo      repository.EventRepository
+	logger         *slog.Logger
+}
+
+func NewAttendanceService(attendanceRepo repository.AttendanceRepository, eventRepo repository.EventRepository, logger *slog.Logger) AttendanceService {
+	return &attendanceService{attendanceRepo: attendanceRepo, eventRepo: eventRepo, logger: logger}
+}
+
+func (s *attendanceService) GetEvent(eventSlug string) (*model.Event, error) {
+	event, err := s.eventRepo.FindBySlug(eventSlug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("event_exists", "event not found")
+		}
+		s.logger.Error("failed to get event for attendance",
+			slog.String("event_slug", eventSlug),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_event_by_slug", err)
+	}
+
+	return event, nil
+}
+
+// GetAttendanceSummary returns tickets sold vs checked in per tier, the recent scan rate
+// and the latest rejected scans of an event
+func (s *attendanceService) GetAttendanceSummary(event *model.Event) (*dto.AttendanceSummaryResponse, error) {
+	now := time.Now()
+
+	tiers, err := s.attendanceRepo.GetTierAttendance(event.ID)
+	if err != nil {
+		s.logger.Error("failed to get tier attendance",
+			slog.Uint64("event_id", uint64(event.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_tier_attendance", err)
+	}
+
+	scanCounts, err := s.attendanceRepo.GetScansPerMinute(event.ID, now.Add(-attendanceScanWindow).Truncate(time.Minute))
+	if err != nil {
+		s.logger.Error("failed to get scans per minute",
+			slog.Uint64("event_id", uint64(event.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_scans_per_minute", err)
+	}
+
+	rejections, err := s.attendanceRepo.GetRecentRejectedScans(event.ID, attendanceRecentRejections)
+	if err != nil {
+		s.logger.Error("failed to get recent rejected scans",
+			slog.Uint64("event_id", uint64(event.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_recent_rejected_scans", err)
+	}
+
+	summary := &dto.AttendanceSummaryResponse{
+		EventID:          event.ID,
+		EventSlug:        event.Slug,
+		EventName:        event.Name,
+		Tiers:            make([]dto.TierAttendanceResponse, 0, len(tiers)),
+		ScansPerMinute:   make([]dto.ScanRateResponse, 0, len(scanCounts)),
+		RecentRejections: make([]dto.TicketScanResponse, 0, len(rejections)),
+		GeneratedAt:      now,
+	}
+
+	for _, tier := range tiers {
+		summary.Sold += tier.Sold
+		summary.CheckedIn += tier.CheckedIn
+		summary.Inside += tier.Inside
+		summary.Tiers = append(summary.Tiers, dto.TierAttendanceResponse{
+			EventPriceID: tier.EventPriceID,
+			Name:         tier.Name,
+			Quota:        tier.Quota,
+			Sold:         tier.Sold,
+			CheckedIn:    tier.CheckedIn,
+			Inside:       tier.Inside,
+		})
+	}
+
+	for _, count := range scanCounts {
+		summary.ScansPerMinute = append(summary.ScansPerMinute, dto.ScanRateResponse{
+			Minute:   count.Minute,
+			Accepted: count.Accepted,
+			Rejected: count.Rejected,
+		})
+	}
+
+	for _, scan := range rejections {
+		summary.RecentRejections = append(summary.RecentRejections, dto.ToTicketScanResponse(scan))
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/pkg/events"
+	"log/slog"
+	"regexp"
+)
+
+const (
+	defaultDeadLetterPageSize = 50
+	maxDeadLetterPageSize     = 200
+)
+
+var streamIDPattern = regexp.MustCompile(`^\d+-\d+$`)
+
+// DeadLetterService lets admins inspect, replay and discard events that exhausted their retries
+type DeadLetterService interface {
+	ListDeadLetters(afterID string, count int) (*dto.DeadLetterListResponse, error)
+	ReplayDeadLetter(id string) (*dto.DeadLetterResponse, error)
+	DiscardDeadLetter(id string) error
+}
+
+type deadLetterService struct {
+	eventBus *events.EventBus
+	logger   *slog.Logger
+}
+
+func NewDeadLetterService(eventBus *events.EventBus, logger *slog.Logger) DeadLetterService {
+	return &deadLetterService{eventBus: eventBus, logger: logger}
+}
+
+func (s *deadLetterService) ListDeadLetters(afterID string, count int) (*dto.DeadLetterListResponse, error) {
+	if afterID != "" && !streamIDPattern.MatchString(afterID) {
+		return nil, apperrors.NewValidationError("after", "invalid dead letter ID", afterID)
+	}
+	if count <= 0 {
+		count = defaultDeadLetterPageSize
+	}
+	if count > maxDeadLetterPageSize {
+		count = maxDeadLetterPageSize
+	}
+
+	deadLetters, total, err := s.eventBus.ListDeadLetters(afterID, int64(count))
+	if err != nil {
+		s.logger.Error("failed to list dead letters", slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("list_dead_letters", err)
+	}
+
+	response := &dto.DeadLetterListResponse{
+		Total:       total,
+		DeadLetters: make([]dto.DeadLetterResponse, 0, len(deadLetters)),
+	}
+	for _, deadLetter := range deadLetters {
+		response.DeadLetters = append(response.DeadLetters, dto.ToDeadLetterResponse(deadLetter))
+	}
+	if len(deadLetters) == count {
+		response.NextAfter = deadLetters[len(deadLetters)-1].ID
+	}
+	return response, nil
+}
+
+func (s *deadLetterService) ReplayDeadLetter(id string) (*dto.DeadLetterResponse, error) {
+	if !streamIDPattern.MatchString(id) {
+		return nil, apperrors.NewValidationError("id", "invalid dead letter ID", id)
+	}
+
+	deadLetter, err := s.eventBus.ReplayDeadLetter(id)
+	if err != nil {
+		if errors.Is(err, events.ErrDeadLetterNotFound) {
+			return nil, apperrors.NewBusinessRuleError("dead_letter_exists", "dead letter not found")
+		}
+		s.logger.Error("failed to replay dead letter", slog.String("id", id), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("replay_dead_letter", err)
+	}
+
+	response := dto.ToDeadLetterResponse(*deadLetter)
+	return &response, nil
+}
+
+func (s *deadLetterService) DiscardDeadLetter(id string) error {
+	if !streamIDPattern.MatchString(id) {
+		return apperrors.NewValidationError("id", "invalid dead letter ID", id)
+	}
+
+	if err := s.eventBus.DiscardDeadLetter(id); err != nil {
+		if errors.Is(err, events.ErrDeadLetterNotFound) {
+			return apperrors.NewBusinessRuleError("dead_letter_exists", "dead letter not found")
+		}
+		s.logger.Error("failed to discard dead letter", slog.String("id", id), slog.String("error", err.Error()))
+		return apperrors.NewSystemError("discard_dead_letter", err)
+	}
+	return nil
+}
@@ -5,12 +5,16 @@ import (
 	"learn/internal/config"
 	"learn/internal/pkg/email"
 	"log/slog"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
 
 type EmailService interface {
 	SendOTP(to string, otp string) error
+	SendTicketTransferInvite(to string, senderName string, eventName string) error
+	SendEventCancellation(to string, eventName string, reason string) error
+	SendWaitlistOffer(to string, eventName string, priceName string, quantity int, expiresAt time.Time) error
 }
 
 type emailService struct {
@@ -55,3 +59,100 @@ func (s *emailService) SendOTP(to string, otp string) error {
 	s.logger.Info("OTP sent successfully", slog.String("to", to))
 	return nil
 }
+
+func (s *emailService) SendTicketTransferInvite(to string, senderName string, eventName string) error {
+	smtpHost := config.AppConfig.SMTPHost
+	password := config.AppConfig.SMTPPassword
+
+	// If SMTP credentials are not set (mock/dev), just log
+	if smtpHost == "" || password == "" {
+		s.logger.Warn("SMTP credentials not set, logging ticket transfer invite instead",
+			slog.String("to", to),
+			slog.String("event", eventName))
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", config.AppConfig.SMTPFromEmail)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "A ticket has been sent to you")
+	m.SetBody("text/plain", fmt.Sprintf(
+		"%s wants to transfer a ticket for %s to you.\n\nLog in or register with this email address to accept it.",
+		senderName, eventName))
+
+	d := gomail.NewDialer(smtpHost, config.AppConfig.SMTPPort, config.AppConfig.SMTPUser, password)
+	if err := d.DialAndSend(m); err != nil {
+		s.logger.Error("failed to send email", slog.String("error", err.Error()))
+		return err
+	}
+
+	s.logger.Info("ticket transfer invite sent successfully", slog.String("to", to))
+	return nil
+}
+
+func (s *emailService) SendEventCancellation(to string, eventName string, reason string) error {
+	smtpHost := config.AppConfig.SMTPHost
+	password := config.AppConfig.SMTPPassword
+
+	// If SMTP credentials are not set (mock/dev), just log
+	if smtpHost == "" || password == "" {
+		s.logger.Warn("SMTP credentials not set, logging event cancellation notice instead",
+			slog.String("to", to),
+			slog.String("event", eventName))
+		return nil
+	}
+
+	body := fmt.Sprintf("We are sorry to tell you that %s has been cancelled.", eventName)
+	if reason != "" {
+		body += fmt.Sprintf("\n\nReason: %s", reason)
+	}
+	body += "\n\nYour tickets are no longer valid. Paid orders are refunded automatically; " +
+		"payments made by bank transfer or at a convenience store are returned manually by the organizer."
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", config.AppConfig.SMTPFromEmail)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", fmt.Sprintf("%s has been cancelled", eventName))
+	m.SetBody("text/plain", body)
+
+	d := gomail.NewDialer(smtpHost, config.AppConfig.SMTPPort, config.AppConfig.SMTPUser, password)
+	if err := d.DialAndSend(m); err != nil {
+		s.logger.Error("failed to send email", slog.String("error", err.Error()))
+		return err
+	}
+
+	s.logger.Info("event cancellation notice sent successfully", slog.String("to", to))
+	return nil
+}
+
+func (s *emailService) SendWaitlistOffer(to string, eventName string, priceName string, quantity int, expiresAt time.Time) error {
+	smtpHost := config.AppConfig.SMTPHost
+	password := config.AppConfig.SMTPPassword
+
+	// If SMTP credentials are not set (mock/dev), just log
+	if smtpHost == "" || password == "" {
+		s.logger.Warn("SMTP credentials not set, logging waitlist offer instead",
+			slog.String("to", to),
+			slog.String("event", eventName),
+			slog.Time("expires_at", expiresAt))
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", config.AppConfig.SMTPFromEmail)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", fmt.Sprintf("Tickets for %s are available for you", eventName))
+	m.SetBody("text/plain", fmt.Sprintf(
+		"Good news! %d %s ticket(s) for %s came back and are held for you.\n\n"+
+			"Place your order before %s to get them, after that they are offered to the next person on the waitlist.",
+		quantity, priceName, eventName, expiresAt.Format("02 Jan 2006 15:04 MST")))
+
+	d := gomail.NewDialer(smtpHost, config.AppConfig.SMTPPort, config.AppConfig.SMTPUser, password)
+	if err := d.DialAndSend(m); err != nil {
+		s.logger.Error("failed to send email", slog.String("error", err.Error()))
+		return err
+	}
+
+	s.logger.Info("waitlist offer sent successfully", slog.String("to", to))
+	return nil
+}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/model"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/queue"
+	"learn/internal/repository"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// eventCancellationBatchSize is the number of orders loaded per round of a cancellation run
+	eventCancellationBatchSize = 50
+	// staleRefundClaimAge is how long a refund may stay PROCESSING before a run treats its approval
+	// as interrupted. An approval holds the claim only for the gateway call.
+	staleRefundClaimAge = 15 * time.Minute
+)
+
+type EventCancellationService interface {
+	StartCancellation(ctx context.Context, event *model.Event, userID uint, reason string) (*model.EventCancellation, error)
+	GetCancellation(eventSlug string) (*dto.EventCancellationResponse, error)
+	ResumeCancellation(ctx context.Context, eventSlug string, userID uint) (*dto.EventCancellationResponse, error)
+	ResumeUnfinished()
+	ProcessCancellation(ctx context.Context, cancellationID uint) error
+}
+
+type eventCancellationService struct {
+	cancellationRepo  repository.EventCancellationRepository
+	eventRepo         repository.EventRepository
+	orderRepo         repository.OrderRepository
+	orderStateMachine OrderStateMachine
+	paymentRepo       repository.PaymentRepository
+	refundRepo        repository.RefundRepository
+	refundService     RefundService
+	emailService      EmailService
+	jobQueue          *queue.JobQueue
+	logger            *slog.Logger
+	eventBus          events.EventPublisher
+}
+
+func NewEventCancellationService(
+	cancellationRepo repository.EventCancellationRepository,
+	eventRepo repository.EventRepository,
+	orderRepo repository.OrderRepository,
+	orderStateMachine OrderStateMachine,
+	paymentRepo repository.PaymentRepository,
+	refundRepo repository.RefundRepository,
+	refundService RefundService,
+	emailService EmailService,
+	jobQueue *queue.JobQueue,
+	logger *slog.Logger,
+	eventBus events.EventPublisher,
+) EventCancellationService {
+	return &eventCancellationService{
+		cancellationRepo:  cancellationRepo,
+		eventRepo:         eventRepo,
+		orderRepo:         orderRepo,
+		orderStateMachine: orderStateMachine,
+		paymentRepo:       paymentRepo,
+		refundRepo:        refundRepo,
+		refundService:     refundService,
+		emailService:      emailService,
+		jobQueue:          jobQueue,
+		logger:            logger,
+		eventBus:          eventBus,
+	}
+}
+
+// StartCancellation records a cancellation run for a cancelled event and hands it to the job queue
+func (s *eventCancellationService) StartCancellation(ctx context.Context, event *model.Event, userID uint, reason string) (*model.EventCancellation, error) {
+	total, err := s.cancellationRepo.CountOpenOrdersForEvent(event.ID)
+	if err != nil {
+		s.logger.Error("failed to count orders of cancelled event", slog.Uint64("event_id", uint64(event.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("count_event_orders", err)
+	}
+
+	cancellation := model.EventCancellation{
+		EventID:           event.ID,
+		RequestedByUserID: userID,
+		Reason:            strings.TrimSpace(reason),
+		Status:            model.EventCancellationRunning,
+		TotalOrders:       int(total),
+	}
+	if err := s.cancellationRepo.CreateCancellation(&cancellation); err != nil {
+		if errors.Is(err, repository.ErrEventCancellationExists) {
+			return nil, apperrors.NewBusinessRuleError("event_cancellation", "cancellation of this event was already started")
+		}
+		s.logger.Error("failed to create event cancellation", slog.Uint64("event_id", uint64(event.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("create_event_cancellation", err)
+	}
+
+	s.eventBus.Publish(ctx, events.EventCancelledEvent{
+		EventID:        event.ID,
+		CancellationID: cancellation.ID,
+		UserID:         userID,
+		Reason:         cancellation.Reason,
+		CancelledAt:    cancellation.CreatedAt,
+	})
+
+	s.logger.Info("event cancellation started",
+		slog.Uint64("event_id", uint64(event.ID)),
+		slog.Uint64("cancellation_id", uint64(cancellation.ID)),
+		slog.Int("orders", cancellation.TotalOrders))
+
+	// A run that could not be queued stays RUNNING and is queued again on the next start
+	if err := s.enqueue(ctx, cancellation.ID); err != nil {
+		s.logger.Error("failed to enqueue event cancellation", slog.Uint64("cancellation_id", uint64(cancellation.ID)), slog.String("error", err.Error()))
+	}
+	return &cancellation, nil
+}
+
+func (s *eventCancellationService) GetCancellation(eventSlug string) (*dto.EventCancellationResponse, error) {
+	event, err := s.getEvent(eventSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	cancellation, err := s.cancellationRepo.GetCancellationByEventID(event.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("event_cancellation", "event has no cancellation run")
+		}
+		s.logger.Error("failed to get event cancellation", slog.Uint64("event_id", uint64(event.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_event_cancellation", err)
+	}
+
+	response := dto.ToEventCancellationResponse(*cancellation)
+	return &response, nil
+}
+
+// ResumeCancellation queues an interrupted run again, retries the failed orders of a
+// partial run, or starts the run of a cancelled event that never got one
+func (s *eventCancellationService) ResumeCancellation(ctx context.Context, eventSlug string, userID uint) (*dto.EventCancellationResponse, error) {
+	event, err := s.getEvent(eventSlug)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != model.Cancelled {
+		return nil, apperrors.NewBusinessRuleError("event_status", "event is not cancelled")
+	}
+
+	cancellation, err := s.cancellationRepo.GetCancellationByEventID(event.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if _, err := s.StartCancellation(ctx, event, userID, ""); err != nil {
+			return nil, err
+		}
+		return s.GetCancellation(eventSlug)
+	}
+	if err != nil {
+		s.logger.Error("failed to get event cancellation", slog.Uint64("event_id", uint64(event.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_event_cancellation", err)
+	}
+
+	if cancellation.IsFinished() {
+		return nil, apperrors.NewBusinessRuleError("event_cancellation", "cancellation of this event is already completed")
+	}
+
+	if cancellation.Status == model.EventCancellationPartial {
+		// Failed orders are behind the cursor, so the retry walks every open order again
+		total, err := s.cancellationRepo.CountOpenOrdersForEvent(event.ID)
+		if err != nil {
+			s.logger.Error("failed to count orders of cancelled event", slog.Uint64("event_id", uint64(event.ID)), slog.String("error", err.Error()))
+			return nil, apperrors.NewSystemError("count_event_orders", err)
+		}
+		cancellation.Status = model.EventCancellationRunning
+		cancellation.LastOrderID = 0
+		cancellation.TotalOrders = int(total)
+		cancellation.ProcessedOrders = 0
+		cancellation.FailedOrders = 0
+		cancellation.LastError = ""
+		cancellation.CompletedAt = nil
+		if err := s.cancellationRepo.SaveCancellation(cancellation); err != nil {
+			s.logger.Error("failed to reset event cancellation", slog.Uint64("cancellation_id", uint64(cancellation.ID)), slog.String("error", err.Error()))
+			return nil, apperrors.NewSystemError("save_event_cancellation", err)
+		}
+	}
+
+	s.logger.Info("event cancellation resumed",
+		slog.Uint64("cancellation_id", uint64(cancellation.ID)),
+		slog.Uint64("user_id", uint64(userID)))
+
+	if err := s.enqueue(ctx, cancellation.ID); err != nil {
+		s.logger.Error("failed to enqueue event cancellation", slog.Uint64("cancellation_id", uint64(cancellation.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("enqueue_event_cancellation", err)
+	}
+	response := dto.ToEventCancellationResponse(*cancellation)
+	return &response, nil
+}
+
+// ResumeUnfinished queues every run that was still running when the process stopped
+func (s *eventCancellationService) ResumeUnfinished() {
+	cancellations, err := s.cancellationRepo.GetUnfinishedCancellations()
+	if err != nil {
+		s.logger.Error("failed to get unfinished event cancellations", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, cancellation := range cancellations {
+		s.logger.Info("resuming event cancellation",
+			slog.Uint64("cancellation_id", uint64(cancellation.ID)),
+			slog.Uint64("last_order_id", uint64(cancellation.LastOrderID)))
+		if err := s.enqueue(context.Background(), cancellation.ID); err != nil {
+			s.logger.Error("failed to enqueue event cancellation", slog.Uint64("cancellation_id", uint64(cancellation.ID)), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// enqueue queues the run once, a run that is already queued or running is not queued twice
+func (s *eventCancellationService) enqueue(ctx context.Context, cancellationID uint) error {
+	_, err := s.jobQueue.Enqueue(ctx, queue.JobEventCancellation,
+		queue.EventCancellationPayload{CancellationID: cancellationID},
+		queue.WithJobID(fmt.Sprintf("event_cancellation_%d", cancellationID)))
+	return err
+}
+
+// ProcessCancellation walks the open orders of the event after the cursor. Progress is saved after
+// every order, so a retried or resumed job continues where the previous one stopped.
+func (s *eventCancellationService) ProcessCancellation(ctx context.Context, cancellationID uint) error {
+	cancellation, err := s.cancellationRepo.GetCancellationByID(cancellationID)
+	if err != nil {
+		return err
+	}
+	if cancellation.Status != model.EventCancellationRunning {
+		return nil
+	}
+
+	for {
+		orders, err := s.cancellationRepo.GetOpenOrdersForEvent(cancellation.EventID, cancellation.LastOrderID, eventCancellationBatchSize)
+		if err != nil {
+			s.saveError(cancellation, err)
+			return err
+		}
+		if len(orders) == 0 {
+			break
+		}
+
+		for i := range orders {
+			// Progress is saved, a run stopped by shutdown continues from the next order
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			order := &orders[i]
+			if err := s.processOrder(cancellation, order); err != nil {
+				cancellation.FailedOrders++
+				cancellation.LastError = fmt.Sprintf("order %d: %s", order.ID, err.Error())
+				s.logger.Error("failed to cancel order of cancelled event",
+					slog.Uint64("cancellation_id", uint64(cancellation.ID)),
+					slog.Uint64("order_id", uint64(order.ID)),
+					slog.String("error", err.Error()))
+			}
+
+			cancellation.ProcessedOrders++
+			cancellation.LastOrderID = order.ID
+			if err := s.cancellationRepo.SaveCancellation(cancellation); err != nil {
+				s.logger.Error("failed to save event cancellation progress",
+					slog.Uint64("cancellation_id", uint64(cancellation.ID)),
+					slog.String("error", err.Error()))
+				return err
+			}
+		}
+	}
+
+	now := time.Now()
+	cancellation.Status = model.EventCancellationCompleted
+	if cancellation.FailedOrders > 0 {
+		cancellation.Status = model.EventCancellationPartial
+	}
+	cancellation.CompletedAt = &now
+	if err := s.cancellationRepo.SaveCancellation(cancellation); err != nil {
+		s.logger.Error("failed to finish event cancellation",
+			slog.Uint64("cancellation_id", uint64(cancellation.ID)),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	s.logger.Info("event cancellation finished",
+		slog.Uint64("cancellation_id", uint64(cancellation.ID)),
+		slog.String("status", string(cancellation.Status)),
+		slog.Int("cancelled_orders", cancellation.CancelledOrders),
+		slog.Int("refunded_orders", cancellation.RefundedOrders),
+		slog.Int("failed_orders", cancellation.FailedOrders))
+
+	return nil
+}
+
+// processOrder cancels a pending order, or refunds a paid one and tells its ticket holders
+func (s *eventCancellationService) processOrder(cancellation *model.EventCancellation, order *model.Order) error {
+	switch order.Status {
+	case model.OrderPending:
+		cancelled, err := s.orderStateMachine.Cancel(context.Background(), order.ID, "Event cancelled")
+		if err != nil {
+			return err
+		}
+		if cancelled {
+			cancellation.CancelledOrders++
+		}
+		return nil
+	case model.OrderPaid:
+		voided, err := s.refundOrder(cancellation, order)
+		if err != nil {
+			return err
+		}
+		cancellation.RefundedOrders++
+		cancellation.TicketsVoided += voided
+		cancellation.EmailsSent += s.notifyHolders(cancellation, order)
+		return nil
+	}
+	return nil
+}
+
+// refundOrder approves the refunds the buyer already requested, then refunds every ticket
+// left. It returns the number of tickets voided.
+func (s *eventCancellationService) refundOrder(cancellation *model.EventCancellation, order *model.Order) (int, error) {
+	review := dto.ReviewRefundRequest{Note: "Event cancelled"}
+	voided := 0
+
+	// An approval interrupted by a crash leaves its refund PROCESSING, holding the tickets forever
+	released, err := s.refundRepo.ReleaseStaleRefunds(order.ID, time.Now().Add(-staleRefundClaimAge))
+	if err != nil {
+		return 0, err
+	}
+	if released > 0 {
+		s.logger.Warn("released interrupted refunds of cancelled event order",
+			slog.Uint64("order_id", uint64(order.ID)),
+			slog.Int64("refunds", released))
+	}
+
+	requested, err := s.refundRepo.GetRequestedRefundsByOrderID(order.ID)
+	if err != nil {
+		return 0, err
+	}
+	for _, refund := range requested {
+		approved, err := s.refundService.ApproveRefund(context.Background(), refund.ID, review, cancellation.RequestedByUserID)
+		if err != nil {
+			return voided, err
+		}
+		voided += len(approved.Tickets)
+	}
+
+	payment, err := s.paymentRepo.GetPaymentByOrderID(order.ID)
+	if err != nil {
+		return voided, err
+	}
+
+	reason := "Event cancelled"
+	if cancellation.Reason != "" {
+		reason += ": " + cancellation.Reason
+	}
+	refund := model.Refund{
+		OrderID:           order.ID,
+		PaymentID:         payment.ID,
+		RequestedByUserID: order.UserID,
+		Reason:            reason,
+	}
+	if err := s.refundRepo.CreateEventCancellationRefund(&refund); err != nil {
+		// Nothing is left to refund when every ticket is voided or refunded already
+		if errors.Is(err, repository.ErrRefundTicketUnavailable) || errors.Is(err, repository.ErrRefundOrderStatus) {
+			return voided, nil
+		}
+		return voided, err
+	}
+
+	approved, err := s.refundService.ApproveRefund(context.Background(), refund.ID, review, cancellation.RequestedByUserID)
+	if err != nil {
+		return voided, err
+	}
+	return voided + len(approved.Tickets), nil
+}
+
+// notifyHolders emails the buyer and everyone holding a ticket of the order, returning the number of emails sent
+func (s *eventCancellationService) notifyHolders(cancellation *model.EventCancellation, order *model.Order) int {
+	recipients := []string{order.User.Email}
+	for _, ticket := range order.Tickets {
+		recipients = append(recipients, ticket.OwnerEmail)
+	}
+
+	sent := 0
+	seen := make(map[string]bool, len(recipients))
+	for _, to := range recipients {
+		key := strings.ToLower(strings.TrimSpace(to))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if err := s.emailService.SendEventCancellation(to, cancellation.Event.Name, cancellation.Reason); err != nil {
+			s.logger.Error("failed to send event cancellation notice",
+				slog.Uint64("order_id", uint64(order.ID)),
+				slog.String("to", to),
+				slog.String("error", err.Error()))
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+func (s *eventCancellationService) saveError(cancellation *model.EventCancellation, err error) {
+	cancellation.LastError = err.Error()
+	if saveErr := s.cancellationRepo.SaveCancellation(cancellation); saveErr != nil {
+		s.logger.Error("failed to save event cancellation error",
+			slog.Uint64("cancellation_id", uint64(cancellation.ID)),
+			slog.String("error", saveErr.Error()))
+	}
+}
+
+func (s *eventCancellationService) getEvent(eventSlug string) (*model.Event, error) {
+	event, err := s.eventRepo.FindBySlug(eventSlug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("event_exists", "event not found")
+		}
+		s.logger.Error("failed to get event", slog.String("slug", eventSlug), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("find_event_by_slug", err)
+	}
+	return event, nil
+}
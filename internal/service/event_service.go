@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"learn/internal/dto"
+	apperrors "learn/internal/errors"
 	"learn/internal/model"
 	"learn/internal/pkg/slug"
 	"learn/internal/repository"
@@ -16,18 +18,19 @@ type EventService interface {
 	CreateEvent(input dto.CreateEventInput) (*model.Event, error)
 	GetEventBySlug(slug string) (*model.Event, error)
 	GetEventsByGuestSlug(guestSlug string) ([]model.Event, error)
-	UpdateEvent(slug string, input dto.UpdateEventInput) (*model.Event, error)
+	UpdateEvent(ctx context.Context, slug string, input dto.UpdateEventInput, userID uint) (*model.Event, error)
 }
 
 type eventService struct {
-	eventRepo repository.EventRepository
-	venueRepo repository.VenueRepository
-	guestRepo repository.GuestRepository
-	logger    *slog.Logger
+	eventRepo           repository.EventRepository
+	venueRepo           repository.VenueRepository
+	guestRepo           repository.GuestRepository
+	cancellationService EventCancellationService
+	logger              *slog.Logger
 }
 
-func NewEventService(eventRepo repository.EventRepository, venueRepo repository.VenueRepository, guestRepo repository.GuestRepository, logger *slog.Logger) EventService {
-	return &eventService{eventRepo: eventRepo, venueRepo: venueRepo, guestRepo: guestRepo, logger: logger}
+func NewEventService(eventRepo repository.EventRepository, venueRepo repository.VenueRepository, guestRepo repository.GuestRepository, cancellationService EventCancellationService, logger *slog.Logger) EventService {
+	return &eventService{eventRepo: eventRepo, venueRepo: venueRepo, guestRepo: guestRepo, cancellationService: cancellationService, logger: logger}
 }
 
 func (s *eventService) CreateEvent(input dto.CreateEventInput) (*model.Event, error) {
@@ -56,14 +59,24 @@ func (s *eventService) CreateEvent(input dto.CreateEventInput) (*model.Event, er
 
 	// Create the event first
 	event := model.Event{
-		VenueID:        input.VenueID,
-		Name:           input.Name,
-		Slug:           uniqueSlug,
-		Description:    input.Description,
-		EventStartAt:   input.EventStartAt,
-		Status:         input.Status,
-		SalesStartDate: input.SalesStartDate,
-		SalesEndDate:   input.SalesEndDate,
+		VenueID:                   input.VenueID,
+		Name:                      input.Name,
+		Slug:                      uniqueSlug,
+		Description:               input.Description,
+		EventStartAt:              input.EventStartAt,
+		Status:                    input.Status,
+		SalesStartDate:            input.SalesStartDate,
+		SalesEndDate:              input.SalesEndDate,
+		AllowReentry:              input.AllowReentry,
+		TransfersDisabled:         input.TransfersDisabled,
+		TransferCutoffHours:       model.DefaultTransferCutoffHours,
+		PaymentDueMinutes:         input.PaymentDueMinutes,
+		WaitingRoomEnabled:        input.WaitingRoomEnabled,
+		WaitingRoomAdmitPerMinute: input.WaitingRoomAdmitPerMinute,
+	}
+
+	if input.TransferCutoffHours != nil {
+		event.TransferCutoffHours = *input.TransferCutoffHours
 	}
 
 	if event.Status == "" {
@@ -137,12 +150,18 @@ func (s *eventService) GetEventsByGuestSlug(guestSlug string) ([]model.Event, er
 	return s.eventRepo.GetEventsByGuestSlug(guestSlug)
 }
 
-func (s *eventService) UpdateEvent(slug string, input dto.UpdateEventInput) (*model.Event, error) {
+func (s *eventService) UpdateEvent(ctx context.Context, slug string, input dto.UpdateEventInput, userID uint) (*model.Event, error) {
 	event, err := s.eventRepo.FindBySlug(slug)
 	if err != nil {
 		return nil, err
 	}
 
+	// Orders of a cancelled event are being cancelled and refunded, so it cannot be reopened
+	wasCancelled := event.Status == model.Cancelled
+	if wasCancelled && input.Status != nil && *input.Status != model.Cancelled {
+		return nil, apperrors.NewBusinessRuleError("event_status", "cancelled events cannot be reopened")
+	}
+
 	if input.Name != nil {
 		event.Name = *input.Name
 	}
@@ -168,6 +187,34 @@ func (s *eventService) UpdateEvent(slug string, input dto.UpdateEventInput) (*mo
 		event.SalesEndDate = *input.SalesEndDate
 	}
 
+	if input.AllowReentry != nil {
+		event.AllowReentry = *input.AllowReentry
+	}
+
+	if input.TransfersDisabled != nil {
+		event.TransfersDisabled = *input.TransfersDisabled
+	}
+
+	if input.TransferCutoffHours != nil {
+		event.TransferCutoffHours = *input.TransferCutoffHours
+	}
+
+	if input.PaymentDueMinutes != nil {
+		if *input.PaymentDueMinutes == 0 {
+			event.PaymentDueMinutes = nil
+		} else {
+			event.PaymentDueMinutes = input.PaymentDueMinutes
+		}
+	}
+
+	if input.WaitingRoomEnabled != nil {
+		event.WaitingRoomEnabled = *input.WaitingRoomEnabled
+	}
+
+	if input.WaitingRoomAdmitPerMinute != nil {
+		event.WaitingRoomAdmitPerMinute = *input.WaitingRoomAdmitPerMinute
+	}
+
 	if input.VenueID != nil {
 		// Check if venue exists
 		_, err := s.venueRepo.GetVenueByID(*input.VenueID)
@@ -223,6 +270,16 @@ func (s *eventService) UpdateEvent(slug string, input dto.UpdateEventInput) (*mo
 		return nil, err
 	}
 
+	if !wasCancelled && event.Status == model.Cancelled {
+		reason := ""
+		if input.CancellationReason != nil {
+			reason = *input.CancellationReason
+		}
+		if _, err := s.cancellationService.StartCancellation(ctx, event, userID, reason); err != nil {
+			return nil, err
+		}
+	}
+
 	// Reload the event to get the latest associations
 	updatedEvent, err := s.eventRepo.GetEventByID(event.ID)
 	if err != nil {
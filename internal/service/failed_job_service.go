@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/pkg/queue"
+	"log/slog"
+)
+
+const (
+	defaultFailedJobPageSize = 50
+	maxFailedJobPageSize     = 200
+)
+
+// FailedJobService lets admins watch the job queue and retry or delete jobs that exhausted their attempts
+type FailedJobService interface {
+	GetStats(ctx context.Context) (*dto.JobQueueStatsResponse, error)
+	ListFailedJobs(ctx context.Context, offset int64, count int64) (*dto.FailedJobListResponse, error)
+	RetryFailedJob(ctx context.Context, id string) (*dto.FailedJobResponse, error)
+	DeleteFailedJob(ctx context.Context, id string) error
+}
+
+type failedJobService struct {
+	jobQueue *queue.JobQueue
+	logger   *slog.Logger
+}
+
+func NewFailedJobService(jobQueue *queue.JobQueue, logger *slog.Logger) FailedJobService {
+	return &failedJobService{jobQueue: jobQueue, logger: logger}
+}
+
+func (s *failedJobService) GetStats(ctx context.Context) (*dto.JobQueueStatsResponse, error) {
+	stats, err := s.jobQueue.Stats(ctx)
+	if err != nil {
+		s.logger.Error("failed to get job queue stats", slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_job_queue_stats", err)
+	}
+
+	response := dto.ToJobQueueStatsResponse(stats)
+	return &response, nil
+}
+
+func (s *failedJobService) ListFailedJobs(ctx context.Context, offset int64, count int64) (*dto.FailedJobListResponse, error) {
+	if offset < 0 {
+		return nil, apperrors.NewValidationError("offset", "offset must not be negative", offset)
+	}
+	if count <= 0 {
+		count = defaultFailedJobPageSize
+	}
+	if count > maxFailedJobPageSize {
+		count = maxFailedJobPageSize
+	}
+
+	jobs, total, err := s.jobQueue.FailedJobs(ctx, offset, count)
+	if err != nil {
+		s.logger.Error("failed to list failed jobs", slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("list_failed_jobs", err)
+	}
+
+	response := &dto.FailedJobListResponse{
+		Total:  total,
+		Offset: offset,
+		Jobs:   make([]dto.FailedJobResponse, 0, len(jobs)),
+	}
+	for _, job := range jobs {
+		response.Jobs = append(response.Jobs, dto.ToFailedJobResponse(job))
+	}
+	return response, nil
+}
+
+func (s *failedJobService) RetryFailedJob(ctx context.Context, id string) (*dto.FailedJobResponse, error) {
+	job, err := s.jobQueue.RetryFailed(ctx, id)
+	if err != nil {
+		if errors.Is(err, queue.ErrJobNotFound) {
+			return nil, apperrors.NewBusinessRuleError("failed_job_exists", "failed job not found")
+		}
+		s.logger.Error("failed to retry failed job", slog.String("job_id", id), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("retry_failed_job", err)
+	}
+
+	s.logger.Info("Failed job queued again", slog.String("job_id", id), slog.String("job_type", job.Type))
+	response := dto.ToFailedJobResponse(*job)
+	return &response, nil
+}
+
+func (s *failedJobService) DeleteFailedJob(ctx context.Context, id string) error {
+	if err := s.jobQueue.DeleteFailed(ctx, id); err != nil {
+		if errors.Is(err, queue.ErrJobNotFound) {
+			return apperrors.NewBusinessRuleError("failed_job_exists", "failed job not found")
+		}
+		s.logger.Error("failed to delete failed job", slog.String("job_id", id), slog.String("error", err.Error()))
+		return apperrors.NewSystemError("delete_failed_job", err)
+	}
+	return nil
+}
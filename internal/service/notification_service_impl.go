@@ -1,78 +1,74 @@
 package service
 
 import (
+	"context"
 	"errors"
 	apperrors "learn/internal/errors"
+	"learn/internal/gateway"
 	"learn/internal/model"
 	"log/slog"
+	"net/http"
 
 	"gorm.io/gorm"
 )
 
-func (s *paymentService) HandleNotification(payload map[string]interface{}) error {
+// HandleNotification processes a webhook of the given provider. The provider verifies its own
+// signature and translates its status, so only the payment status transition is handled here.
+func (s *paymentService) HandleNotification(ctx context.Context, provider string, header http.Header, body []byte) error {
 	// 1. Verify Signature
-	valid, err := s.midtransGateway.VerifyPaymentNotification(payload)
-	if err != nil {
-		s.logger.Error("failed to verify notification signature", slog.String("error", err.Error()))
-		return apperrors.NewSystemError("verify_notification", err)
-	}
-	if !valid {
-		s.logger.Warn("invalid notification signature")
-		return apperrors.NewBusinessRuleError("notification_signature", "invalid signature")
+	paymentGateway, ok := s.gateways.ByName(provider)
+	if !ok {
+		return apperrors.NewBusinessRuleError("payment_provider", "unknown payment provider")
 	}
 
-	// 2. Get Order ID and Transaction Status
-	orderIDStr, _ := payload["order_id"].(string)
-	transactionStatus, _ := payload["transaction_status"].(string)
-	fraudStatus, _ := payload["fraud_status"].(string)
-	transactionID, _ := payload["transaction_id"].(string)
-	if orderIDStr == "" || transactionStatus == "" || transactionID == "" {
-		return apperrors.NewBusinessRuleError("notification_payload", "missing required notification fields")
+	notification, err := paymentGateway.VerifyNotification(header, body)
+	if err != nil {
+		if errors.Is(err, gateway.ErrInvalidSignature) {
+			s.logger.Warn("invalid notification signature", slog.String("provider", provider))
+			return apperrors.NewBusinessRuleError("notification_signature", "invalid signature")
+		}
+		s.logger.Warn("invalid notification payload", slog.String("provider", provider), slog.String("error", err.Error()))
+		return apperrors.NewBusinessRuleError("notification_payload", err.Error())
 	}
 
-	s.logger.Info("processing midtrans notification",
-		slog.String("order_id", orderIDStr),
-		slog.String("transaction_status", transactionStatus),
-		slog.String("fraud_status", fraudStatus),
+	s.logger.Info("processing payment notification",
+		slog.String("provider", provider),
+		slog.String("reference", notification.Reference),
+		slog.String("transaction_status", notification.RawStatus),
 	)
 
-	// 3. Get Payment by Transaction ID
-	payment, err := s.paymentRepository.GetPaymentByTransactionID(transactionID)
+	if notification.Ignore {
+		s.logger.Info("ignoring transaction status",
+			slog.String("provider", provider),
+			slog.String("status", notification.RawStatus),
+			slog.String("transaction_id", notification.TransactionID))
+		return nil
+	}
+
+	// 2. Get Payment by Transaction ID
+	payment, err := s.paymentRepository.GetPaymentByTransactionID(notification.TransactionID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			s.logger.Warn("payment not found for notification", slog.String("transaction_id", transactionID))
+			s.logger.Warn("payment not found for notification", slog.String("transaction_id", notification.TransactionID))
 			return apperrors.NewBusinessRuleError("payment_not_found", "payment not found")
 		}
 		s.logger.Error("failed to get payment by transaction ID", slog.String("error", err.Error()))
 		return apperrors.NewSystemError("get_payment_by_transaction_id", err)
 	}
 
-	// 4. Determine New Status
-	var newStatus model.PaymentStatus
-
-	switch transactionStatus {
-	case "capture":
-		if fraudStatus == "challenge" {
-			newStatus = model.PaymentStatusPending // Admin need to review
-		} else if fraudStatus == "accept" {
-			newStatus = model.PaymentStatusSuccess
-		}
-	case "settlement":
-		newStatus = model.PaymentStatusSuccess
-	case "deny", "cancel", "expire":
-		newStatus = model.PaymentStatusFailed
-	case "refund", "partial_refund":
-		newStatus = model.PaymentStatusRefunded
-	case "pending":
-		newStatus = model.PaymentStatusPending
-	default:
-		s.logger.Info("ignoring transaction status", slog.String("status", transactionStatus))
-		return nil
+	if payment.Provider != provider {
+		s.logger.Warn("notification provider does not match payment",
+			slog.Uint64("payment_id", uint64(payment.ID)),
+			slog.String("payment_provider", payment.Provider),
+			slog.String("provider", provider))
+		return apperrors.NewBusinessRuleError("payment_not_found", "payment not found")
 	}
 
-	// 5. Update Payment Status if changed. Duplicate notifications are intentionally idempotent.
+	newStatus := notification.Status
+
+	// 3. Update Payment Status if changed. Duplicate notifications are intentionally idempotent.
 	if payment.PaymentStatus == newStatus {
-		s.logger.Info("ignoring duplicate midtrans notification",
+		s.logger.Info("ignoring duplicate payment notification",
 			slog.Uint64("payment_id", uint64(payment.ID)),
 			slog.String("status", string(newStatus)),
 		)
@@ -88,7 +84,7 @@ func (s *paymentService) HandleNotification(payload map[string]interface{}) erro
 		return nil
 	}
 
-	_, err = s.UpdatePaymentStatus(payment.ID, newStatus)
+	_, err = s.UpdatePaymentStatus(ctx, payment.ID, newStatus)
 	if err != nil {
 		s.logger.Error("failed to update payment status from notification", slog.String("error", err.Error()))
 		return err
@@ -1,32 +1,32 @@
 package service
 
 import (
+	"context"
 	apperrors "learn/internal/errors"
 	"learn/internal/model"
-	"learn/internal/pkg/events"
 	"learn/internal/repository"
 	"log/slog"
 )
 
 type orderCancellationService struct {
-	orderRepo repository.OrderRepository
-	logger    *slog.Logger
-	eventBus  *events.EventBus
+	orderRepo         repository.OrderRepository
+	orderStateMachine OrderStateMachine
+	logger            *slog.Logger
 }
 
 type OrderCancellationService interface {
-	CancelOrder(orderID uint, userID uint, reason string) error
+	CancelOrder(ctx context.Context, orderID uint, userID uint, reason string) error
 }
 
-func NewOrderCancellationService(orderRepo repository.OrderRepository, logger *slog.Logger, eventBus *events.EventBus) OrderCancellationService {
+func NewOrderCancellationService(orderRepo repository.OrderRepository, orderStateMachine OrderStateMachine, logger *slog.Logger) OrderCancellationService {
 	return &orderCancellationService{
-		orderRepo: orderRepo,
-		logger:    logger,
-		eventBus:  eventBus,
+		orderRepo:         orderRepo,
+		orderStateMachine: orderStateMachine,
+		logger:            logger,
 	}
 }
 
-func (s *orderCancellationService) CancelOrder(orderID uint, userID uint, reason string) error {
+func (s *orderCancellationService) CancelOrder(ctx context.Context, orderID uint, userID uint, reason string) error {
 	// Get the order
 	order, err := s.orderRepo.GetOrderByID(orderID)
 	if err != nil {
@@ -52,33 +52,14 @@ func (s *orderCancellationService) CancelOrder(orderID uint, userID uint, reason
 		return apperrors.NewBusinessRuleError("order_status", "paid orders cannot be cancelled manually")
 	}
 
-	// Update order status to cancelled
-	order.Status = model.OrderCancelled
-	err = s.orderRepo.UpdateOrder(order)
+	// Cancel only while still pending, the order may have been paid or expired since it was read
+	cancelled, err := s.orderStateMachine.Cancel(ctx, order.ID, reason)
 	if err != nil {
-		s.logger.Error("Failed to update order status to cancelled",
-			slog.Uint64("order_id", uint64(orderID)),
-			slog.String("error", err.Error()))
-		return apperrors.NewSystemError("update_order_status", err)
-	}
-
-	// Restore quotas for the order
-	err = s.restoreQuotasForOrder(order)
-	if err != nil {
-		s.logger.Error("Failed to restore quotas for cancelled order",
-			slog.Uint64("order_id", uint64(orderID)),
-			slog.String("error", err.Error()))
-		// Don't return error here as the order is already cancelled
+		return err
 	}
-
-	// Publish OrderCancelledEvent
-	orderCancelledEvent := events.OrderCancelledEvent{
-		OrderID:     order.ID,
-		UserID:      userID,
-		Reason:      reason,
-		CancelledAt: order.UpdatedAt,
+	if !cancelled {
+		return apperrors.NewBusinessRuleError("order_status", "order is no longer pending")
 	}
-	s.eventBus.Publish(orderCancelledEvent)
 
 	s.logger.Info("Order cancelled successfully",
 		slog.Uint64("order_id", uint64(orderID)),
@@ -86,29 +67,3 @@ func (s *orderCancellationService) CancelOrder(orderID uint, userID uint, reason
 
 	return nil
 }
-
-// restoreQuotasForOrder restores the quotas for a cancelled order
-func (s *orderCancellationService) restoreQuotasForOrder(order *model.Order) error {
-	// Get order line items to determine which quotas to restore
-	orderWithLineItems, err := s.orderRepo.GetOrderByIDWithLineItems(order.ID)
-	if err != nil {
-		return err
-	}
-
-	// Restore quotas for each line item
-	for _, lineItem := range orderWithLineItems.OrderLineItems {
-		err := s.orderRepo.RestoreQuota(lineItem.EventPriceID, lineItem.Quantity)
-		if err != nil {
-			s.logger.Error("Failed to restore quota",
-				slog.Uint64("event_price_id", uint64(lineItem.EventPriceID)),
-				slog.Int("quantity", lineItem.Quantity),
-				slog.String("error", err.Error()))
-			return err
-		}
-	}
-
-	s.logger.Info("Quotas restored for cancelled order",
-		slog.Uint64("order_id", uint64(order.ID)))
-
-	return nil
-}
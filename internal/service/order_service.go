@@ -1,40 +1,50 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"learn/internal/config"
 	"learn/internal/dto"
 	apperrors "learn/internal/errors"
 	"learn/internal/model"
 	"learn/internal/pkg/events"
+	"learn/internal/pkg/waitingroom"
 	"learn/internal/repository"
 	"log/slog"
 	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
 )
 
 type orderService struct {
-	orderRepo repository.OrderRepository
-	logger    *slog.Logger
-	redis     *redis.Client
-	eventBus  *events.EventBus
+	orderRepo    repository.OrderRepository
+	paymentRepo  repository.PaymentRepository
+	waitlistRepo repository.WaitlistRepository
+	logger       *slog.Logger
+	redis        *redis.Client
+	waitingRoom  *waitingroom.Room
 }
 
 type OrderService interface {
-	CreateOrder(input dto.NewOrderInput, userID uint) (*model.Order, error)
+	CreateOrder(ctx context.Context, input dto.NewOrderInput, userID uint) (*model.Order, error)
+	GetOrderDetail(orderID uint, userID uint) (*dto.OrderDetailResponse, error)
+	ExtendHold(ctx context.Context, orderID uint, userID uint) (*model.Order, error)
 }
 
-func NewOrderService(orderRepo repository.OrderRepository, logger *slog.Logger, eventBus *events.EventBus) OrderService {
+func NewOrderService(orderRepo repository.OrderRepository, paymentRepo repository.PaymentRepository, waitlistRepo repository.WaitlistRepository, logger *slog.Logger) OrderService {
 	return &orderService{
-		orderRepo: orderRepo,
-		logger:    logger,
-		redis:     config.Rdb, // Using the global Redis client from config
-		eventBus:  eventBus,
+		orderRepo:    orderRepo,
+		paymentRepo:  paymentRepo,
+		waitlistRepo: waitlistRepo,
+		logger:       logger,
+		redis:        config.Rdb, // Using the global Redis client from config
+		waitingRoom:  waitingroom.NewRoom(config.Rdb),
 	}
 }
 
-func (s *orderService) CreateOrder(input dto.NewOrderInput, userID uint) (*model.Order, error) {
+func (s *orderService) CreateOrder(ctx context.Context, input dto.NewOrderInput, userID uint) (*model.Order, error) {
 	eventID, err := strconv.ParseUint(input.EventID, 10, 32)
 	if err != nil {
 		return nil, apperrors.NewValidationError("event_id", "invalid event id", input.EventID)
@@ -54,6 +64,14 @@ func (s *orderService) CreateOrder(input dto.NewOrderInput, userID uint) (*model
 		return nil, apperrors.NewBusinessRuleError("event_sales_period", "event is not within sales period")
 	}
 
+	var admission *waitingroom.Claims
+	if event.WaitingRoomEnabled {
+		admission, err = waitingroom.VerifyAdmission(input.AdmissionToken, event.ID, userID)
+		if err != nil {
+			return nil, apperrors.NewBusinessRuleError("waiting_room_admission", "a valid admission token from the waiting room is required: "+err.Error())
+		}
+	}
+
 	var priceIDs []uint
 	quantityMap := make(map[uint]int)
 	totalQuantity := 0 // Track total tickets ordered
@@ -92,6 +110,17 @@ func (s *orderService) CreateOrder(input dto.NewOrderInput, userID uint) (*model
 		return nil, apperrors.NewBusinessRuleError("event_prices_exist", "one or more prices not found")
 	}
 
+	// Quota set aside for the waitlist is only sold to users holding an offer
+	offers, err := s.waitlistRepo.GetOpenOffers(userID, priceIDs, time.Now())
+	if err != nil {
+		s.logger.Error("failed to get waitlist offers", slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_waitlist_offers", err)
+	}
+	offered := make(map[uint]int)
+	for _, offer := range offers {
+		offered[offer.EventPriceID] = offer.Quantity
+	}
+
 	var totalPrice int64
 	priceUpdates := make(map[uint]int)
 
@@ -101,8 +130,8 @@ func (s *orderService) CreateOrder(input dto.NewOrderInput, userID uint) (*model
 		}
 
 		quantity := quantityMap[price.ID]
-		if price.Quota < quantity {
-			return nil, apperrors.NewBusinessRuleError("ticket_quota", "not enough quota for ticket")
+		if price.Quota-price.WaitlistQuota+offered[price.ID] < quantity {
+			return nil, apperrors.NewBusinessRuleError("ticket_quota", "not enough quota for ticket, join the waitlist to get an offer when tickets come back")
 		}
 
 		// Calculate total price using integer arithmetic to avoid floating point errors
@@ -131,31 +160,137 @@ func (s *orderService) CreateOrder(input dto.NewOrderInput, userID uint) (*model
 
 	defer s.redis.Del(config.Ctx, orderLockKey) // Clean up lock
 
+	// An admission is good for one order, it is given back when the order cannot be created
+	if admission != nil {
+		consumed, err := s.waitingRoom.ConsumeAdmission(ctx, input.AdmissionToken, admission.ExpiresAt)
+		if err != nil {
+			s.logger.Error("failed to consume admission token", slog.String("error", err.Error()))
+			return nil, apperrors.NewSystemError("consume_admission", err)
+		}
+		if !consumed {
+			return nil, apperrors.NewBusinessRuleError("waiting_room_admission", "the admission token was already used for an order")
+		}
+	}
+
+	// The quota is held for a short window, starting a payment replaces it with the payment due
+	// of the chosen method
+	holdExpiresAt := time.Now().Add(config.AppConfig.OrderHoldWindow)
 	order := &model.Order{
-		UserID:     userID,
-		TotalPrice: totalPrice,
-		Status:     model.OrderPending,
-		PaymentDue: time.Now().Add(24 * time.Hour),
+		UserID:        userID,
+		TotalPrice:    totalPrice,
+		Status:        model.OrderPending,
+		PaymentDue:    holdExpiresAt,
+		HoldExpiresAt: &holdExpiresAt,
 	}
 
-	err = s.orderRepo.CreateOrderInTransaction(order, prices, priceUpdates)
+	// OrderCreatedEvent is committed with the order and published by the outbox relay
+	err = s.orderRepo.CreateOrderInTransaction(ctx, order, prices, priceUpdates, func(order *model.Order) repository.DomainEvent {
+		return events.OrderCreatedEvent{
+			OrderID:    order.ID,
+			UserID:     userID,
+			TotalPrice: totalPrice,
+			CreatedAt:  time.Now(),
+		}
+	})
 	if err != nil {
+		if admission != nil {
+			if releaseErr := s.waitingRoom.ReleaseAdmission(ctx, input.AdmissionToken); releaseErr != nil {
+				s.logger.Error("failed to release admission token", slog.String("error", releaseErr.Error()))
+			}
+		}
+		if errors.Is(err, repository.ErrNotEnoughQuota) {
+			return nil, apperrors.NewBusinessRuleError("ticket_quota", "not enough quota for ticket, join the waitlist to get an offer when tickets come back")
+		}
 		s.logger.Error("failed to create order", slog.String("error", err.Error()))
 		return nil, apperrors.NewSystemError("create_order_transaction", err)
 	}
 
-	// Publish OrderCreatedEvent
-	orderCreatedEvent := events.OrderCreatedEvent{
-		OrderID:    order.ID,
-		UserID:     userID,
-		TotalPrice: totalPrice,
-		CreatedAt:  time.Now(),
+	return order, nil
+}
+
+// ExtendHold extends the hold of a pending order once by ORDER_HOLD_EXTENSION, as long as it has not
+// expired and no payment was started
+func (s *orderService) ExtendHold(ctx context.Context, orderID uint, userID uint) (*model.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("order_exists", "order not found")
+		}
+		s.logger.Error("failed to get order for hold extension",
+			slog.Uint64("order_id", uint64(orderID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_order", err)
+	}
+
+	if order.UserID != userID {
+		return nil, apperrors.NewBusinessRuleError("order_authorization", "you are not authorized to access this order")
+	}
+	if order.Status != model.OrderPending {
+		return nil, apperrors.NewBusinessRuleError("order_status", "only pending orders can be extended")
+	}
+	if order.HoldExpiresAt == nil {
+		return nil, apperrors.NewBusinessRuleError("order_hold", "a payment was already started for this order")
+	}
+	if order.HoldExtended {
+		return nil, apperrors.NewBusinessRuleError("order_hold", "the hold can only be extended once")
+	}
+	if !time.Now().Before(*order.HoldExpiresAt) {
+		return nil, apperrors.NewBusinessRuleError("order_hold", "the hold has expired")
 	}
-	s.eventBus.Publish(orderCreatedEvent)
+
+	extendTo := order.HoldExpiresAt.Add(config.AppConfig.OrderHoldExtension)
+	extended, err := s.orderRepo.ExtendOrderHold(ctx, order, extendTo)
+	if err != nil {
+		s.logger.Error("failed to extend order hold",
+			slog.Uint64("order_id", uint64(orderID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("extend_order_hold", err)
+	}
+	if !extended {
+		// A concurrent request extended the hold, started a payment or the order expired
+		return nil, apperrors.NewBusinessRuleError("order_hold", "the hold can no longer be extended")
+	}
+
+	s.logger.Info("Order hold extended",
+		slog.Uint64("order_id", uint64(orderID)),
+		slog.Time("hold_expires_at", extendTo))
 
 	return order, nil
 }
 
+func (s *orderService) GetOrderDetail(orderID uint, userID uint) (*dto.OrderDetailResponse, error) {
+	order, err := s.orderRepo.GetOrderDetailByID(orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("order_exists", "order not found")
+		}
+		s.logger.Error("failed to get order detail",
+			slog.Uint64("order_id", uint64(orderID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_order_detail", err)
+	}
+
+	// Check if the order belongs to the user
+	if order.UserID != userID {
+		s.logger.Error("User not authorized to view order",
+			slog.Uint64("user_id", uint64(userID)),
+			slog.Uint64("order_id", uint64(orderID)))
+		return nil, apperrors.NewBusinessRuleError("order_authorization", "you are not authorized to view this order")
+	}
+
+	// An order without a payment yet is still a valid order, so only real failures are surfaced
+	payment, err := s.paymentRepo.GetPaymentByOrderID(order.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Error("failed to get payment for order detail",
+			slog.Uint64("order_id", uint64(orderID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_payment_by_order_id", err)
+	}
+
+	response := dto.ToOrderDetailResponse(*order, payment)
+	return &response, nil
+}
+
 // checkRecentOrders checks how many orders a user has placed in the last hour
 func (s *orderService) checkRecentOrders(userID uint) (int, error) {
 	key := "recent_orders:" + strconv.FormatUint(uint64(userID), 10)
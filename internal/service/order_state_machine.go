@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	apperrors "learn/internal/errors"
+	"learn/internal/model"
+	"learn/internal/pkg/events"
+	"learn/internal/repository"
+	"log/slog"
+	"time"
+)
+
+// OrderStateMachine cancels orders for the services: manual cancellation, expiration and event
+// cancellation. Payments mark orders paid or cancelled and refunds mark them refunded inside their
+// own repository transactions. All of them go through the repository's conditional update on the
+// current status, which only allows the transitions of model.CanTransitionOrderStatus and restores
+// the quota in the same transaction when cancelling, so racing cancellations, expirations and
+// payments can neither restore quota twice nor cancel an order that was just paid.
+type OrderStateMachine interface {
+	// Cancel cancels a PENDING order and records an OrderCancelledEvent with it. It returns false
+	// when the order already left PENDING.
+	Cancel(ctx context.Context, orderID uint, reason string) (bool, error)
+}
+
+type orderStateMachine struct {
+	orderRepo repository.OrderRepository
+	logger    *slog.Logger
+}
+
+func NewOrderStateMachine(orderRepo repository.OrderRepository, logger *slog.Logger) OrderStateMachine {
+	return &orderStateMachine{orderRepo: orderRepo, logger: logger}
+}
+
+func (m *orderStateMachine) Cancel(ctx context.Context, orderID uint, reason string) (bool, error) {
+	// OrderCancelledEvent is committed with the status change and published by the outbox relay
+	_, cancelled, err := m.orderRepo.TransitionOrderStatus(ctx, orderID, model.OrderPending, model.OrderCancelled, func(order *model.Order) repository.DomainEvent {
+		return events.OrderCancelledEvent{
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			Reason:      reason,
+			CancelledAt: time.Now(),
+		}
+	})
+	if err != nil {
+		return false, m.transitionError(orderID, model.OrderCancelled, err)
+	}
+
+	if cancelled {
+		m.logger.Info("Order cancelled and quota restored",
+			slog.Uint64("order_id", uint64(orderID)),
+			slog.String("reason", reason))
+	}
+	return cancelled, nil
+}
+
+func (m *orderStateMachine) transitionError(orderID uint, to model.OrderStatus, err error) error {
+	if errors.Is(err, repository.ErrOrderTransition) {
+		return apperrors.NewBusinessRuleError("order_status", err.Error())
+	}
+	m.logger.Error("failed to change order status",
+		slog.Uint64("order_id", uint64(orderID)),
+		slog.String("status", string(to)),
+		slog.String("error", err.Error()))
+	return apperrors.NewSystemError("update_order_status", err)
+}
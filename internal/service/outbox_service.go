@@ -0,0 +1,42 @@
+package service
+
+import (
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/repository"
+	"log/slog"
+	"time"
+)
+
+// OutboxService reports the health of the transactional outbox
+type OutboxService interface {
+	GetStats() (*dto.OutboxStatsResponse, error)
+}
+
+type outboxService struct {
+	outboxRepo repository.OutboxRepository
+	logger     *slog.Logger
+}
+
+func NewOutboxService(outboxRepo repository.OutboxRepository, logger *slog.Logger) OutboxService {
+	return &outboxService{outboxRepo: outboxRepo, logger: logger}
+}
+
+func (s *outboxService) GetStats() (*dto.OutboxStatsResponse, error) {
+	stats, err := s.outboxRepo.GetStats()
+	if err != nil {
+		s.logger.Error("failed to get outbox stats", slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_outbox_stats", err)
+	}
+
+	response := &dto.OutboxStatsResponse{
+		Pending:         stats.Pending,
+		Retrying:        stats.Retrying,
+		SentLastHour:    stats.SentLastHour,
+		OldestPendingAt: stats.OldestPendingAt,
+	}
+	if stats.OldestPendingAt != nil {
+		response.OldestPendingAgeSeconds = int64(time.Since(*stats.OldestPendingAt).Seconds())
+	}
+	return response, nil
+}
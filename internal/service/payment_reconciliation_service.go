@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/gateway"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// reconciliationBatchSize is the number of pending payments loaded per round of a reconciliation run
+const reconciliationBatchSize = 100
+
+// PaymentReconciliationService catches up on lost webhooks by asking the gateway for the status
+// of payments that stayed PENDING, and queues the mismatches it must not apply for admin review.
+type PaymentReconciliationService interface {
+	Reconcile(olderThan time.Duration) (*dto.ReconciliationSummary, error)
+	ResolveIssue(issueID uint, input dto.ResolveReconciliationIssueRequest, userID uint) (*dto.ReconciliationIssueResponse, error)
+}
+
+type paymentReconciliationService struct {
+	reconciliationRepo repository.PaymentReconciliationRepository
+	paymentRepo        repository.PaymentRepository
+	paymentService     PaymentService
+	gateways           *gateway.Registry
+	logger             *slog.Logger
+}
+
+func NewPaymentReconciliationService(
+	reconciliationRepo repository.PaymentReconciliationRepository,
+	paymentRepo repository.PaymentRepository,
+	paymentService PaymentService,
+	gateways *gateway.Registry,
+	logger *slog.Logger,
+) PaymentReconciliationService {
+	return &paymentReconciliationService{
+		reconciliationRepo: reconciliationRepo,
+		paymentRepo:        paymentRepo,
+		paymentService:     paymentService,
+		gateways:           gateways,
+		logger:             logger,
+	}
+}
+
+// Reconcile checks every payment that has been PENDING for longer than olderThan. A failure on one
+// payment is counted and logged, the run goes on with the next one.
+func (s *paymentReconciliationService) Reconcile(olderThan time.Duration) (*dto.ReconciliationSummary, error) {
+	summary := &dto.ReconciliationSummary{StartedAt: time.Now()}
+	cutoff := summary.StartedAt.Add(-olderThan)
+
+	var lastPaymentID uint
+	for {
+		payments, err := s.reconciliationRepo.GetPendingPaymentsCreatedBefore(cutoff, lastPaymentID, reconciliationBatchSize)
+		if err != nil {
+			s.logger.Error("failed to load pending payments for reconciliation", slog.String("error", err.Error()))
+			return nil, apperrors.NewSystemError("get_pending_payments", err)
+		}
+
+		for _, payment := range payments {
+			s.reconcilePayment(payment, summary)
+			lastPaymentID = payment.ID
+		}
+
+		if len(payments) < reconciliationBatchSize {
+			break
+		}
+	}
+
+	summary.Duration = time.Since(summary.StartedAt).Round(time.Millisecond).String()
+	s.logger.Info("payment reconciliation finished",
+		slog.Int("checked", summary.Checked),
+		slog.Int("applied", summary.Applied),
+		slog.Int("unchanged", summary.Unchanged),
+		slog.Int("flagged", summary.Flagged),
+		slog.Int("failed", summary.Failed))
+
+	return summary, nil
+}
+
+func (s *paymentReconciliationService) reconcilePayment(payment model.Payment, summary *dto.ReconciliationSummary) {
+	summary.Checked++
+	logger := s.logger.With(slog.Uint64("payment_id", uint64(payment.ID)), slog.String("provider", payment.Provider))
+
+	paymentGateway, ok := s.gateways.ByName(payment.Provider)
+	if !ok {
+		logger.Warn("payment provider is not registered, skipping reconciliation")
+		summary.Failed++
+		return
+	}
+
+	status, err := paymentGateway.GetStatus(payment.TransactionID)
+	if err != nil {
+		logger.Error("failed to get transaction status", slog.String("error", err.Error()))
+		summary.Failed++
+		return
+	}
+
+	// Unknown statuses and transactions that are still pending at the gateway are left alone
+	if status.Status == "" || status.Status == payment.PaymentStatus {
+		summary.Unchanged++
+		return
+	}
+
+	if status.Status == model.PaymentStatusSuccess {
+		if status.Amount != 0 && status.Amount != payment.Order.TotalPrice {
+			s.flag(payment, status, model.ReconciliationAmountMismatch,
+				fmt.Sprintf("gateway settled %d but the order total is %d", status.Amount, payment.Order.TotalPrice), summary)
+			return
+		}
+		if payment.Order.Status != model.OrderPending {
+			s.flag(payment, status, model.ReconciliationPaidAfterCancellation,
+				fmt.Sprintf("gateway settled the payment after the order became %s", payment.Order.Status), summary)
+			return
+		}
+	}
+
+	if !model.CanTransitionPaymentStatus(payment.PaymentStatus, status.Status) {
+		logger.Warn("ignoring invalid payment status transition from gateway",
+			slog.String("from", string(payment.PaymentStatus)),
+			slog.String("to", string(status.Status)))
+		summary.Unchanged++
+		return
+	}
+
+	if status.Status == model.PaymentStatusFailed && payment.Order.Status != model.OrderPending {
+		// The order was already closed and its quota restored, only the payment is caught up
+		_, _, err = s.paymentRepo.UpdatePaymentStatusInTransaction(context.Background(), payment.ID, status.Status, nil)
+	} else {
+		_, err = s.paymentService.UpdatePaymentStatus(context.Background(), payment.ID, status.Status)
+	}
+	if err != nil {
+		logger.Error("failed to apply reconciled payment status", slog.String("status", string(status.Status)), slog.String("error", err.Error()))
+		summary.Failed++
+		return
+	}
+
+	logger.Info("applied missed payment status transition",
+		slog.String("from", string(payment.PaymentStatus)),
+		slog.String("to", string(status.Status)),
+		slog.String("gateway_status", status.RawStatus))
+	summary.Applied++
+}
+
+func (s *paymentReconciliationService) flag(payment model.Payment, status *gateway.TransactionStatus, issueType model.ReconciliationIssueType, detail string, summary *dto.ReconciliationSummary) {
+	issue := model.PaymentReconciliationIssue{
+		PaymentID:      payment.ID,
+		OrderID:        payment.OrderID,
+		Type:           issueType,
+		Status:         model.ReconciliationIssueOpen,
+		GatewayStatus:  status.RawStatus,
+		ExpectedAmount: payment.Order.TotalPrice,
+		GatewayAmount:  status.Amount,
+		Detail:         detail,
+	}
+
+	created, err := s.reconciliationRepo.CreateIssue(&issue)
+	if err != nil {
+		s.logger.Error("failed to flag payment reconciliation issue",
+			slog.Uint64("payment_id", uint64(payment.ID)),
+			slog.String("type", string(issueType)),
+			slog.String("error", err.Error()))
+		summary.Failed++
+		return
+	}
+
+	if created {
+		s.logger.Warn("payment flagged for reconciliation review",
+			slog.Uint64("payment_id", uint64(payment.ID)),
+			slog.String("type", string(issueType)),
+			slog.String("detail", detail))
+		summary.Flagged++
+		return
+	}
+	summary.Unchanged++
+}
+
+func (s *paymentReconciliationService) ResolveIssue(issueID uint, input dto.ResolveReconciliationIssueRequest, userID uint) (*dto.ReconciliationIssueResponse, error) {
+	note := strings.TrimSpace(input.Note)
+	if note == "" {
+		return nil, apperrors.NewValidationError("note", "resolution note is required", input.Note)
+	}
+
+	issue, err := s.reconciliationRepo.ResolveIssue(issueID, userID, note)
+	if err != nil {
+		if errors.Is(err, repository.ErrReconciliationIssueResolved) {
+			if _, getErr := s.reconciliationRepo.GetIssueByID(issueID); errors.Is(getErr, gorm.ErrRecordNotFound) {
+				return nil, apperrors.NewBusinessRuleError("reconciliation_issue_exists", "reconciliation issue not found")
+			}
+			return nil, apperrors.NewBusinessRuleError("reconciliation_issue_status", "reconciliation issue is already resolved")
+		}
+		s.logger.Error("failed to resolve reconciliation issue", slog.Uint64("issue_id", uint64(issueID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("resolve_reconciliation_issue", err)
+	}
+
+	response := dto.ToReconciliationIssueResponse(*issue)
+	return &response, nil
+}
@@ -1,32 +1,32 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"learn/internal/config"
 	"learn/internal/dto"
 	apperrors "learn/internal/errors"
-	midtransGateway "learn/internal/gateway/midtrans"
+	"learn/internal/gateway"
 	"learn/internal/model"
 	"learn/internal/pkg/events"
-	"learn/internal/pkg/queue"
 	"learn/internal/repository"
 	"log/slog"
+	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/midtrans/midtrans-go/coreapi"
 	"gorm.io/gorm"
 )
 
 type PaymentService interface {
-	CreatePayment(req *dto.CreatePaymentRequest, userID uint) (*model.Payment, error)
+	CreatePayment(ctx context.Context, req *dto.CreatePaymentRequest, userID uint) (*model.Payment, error)
 	GetPaymentByID(paymentID uint) (*model.Payment, error)
 	GetPaymentByOrderID(orderID uint) (*model.Payment, error)
 	UpdatePayment(paymentID uint, req *dto.UpdatePaymentRequest) (*model.Payment, error)
-	UpdatePaymentStatus(paymentID uint, status model.PaymentStatus) (*model.Payment, error)
+	UpdatePaymentStatus(ctx context.Context, paymentID uint, status model.PaymentStatus) (*model.Payment, error)
 	DeletePayment(paymentID uint) error
-	HandleNotification(payload map[string]interface{}) error
+	HandleNotification(ctx context.Context, provider string, header http.Header, body []byte) error
 }
 
 type paymentService struct {
@@ -35,27 +35,23 @@ type paymentService struct {
 	ticketRepository  repository.TicketRepository
 	eventRepository   repository.EventRepository
 	logger            *slog.Logger
-	jobQueue          *queue.JobQueue
-	eventBus          *events.EventBus
-	midtransGateway   midtransGateway.MidtransGateway // Added
+	gateways          *gateway.Registry
 }
 
-func NewPaymentService(paymentRepo repository.PaymentRepository, orderRepo repository.OrderRepository, ticketRepo repository.TicketRepository, eventRepo repository.EventRepository, logger *slog.Logger, eventBus *events.EventBus) PaymentService {
+func NewPaymentService(paymentRepo repository.PaymentRepository, orderRepo repository.OrderRepository, ticketRepo repository.TicketRepository, eventRepo repository.EventRepository, logger *slog.Logger, gateways *gateway.Registry) PaymentService {
 	return &paymentService{
 		paymentRepository: paymentRepo,
 		orderRepository:   orderRepo,
 		ticketRepository:  ticketRepo,
 		eventRepository:   eventRepo,
 		logger:            logger,
-		jobQueue:          queue.NewJobQueue(5, logger),
-		eventBus:          eventBus,
-		midtransGateway:   midtransGateway.NewMidtransGateway(logger), // Initialize Gateway
+		gateways:          gateways,
 	}
 }
 
-func (s *paymentService) CreatePayment(req *dto.CreatePaymentRequest, userID uint) (*model.Payment, error) {
+func (s *paymentService) CreatePayment(ctx context.Context, req *dto.CreatePaymentRequest, userID uint) (*model.Payment, error) {
 	// Check if order exists
-	order, err := s.orderRepository.GetOrderByID(req.OrderID)
+	order, err := s.orderRepository.GetOrderByIDWithLineItems(req.OrderID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, apperrors.NewBusinessRuleError("order_exists", "order not found")
@@ -79,77 +75,98 @@ func (s *paymentService) CreatePayment(req *dto.CreatePaymentRequest, userID uin
 		return nil, apperrors.NewBusinessRuleError("payment_unique", "payment already exists for this order")
 	}
 
-	// Process Payment with Midtrans
-	var midtransResp *coreapi.ChargeResponse
-	var midtransErr error
-
-	// Create unique Order ID for Midtrans (orderID-timestamp)
-	midtransOrderID := fmt.Sprintf("ORDER-%d-%d", order.ID, time.Now().Unix())
+	if order.HoldExpiresAt != nil && !time.Now().Before(order.PaymentDue) {
+		return nil, apperrors.NewBusinessRuleError("order_hold", "the order hold has expired")
+	}
 
-	switch req.PaymentMethod {
-	case model.PaymentMethodBankTransferBCA:
-		midtransResp, midtransErr = s.midtransGateway.ChargeBankTransfer(midtransOrderID, order.TotalPrice, "bca")
-	case model.PaymentMethodBankTransferBNI:
-		midtransResp, midtransErr = s.midtransGateway.ChargeBankTransfer(midtransOrderID, order.TotalPrice, "bni")
-	case model.PaymentMethodBankTransferBRI:
-		midtransResp, midtransErr = s.midtransGateway.ChargeBankTransfer(midtransOrderID, order.TotalPrice, "bri")
-	case model.PaymentMethodGopay:
-		midtransResp, midtransErr = s.midtransGateway.ChargeGopay(midtransOrderID, order.TotalPrice)
-	case model.PaymentMethodIndomaret:
-		midtransResp, midtransErr = s.midtransGateway.ChargeIndomaret(midtransOrderID, order.TotalPrice, "Payment for Order "+strconv.Itoa(int(order.ID)))
-	default:
+	paymentGateway, ok := s.gateways.ForMethod(req.PaymentMethod)
+	if !ok {
 		return nil, apperrors.NewBusinessRuleError("payment_method", "unsupported payment method")
 	}
 
-	if midtransErr != nil {
-		return nil, apperrors.NewSystemError("midtrans_charge", midtransErr)
+	paymentDue, err := s.paymentDue(order, req.PaymentMethod)
+	if err != nil {
+		s.logger.Error("failed to get payment due of order", slog.Uint64("order_id", uint64(order.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_payment_due", err)
 	}
 
-	// Parse Midtrans Response
-	payment := &model.Payment{
-		OrderID:       req.OrderID,
-		PaymentMethod: req.PaymentMethod,
-		TransactionID: midtransResp.TransactionID,
-		PaymentStatus: model.PaymentStatusPending,
+	// Create unique reference for the provider (orderID-timestamp)
+	chargeReq := gateway.ChargeRequest{
+		Reference:   fmt.Sprintf("ORDER-%d-%d", order.ID, time.Now().Unix()),
+		Amount:      order.TotalPrice,
+		Method:      req.PaymentMethod,
+		Description: "Payment for Order " + strconv.Itoa(int(order.ID)),
+		PayerEmail:  order.User.Email,
+		ExpiresAt:   paymentDue,
 	}
 
-	// Extract specific fields based on payment method
-	if len(midtransResp.VaNumbers) > 0 {
-		payment.VirtualAccountNumber = midtransResp.VaNumbers[0].VANumber
-	}
-	if len(midtransResp.Actions) > 0 {
-		for _, action := range midtransResp.Actions {
-			if action.Name == "generate-qr-code" {
-				payment.PaymentURL = action.URL
-			}
-		}
+	charge, err := paymentGateway.Charge(chargeReq)
+	if err != nil {
+		return nil, apperrors.NewSystemError(paymentGateway.Name()+"_charge", err)
 	}
-	if midtransResp.PaymentType == "indomaret" {
-		payment.PaymentCode = midtransResp.PaymentCode
+
+	payment := &model.Payment{
+		OrderID:              req.OrderID,
+		PaymentMethod:        req.PaymentMethod,
+		TransactionID:        charge.TransactionID,
+		Provider:             paymentGateway.Name(),
+		PaymentStatus:        model.PaymentStatusPending,
+		PaymentURL:           charge.PaymentURL,
+		VirtualAccountNumber: charge.VirtualAccountNumber,
+		PaymentCode:          charge.PaymentCode,
+		BillKey:              charge.BillKey,
+		BillerCode:           charge.BillerCode,
 	}
 
-	if err := s.paymentRepository.CreatePaymentInTransaction(payment); err != nil {
+	// PaymentCreatedEvent is committed with the payment and published by the outbox relay
+	err = s.paymentRepository.CreatePaymentInTransaction(ctx, payment, paymentDue, func(payment *model.Payment) repository.DomainEvent {
+		return events.PaymentCreatedEvent{
+			PaymentID: payment.ID,
+			OrderID:   req.OrderID,
+			Method:    req.PaymentMethod,
+			Amount:    order.TotalPrice,
+			CreatedAt: time.Now(),
+		}
+	})
+	if err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			return nil, apperrors.NewBusinessRuleError("payment_unique", "payment already exists for this order")
 		}
 		if errors.Is(err, gorm.ErrInvalidTransaction) {
 			return nil, apperrors.NewBusinessRuleError("order_status", "payment can only be created for pending orders")
 		}
+		if errors.Is(err, repository.ErrOrderHoldExpired) {
+			return nil, apperrors.NewBusinessRuleError("order_hold", "the order hold has expired")
+		}
 		s.logger.Error("failed to create payment in repository", slog.String("error", err.Error()))
 		return nil, apperrors.NewSystemError("create_payment", err)
 	}
 
-	// Publish PaymentCreatedEvent
-	paymentCreatedEvent := events.PaymentCreatedEvent{
-		PaymentID: payment.ID,
-		OrderID:   req.OrderID,
-		Method:    req.PaymentMethod,
-		Amount:    order.TotalPrice,
-		CreatedAt: time.Now(),
+	return payment, nil
+}
+
+// paymentDue is when the order is cancelled if the payment is still pending: the window of the
+// payment method, capped by the payment due of the event when the organizer set one
+func (s *paymentService) paymentDue(order *model.Order, method model.PaymentMethod) (time.Time, error) {
+	window := config.PaymentDueFor(string(method))
+
+	if len(order.OrderLineItems) > 0 {
+		price, err := s.eventRepository.GetEventPriceByID(order.OrderLineItems[0].EventPriceID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		event, err := s.eventRepository.GetEventByID(price.EventID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if event.PaymentDueMinutes != nil {
+			if eventWindow := time.Duration(*event.PaymentDueMinutes) * time.Minute; eventWindow < window {
+				window = eventWindow
+			}
+		}
 	}
-	s.eventBus.Publish(paymentCreatedEvent)
 
-	return payment, nil
+	return time.Now().Add(window), nil
 }
 
 func (s *paymentService) GetPaymentByID(paymentID uint) (*model.Payment, error) {
@@ -200,7 +217,7 @@ func (s *paymentService) UpdatePayment(paymentID uint, req *dto.UpdatePaymentReq
 	return payment, nil
 }
 
-func (s *paymentService) UpdatePaymentStatus(paymentID uint, status model.PaymentStatus) (*model.Payment, error) {
+func (s *paymentService) UpdatePaymentStatus(ctx context.Context, paymentID uint, status model.PaymentStatus) (*model.Payment, error) {
 	// Use Redis lock to prevent concurrent updates to the same payment
 	lockKey := "payment_lock:" + fmt.Sprintf("%d", paymentID)
 	set, err := s.paymentRepository.GetRedisClient().SetNX(config.Ctx, lockKey, "locked", 30*time.Second).Result()
@@ -226,20 +243,18 @@ func (s *paymentService) UpdatePaymentStatus(paymentID uint, status model.Paymen
 		return nil, apperrors.NewBusinessRuleError("payment_status_transition", fmt.Sprintf("cannot transition payment status from %s to %s", payment.PaymentStatus, status))
 	}
 
-	payment, changed, err := s.paymentRepository.UpdatePaymentStatusInTransaction(paymentID, status)
-	if err != nil {
-		s.logger.Error("failed to update payment status in transaction", slog.Uint64("payment_id", uint64(paymentID)), slog.String("error", err.Error()))
-		return nil, apperrors.NewSystemError("update_payment_status", err)
-	}
-
-	if changed {
-		paymentStatusEvent := events.PaymentStatusUpdatedEvent{
+	// PaymentStatusUpdatedEvent is committed with the status change and published by the outbox relay
+	payment, _, err = s.paymentRepository.UpdatePaymentStatusInTransaction(ctx, paymentID, status, func(payment *model.Payment) repository.DomainEvent {
+		return events.PaymentStatusUpdatedEvent{
 			PaymentID: paymentID,
 			OrderID:   payment.OrderID,
 			Status:    status,
 			UpdatedAt: time.Now(),
 		}
-		s.eventBus.Publish(paymentStatusEvent)
+	})
+	if err != nil {
+		s.logger.Error("failed to update payment status in transaction", slog.Uint64("payment_id", uint64(paymentID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("update_payment_status", err)
 	}
 
 	return payment, nil
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	apperrors "learn/internal/errors"
+	"learn/internal/gateway/fake"
+	"learn/internal/gateway/midtrans"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"log/slog"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// PaymentSimulatorService fires signed notifications from the fake gateway, so the
+// order -> payment -> ticket flow can run without Midtrans
+type PaymentSimulatorService interface {
+	SimulateNotification(ctx context.Context, paymentID uint, transactionStatus string) (*model.Payment, error)
+}
+
+type paymentSimulatorService struct {
+	fakeGateway    *fake.Gateway
+	paymentRepo    repository.PaymentRepository
+	orderRepo      repository.OrderRepository
+	paymentService PaymentService
+	logger         *slog.Logger
+}
+
+func NewPaymentSimulatorService(fakeGateway *fake.Gateway, paymentRepo repository.PaymentRepository, orderRepo repository.OrderRepository, paymentService PaymentService, logger *slog.Logger) PaymentSimulatorService {
+	return &paymentSimulatorService{
+		fakeGateway:    fakeGateway,
+		paymentRepo:    paymentRepo,
+		orderRepo:      orderRepo,
+		paymentService: paymentService,
+		logger:         logger,
+	}
+}
+
+func (s *paymentSimulatorService) SimulateNotification(ctx context.Context, paymentID uint, transactionStatus string) (*model.Payment, error) {
+	if !fake.IsSupportedStatus(transactionStatus) {
+		return nil, apperrors.NewValidationError("transaction_status", "unsupported transaction status", transactionStatus)
+	}
+
+	payment, err := s.paymentRepo.GetPaymentByID(paymentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("payment_exists", "payment not found")
+		}
+		s.logger.Error("failed to get payment for simulated notification", slog.Uint64("payment_id", uint64(paymentID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_payment_by_id", err)
+	}
+
+	if payment.Provider != midtrans.ProviderName {
+		return nil, apperrors.NewBusinessRuleError("payment_provider", "only Midtrans payments can be simulated")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(payment.OrderID)
+	if err != nil {
+		s.logger.Error("failed to get order for simulated notification", slog.Uint64("order_id", uint64(payment.OrderID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_order_by_id", err)
+	}
+
+	body, err := s.fakeGateway.BuildNotification(*payment, order.TotalPrice, transactionStatus)
+	if err != nil {
+		return nil, apperrors.NewSystemError("build_notification", err)
+	}
+
+	s.logger.Info("firing simulated payment notification",
+		slog.Uint64("payment_id", uint64(payment.ID)),
+		slog.String("transaction_status", transactionStatus))
+
+	if err := s.paymentService.HandleNotification(ctx, midtrans.ProviderName, http.Header{}, body); err != nil {
+		return nil, err
+	}
+
+	return s.paymentService.GetPaymentByID(payment.ID)
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/gateway"
+	"learn/internal/model"
+	"learn/internal/pkg/events"
+	"learn/internal/repository"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefundService interface {
+	RequestRefund(input dto.CreateRefundRequest, userID uint) (*dto.RefundResponse, error)
+	GetRefund(refundID uint, user model.User) (*dto.RefundResponse, error)
+	ApproveRefund(ctx context.Context, refundID uint, input dto.ReviewRefundRequest, reviewerID uint) (*dto.RefundResponse, error)
+	RejectRefund(refundID uint, input dto.ReviewRefundRequest, reviewerID uint) (*dto.RefundResponse, error)
+	SettleRefund(refundID uint, input dto.SettleRefundRequest, reviewerID uint) (*dto.RefundResponse, error)
+}
+
+type refundService struct {
+	refundRepo  repository.RefundRepository
+	orderRepo   repository.OrderRepository
+	paymentRepo repository.PaymentRepository
+	logger      *slog.Logger
+	eventBus    events.EventPublisher
+	gateways    *gateway.Registry
+}
+
+func NewRefundService(refundRepo repository.RefundRepository, orderRepo repository.OrderRepository, paymentRepo repository.PaymentRepository, logger *slog.Logger, eventBus events.EventPublisher, gateways *gateway.Registry) RefundService {
+	return &refundService{
+		refundRepo:  refundRepo,
+		orderRepo:   orderRepo,
+		paymentRepo: paymentRepo,
+		logger:      logger,
+		eventBus:    eventBus,
+		gateways:    gateways,
+	}
+}
+
+// RequestRefund lets the buyer ask for the money of some or all unused tickets of a paid order
+func (s *refundService) RequestRefund(input dto.CreateRefundRequest, userID uint) (*dto.RefundResponse, error) {
+	order, err := s.orderRepo.GetOrderByID(input.OrderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("order_exists", "order not found")
+		}
+		s.logger.Error("failed to get order for refund", slog.Uint64("order_id", uint64(input.OrderID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_order_by_id", err)
+	}
+
+	if order.UserID != userID {
+		return nil, apperrors.NewBusinessRuleError("order_authorization", "you are not authorized to refund this order")
+	}
+
+	payment, err := s.paymentRepo.GetPaymentByOrderID(order.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("payment_exists", "payment not found for this order")
+		}
+		s.logger.Error("failed to get payment for refund", slog.Uint64("order_id", uint64(order.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_payment_by_order_id", err)
+	}
+
+	if payment.PaymentStatus != model.PaymentStatusSuccess {
+		return nil, apperrors.NewBusinessRuleError("payment_status", "only successful payments can be refunded")
+	}
+
+	refund := model.Refund{
+		OrderID:           order.ID,
+		PaymentID:         payment.ID,
+		RequestedByUserID: userID,
+		Reason:            strings.TrimSpace(input.Reason),
+	}
+	if err := s.refundRepo.CreateRefund(&refund, input.TicketIDs); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRefundOrderStatus):
+			return nil, apperrors.NewBusinessRuleError("order_status", "only paid orders can be refunded")
+		case errors.Is(err, repository.ErrRefundTicketUnavailable):
+			return nil, apperrors.NewBusinessRuleError("refund_tickets", "some tickets are used, transferred, already refunded or already requested for refund")
+		}
+		s.logger.Error("failed to create refund", slog.Uint64("order_id", uint64(order.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("create_refund", err)
+	}
+
+	s.logger.Info("refund requested",
+		slog.Uint64("refund_id", uint64(refund.ID)),
+		slog.Uint64("order_id", uint64(order.ID)),
+		slog.String("type", string(refund.Type)),
+		slog.Int64("amount", refund.Amount))
+
+	return s.refundResponse(refund.ID)
+}
+
+// GetRefund returns a refund to its requester or to reviewers
+func (s *refundService) GetRefund(refundID uint, user model.User) (*dto.RefundResponse, error) {
+	refund, err := s.getRefund(refundID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.UserType == model.Attendee && refund.RequestedByUserID != user.ID {
+		return nil, apperrors.NewBusinessRuleError("refund_exists", "refund not found")
+	}
+
+	response := dto.ToRefundResponse(*refund)
+	return &response, nil
+}
+
+// ApproveRefund returns the money through the payment gateway, or records a manual
+// settlement for methods the gateway cannot refund, then voids the refunded tickets
+func (s *refundService) ApproveRefund(ctx context.Context, refundID uint, input dto.ReviewRefundRequest, reviewerID uint) (*dto.RefundResponse, error) {
+	refund, err := s.refundRepo.ClaimRefund(refundID, reviewerID, strings.TrimSpace(input.Note))
+	if err != nil {
+		return nil, s.mapRefundError(err, refundID, "claim_refund")
+	}
+
+	payment, err := s.paymentRepo.GetPaymentByID(refund.PaymentID)
+	if err != nil {
+		s.releaseRefund(refund.ID)
+		s.logger.Error("failed to get payment for refund", slog.Uint64("refund_id", uint64(refund.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_payment_by_id", err)
+	}
+
+	paymentGateway, ok := s.gateways.ByName(payment.Provider)
+	if !ok {
+		s.releaseRefund(refund.ID)
+		return nil, apperrors.NewBusinessRuleError("payment_provider", "payment provider "+payment.Provider+" is not configured")
+	}
+
+	result, err := paymentGateway.Refund(gateway.RefundRequest{
+		TransactionID: payment.TransactionID,
+		Method:        payment.PaymentMethod,
+		RefundKey:     fmt.Sprintf("REFUND-%d", refund.ID),
+		Amount:        refund.Amount,
+		Reason:        refund.Reason,
+	})
+	if err != nil {
+		s.releaseRefund(refund.ID)
+		s.logger.Error("gateway refund failed", slog.Uint64("refund_id", uint64(refund.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("gateway_refund", err)
+	}
+
+	status := model.RefundCompleted
+	if result.Mode == gateway.RefundModeManual {
+		status = model.RefundManualSettlement
+	}
+
+	// The money may already be on its way back, so the refund stays PROCESSING if this fails
+	applied, fullyRefunded, err := s.refundRepo.ApplyRefund(refund.ID, status, result.RefundKey)
+	if err != nil {
+		s.logger.Error("failed to apply approved refund",
+			slog.Uint64("refund_id", uint64(refund.ID)),
+			slog.String("refund_key", result.RefundKey),
+			slog.String("error", err.Error()))
+		if errors.Is(err, repository.ErrRefundTicketUnavailable) {
+			return nil, apperrors.NewBusinessRuleError("refund_tickets", "some tickets were used or transferred after the refund was requested")
+		}
+		return nil, apperrors.NewSystemError("apply_refund", err)
+	}
+
+	ticketIDs := make([]uint, 0, len(applied.Tickets))
+	for _, item := range applied.Tickets {
+		ticketIDs = append(ticketIDs, item.TicketID)
+	}
+
+	orderRefundedEvent := events.OrderRefundedEvent{
+		RefundID:      applied.ID,
+		OrderID:       applied.OrderID,
+		UserID:        applied.RequestedByUserID,
+		Amount:        applied.Amount,
+		TicketIDs:     ticketIDs,
+		FullyRefunded: fullyRefunded,
+		Manual:        status == model.RefundManualSettlement,
+		RefundedAt:    time.Now(),
+	}
+	s.eventBus.Publish(ctx, orderRefundedEvent)
+
+	s.logger.Info("refund approved",
+		slog.Uint64("refund_id", uint64(applied.ID)),
+		slog.Uint64("order_id", uint64(applied.OrderID)),
+		slog.String("status", string(status)),
+		slog.Bool("fully_refunded", fullyRefunded),
+		slog.Uint64("reviewer_id", uint64(reviewerID)))
+
+	return s.refundResponse(applied.ID)
+}
+
+func (s *refundService) RejectRefund(refundID uint, input dto.ReviewRefundRequest, reviewerID uint) (*dto.RefundResponse, error) {
+	refund, err := s.refundRepo.RejectRefund(refundID, reviewerID, strings.TrimSpace(input.Note))
+	if err != nil {
+		return nil, s.mapRefundError(err, refundID, "reject_refund")
+	}
+
+	s.logger.Info("refund rejected",
+		slog.Uint64("refund_id", uint64(refund.ID)),
+		slog.Uint64("reviewer_id", uint64(reviewerID)))
+
+	response := dto.ToRefundResponse(*refund)
+	return &response, nil
+}
+
+// SettleRefund marks a manual settlement refund as paid out once the money was transferred back
+func (s *refundService) SettleRefund(refundID uint, input dto.SettleRefundRequest, reviewerID uint) (*dto.RefundResponse, error) {
+	refund, err := s.refundRepo.SettleRefund(refundID, strings.TrimSpace(input.Reference))
+	if err != nil {
+		return nil, s.mapRefundError(err, refundID, "settle_refund")
+	}
+
+	s.logger.Info("manual refund settled",
+		slog.Uint64("refund_id", uint64(refund.ID)),
+		slog.Uint64("reviewer_id", uint64(reviewerID)))
+
+	response := dto.ToRefundResponse(*refund)
+	return &response, nil
+}
+
+func (s *refundService) getRefund(refundID uint) (*model.Refund, error) {
+	refund, err := s.refundRepo.GetRefundByID(refundID)
+	if err != nil {
+		return nil, s.mapRefundError(err, refundID, "get_refund_by_id")
+	}
+	return refund, nil
+}
+
+func (s *refundService) refundResponse(refundID uint) (*dto.RefundResponse, error) {
+	refund, err := s.getRefund(refundID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dto.ToRefundResponse(*refund)
+	return &response, nil
+}
+
+func (s *refundService) releaseRefund(refundID uint) {
+	if err := s.refundRepo.ReleaseRefund(refundID); err != nil {
+		s.logger.Error("failed to release refund after error", slog.Uint64("refund_id", uint64(refundID)), slog.String("error", err.Error()))
+	}
+}
+
+func (s *refundService) mapRefundError(err error, refundID uint, operation string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperrors.NewBusinessRuleError("refund_exists", "refund not found")
+	case errors.Is(err, repository.ErrRefundStatus):
+		return apperrors.NewBusinessRuleError("refund_status", "refund is not in a status that allows this action")
+	case errors.Is(err, repository.ErrRefundTicketUnavailable):
+		return apperrors.NewBusinessRuleError("refund_tickets", "some tickets were used or transferred after the refund was requested")
+	}
+	s.logger.Error("refund operation failed",
+		slog.String("operation", operation),
+		slog.Uint64("refund_id", uint64(refundID)),
+		slog.String("error", err.Error()))
+	return apperrors.NewSystemError(operation, err)
+}
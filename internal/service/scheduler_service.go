@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/model"
+	"learn/internal/pkg/cron"
+	"learn/internal/repository"
+	"log/slog"
+)
+
+// SchedulerService lets admins see the scheduled jobs and run them on demand
+type SchedulerService interface {
+	ListJobs(ctx context.Context) ([]dto.SchedulerJobResponse, error)
+	TriggerJob(ctx context.Context, name string, userID uint) error
+}
+
+type schedulerService struct {
+	scheduler *cron.Scheduler
+	runRepo   repository.SchedulerRunRepository
+	logger    *slog.Logger
+}
+
+func NewSchedulerService(scheduler *cron.Scheduler, runRepo repository.SchedulerRunRepository, logger *slog.Logger) SchedulerService {
+	return &schedulerService{scheduler: scheduler, runRepo: runRepo, logger: logger}
+}
+
+func (s *schedulerService) ListJobs(ctx context.Context) ([]dto.SchedulerJobResponse, error) {
+	statuses, err := s.scheduler.Status(ctx)
+	if err != nil {
+		s.logger.Error("failed to get scheduler job status", slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_scheduler_jobs", err)
+	}
+
+	names := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		names = append(names, status.Name)
+	}
+	lastRuns, err := s.runRepo.GetLastRuns(names)
+	if err != nil {
+		s.logger.Error("failed to get last scheduler runs", slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_scheduler_jobs", err)
+	}
+
+	jobs := make([]dto.SchedulerJobResponse, 0, len(statuses))
+	for _, status := range statuses {
+		var lastRun *model.SchedulerRun
+		if run, ok := lastRuns[status.Name]; ok {
+			lastRun = &run
+		}
+		jobs = append(jobs, dto.ToSchedulerJobResponse(status, lastRun))
+	}
+	return jobs, nil
+}
+
+func (s *schedulerService) TriggerJob(ctx context.Context, name string, userID uint) error {
+	if err := s.scheduler.Trigger(ctx, name, userID); err != nil {
+		if errors.Is(err, cron.ErrUnknownJob) {
+			return apperrors.NewBusinessRuleError("scheduler_job_exists", "scheduler job not found")
+		}
+		s.logger.Error("failed to trigger scheduler job", slog.String("job", name), slog.String("error", err.Error()))
+		return apperrors.NewSystemError("trigger_scheduler_job", err)
+	}
+
+	s.logger.Info("Scheduler job triggered", slog.String("job", name), slog.Uint64("user_id", uint64(userID)))
+	return nil
+}
@@ -1,63 +1,440 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"learn/internal/config"
 	"learn/internal/dto"
 	apperrors "learn/internal/errors"
 	"learn/internal/model"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/ticketqr"
 	"learn/internal/repository"
 	"log/slog"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type TicketService interface {
-	CheckInTicket(input dto.CheckInTicketRequest, userID uint) (*dto.CheckInTicketResponse, error)
+	CheckInTicket(ctx context.Context, input dto.CheckInTicketRequest, userID uint) (*dto.CheckInTicketResponse, error)
+	GetMyTickets(userID uint) ([]dto.TicketWalletGroup, error)
+	GetMyTicket(ticketCode string, userID uint) (*dto.TicketDetailResponse, error)
+	GetTicketQRCodePath(ticketCode string, userID uint) (string, error)
+	GetCheckInManifest(eventSlug string, deviceID string, userID uint) (*dto.CheckInManifestResponse, error)
+	SyncOfflineScans(ctx context.Context, input dto.SyncOfflineScansRequest, userID uint) (*dto.SyncOfflineScansResponse, error)
 }
 
+// offlineScanClockSkew is how far in the future an offline scan timestamp may be
+// before it is treated as a device clock problem
+const offlineScanClockSkew = 5 * time.Minute
+
 type ticketService struct {
 	ticketRepo repository.TicketRepository
+	eventRepo  repository.EventRepository
 	logger     *slog.Logger
+	eventBus   events.EventPublisher
+}
+
+func NewTicketService(ticketRepo repository.TicketRepository, eventRepo repository.EventRepository, logger *slog.Logger, eventBus events.EventPublisher) TicketService {
+	return &ticketService{ticketRepo: ticketRepo, eventRepo: eventRepo, logger: logger, eventBus: eventBus}
 }
 
-func NewTicketService(ticketRepo repository.TicketRepository, logger *slog.Logger) TicketService {
-	return &ticketService{ticketRepo: ticketRepo, logger: logger}
+func (s *ticketService) CheckInTicket(ctx context.Context, input dto.CheckInTicketRequest, userID uint) (*dto.CheckInTicketResponse, error) {
+	eventID, err := s.scannedEventID(input.EventSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket, order, err := s.checkIn(ctx, scanAttempt{
+		Content:   input.TicketCode,
+		Direction: model.ScanDirection(strings.ToUpper(input.Direction)),
+		DeviceID:  input.DeviceID,
+		Gate:      input.Gate,
+		EventID:   eventID,
+	}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dto.ToCheckInTicketResponse(*ticket, *order)
+	return &response, nil
+}
+
+// GetCheckInManifest returns the hashes of every valid ticket of an event so a scanner
+// device can keep validating tickets while it has no connectivity
+func (s *ticketService) GetCheckInManifest(eventSlug string, deviceID string, userID uint) (*dto.CheckInManifestResponse, error) {
+	event, err := s.eventRepo.FindBySlug(eventSlug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("event_exists", "event not found")
+		}
+		s.logger.Error("failed to get event for check-in manifest",
+			slog.String("event_slug", eventSlug),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_event_by_slug", err)
+	}
+
+	tickets, err := s.ticketRepo.GetPaidTicketsByEventID(event.ID)
+	if err != nil {
+		s.logger.Error("failed to get tickets for check-in manifest",
+			slog.Uint64("event_id", uint64(event.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_paid_tickets_by_event", err)
+	}
+
+	entries := make([]dto.CheckInManifestEntry, 0, len(tickets))
+	for _, ticket := range tickets {
+		entry := dto.CheckInManifestEntry{
+			TicketHash: ticketqr.Hash(ticketqr.TokenForTicket(ticket, event.ID)),
+			Type:       ticket.Type,
+			IsScanned:  ticket.IsScanned,
+		}
+		if config.AppConfig.TicketAllowPlainCodes {
+			entry.CodeHash = ticketqr.Hash(ticket.TicketCode)
+		}
+		entries = append(entries, entry)
+	}
+
+	s.logger.Info("check-in manifest issued",
+		slog.Uint64("event_id", uint64(event.ID)),
+		slog.String("device_id", deviceID),
+		slog.Uint64("user_id", uint64(userID)),
+		slog.Int("tickets", len(entries)))
+
+	return &dto.CheckInManifestResponse{
+		EventID:     event.ID,
+		EventSlug:   event.Slug,
+		DeviceID:    deviceID,
+		GeneratedAt: time.Now(),
+		Tickets:     entries,
+	}, nil
 }
 
-func (s *ticketService) CheckInTicket(input dto.CheckInTicketRequest, userID uint) (*dto.CheckInTicketResponse, error) {
-	ticketCode := strings.TrimSpace(input.TicketCode)
+// SyncOfflineScans replays scans a device recorded while offline. Scans are applied oldest
+// first through the same row-locked check-in as live scans, so when two gates scanned the
+// same ticket the earliest scan wins and the others are reported as rejected.
+func (s *ticketService) SyncOfflineScans(ctx context.Context, input dto.SyncOfflineScansRequest, userID uint) (*dto.SyncOfflineScansResponse, error) {
+	eventID, err := s.scannedEventID(input.EventSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	scans := make([]dto.OfflineScanInput, len(input.Scans))
+	copy(scans, input.Scans)
+	sort.SliceStable(scans, func(i, j int) bool {
+		return scans[i].ScannedAt.Before(scans[j].ScannedAt)
+	})
+
+	result := &dto.SyncOfflineScansResponse{
+		DeviceID: input.DeviceID,
+		Results:  make([]dto.OfflineScanResult, 0, len(scans)),
+	}
+
+	latestAllowed := time.Now().Add(offlineScanClockSkew)
+	for _, scan := range scans {
+		scanResult := dto.OfflineScanResult{ScanID: scan.ScanID, Status: dto.OfflineScanAccepted}
+
+		attempt := scanAttempt{
+			Content:   scan.TicketCode,
+			Direction: model.ScanDirection(strings.ToUpper(scan.Direction)),
+			DeviceID:  input.DeviceID,
+			Gate:      scan.Gate,
+			ScannedAt: scan.ScannedAt,
+			Offline:   true,
+			EventID:   eventID,
+		}
+
+		if scan.ScannedAt.After(latestAllowed) {
+			scanResult.Status = dto.OfflineScanRejected
+			scanResult.Reason = "scanned_at_in_future"
+		} else if ticket, _, err := s.checkIn(ctx, attempt, userID); err != nil {
+			scanResult.Status = dto.OfflineScanRejected
+			scanResult.Reason = rejectionReason(err)
+		} else {
+			scanResult.TicketID = ticket.ID
+		}
+
+		if scanResult.Status == dto.OfflineScanAccepted {
+			result.Accepted++
+		} else {
+			result.Rejected++
+		}
+		result.Results = append(result.Results, scanResult)
+	}
+
+	s.logger.Info("offline scans synced",
+		slog.String("device_id", input.DeviceID),
+		slog.Uint64("user_id", uint64(userID)),
+		slog.Int("accepted", result.Accepted),
+		slog.Int("rejected", result.Rejected))
+
+	return result, nil
+}
+
+// scanAttempt describes a single scan at the gate, either live or replayed from an offline device
+type scanAttempt struct {
+	Content   string
+	Direction model.ScanDirection
+	DeviceID  string
+	Gate      string
+	ScannedAt time.Time
+	Offline   bool
+	EventID   *uint // Event the gate is scanning for, if the device sent it
+}
+
+// scanRejectionMessages holds the messages of the rules enforced by model.ScanRejection
+var scanRejectionMessages = map[string]string{
+	"ticket_voided":          "ticket has been refunded",
+	"ticket_refund_pending":  "ticket has a pending refund",
+	"ticket_order_status":    "ticket order is not paid",
+	"ticket_already_inside":  "ticket is already checked in",
+	"ticket_already_scanned": "ticket has already been checked in",
+	"ticket_not_inside":      "ticket is not checked in",
+	"scan_direction":         "invalid scan direction",
+}
+
+// checkIn validates scanned QR content or a plain ticket code, applies the scan and records
+// the attempt in the scan audit log
+func (s *ticketService) checkIn(ctx context.Context, attempt scanAttempt, userID uint) (*model.Ticket, *model.Order, error) {
+	ticketCode := strings.TrimSpace(attempt.Content)
 	if ticketCode == "" {
-		return nil, apperrors.NewValidationError("ticket_code", "ticket code is required", input.TicketCode)
+		return nil, nil, apperrors.NewValidationError("ticket_code", "ticket code is required", attempt.Content)
+	}
+
+	if attempt.Direction == "" {
+		attempt.Direction = model.ScanIn
+	}
+	if !attempt.Direction.IsValid() {
+		return nil, nil, apperrors.NewValidationError("direction", "direction must be IN or OUT", string(attempt.Direction))
+	}
+
+	if attempt.ScannedAt.IsZero() {
+		attempt.ScannedAt = time.Now()
+	}
+
+	scan := &model.TicketScan{
+		EventID:       attempt.EventID,
+		ScannerUserID: userID,
+		DeviceID:      attempt.DeviceID,
+		Gate:          attempt.Gate,
+		Direction:     attempt.Direction,
+		Offline:       attempt.Offline,
+		ScannedAt:     attempt.ScannedAt,
+	}
+
+	var result *repository.CheckInResult
+	var err error
+
+	if ticketqr.IsSigned(ticketCode) {
+		// Reject forged or corrupted QR codes before hitting the database
+		claims, verifyErr := ticketqr.Verify(ticketCode, ticketqr.SigningKey())
+		if verifyErr != nil {
+			s.logger.Warn("rejected ticket QR code",
+				slog.Uint64("user_id", uint64(userID)),
+				slog.String("error", verifyErr.Error()))
+			s.recordRejectedScan(ctx, scan, "ticket_signature")
+			return nil, nil, apperrors.NewBusinessRuleError("ticket_signature", "invalid ticket QR code")
+		}
+
+		result, err = s.ticketRepo.CheckInTicketByQR(claims.TicketID, claims.EventID, claims.IssuedAt, scan)
+		if errors.Is(err, repository.ErrTicketQRMismatch) {
+			scan.TicketID = &claims.TicketID
+			scan.EventID = &claims.EventID
+			s.recordRejectedScan(ctx, scan, "ticket_qr_revoked")
+			return nil, nil, apperrors.NewBusinessRuleError("ticket_qr_revoked", "ticket QR code is no longer valid")
+		}
+	} else {
+		// Plain ticket codes are only accepted during the migration to signed QR codes
+		if !config.AppConfig.TicketAllowPlainCodes {
+			s.recordRejectedScan(ctx, scan, "ticket_plain_code")
+			return nil, nil, apperrors.NewBusinessRuleError("ticket_plain_code", "plain ticket codes are no longer accepted, please scan the ticket QR code")
+		}
+
+		result, err = s.ticketRepo.CheckInTicketByCode(ticketCode, scan)
 	}
 
-	ticket, order, checkedIn, err := s.ticketRepo.CheckInTicketByCode(ticketCode)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, apperrors.NewBusinessRuleError("ticket_exists", "ticket not found")
+			s.recordRejectedScan(ctx, scan, "ticket_exists")
+			return nil, nil, apperrors.NewBusinessRuleError("ticket_exists", "ticket not found")
 		}
 
 		s.logger.Error("failed to check in ticket",
-			slog.String("ticket_code", ticketCode),
 			slog.Uint64("user_id", uint64(userID)),
 			slog.String("error", err.Error()))
-		return nil, apperrors.NewSystemError("check_in_ticket", err)
+		return nil, nil, apperrors.NewSystemError("check_in_ticket", err)
 	}
 
-	if order.Status != model.OrderPaid {
-		return nil, apperrors.NewBusinessRuleError("ticket_order_status", "ticket order is not paid")
-	}
+	s.publishScan(ctx, scan, result.Ticket.EventPriceID)
 
-	if !checkedIn {
-		return nil, apperrors.NewBusinessRuleError("ticket_already_scanned", "ticket has already been checked in")
+	// Rejections decided under the ticket lock are already recorded by the repository
+	if result.Rejection != "" {
+		return nil, nil, apperrors.NewBusinessRuleError(result.Rejection, scanRejectionMessages[result.Rejection])
 	}
 
-	s.logger.Info("ticket checked in",
-		slog.String("ticket_code", ticket.TicketCode),
-		slog.Uint64("ticket_id", uint64(ticket.ID)),
-		slog.Uint64("order_id", uint64(ticket.OrderID)),
+	s.logger.Info("ticket scanned",
+		slog.String("ticket_code", result.Ticket.TicketCode),
+		slog.Uint64("ticket_id", uint64(result.Ticket.ID)),
+		slog.Uint64("order_id", uint64(result.Ticket.OrderID)),
+		slog.String("direction", string(scan.Direction)),
+		slog.String("gate", scan.Gate),
 		slog.Uint64("user_id", uint64(userID)))
 
-	response := dto.ToCheckInTicketResponse(*ticket, *order)
+	return result.Ticket, result.Order, nil
+}
+
+// scannedEventID resolves the event a scanner device says it is scanning for. Scans rejected
+// before a ticket is found only reach that event's dashboards through it.
+func (s *ticketService) scannedEventID(eventSlug string) (*uint, error) {
+	if eventSlug == "" {
+		return nil, nil
+	}
+
+	event, err := s.eventRepo.FindBySlug(eventSlug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("event_exists", "event not found")
+		}
+		s.logger.Error("failed to get event for ticket scan",
+			slog.String("event_slug", eventSlug),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_event_by_slug", err)
+	}
+
+	return &event.ID, nil
+}
+
+// recordRejectedScan stores a scan that was rejected before a ticket could be locked.
+// Failing to write the audit row must not change the answer given to the scanner.
+func (s *ticketService) recordRejectedScan(ctx context.Context, scan *model.TicketScan, reason string) {
+	scan.Result = model.ScanRejected
+	scan.Reason = reason
+	if err := s.ticketRepo.CreateTicketScan(scan); err != nil {
+		s.logger.Error("failed to record rejected ticket scan",
+			slog.Uint64("user_id", uint64(scan.ScannerUserID)),
+			slog.String("reason", reason),
+			slog.String("error", err.Error()))
+		return
+	}
+
+	s.publishScan(ctx, scan, 0)
+}
+
+// publishScan feeds recorded scans of a known event to the live attendance dashboards
+func (s *ticketService) publishScan(ctx context.Context, scan *model.TicketScan, eventPriceID uint) {
+	if scan.EventID == nil {
+		return
+	}
+
+	scannedEvent := events.TicketScannedEvent{
+		ScanID:       scan.ID,
+		EventID:      *scan.EventID,
+		EventPriceID: eventPriceID,
+		Direction:    scan.Direction,
+		Result:       scan.Result,
+		Reason:       scan.Reason,
+		Gate:         scan.Gate,
+		DeviceID:     scan.DeviceID,
+		Offline:      scan.Offline,
+		ScannedAt:    scan.ScannedAt,
+	}
+	if scan.TicketID != nil {
+		scannedEvent.TicketID = *scan.TicketID
+	}
+
+	s.eventBus.Publish(ctx, scannedEvent)
+}
+
+// rejectionReason maps a check-in error to the short reason reported back to scanner devices
+func rejectionReason(err error) string {
+	switch appErr := err.(type) {
+	case apperrors.BusinessRuleError:
+		return appErr.Rule
+	case apperrors.ValidationError:
+		return "invalid_" + appErr.Field
+	default:
+		return "system_error"
+	}
+}
+
+func (s *ticketService) GetMyTickets(userID uint) ([]dto.TicketWalletGroup, error) {
+	tickets, err := s.ticketRepo.GetTicketsByUserID(userID)
+	if err != nil {
+		s.logger.Error("failed to get tickets for user",
+			slog.Uint64("user_id", uint64(userID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_tickets_by_user", err)
+	}
+
+	return dto.ToTicketWalletGroups(tickets), nil
+}
+
+func (s *ticketService) GetMyTicket(ticketCode string, userID uint) (*dto.TicketDetailResponse, error) {
+	ticket, err := s.getOwnedTicket(ticketCode, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dto.ToTicketDetailResponse(*ticket)
 	return &response, nil
 }
+
+// GetTicketQRCodePath returns the stored QR image of a ticket, regenerating it when the file is gone
+func (s *ticketService) GetTicketQRCodePath(ticketCode string, userID uint) (string, error) {
+	ticket, err := s.getOwnedTicket(ticketCode, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if ticket.QrCodePath != "" {
+		if _, err := os.Stat(ticket.QrCodePath); err == nil {
+			return ticket.QrCodePath, nil
+		}
+	}
+
+	qrPath, err := ticketqr.GenerateTicketQRCode(*ticket, ticket.EventPrice.EventID)
+	if err != nil {
+		s.logger.Error("failed to regenerate QR code for ticket",
+			slog.Uint64("ticket_id", uint64(ticket.ID)),
+			slog.String("error", err.Error()))
+		return "", apperrors.NewSystemError("generate_qr_code", err)
+	}
+
+	if qrPath != ticket.QrCodePath {
+		if err := s.ticketRepo.UpdateQRCodePath(ticket.ID, qrPath); err != nil {
+			// The image is already on disk, so the request can still be served
+			s.logger.Error("failed to update QR code path for ticket",
+				slog.Uint64("ticket_id", uint64(ticket.ID)),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	s.logger.Info("QR code regenerated for ticket", slog.Uint64("ticket_id", uint64(ticket.ID)))
+
+	return qrPath, nil
+}
+
+func (s *ticketService) getOwnedTicket(ticketCode string, userID uint) (*model.Ticket, error) {
+	ticketCode = strings.TrimSpace(ticketCode)
+	if ticketCode == "" {
+		return nil, apperrors.NewValidationError("ticket_code", "ticket code is required", ticketCode)
+	}
+
+	ticket, err := s.ticketRepo.GetTicketByCodeForUser(ticketCode, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("ticket_exists", "ticket not found")
+		}
+
+		s.logger.Error("failed to get ticket for user",
+			slog.Uint64("user_id", uint64(userID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_ticket_by_code", err)
+	}
+
+	return ticket, nil
+}
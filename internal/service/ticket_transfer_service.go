@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/model"
+	"learn/internal/pkg/events"
+	"learn/internal/pkg/random"
+	"learn/internal/pkg/ticketqr"
+	"learn/internal/repository"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TicketTransferService interface {
+	StartTransfer(ticketCode string, input dto.StartTicketTransferRequest, user model.User) (*dto.TicketTransferResponse, error)
+	CancelTransfer(ticketCode string, userID uint) error
+	GetIncomingTransfers(user model.User) ([]dto.TicketTransferResponse, error)
+	AcceptTransfer(ctx context.Context, transferID uint, user model.User) (*dto.TicketDetailResponse, error)
+}
+
+type ticketTransferService struct {
+	transferRepo repository.TicketTransferRepository
+	ticketRepo   repository.TicketRepository
+	emailService EmailService
+	logger       *slog.Logger
+	eventBus     events.EventPublisher
+}
+
+func NewTicketTransferService(transferRepo repository.TicketTransferRepository, ticketRepo repository.TicketRepository, emailService EmailService, logger *slog.Logger, eventBus events.EventPublisher) TicketTransferService {
+	return &ticketTransferService{
+		transferRepo: transferRepo,
+		ticketRepo:   ticketRepo,
+		emailService: emailService,
+		logger:       logger,
+		eventBus:     eventBus,
+	}
+}
+
+// StartTransfer offers one of the user's tickets to whoever registers or logs in with the recipient email
+func (s *ticketTransferService) StartTransfer(ticketCode string, input dto.StartTicketTransferRequest, user model.User) (*dto.TicketTransferResponse, error) {
+	recipientEmail := strings.ToLower(strings.TrimSpace(input.RecipientEmail))
+	if strings.EqualFold(recipientEmail, user.Email) {
+		return nil, apperrors.NewValidationError("recipient_email", "cannot transfer a ticket to yourself", input.RecipientEmail)
+	}
+
+	ticket, err := s.getHeldTicket(ticketCode, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket.VoidedAt != nil {
+		return nil, apperrors.NewBusinessRuleError("ticket_voided", "ticket has been refunded")
+	}
+
+	if ticket.IsScanned {
+		return nil, apperrors.NewBusinessRuleError("ticket_already_scanned", "ticket has already been checked in")
+	}
+
+	if err := checkTransferWindow(ticket.EventPrice.Event); err != nil {
+		return nil, err
+	}
+
+	transfer := model.TicketTransfer{
+		TicketID:   ticket.ID,
+		FromUserID: user.ID,
+		ToEmail:    recipientEmail,
+	}
+	if err := s.transferRepo.CreateTransfer(&transfer); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTicketTransferPending):
+			return nil, apperrors.NewBusinessRuleError("ticket_transfer_pending", "ticket already has a pending transfer")
+		case errors.Is(err, repository.ErrTicketRefundPending):
+			return nil, apperrors.NewBusinessRuleError("ticket_refund_pending", "ticket has a pending refund")
+		}
+		s.logger.Error("failed to create ticket transfer",
+			slog.Uint64("ticket_id", uint64(ticket.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("create_ticket_transfer", err)
+	}
+
+	// The recipient also finds the transfer in their incoming list, so a failed email is not fatal
+	if err := s.emailService.SendTicketTransferInvite(recipientEmail, user.Name, ticket.EventPrice.Event.Name); err != nil {
+		s.logger.Error("failed to send ticket transfer invite",
+			slog.Uint64("transfer_id", uint64(transfer.ID)),
+			slog.String("error", err.Error()))
+	}
+
+	s.logger.Info("ticket transfer started",
+		slog.Uint64("transfer_id", uint64(transfer.ID)),
+		slog.Uint64("ticket_id", uint64(ticket.ID)),
+		slog.Uint64("user_id", uint64(user.ID)))
+
+	transfer.Ticket = *ticket
+	response := dto.ToTicketTransferResponse(transfer)
+	return &response, nil
+}
+
+func (s *ticketTransferService) CancelTransfer(ticketCode string, userID uint) error {
+	ticket, err := s.getHeldTicket(ticketCode, userID)
+	if err != nil {
+		return err
+	}
+
+	transfer, err := s.transferRepo.GetPendingTransferByTicketID(ticket.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.NewBusinessRuleError("ticket_transfer_exists", "ticket has no pending transfer")
+		}
+		s.logger.Error("failed to get pending ticket transfer",
+			slog.Uint64("ticket_id", uint64(ticket.ID)),
+			slog.String("error", err.Error()))
+		return apperrors.NewSystemError("get_pending_ticket_transfer", err)
+	}
+
+	if err := s.transferRepo.CancelTransfer(transfer.ID); err != nil {
+		if errors.Is(err, repository.ErrTicketTransferNotPending) {
+			return apperrors.NewBusinessRuleError("ticket_transfer_status", "ticket transfer is no longer pending")
+		}
+		s.logger.Error("failed to cancel ticket transfer",
+			slog.Uint64("transfer_id", uint64(transfer.ID)),
+			slog.String("error", err.Error()))
+		return apperrors.NewSystemError("cancel_ticket_transfer", err)
+	}
+
+	s.logger.Info("ticket transfer cancelled",
+		slog.Uint64("transfer_id", uint64(transfer.ID)),
+		slog.Uint64("user_id", uint64(userID)))
+
+	return nil
+}
+
+func (s *ticketTransferService) GetIncomingTransfers(user model.User) ([]dto.TicketTransferResponse, error) {
+	transfers, err := s.transferRepo.GetPendingTransfersByEmail(user.Email)
+	if err != nil {
+		s.logger.Error("failed to get incoming ticket transfers",
+			slog.Uint64("user_id", uint64(user.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_incoming_ticket_transfers", err)
+	}
+
+	return dto.ToTicketTransferResponses(transfers), nil
+}
+
+// AcceptTransfer moves the ticket to the user and issues a new ticket code and QR, so the
+// sender's copy of the ticket stops working
+func (s *ticketTransferService) AcceptTransfer(ctx context.Context, transferID uint, user model.User) (*dto.TicketDetailResponse, error) {
+	transfer, err := s.transferRepo.GetTransferByID(transferID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("ticket_transfer_exists", "ticket transfer not found")
+		}
+		s.logger.Error("failed to get ticket transfer",
+			slog.Uint64("transfer_id", uint64(transferID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_ticket_transfer", err)
+	}
+
+	// Transfers addressed to someone else are reported as missing
+	if !strings.EqualFold(transfer.ToEmail, user.Email) {
+		return nil, apperrors.NewBusinessRuleError("ticket_transfer_exists", "ticket transfer not found")
+	}
+
+	if transfer.Status != model.TransferPending {
+		return nil, apperrors.NewBusinessRuleError("ticket_transfer_status", "ticket transfer is no longer pending")
+	}
+
+	event := transfer.Ticket.EventPrice.Event
+	if err := checkTransferWindow(event); err != nil {
+		return nil, err
+	}
+
+	oldQRPath := transfer.Ticket.QrCodePath
+	_, ticket, err := s.transferRepo.AcceptTransfer(transfer.ID, user, repository.TicketReissue{
+		TicketCode: random.String(10),
+		QrIssuedAt: time.Now(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTicketTransferNotPending):
+			return nil, apperrors.NewBusinessRuleError("ticket_transfer_status", "ticket transfer is no longer pending")
+		case errors.Is(err, repository.ErrTicketTransferStale):
+			return nil, apperrors.NewBusinessRuleError("ticket_transfer_stale", "ticket can no longer be transferred")
+		case errors.Is(err, repository.ErrTicketRefundPending):
+			return nil, apperrors.NewBusinessRuleError("ticket_refund_pending", "ticket has a pending refund")
+		}
+		s.logger.Error("failed to accept ticket transfer",
+			slog.Uint64("transfer_id", uint64(transfer.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("accept_ticket_transfer", err)
+	}
+
+	// A missing image is regenerated when the ticket QR is downloaded, so failures are only logged
+	qrPath, err := ticketqr.GenerateTicketQRCode(*ticket, event.ID)
+	if err != nil {
+		s.logger.Error("failed to generate QR code for transferred ticket",
+			slog.Uint64("ticket_id", uint64(ticket.ID)),
+			slog.String("error", err.Error()))
+	} else if err := s.ticketRepo.UpdateQRCodePath(ticket.ID, qrPath); err != nil {
+		s.logger.Error("failed to update QR code path for transferred ticket",
+			slog.Uint64("ticket_id", uint64(ticket.ID)),
+			slog.String("error", err.Error()))
+	} else {
+		ticket.QrCodePath = qrPath
+	}
+
+	if oldQRPath != "" && oldQRPath != ticket.QrCodePath {
+		os.Remove(oldQRPath)
+	}
+
+	transferredEvent := events.TicketTransferredEvent{
+		TransferID:    transfer.ID,
+		TicketID:      ticket.ID,
+		EventID:       event.ID,
+		FromUserID:    transfer.FromUserID,
+		ToUserID:      user.ID,
+		TransferredAt: time.Now(),
+	}
+	s.eventBus.Publish(ctx, transferredEvent)
+
+	s.logger.Info("ticket transfer accepted",
+		slog.Uint64("transfer_id", uint64(transfer.ID)),
+		slog.Uint64("ticket_id", uint64(ticket.ID)),
+		slog.Uint64("from_user_id", uint64(transfer.FromUserID)),
+		slog.Uint64("to_user_id", uint64(user.ID)))
+
+	ticket.EventPrice = transfer.Ticket.EventPrice
+	response := dto.ToTicketDetailResponse(*ticket)
+	return &response, nil
+}
+
+func (s *ticketTransferService) getHeldTicket(ticketCode string, userID uint) (*model.Ticket, error) {
+	ticketCode = strings.TrimSpace(ticketCode)
+	if ticketCode == "" {
+		return nil, apperrors.NewValidationError("ticket_code", "ticket code is required", ticketCode)
+	}
+
+	ticket, err := s.ticketRepo.GetTicketByCodeForUser(ticketCode, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("ticket_exists", "ticket not found")
+		}
+		s.logger.Error("failed to get ticket for user",
+			slog.Uint64("user_id", uint64(userID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_ticket_by_code", err)
+	}
+
+	return ticket, nil
+}
+
+// checkTransferWindow enforces the organizer's transfer switch and cutoff of an event
+func checkTransferWindow(event model.Event) error {
+	if event.TransfersDisabled {
+		return apperrors.NewBusinessRuleError("ticket_transfer_disabled", "ticket transfers are disabled for this event")
+	}
+	if !time.Now().Before(event.TransferDeadline()) {
+		return apperrors.NewBusinessRuleError("ticket_transfer_cutoff", "ticket transfers are closed for this event")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"learn/internal/config"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/model"
+	"learn/internal/pkg/waitingroom"
+	"learn/internal/repository"
+	"log/slog"
+	"time"
+)
+
+// waitingRoomKeepAfterSales is how long the queue of an event outlives its sales period
+const waitingRoomKeepAfterSales = time.Hour
+
+// WaitingRoomService queues buyers of events with the waiting room on and lets them in at the rate
+// of the event
+type WaitingRoomService interface {
+	Join(ctx context.Context, slug string, userID uint) (*dto.WaitingRoomStatusResponse, error)
+	GetStatus(ctx context.Context, slug string, userID uint) (*dto.WaitingRoomStatusResponse, error)
+	AdmitDue(ctx context.Context) (string, error)
+}
+
+type waitingRoomService struct {
+	eventRepo repository.EventRepository
+	room      *waitingroom.Room
+	logger    *slog.Logger
+}
+
+func NewWaitingRoomService(eventRepo repository.EventRepository, room *waitingroom.Room, logger *slog.Logger) WaitingRoomService {
+	return &waitingRoomService{eventRepo: eventRepo, room: room, logger: logger}
+}
+
+// Join puts the user at the back of the queue. Users can join before sales open, nobody is let in
+// until they do. Joining again keeps the place in the queue.
+func (s *waitingRoomService) Join(ctx context.Context, slug string, userID uint) (*dto.WaitingRoomStatusResponse, error) {
+	event, err := s.waitingRoomEvent(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if event.Status != model.Published {
+		return nil, apperrors.NewBusinessRuleError("event_published", "event is not published")
+	}
+	if now.After(event.SalesEndDate) {
+		return nil, apperrors.NewBusinessRuleError("event_sales_period", "event sales have ended")
+	}
+
+	position, err := s.room.Join(ctx, event.ID, userID, keepFor(event, now))
+	if err != nil {
+		s.logger.Error("failed to join waiting room", slog.Uint64("event_id", uint64(event.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("join_waiting_room", err)
+	}
+	return s.statusResponse(event, userID, position, now), nil
+}
+
+func (s *waitingRoomService) GetStatus(ctx context.Context, slug string, userID uint) (*dto.WaitingRoomStatusResponse, error) {
+	event, err := s.waitingRoomEvent(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := s.room.Position(ctx, event.ID, userID)
+	if err != nil {
+		s.logger.Error("failed to get waiting room position", slog.Uint64("event_id", uint64(event.ID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_waiting_room_status", err)
+	}
+	return s.statusResponse(event, userID, position, time.Now()), nil
+}
+
+// AdmitDue lets in the users each event on sale has earned since the previous run. It runs as a
+// scheduled job, on one node at a time.
+func (s *waitingRoomService) AdmitDue(ctx context.Context) (string, error) {
+	now := time.Now()
+	events, err := s.eventRepo.GetWaitingRoomEventsOnSale(now)
+	if err != nil {
+		return "", fmt.Errorf("find waiting room events: %w", err)
+	}
+	if len(events) == 0 {
+		return "no waiting room events on sale", nil
+	}
+
+	admitted, failed := int64(0), 0
+	for _, event := range events {
+		if ctx.Err() != nil {
+			break
+		}
+
+		keep := keepFor(&event, now)
+		credit, err := s.room.AdmissionCredit(ctx, event.ID, admitPerMinute(&event), time.Minute, keep)
+		if err == nil {
+			var count int64
+			count, err = s.room.Admit(ctx, event.ID, credit, config.AppConfig.WaitingRoomAdmissionTTL, keep)
+			admitted += count
+		}
+		if err != nil {
+			s.logger.Error("failed to admit from waiting room",
+				slog.Uint64("event_id", uint64(event.ID)),
+				slog.String("error", err.Error()))
+			failed++
+		}
+	}
+
+	summary := fmt.Sprintf("events %d, admitted %d, failed %d", len(events), admitted, failed)
+	if failed > 0 {
+		return summary, fmt.Errorf("failed to admit for %d of %d events", failed, len(events))
+	}
+	return summary, nil
+}
+
+func (s *waitingRoomService) waitingRoomEvent(slug string) (*model.Event, error) {
+	event, err := s.eventRepo.FindBySlug(slug)
+	if err != nil {
+		return nil, apperrors.NewBusinessRuleError("event_exists", "event not found")
+	}
+	if !event.WaitingRoomEnabled {
+		return nil, apperrors.NewBusinessRuleError("waiting_room_enabled", "event has no waiting room")
+	}
+	return event, nil
+}
+
+func (s *waitingRoomService) statusResponse(event *model.Event, userID uint, position waitingroom.Position, now time.Time) *dto.WaitingRoomStatusResponse {
+	rate := admitPerMinute(event)
+	response := &dto.WaitingRoomStatusResponse{
+		EventID:        event.ID,
+		State:          dto.WaitingRoomNotInQueue,
+		QueueLength:    position.QueueLength,
+		AdmitPerMinute: rate,
+		SalesStartAt:   event.SalesStartDate,
+		ServerTime:     now,
+	}
+
+	switch {
+	case position.Admitted:
+		expiresAt := position.AdmissionExpiresAt
+		response.State = dto.WaitingRoomAdmitted
+		response.AdmissionExpiresAt = &expiresAt
+		response.AdmissionToken = waitingroom.Sign(waitingroom.Claims{
+			EventID:   event.ID,
+			UserID:    userID,
+			ExpiresAt: expiresAt,
+		}, waitingroom.SigningKey())
+	case position.Position > 0:
+		response.State = dto.WaitingRoomWaiting
+		response.Position = position.Position
+
+		// Users ahead are let in at the rate once sales open
+		wait := time.Duration(position.Position) * time.Minute / time.Duration(rate)
+		if now.Before(event.SalesStartDate) {
+			wait += event.SalesStartDate.Sub(now)
+		}
+		response.EstimatedWaitSeconds = int64(wait.Seconds())
+	}
+	return response
+}
+
+// admitPerMinute is the admission rate of the event, falling back to WAITING_ROOM_ADMIT_PER_MINUTE
+func admitPerMinute(event *model.Event) int {
+	if event.WaitingRoomAdmitPerMinute > 0 {
+		return event.WaitingRoomAdmitPerMinute
+	}
+	if config.AppConfig.WaitingRoomAdmitPerMinute > 0 {
+		return config.AppConfig.WaitingRoomAdmitPerMinute
+	}
+	return 1
+}
+
+// keepFor is how long the waiting room keys of the event should live from now
+func keepFor(event *model.Event, now time.Time) time.Duration {
+	keep := event.SalesEndDate.Sub(now) + waitingRoomKeepAfterSales
+	if keep < waitingRoomKeepAfterSales {
+		return waitingRoomKeepAfterSales
+	}
+	return keep
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learn/internal/config"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/model"
+	"learn/internal/repository"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WaitlistService lets buyers wait for sold-out price tiers and offers them the quota that comes
+// back from cancelled, expired and refunded orders
+type WaitlistService interface {
+	Join(ctx context.Context, input dto.JoinWaitlistRequest, userID uint) (*model.WaitlistEntry, error)
+	GetMyEntries(userID uint) ([]model.WaitlistEntry, error)
+	Leave(ctx context.Context, entryID uint, userID uint) (*model.WaitlistEntry, error)
+	GetSummary(eventSlug string) (*dto.WaitlistSummaryResponse, error)
+	OfferReturnedQuota(ctx context.Context) (string, error)
+}
+
+type waitlistService struct {
+	waitlistRepo repository.WaitlistRepository
+	eventRepo    repository.EventRepository
+	emailService EmailService
+	logger       *slog.Logger
+}
+
+func NewWaitlistService(waitlistRepo repository.WaitlistRepository, eventRepo repository.EventRepository, emailService EmailService, logger *slog.Logger) WaitlistService {
+	return &waitlistService{
+		waitlistRepo: waitlistRepo,
+		eventRepo:    eventRepo,
+		emailService: emailService,
+		logger:       logger,
+	}
+}
+
+// Join puts the user at the back of the waitlist of a tier that cannot sell the requested quantity
+func (s *waitlistService) Join(ctx context.Context, input dto.JoinWaitlistRequest, userID uint) (*model.WaitlistEntry, error) {
+	price, err := s.eventRepo.GetEventPriceByID(input.EventPriceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("event_prices_exist", "price not found")
+		}
+		return nil, apperrors.NewSystemError("get_event_price", err)
+	}
+
+	event, err := s.eventRepo.GetEventByID(price.EventID)
+	if err != nil {
+		return nil, apperrors.NewBusinessRuleError("event_exists", "event not found")
+	}
+	if event.Status != model.Published {
+		return nil, apperrors.NewBusinessRuleError("event_published", "event is not published")
+	}
+	if time.Now().After(event.SalesEndDate) {
+		return nil, apperrors.NewBusinessRuleError("event_sales_period", "event sales have ended")
+	}
+
+	entry := &model.WaitlistEntry{
+		EventPriceID: price.ID,
+		UserID:       userID,
+		Quantity:     input.Quantity,
+	}
+	if err := s.waitlistRepo.CreateEntry(ctx, entry); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrWaitlistNotSoldOut):
+			return nil, apperrors.NewBusinessRuleError("waitlist_sold_out", "tickets of this price are still available, order them instead")
+		case errors.Is(err, repository.ErrWaitlistEntryExists), errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, apperrors.NewBusinessRuleError("waitlist_entry_exists", "you are already on the waitlist of this price")
+		}
+		s.logger.Error("failed to join waitlist",
+			slog.Uint64("event_price_id", uint64(price.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("join_waitlist", err)
+	}
+
+	s.logger.Info("User joined waitlist",
+		slog.Uint64("entry_id", uint64(entry.ID)),
+		slog.Uint64("event_price_id", uint64(price.ID)),
+		slog.Uint64("user_id", uint64(userID)))
+
+	return s.waitlistRepo.GetEntryByID(entry.ID)
+}
+
+func (s *waitlistService) GetMyEntries(userID uint) ([]model.WaitlistEntry, error) {
+	entries, err := s.waitlistRepo.GetUserEntries(userID)
+	if err != nil {
+		s.logger.Error("failed to get waitlist entries", slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_waitlist_entries", err)
+	}
+	return entries, nil
+}
+
+// Leave takes the user off the waitlist. Quota held by an open offer passes to the next user.
+func (s *waitlistService) Leave(ctx context.Context, entryID uint, userID uint) (*model.WaitlistEntry, error) {
+	entry, err := s.waitlistRepo.GetEntryByID(entryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("waitlist_entry_exists", "waitlist entry not found")
+		}
+		return nil, apperrors.NewSystemError("get_waitlist_entry", err)
+	}
+	if entry.UserID != userID {
+		return nil, apperrors.NewBusinessRuleError("waitlist_authorization", "you are not authorized to access this waitlist entry")
+	}
+
+	if _, err := s.waitlistRepo.LeaveEntry(ctx, entryID); err != nil {
+		if errors.Is(err, repository.ErrWaitlistEntryStatus) {
+			return nil, apperrors.NewBusinessRuleError("waitlist_entry_status", "waitlist entry is no longer active")
+		}
+		s.logger.Error("failed to leave waitlist",
+			slog.Uint64("entry_id", uint64(entryID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("leave_waitlist", err)
+	}
+
+	return s.waitlistRepo.GetEntryByID(entryID)
+}
+
+// GetSummary returns the waitlist size and offer conversion of every tier of an event
+func (s *waitlistService) GetSummary(eventSlug string) (*dto.WaitlistSummaryResponse, error) {
+	event, err := s.eventRepo.FindBySlug(eventSlug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("event_exists", "event not found")
+		}
+		return nil, apperrors.NewSystemError("get_event_by_slug", err)
+	}
+
+	tiers, err := s.waitlistRepo.GetTierStats(event.ID)
+	if err != nil {
+		s.logger.Error("failed to get waitlist stats",
+			slog.Uint64("event_id", uint64(event.ID)),
+			slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_waitlist_stats", err)
+	}
+
+	summary := &dto.WaitlistSummaryResponse{
+		EventID:   event.ID,
+		EventSlug: event.Slug,
+		Tiers:     make([]dto.WaitlistTierResponse, 0, len(tiers)),
+	}
+	for _, tier := range tiers {
+		var conversionRate float64
+		if decided := tier.Converted + tier.Expired; decided > 0 {
+			conversionRate = float64(tier.Converted) / float64(decided)
+		}
+
+		summary.Waiting += tier.Waiting
+		summary.Converted += tier.Converted
+		summary.Tiers = append(summary.Tiers, dto.WaitlistTierResponse{
+			EventPriceID:    tier.EventPriceID,
+			Name:            tier.Name,
+			Waiting:         tier.Waiting,
+			WaitingQuantity: tier.WaitingQuantity,
+			Offered:         tier.Offered,
+			Converted:       tier.Converted,
+			Expired:         tier.Expired,
+			Left:            tier.Left,
+			ConversionRate:  conversionRate,
+		})
+	}
+	return summary, nil
+}
+
+// OfferReturnedQuota expires unused offers and offers the quota of every tier with users waiting,
+// then emails the new offers. It runs as a scheduled job, on one node at a time.
+func (s *waitlistService) OfferReturnedQuota(ctx context.Context) (string, error) {
+	priceIDs, err := s.waitlistRepo.GetPriceIDsToOffer(time.Now())
+	if err != nil {
+		return "", fmt.Errorf("find waitlisted prices: %w", err)
+	}
+	if len(priceIDs) == 0 {
+		return "no quota to offer", nil
+	}
+
+	offered, failed := 0, 0
+	for _, priceID := range priceIDs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		now := time.Now()
+		entries, err := s.waitlistRepo.OfferQuota(ctx, priceID, now, now.Add(config.AppConfig.WaitlistOfferWindow))
+		if err != nil {
+			s.logger.Error("failed to offer waitlist quota",
+				slog.Uint64("event_price_id", uint64(priceID)),
+				slog.String("error", err.Error()))
+			failed++
+			continue
+		}
+
+		// The offer stands even if the email fails, the user also sees it in their waitlist
+		for _, entry := range entries {
+			offered++
+			if err := s.emailService.SendWaitlistOffer(entry.User.Email, entry.EventPrice.Event.Name, entry.EventPrice.Name, entry.Quantity, *entry.OfferExpiresAt); err != nil {
+				s.logger.Error("failed to send waitlist offer",
+					slog.Uint64("entry_id", uint64(entry.ID)),
+					slog.String("error", err.Error()))
+			}
+		}
+	}
+
+	summary := fmt.Sprintf("prices %d, offered %d, failed %d", len(priceIDs), offered, failed)
+	if failed > 0 {
+		return summary, fmt.Errorf("failed to offer quota of %d of %d prices", failed, len(priceIDs))
+	}
+	return summary, nil
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"learn/internal/config"
+	"learn/internal/dto"
+	apperrors "learn/internal/errors"
+	"learn/internal/model"
+	"learn/internal/pkg/random"
+	"learn/internal/pkg/webhook"
+	"learn/internal/repository"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// webhookDispatchBatchSize is the number of deliveries sent concurrently per dispatch round
+	webhookDispatchBatchSize = 20
+	webhookRequestTimeout    = 10 * time.Second
+	// webhookClaimLease keeps claimed deliveries away from other dispatchers while they are sent
+	webhookClaimLease     = webhookRequestTimeout + time.Minute
+	webhookMaxBackoff     = 6 * time.Hour
+	webhookResponseLimit  = 1024 // Bytes of the response body kept in the delivery log
+	webhookSecretLength   = 40
+	webhookMaxEndpoints   = 10 // Per user
+	webhookUserAgent      = "learn-webhooks/1"
+	webhookContentType    = "application/json"
+	webhookDisabledReason = "disabled by user"
+)
+
+// WebhookService manages the webhook endpoints of organizers and sends the queued deliveries
+type WebhookService interface {
+	CreateEndpoint(input dto.CreateWebhookEndpointRequest, userID uint) (*dto.WebhookEndpointCreatedResponse, error)
+	GetEndpoints(userID uint) ([]dto.WebhookEndpointResponse, error)
+	GetEndpoint(endpointID uint, userID uint) (*dto.WebhookEndpointResponse, error)
+	UpdateEndpoint(endpointID uint, input dto.UpdateWebhookEndpointRequest, userID uint) (*dto.WebhookEndpointResponse, error)
+	DeleteEndpoint(endpointID uint, userID uint) error
+	RedeliverDelivery(endpointID uint, deliveryID uint, userID uint) (*dto.WebhookDeliveryResponse, error)
+	DispatchDue() (int, error)
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	eventRepo   repository.EventRepository
+	client      *http.Client
+	logger      *slog.Logger
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository, eventRepo repository.EventRepository, logger *slog.Logger) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		eventRepo:   eventRepo,
+		client: &http.Client{
+			Timeout: webhookRequestTimeout,
+			// Deliveries never reach loopback, private or link-local addresses, whatever the URL resolves to
+			Transport: webhook.NewTransport(),
+			// A redirect is reported as a failed delivery instead of being followed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger: logger,
+	}
+}
+
+func (s *webhookService) CreateEndpoint(input dto.CreateWebhookEndpointRequest, userID uint) (*dto.WebhookEndpointCreatedResponse, error) {
+	if err := validateWebhookURL(input.URL); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webhookRepo.GetEndpointsByUserID(userID)
+	if err != nil {
+		s.logger.Error("failed to count webhook endpoints", slog.Uint64("user_id", uint64(userID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_webhook_endpoints", err)
+	}
+	if len(existing) >= webhookMaxEndpoints {
+		return nil, apperrors.NewBusinessRuleError("webhook_endpoint_limit", fmt.Sprintf("at most %d webhook endpoints are allowed", webhookMaxEndpoints))
+	}
+
+	endpoint := model.WebhookEndpoint{
+		UserID:      userID,
+		URL:         input.URL,
+		Secret:      random.String(webhookSecretLength),
+		EventTypes:  joinWebhookEventTypes(input.EventTypes),
+		Description: input.Description,
+		Active:      true,
+	}
+	if input.EventSlug != "" {
+		event, err := s.getEvent(input.EventSlug)
+		if err != nil {
+			return nil, err
+		}
+		endpoint.EventID = &event.ID
+		endpoint.Event = event
+	}
+
+	if err := s.webhookRepo.CreateEndpoint(&endpoint); err != nil {
+		s.logger.Error("failed to create webhook endpoint", slog.Uint64("user_id", uint64(userID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("create_webhook_endpoint", err)
+	}
+
+	s.logger.Info("Webhook endpoint created", slog.Uint64("endpoint_id", uint64(endpoint.ID)), slog.Uint64("user_id", uint64(userID)))
+	return &dto.WebhookEndpointCreatedResponse{
+		WebhookEndpointResponse: dto.ToWebhookEndpointResponse(endpoint),
+		Secret:                  endpoint.Secret,
+	}, nil
+}
+
+func (s *webhookService) GetEndpoints(userID uint) ([]dto.WebhookEndpointResponse, error) {
+	endpoints, err := s.webhookRepo.GetEndpointsByUserID(userID)
+	if err != nil {
+		s.logger.Error("failed to get webhook endpoints", slog.Uint64("user_id", uint64(userID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_webhook_endpoints", err)
+	}
+	return dto.ToWebhookEndpointResponses(endpoints), nil
+}
+
+func (s *webhookService) GetEndpoint(endpointID uint, userID uint) (*dto.WebhookEndpointResponse, error) {
+	endpoint, err := s.getEndpoint(endpointID, userID)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToWebhookEndpointResponse(*endpoint)
+	return &response, nil
+}
+
+func (s *webhookService) UpdateEndpoint(endpointID uint, input dto.UpdateWebhookEndpointRequest, userID uint) (*dto.WebhookEndpointResponse, error) {
+	endpoint, err := s.getEndpoint(endpointID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.URL != nil {
+		if err := validateWebhookURL(*input.URL); err != nil {
+			return nil, err
+		}
+		endpoint.URL = *input.URL
+	}
+	if input.EventTypes != nil {
+		endpoint.EventTypes = joinWebhookEventTypes(input.EventTypes)
+	}
+	if input.Description != nil {
+		endpoint.Description = *input.Description
+	}
+	if input.EventSlug != nil {
+		endpoint.EventID = nil
+		endpoint.Event = nil
+		if *input.EventSlug != "" {
+			event, err := s.getEvent(*input.EventSlug)
+			if err != nil {
+				return nil, err
+			}
+			endpoint.EventID = &event.ID
+			endpoint.Event = event
+		}
+	}
+	if input.Active != nil && *input.Active != endpoint.Active {
+		endpoint.Active = *input.Active
+		if endpoint.Active {
+			endpoint.ConsecutiveFailures = 0
+			endpoint.DisabledAt = nil
+			endpoint.DisabledReason = ""
+		} else {
+			now := time.Now()
+			endpoint.DisabledAt = &now
+			endpoint.DisabledReason = webhookDisabledReason
+		}
+	}
+
+	if err := s.webhookRepo.UpdateEndpoint(endpoint); err != nil {
+		s.logger.Error("failed to update webhook endpoint", slog.Uint64("endpoint_id", uint64(endpointID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("update_webhook_endpoint", err)
+	}
+
+	response := dto.ToWebhookEndpointResponse(*endpoint)
+	return &response, nil
+}
+
+func (s *webhookService) DeleteEndpoint(endpointID uint, userID uint) error {
+	if _, err := s.getEndpoint(endpointID, userID); err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.DeleteEndpoint(endpointID); err != nil {
+		s.logger.Error("failed to delete webhook endpoint", slog.Uint64("endpoint_id", uint64(endpointID)), slog.String("error", err.Error()))
+		return apperrors.NewSystemError("delete_webhook_endpoint", err)
+	}
+	return nil
+}
+
+// RedeliverDelivery queues a delivery again with the same payload and a fresh attempt count
+func (s *webhookService) RedeliverDelivery(endpointID uint, deliveryID uint, userID uint) (*dto.WebhookDeliveryResponse, error) {
+	endpoint, err := s.getEndpoint(endpointID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !endpoint.Active {
+		return nil, apperrors.NewBusinessRuleError("webhook_endpoint_active", "webhook endpoint is disabled, enable it before redelivering")
+	}
+
+	delivery, err := s.webhookRepo.GetDeliveryForEndpoint(deliveryID, endpointID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("webhook_delivery_exists", "webhook delivery not found")
+		}
+		s.logger.Error("failed to get webhook delivery", slog.Uint64("delivery_id", uint64(deliveryID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_webhook_delivery", err)
+	}
+
+	if err := s.webhookRepo.ResetDelivery(delivery.ID); err != nil {
+		s.logger.Error("failed to reset webhook delivery", slog.Uint64("delivery_id", uint64(deliveryID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("reset_webhook_delivery", err)
+	}
+
+	delivery, err = s.webhookRepo.GetDeliveryForEndpoint(deliveryID, endpointID)
+	if err != nil {
+		return nil, apperrors.NewSystemError("get_webhook_delivery", err)
+	}
+	response := dto.ToWebhookDeliveryResponse(*delivery)
+	return &response, nil
+}
+
+// DispatchDue sends the deliveries that are due, batch by batch until none are left, and returns
+// the number of attempts made
+func (s *webhookService) DispatchDue() (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := s.webhookRepo.ClaimDueDeliveries(webhookDispatchBatchSize, webhookClaimLease)
+		if err != nil {
+			return attempted, err
+		}
+
+		var wg sync.WaitGroup
+		for i := range deliveries {
+			wg.Add(1)
+			go func(delivery *model.WebhookDelivery) {
+				defer wg.Done()
+				s.deliver(delivery)
+			}(&deliveries[i])
+		}
+		wg.Wait()
+
+		attempted += len(deliveries)
+		if len(deliveries) < webhookDispatchBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// deliver sends a delivery once and records the outcome, scheduling a retry with exponential
+// backoff until the maximum attempts are reached
+func (s *webhookService) deliver(delivery *model.WebhookDelivery) {
+	attempt := s.send(delivery)
+
+	var nextAttemptAt *time.Time
+	attempts := delivery.Attempts + 1
+	if attempt.Error != "" && attempts < config.AppConfig.WebhookMaxAttempts {
+		backoff := config.AppConfig.WebhookRetryBackoff << (attempts - 1)
+		if backoff <= 0 || backoff > webhookMaxBackoff {
+			backoff = webhookMaxBackoff
+		}
+		next := attempt.At.Add(backoff)
+		nextAttemptAt = &next
+	}
+
+	disabled, err := s.webhookRepo.RecordAttempt(delivery, attempt, nextAttemptAt, config.AppConfig.WebhookDisableAfter)
+	if err != nil {
+		s.logger.Error("failed to record webhook attempt", slog.Uint64("delivery_id", uint64(delivery.ID)), slog.String("error", err.Error()))
+		return
+	}
+
+	if attempt.Error != "" {
+		s.logger.Warn("webhook delivery failed",
+			slog.Uint64("delivery_id", uint64(delivery.ID)),
+			slog.Uint64("endpoint_id", uint64(delivery.EndpointID)),
+			slog.Int("attempt", attempts),
+			slog.Bool("retrying", nextAttemptAt != nil),
+			slog.String("error", attempt.Error))
+	}
+	if disabled {
+		s.logger.Warn("webhook endpoint disabled after repeated failures", slog.Uint64("endpoint_id", uint64(delivery.EndpointID)))
+	}
+}
+
+func (s *webhookService) send(delivery *model.WebhookDelivery) repository.WebhookAttempt {
+	now := time.Now()
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, delivery.Endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return repository.WebhookAttempt{Error: err.Error(), At: now}
+	}
+	req.Header.Set("Content-Type", webhookContentType)
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(webhook.EventHeader, string(delivery.EventType))
+	req.Header.Set(webhook.DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(delivery.Endpoint.Secret, now, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return repository.WebhookAttempt{Error: err.Error(), At: now}
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	attempt := repository.WebhookAttempt{StatusCode: resp.StatusCode, Response: string(responseBody), At: now}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = "unexpected status " + resp.Status
+	}
+	return attempt
+}
+
+func (s *webhookService) getEndpoint(endpointID uint, userID uint) (*model.WebhookEndpoint, error) {
+	endpoint, err := s.webhookRepo.GetEndpointForUser(endpointID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("webhook_endpoint_exists", "webhook endpoint not found")
+		}
+		s.logger.Error("failed to get webhook endpoint", slog.Uint64("endpoint_id", uint64(endpointID)), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_webhook_endpoint", err)
+	}
+	return endpoint, nil
+}
+
+func (s *webhookService) getEvent(eventSlug string) (*model.Event, error) {
+	event, err := s.eventRepo.FindBySlug(eventSlug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewBusinessRuleError("event_exists", "event not found")
+		}
+		s.logger.Error("failed to get event for webhook endpoint", slog.String("event_slug", eventSlug), slog.String("error", err.Error()))
+		return nil, apperrors.NewSystemError("get_event_by_slug", err)
+	}
+	return event, nil
+}
+
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return apperrors.NewValidationError("url", "webhook URL must be an absolute http or https URL", rawURL)
+	}
+	if err := webhook.CheckHost(parsed.Hostname()); err != nil {
+		return apperrors.NewValidationError("url", "webhook URL must not point to a loopback, private or link-local address", rawURL)
+	}
+	return nil
+}
+
+// joinWebhookEventTypes stores the event types once each, in the order given
+func joinWebhookEventTypes(eventTypes []model.WebhookEventType) string {
+	seen := make(map[model.WebhookEventType]bool, len(eventTypes))
+	types := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !seen[eventType] {
+			seen[eventType] = true
+			types = append(types, string(eventType))
+		}
+	}
+	return strings.Join(types, ",")
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"io"
+	"learn/internal/config"
+	"learn/internal/model"
+	"learn/internal/pkg/webhook"
+	"learn/internal/repository"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhookRepository keeps deliveries in memory. Every pending delivery of an active endpoint is
+// due, so a test runs one attempt per DispatchDue call without waiting for the backoff.
+type fakeWebhookRepository struct {
+	repository.WebhookRepository
+
+	mu         sync.Mutex
+	endpoint   *model.WebhookEndpoint
+	deliveries []*model.WebhookDelivery
+}
+
+func (r *fakeWebhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []model.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == model.WebhookDeliveryPending && r.endpoint.Active && len(due) < limit {
+			claimed := *delivery
+			claimed.Endpoint = *r.endpoint
+			due = append(due, claimed)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeWebhookRepository) RecordAttempt(delivery *model.WebhookDelivery, attempt repository.WebhookAttempt, nextAttemptAt *time.Time, disableAfter int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.deliveries[delivery.ID-1]
+	stored.Attempts++
+	stored.LastAttemptAt = &attempt.At
+	stored.LastStatusCode = attempt.StatusCode
+	stored.LastError = attempt.Error
+	stored.LastResponse = attempt.Response
+	switch {
+	case attempt.Error == "":
+		stored.Status = model.WebhookDeliveryDelivered
+		stored.DeliveredAt = &attempt.At
+	case nextAttemptAt != nil:
+		stored.NextAttemptAt = *nextAttemptAt
+	default:
+		stored.Status = model.WebhookDeliveryFailed
+	}
+
+	if attempt.Error == "" {
+		r.endpoint.ConsecutiveFailures = 0
+		return false, nil
+	}
+	return r.endpoint.RecordFailure(attempt.At, attempt.Error, disableAfter), nil
+}
+
+func (r *fakeWebhookRepository) queue(payload string) *model.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery := &model.WebhookDelivery{
+		EndpointID: r.endpoint.ID,
+		EventType:  model.WebhookOrderPaid,
+		Payload:    payload,
+		Status:     model.WebhookDeliveryPending,
+	}
+	delivery.ID = uint(len(r.deliveries) + 1)
+	r.deliveries = append(r.deliveries, delivery)
+	return delivery
+}
+
+// receiver is a local HTTP endpoint answering with the given status codes in turn, the last one repeats
+type receiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	requests int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != webhookContentType {
+		rc.t.Errorf("got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+	}
+	if err := webhook.Verify(rc.secret, r.Header.Get(webhook.SignatureHeader), body, time.Minute); err != nil {
+		rc.t.Errorf("signature of delivery %s: %v", r.Header.Get(webhook.DeliveryHeader), err)
+	}
+
+	rc.mu.Lock()
+	status := rc.statuses[min(rc.requests, len(rc.statuses)-1)]
+	rc.requests++
+	rc.mu.Unlock()
+
+	if status == http.StatusFound {
+		w.Header().Set("Location", "/elsewhere")
+	}
+	w.WriteHeader(status)
+	w.Write([]byte("status " + strconv.Itoa(status)))
+}
+
+func newWebhookTest(t *testing.T, maxAttempts int, backoff time.Duration, disableAfter int, statuses ...int) (*webhookService, *fakeWebhookRepository, *receiver) {
+	t.Helper()
+
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+	config.AppConfig.WebhookMaxAttempts = maxAttempts
+	config.AppConfig.WebhookRetryBackoff = backoff
+	config.AppConfig.WebhookDisableAfter = disableAfter
+
+	rc := &receiver{t: t, secret: "whsec_test", statuses: statuses}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	endpoint := &model.WebhookEndpoint{URL: server.URL, Secret: rc.secret, Active: true}
+	endpoint.ID = 1
+	repo := &fakeWebhookRepository{endpoint: endpoint}
+
+	s := NewWebhookService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil))).(*webhookService)
+	// The receiver listens on loopback, which the production transport refuses
+	s.client.Transport = http.DefaultTransport
+	return s, repo, rc
+}
+
+func dispatch(t *testing.T, s *webhookService, want int) {
+	t.Helper()
+	attempted, err := s.DispatchDue()
+	if err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	if attempted != want {
+		t.Fatalf("DispatchDue attempted %d deliveries, want %d", attempted, want)
+	}
+}
+
+func TestWebhookDeliverySucceeds(t *testing.T) {
+	s, repo, rc := newWebhookTest(t, 5, time.Minute, 3, http.StatusNoContent)
+	repo.endpoint.ConsecutiveFailures = 2
+	delivery := repo.queue(`{"event":"order.paid","data":{"order_id":7}}`)
+
+	dispatch(t, s, 1)
+	dispatch(t, s, 0)
+
+	if delivery.Status != model.WebhookDeliveryDelivered || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Fatalf("delivery = %s after %d attempt(s), want DELIVERED after 1", delivery.Status, delivery.Attempts)
+	}
+	if delivery.LastStatusCode != http.StatusNoContent || delivery.LastError != "" {
+		t.Fatalf("last attempt = %d %q, want 204 without error", delivery.LastStatusCode, delivery.LastError)
+	}
+	if repo.endpoint.ConsecutiveFailures != 0 {
+		t.Fatalf("consecutive failures = %d, want 0 after a success", repo.endpoint.ConsecutiveFailures)
+	}
+	if rc.requests != 1 {
+		t.Fatalf("receiver got %d requests, want 1", rc.requests)
+	}
+}
+
+func TestWebhookDeliveryRetriesWithBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		backoff  time.Duration
+		statuses []int
+		want     []time.Duration // Delay before each retry
+	}{
+		{"server errors", time.Minute, []int{http.StatusInternalServerError}, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}},
+		{"redirects are not followed", time.Minute, []int{http.StatusFound}, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}},
+		{"capped at the maximum backoff", 2 * time.Hour, []int{http.StatusBadGateway}, []time.Duration{2 * time.Hour, 4 * time.Hour, webhookMaxBackoff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxAttempts := len(tt.want) + 1
+			s, repo, rc := newWebhookTest(t, maxAttempts, tt.backoff, 100, tt.statuses...)
+			delivery := repo.queue(`{"event":"order.paid"}`)
+
+			for i, wantDelay := range tt.want {
+				dispatch(t, s, 1)
+				if delivery.Status != model.WebhookDeliveryPending {
+					t.Fatalf("attempt %d: delivery = %s, want PENDING", i+1, delivery.Status)
+				}
+				if delay := delivery.NextAttemptAt.Sub(*delivery.LastAttemptAt); delay != wantDelay {
+					t.Fatalf("attempt %d: next attempt in %s, want %s", i+1, delay, wantDelay)
+				}
+			}
+
+			dispatch(t, s, 1)
+			if delivery.Status != model.WebhookDeliveryFailed || delivery.Attempts != maxAttempts {
+				t.Fatalf("delivery = %s after %d attempt(s), want FAILED after %d", delivery.Status, delivery.Attempts, maxAttempts)
+			}
+			if delivery.LastError == "" || delivery.LastStatusCode != tt.statuses[0] {
+				t.Fatalf("last attempt = %d %q, want status %d with an error", delivery.LastStatusCode, delivery.LastError, tt.statuses[0])
+			}
+
+			dispatch(t, s, 0)
+			if rc.requests != maxAttempts {
+				t.Fatalf("receiver got %d requests, want %d", rc.requests, maxAttempts)
+			}
+		})
+	}
+}
+
+func TestWebhookEndpointDisabledAfterConsecutiveFailures(t *testing.T) {
+	// Two failures, a success that resets the count, then failures until the endpoint is disabled
+	s, repo, rc := newWebhookTest(t, 10, time.Minute, 3,
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK, http.StatusServiceUnavailable)
+
+	first := repo.queue(`{"n":1}`)
+	dispatch(t, s, 1)
+	dispatch(t, s, 1)
+	dispatch(t, s, 1)
+	if first.Status != model.WebhookDeliveryDelivered || repo.endpoint.ConsecutiveFailures != 0 || !repo.endpoint.Active {
+		t.Fatalf("after a success: delivery %s, %d failures, active %v", first.Status, repo.endpoint.ConsecutiveFailures, repo.endpoint.Active)
+	}
+
+	second := repo.queue(`{"n":2}`)
+	dispatch(t, s, 1)
+	dispatch(t, s, 1)
+	if !repo.endpoint.Active {
+		t.Fatal("endpoint disabled after 2 consecutive failures, want 3")
+	}
+	dispatch(t, s, 1)
+	if repo.endpoint.Active || repo.endpoint.DisabledAt == nil || repo.endpoint.DisabledReason == "" {
+		t.Fatalf("endpoint active %v after 3 consecutive failures, want disabled with a reason", repo.endpoint.Active)
+	}
+
+	// Deliveries of a disabled endpoint stay pending and are not sent
+	dispatch(t, s, 0)
+	if second.Status != model.WebhookDeliveryPending || second.Attempts != 3 {
+		t.Fatalf("delivery = %s after %d attempt(s), want PENDING after 3", second.Status, second.Attempts)
+	}
+	if rc.requests != 6 {
+		t.Fatalf("receiver got %d requests, want 6", rc.requests)
+	}
+}
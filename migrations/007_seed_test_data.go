@@ -7,6 +7,8 @@ import (
 	"learn/internal/pkg/qrcode"
 	"learn/internal/pkg/random"
 	"learn/internal/pkg/slug"
+	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -139,21 +141,48 @@ func seedTestEvents(db *gorm.DB) ([]uint, map[string]uint, error) {
 	priceIDs := make(map[string]uint)
 
 	for _, e := range events {
-		if err := db.Where("slug = ?", e.Slug).FirstOrCreate(&model.Event{}, e.Event).Error; err != nil {
-			return nil, nil, fmt.Errorf("failed to seed event %s: %w", e.Name, err)
-		}
-
+		// Events and prices are written with the columns of this migration version, later
+		// migrations fill in the defaults of the columns they add
 		var savedEvent model.Event
-		db.Where("slug = ?", e.Slug).First(&savedEvent)
+		if err := db.Where("slug = ?", e.Slug).First(&savedEvent).Error; err != nil {
+			now := time.Now()
+			eventID, err := insertRow(db, "events", map[string]interface{}{
+				"created_at":       now,
+				"updated_at":       now,
+				"venue_id":         e.VenueID,
+				"name":             e.Name,
+				"slug":             e.Slug,
+				"description":      e.Description,
+				"event_start_at":   e.EventStartAt,
+				"status":           string(e.Status),
+				"sales_start_date": e.SalesStartDate,
+				"sales_end_date":   e.SalesEndDate,
+			})
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to seed event %s: %w", e.Name, err)
+			}
+			savedEvent.ID = eventID
+			savedEvent.Slug = e.Slug
+		}
 		eventIDs = append(eventIDs, savedEvent.ID)
 
 		for _, p := range e.prices {
-			price := model.EventPrice{EventID: savedEvent.ID, Name: p.name, Price: p.price, Quota: p.quota}
-			if err := db.Where("event_id = ? AND name = ?", savedEvent.ID, p.name).FirstOrCreate(&model.EventPrice{}, price).Error; err != nil {
-				return nil, nil, fmt.Errorf("failed to seed event price %s: %w", p.name, err)
-			}
 			var savedPrice model.EventPrice
-			db.Where("event_id = ? AND name = ?", savedEvent.ID, p.name).First(&savedPrice)
+			if err := db.Where("event_id = ? AND name = ?", savedEvent.ID, p.name).First(&savedPrice).Error; err != nil {
+				now := time.Now()
+				priceID, err := insertRow(db, "event_prices", map[string]interface{}{
+					"created_at": now,
+					"updated_at": now,
+					"event_id":   savedEvent.ID,
+					"name":       p.name,
+					"price":      p.price,
+					"quota":      p.quota,
+				})
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to seed event price %s: %w", p.name, err)
+				}
+				savedPrice.ID = priceID
+			}
 			priceIDs[savedEvent.Slug+"-"+p.name] = savedPrice.ID
 		}
 	}
@@ -221,58 +250,95 @@ func seedTestOrders(db *gorm.DB, eventIDs []uint, priceIDs map[string]uint) erro
 			})
 		}
 
-		order := model.Order{
-			UserID:     user.ID,
-			TotalPrice: totalPrice,
-			Status:     model.OrderPaid,
-			PaymentDue: time.Now().Add(24 * time.Hour),
-		}
-
-		if err := db.Create(&order).Error; err != nil {
+		now := time.Now()
+		orderID, err := insertRow(db, "orders", map[string]interface{}{
+			"created_at":  now,
+			"updated_at":  now,
+			"user_id":     user.ID,
+			"total_price": totalPrice,
+			"status":      string(model.OrderPaid),
+			"payment_due": now.Add(24 * time.Hour),
+		})
+		if err != nil {
 			return fmt.Errorf("failed to create order for user %s: %w", user.Email, err)
 		}
+		order := model.Order{UserID: user.ID, TotalPrice: totalPrice, Status: model.OrderPaid}
+		order.ID = orderID
 
 		for liIdx := range lineItems {
 			lineItems[liIdx].OrderID = order.ID
-		}
-		if err := db.Create(&lineItems).Error; err != nil {
-			return fmt.Errorf("failed to create order line items: %w", err)
+			if _, err := insertRow(db, "order_line_items", map[string]interface{}{
+				"created_at":     now,
+				"updated_at":     now,
+				"order_id":       order.ID,
+				"event_price_id": lineItems[liIdx].EventPriceID,
+				"quantity":       lineItems[liIdx].Quantity,
+				"price_per_unit": lineItems[liIdx].PricePerUnit,
+				"total_price":    lineItems[liIdx].TotalPrice,
+			}); err != nil {
+				return fmt.Errorf("failed to create order line items: %w", err)
+			}
 		}
 
-		var tickets []model.Ticket
+		// Tickets are written with the columns of this migration version, later columns such as
+		// qr_issued_at are filled by the migrations that add them
+		ticketCount := 0
 		seatIdx := 0
 		for _, li := range lineItems {
 			for range li.Quantity {
 				ticketCode := random.String(10)
-				qrPath, qrErr := qrcode.GenerateQRCodePNG(config.AppConfig.StorageQRPath, ticketCode)
+				qrPath, qrErr := qrcode.GenerateQRCodePNG(config.AppConfig.StorageQRPath, ticketCode, ticketCode)
 				if qrErr != nil {
 					fmt.Printf("  [WARN] failed to generate QR for %s: %v\n", ticketCode, qrErr)
 				}
-				ticket := model.Ticket{
-					OrderID:      order.ID,
-					EventPriceID: li.EventPriceID,
-					Price:        li.PricePerUnit,
-					Type:         "General",
-					TicketCode:   ticketCode,
-					QrCodePath:   qrPath,
-					SeatNumber:   spec.seatNumbers[seatIdx],
-					OwnerName:    user.Name,
-					OwnerEmail:   user.Email,
+				if _, err := insertRow(db, "tickets", map[string]interface{}{
+					"created_at":     now,
+					"updated_at":     now,
+					"order_id":       order.ID,
+					"event_price_id": li.EventPriceID,
+					"price":          li.PricePerUnit,
+					"type":           "General",
+					"ticket_code":    ticketCode,
+					"qr_code_path":   qrPath,
+					"seat_number":    spec.seatNumbers[seatIdx],
+					"owner_name":     user.Name,
+					"owner_email":    user.Email,
+				}); err != nil {
+					return fmt.Errorf("failed to create tickets for order %d: %w", order.ID, err)
 				}
-				tickets = append(tickets, ticket)
+				ticketCount++
 				seatIdx++
 			}
 		}
-		if err := db.Create(&tickets).Error; err != nil {
-			return fmt.Errorf("failed to create tickets for order %d: %w", order.ID, err)
-		}
-		fmt.Printf("Order #%d for %s (%s) — %d tickets with QR codes\n", order.ID, user.Email, spec.eventSlug, len(tickets))
+		fmt.Printf("Order #%d for %s (%s) — %d tickets with QR codes\n", order.ID, user.Email, spec.eventSlug, ticketCount)
 	}
 
 	fmt.Println("Orders with PAID status, tickets, and QR codes generated")
 	return nil
 }
 
+// insertRow inserts a row with exactly the given columns and returns its ID. Seeds use it instead of
+// the models, which also carry the columns added by later migrations.
+func insertRow(db *gorm.DB, table string, row map[string]interface{}) (uint, error) {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	placeholders := make([]string, len(columns))
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		placeholders[i] = "?"
+		values[i] = row[column]
+	}
+
+	var id uint
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	err := db.Raw(query, values...).Scan(&id).Error
+	return id, err
+}
+
 func isMigrationApplied(db *gorm.DB, version string) bool {
 	var count int64
 	db.Table("migrations").Where("version = ?", version).Count(&count)
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("009", "Add QR issue time to tickets", migrate009)
+}
+
+func migrate009(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.Ticket{}); err != nil {
+		return err
+	}
+
+	// Existing tickets get their creation time so regenerated QR codes are stable
+	return db.Exec("UPDATE tickets SET qr_issued_at = created_at WHERE qr_issued_at IS NULL").Error
+}
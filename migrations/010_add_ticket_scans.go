@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("010", "Add ticket scan audit log and re-entry policy", migrate010)
+}
+
+func migrate010(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.Event{}, &model.Ticket{}, &model.TicketScan{}); err != nil {
+		return err
+	}
+
+	// Tickets scanned before check-out existed are treated as still inside the venue
+	return db.Exec("UPDATE tickets SET is_inside = is_scanned").Error
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("011", "Add ticket transfers", migrate011)
+}
+
+func migrate011(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.Event{}, &model.Ticket{}, &model.TicketTransfer{}); err != nil {
+		return err
+	}
+
+	// Existing events get the default transfer cutoff
+	return db.Exec("UPDATE events SET transfer_cutoff_hours = ? WHERE transfer_cutoff_hours IS NULL", model.DefaultTransferCutoffHours).Error
+}
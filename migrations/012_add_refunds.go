@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("012", "Add refunds and voided tickets", migrate012)
+}
+
+func migrate012(db *gorm.DB) error {
+	return db.AutoMigrate(&model.Ticket{}, &model.Refund{}, &model.RefundTicket{})
+}
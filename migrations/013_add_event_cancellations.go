@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("013", "Add event cancellation runs", migrate013)
+}
+
+func migrate013(db *gorm.DB) error {
+	return db.AutoMigrate(&model.EventCancellation{})
+}
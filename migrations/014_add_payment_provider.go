@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("014", "Add payment provider", migrate014)
+}
+
+// migrate014 adds the provider column; existing payments were all charged through Midtrans
+func migrate014(db *gorm.DB) error {
+	return db.AutoMigrate(&model.Payment{})
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("015", "Add payment reconciliation issues", migrate015)
+}
+
+func migrate015(db *gorm.DB) error {
+	return db.AutoMigrate(&model.PaymentReconciliationIssue{})
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("016", "Add outbox events", migrate016)
+}
+
+func migrate016(db *gorm.DB) error {
+	return db.AutoMigrate(&model.OutboxEvent{})
+}
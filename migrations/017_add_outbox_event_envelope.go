@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("017", "Add envelope fields to outbox events", migrate017)
+}
+
+// migrate017 adds the envelope fields, rows already in the outbox become version 1
+func migrate017(db *gorm.DB) error {
+	return db.AutoMigrate(&model.OutboxEvent{})
+}
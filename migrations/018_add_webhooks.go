@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("018", "Add webhook endpoints and deliveries", migrate018)
+}
+
+func migrate018(db *gorm.DB) error {
+	return db.AutoMigrate(&model.WebhookEndpoint{}, &model.WebhookDelivery{})
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("019", "Add scheduler runs", migrate019)
+}
+
+func migrate019(db *gorm.DB) error {
+	return db.AutoMigrate(&model.SchedulerRun{})
+}
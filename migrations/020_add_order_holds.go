@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("020", "Add order holds and per event payment due", migrate020)
+}
+
+func migrate020(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.Order{}, &model.Event{}); err != nil {
+		return err
+	}
+
+	// The expiration job looks up pending orders by payment due every few seconds
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_orders_status_payment_due ON orders (status, payment_due)").Error
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("021", "Add waiting room settings to events", migrate021)
+}
+
+func migrate021(db *gorm.DB) error {
+	return db.AutoMigrate(&model.Event{})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"learn/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("022", "Add price tier waitlist", migrate022)
+}
+
+func migrate022(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.EventPrice{}, &model.WaitlistEntry{}); err != nil {
+		return err
+	}
+
+	// A user has at most one active entry per price tier
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_waitlist_entries_active_user
+		ON waitlist_entries (event_price_id, user_id)
+		WHERE status IN ('WAITING', 'OFFERED') AND deleted_at IS NULL`).Error
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"fmt"
+	"learn/internal/model"
+	"learn/internal/pkg/ticketqr"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration("023", "Regenerate ticket QR codes with signed tokens", migrate023)
+}
+
+// migrate023 rewrites the QR image of every ticket issued before QR codes were signed. Those images
+// hold the plain ticket code under the same file name the signed image uses, so without this the
+// download endpoint keeps serving a code that only passes check-in while plain codes are accepted.
+func migrate023(db *gorm.DB) error {
+	var rows []struct {
+		ID         uint
+		TicketCode string
+		QrIssuedAt time.Time
+		EventID    uint
+	}
+	if err := db.Table("tickets").
+		Select("tickets.id, tickets.ticket_code, tickets.qr_issued_at, event_prices.event_id").
+		Joins("JOIN event_prices ON event_prices.id = tickets.event_price_id").
+		Where("tickets.deleted_at IS NULL").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		ticket := model.Ticket{TicketCode: row.TicketCode, QrIssuedAt: row.QrIssuedAt}
+		ticket.ID = row.ID
+
+		qrPath, err := ticketqr.GenerateTicketQRCode(ticket, row.EventID)
+		if err != nil {
+			return fmt.Errorf("failed to regenerate QR for ticket %d: %w", row.ID, err)
+		}
+		if err := db.Table("tickets").Where("id = ?", row.ID).Update("qr_code_path", qrPath).Error; err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Regenerated %d ticket QR codes with signed tokens\n", len(rows))
+	return nil
+}